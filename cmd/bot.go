@@ -4,8 +4,11 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"net/http"
+
 	"github.com/amirdaaee/TGMon/internal/bot"
 	"github.com/amirdaaee/TGMon/internal/config"
+	"github.com/amirdaaee/TGMon/internal/health"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -36,7 +39,8 @@ var botCmd = &cobra.Command{
 			logrus.WithError(err).Fatal("can not build worker pool")
 		}
 		// ...
-		mediafacade := buildMediaFacade(dbContainer, wp)
+		mediafacade := buildMediaFacade(dbContainer, wp, buildJobReqFacade(dbContainer), nil)
+		tagFacade := buildTagFacade(dbContainer)
 		ll.Info("media facade built")
 		// ...
 		myBot, err := bot.NewBot(tgClient)
@@ -45,7 +49,7 @@ var botCmd = &cobra.Command{
 		}
 		ll.Info("bot built")
 		// ...
-		hndler, err := bot.NewHandler(mediafacade, config.Config().TelegramConfig.ChannelID, wp)
+		hndler, err := bot.NewHandler(mediafacade, tagFacade, dbContainer.GetMinioContainer().GetMinioClient(), config.Config().TelegramConfig.ChannelID, wp)
 		if err != nil {
 			logrus.WithError(err).Fatal("can not build bot handler")
 		}
@@ -53,6 +57,17 @@ var botCmd = &cobra.Command{
 		hndler.Register(myBot)
 		ll.Info("handler registered")
 		// ...
+		checker := buildHealthChecker(dbContainer, wp)
+		checker.Add("bot", myBot.Ping)
+		if addr := config.Config().HealthConfig.BotListenAddr; addr != "" {
+			go func() {
+				ll.Infof("serving health checks on %s", addr)
+				if err := http.ListenAndServe(addr, health.NewHandler(checker)); err != nil {
+					logrus.WithError(err).Error("can not serve health checks")
+				}
+			}()
+		}
+		// ...
 		ll.Warn("starting listening for messages")
 		if err := myBot.Start(); err != nil {
 			logrus.WithError(err).Fatal("can not start bot")
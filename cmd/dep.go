@@ -2,19 +2,26 @@ package cmd
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 
+	"github.com/amirdaaee/TGMon/internal/auth"
 	"github.com/amirdaaee/TGMon/internal/config"
 	"github.com/amirdaaee/TGMon/internal/db"
 	"github.com/amirdaaee/TGMon/internal/db/minio"
 	"github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/dlna"
 	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/filesystem"
+	"github.com/amirdaaee/TGMon/internal/health"
 	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/s3"
 	"github.com/amirdaaee/TGMon/internal/stream"
 	"github.com/amirdaaee/TGMon/internal/tlg"
 	"github.com/amirdaaee/TGMon/internal/types"
 	realMinio "github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
 )
 
 func buildDbContainer() (db.IDbContainer, error) {
@@ -60,17 +67,95 @@ func buildWorkerPool() (stream.IWorkerPool, error) {
 	}
 	return wp, nil
 }
-func buildMediaFacade(dbContainer db.IDbContainer, workerContainer stream.IWorkerPool) facade.IFacade[types.MediaFileDoc] {
+func buildMediaFacade(dbContainer db.IDbContainer, workerContainer stream.IWorkerPool, jobReqFacade facade.IFacade[types.JobReqDoc], invalidators []facade.IMediaCacheInvalidator, observers ...facade.IFacadeObserver[types.MediaFileDoc]) facade.IFacade[types.MediaFileDoc] {
 	cfg := config.Config()
-	return facade.NewFacade(facade.NewMediaCrud(dbContainer, workerContainer, cfg.RuntimeConfig.KeepDupFiles))
+	sCfg := cfg.StashRedirectorConfig
+	osHash := sCfg.Enabled && sCfg.StashEndpoint != "" // only Stash identifies files by oshash
+	return facade.NewFacade(facade.NewMediaCrud(dbContainer, workerContainer, jobReqFacade, cfg.RuntimeConfig.KeepDupFiles, osHash, invalidators...), observers...)
 }
-func buildJobReqFacade(dbContainer db.IDbContainer) facade.IFacade[types.JobReqDoc] {
-	return facade.NewFacade(facade.NewJobReqCrud(dbContainer))
+func buildJobReqFacade(dbContainer db.IDbContainer, observers ...facade.IFacadeObserver[types.JobReqDoc]) facade.IFacade[types.JobReqDoc] {
+	return facade.NewFacade(facade.NewJobReqCrud(dbContainer), observers...)
 }
-func buildJobResFacade(dbContainer db.IDbContainer) facade.IFacade[types.JobResDoc] {
-	return facade.NewFacade(facade.NewJobResCrud(dbContainer))
+func buildJobResFacade(dbContainer db.IDbContainer, jobReqFacade facade.IFacade[types.JobReqDoc], observers ...facade.IFacadeObserver[types.JobResDoc]) facade.IFacade[types.JobResDoc] {
+	return facade.NewFacade(facade.NewJobResCrud(dbContainer, jobReqFacade), observers...)
+}
+func buildTagFacade(dbContainer db.IDbContainer) facade.IFacade[types.TagDoc] {
+	return facade.NewFacade(facade.NewTagCrud(dbContainer))
+}
+func buildPlaylistFacade(dbContainer db.IDbContainer) facade.IFacade[types.PlaylistDoc] {
+	return facade.NewFacade(facade.NewPlaylistCrud(dbContainer))
+}
+func buildUserFacade(dbContainer db.IDbContainer) facade.IFacade[types.UserDoc] {
+	return facade.NewFacade(facade.NewUserCrud(dbContainer))
+}
+func buildApiKeyFacade(dbContainer db.IDbContainer) facade.IFacade[types.ApiKeyDoc] {
+	return facade.NewFacade(facade.NewApiKeyCrud(dbContainer))
+}
+func buildWatchProgressFacade(dbContainer db.IDbContainer) facade.IFacade[types.WatchProgressDoc] {
+	return facade.NewFacade(facade.NewWatchProgressCrud(dbContainer))
+}
+func buildAuthenticator(dbContainer db.IDbContainer) (auth.IAuthenticator, error) {
+	cfg := config.Config()
+	secret := []byte(cfg.AuthConfig.SessionSecret)
+	if len(secret) == 0 {
+		logrus.Warn("AUTH__SESSION_SECRET is not set. using a random secret; sessions will not survive restarts")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("can not generate session secret: %w", err)
+		}
+	}
+	return auth.NewAuthenticator(dbContainer, auth.Config{
+		Secret:           secret,
+		SessionTTL:       cfg.AuthConfig.SessionTTL,
+		MaxLoginFailures: cfg.AuthConfig.MaxLoginFailures,
+		LockoutDuration:  cfg.AuthConfig.LockoutDuration,
+		StaticToken:      cfg.HttpConfig.ApiToken,
+		StreamUrlTTL:     cfg.AuthConfig.StreamUrlTTL,
+	}), nil
 }
 func setupLogger() {
 	cfg := config.Config()
 	log.Setup(cfg.RuntimeConfig.LogLevel)
 }
+
+// buildS3Gateway returns the s3 gateway, or nil when it is disabled.
+func buildS3Gateway(fs *filesystem.DavFS, authenticator auth.IAuthenticator) *s3.Gateway {
+	sCfg := config.Config().S3Config
+	if !sCfg.Enabled {
+		return nil
+	}
+	return s3.NewGateway(s3.Config{Bucket: sCfg.Bucket, Region: sCfg.Region}, fs, authenticator)
+}
+
+// buildDlnaServer returns the dlna media server, or nil when it is disabled.
+func buildDlnaServer(mediaFacade facade.IFacade[types.MediaFileDoc], tagFacade facade.IFacade[types.TagDoc], streamSigner auth.IStreamSigner, minioClient minio.IMinioClient) *dlna.Server {
+	cfg := config.Config()
+	dCfg := cfg.DlnaConfig
+	if !dCfg.Enabled {
+		return nil
+	}
+	dlnaCfg := dlna.Config{
+		FriendlyName: dCfg.FriendlyName,
+		BaseUrl:      dCfg.PublicUrl,
+		MinioClient:  minioClient,
+		PresignTTL:   cfg.HttpConfig.AssetPresignTTL,
+		LanOnly:      dCfg.LanOnly,
+	}
+	if dlnaCfg.BaseUrl == "" {
+		dlnaCfg.BaseUrl = cfg.HttpConfig.PublicUrl
+	}
+	if cfg.HttpConfig.StreamAuth {
+		dlnaCfg.StreamSigner = streamSigner
+	}
+	return dlna.NewServer(dlnaCfg, mediaFacade, tagFacade)
+}
+
+// buildHealthChecker returns the readiness checks shared by all processes: mongo, the minio bucket and the workers.
+func buildHealthChecker(dbContainer db.IDbContainer, wp stream.IWorkerPool) *health.Checker {
+	hCfg := config.Config().HealthConfig
+	checker := health.NewChecker(hCfg.Timeout)
+	checker.Add("mongo", dbContainer.GetMongoContainer().GetMongoClient().Ping)
+	checker.Add("minio", dbContainer.GetMinioContainer().GetMinioClient().Ping)
+	checker.Add("workers", health.WorkersCheck(wp, hCfg.MinWorkers))
+	return checker
+}
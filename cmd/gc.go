@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/config"
+	"github.com/amirdaaee/TGMon/internal/reconcile"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var gcOpts struct {
+	action string
+	grace  time.Duration
+	json   bool
+}
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Collect the minio objects no media references and report the missing ones",
+	Long: `Lists the minio bucket and reports the objects no media references, and the media referencing objects that do not exist.
+With --action QUARANTINE, orphan objects are moved under the quarantine/ prefix; with --action DELETE, they are deleted.
+Orphan objects younger than --grace are kept, as their media may not reference them yet. Objects quarantined for longer
+than --grace are deleted by both actions.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		dbContainer, err := buildDbContainer()
+		if err != nil {
+			logrus.WithError(err).Fatal("can not build db container")
+		}
+		// the collector only lists media, so no worker is needed
+		mediafacade := buildMediaFacade(dbContainer, nil, buildJobReqFacade(dbContainer), nil)
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		opts := buildGCOptions()
+		if cmd.Flags().Changed("action") {
+			opts.Action = reconcile.GCActionEnum(strings.ToUpper(gcOpts.action))
+		}
+		if cmd.Flags().Changed("grace") {
+			opts.GracePeriod = gcOpts.grace
+		}
+		report, err := reconcile.NewObjectCollector(mediafacade, dbContainer.GetMinioContainer().GetMinioClient()).Run(ctx, opts)
+		if err != nil {
+			logrus.WithError(err).Fatal("can not collect objects")
+		}
+		if gcOpts.json {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				logrus.WithError(err).Fatal("can not write report")
+			}
+			return
+		}
+		printGCReport(report)
+	},
+}
+
+func init() {
+	gcCmd.Flags().StringVar(&gcOpts.action, "action", "", "REPORT, QUARANTINE or DELETE orphan objects (or set GC__ACTION)")
+	gcCmd.Flags().DurationVar(&gcOpts.grace, "grace", 0, "keep orphan objects modified more recently (or set GC__GRACE_PERIOD)")
+	gcCmd.Flags().BoolVar(&gcOpts.json, "json", false, "print the report as json")
+	rootCmd.AddCommand(gcCmd)
+}
+
+// buildGCOptions returns the garbage collection options of the config.
+func buildGCOptions() reconcile.GCOptions {
+	gCfg := config.Config().GCConfig
+	return reconcile.GCOptions{
+		Action:      reconcile.GCActionEnum(strings.ToUpper(gCfg.Action)),
+		GracePeriod: gCfg.GracePeriod,
+	}
+}
+
+// printGCReport writes a report for humans to stdout.
+func printGCReport(report *reconcile.GCReport) {
+	fmt.Printf("listed %d objects in %s (%s): %d referenced, %d recent, %d bytes quarantined, %d bytes reclaimed\n",
+		report.Objects, report.FinishedAt.Sub(report.StartedAt).Round(time.Second), report.Action, report.Referenced, report.Recent, report.Quarantined, report.Reclaimed)
+	for _, kind := range []reconcile.ObjectIssueEnum{reconcile.ORPHANObjectIssue, reconcile.MISSINGObjectIssue, reconcile.EXPIREDObjectIssue} {
+		fmt.Printf("%s: %d\n", kind, report.Count(kind))
+	}
+	for _, i := range report.Issues {
+		line := fmt.Sprintf("%-7s %s", i.Kind, i.Object)
+		if i.MediaID != nil {
+			line += fmt.Sprintf(", media %s (%s)", i.MediaID.Hex(), i.Field)
+		} else {
+			line += fmt.Sprintf(", %d bytes", i.Size)
+		}
+		switch {
+		case i.Fixed && i.Kind == reconcile.EXPIREDObjectIssue:
+			line += ": delete"
+		case i.Fixed:
+			line += ": " + strings.ToLower(string(report.Action))
+		case i.Error != "":
+			line += ": " + i.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+// gcTask runs the garbage collection every interval until ctx is done. Failed runs are logged and retried at the
+// next interval.
+func gcTask(ctx context.Context, collector *reconcile.ObjectCollector, interval time.Duration) error {
+	ll := logrus.WithField("at", "gcTask")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		report, err := collector.Run(ctx, buildGCOptions())
+		if err != nil {
+			ll.WithError(err).Error("can not collect objects")
+			continue
+		}
+		ll.Infof("objects collected (%s): %d orphan, %d missing, %d expired, %d bytes quarantined, %d bytes reclaimed",
+			report.Action, report.Count(reconcile.ORPHANObjectIssue), report.Count(reconcile.MISSINGObjectIssue), report.Count(reconcile.EXPIREDObjectIssue), report.Quarantined, report.Reclaimed)
+		for _, i := range report.Issues {
+			if i.Kind == reconcile.MISSINGObjectIssue {
+				ll.Warnf("%s: %s of media %s", i.Kind, i.Field, i.MediaID.Hex())
+			}
+		}
+	}
+}
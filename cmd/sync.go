@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/config"
+	"github.com/amirdaaee/TGMon/internal/reconcile"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+)
+
+var syncOpts struct {
+	from  int
+	to    int
+	fix   bool
+	prune bool
+	json  bool
+}
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Compare the storage channel with the media and report the differences",
+	Long: `Walks the history of the storage channel and reports document messages without a media,
+media whose message is gone, and media whose message now holds a different file.
+With --fix, media are created for document messages without one; with --prune, media whose message is gone are deleted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		dbContainer, err := buildDbContainer()
+		if err != nil {
+			logrus.WithError(err).Fatal("can not build db container")
+		}
+		wp, err := buildWorkerPool()
+		if err != nil {
+			logrus.WithError(err).Fatal("can not build worker pool")
+		}
+		mediafacade := buildMediaFacade(dbContainer, wp, buildJobReqFacade(dbContainer), nil)
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		opts := buildSyncOptions()
+		opts.From, opts.To = syncOpts.from, syncOpts.to
+		opts.Fix = opts.Fix || syncOpts.fix
+		opts.Prune = opts.Prune || syncOpts.prune
+		report, err := reconcile.NewReconciler(mediafacade, wp).Run(ctx, opts)
+		if err != nil {
+			logrus.WithError(err).Fatal("can not sync channel")
+		}
+		if syncOpts.json {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				logrus.WithError(err).Fatal("can not write report")
+			}
+			return
+		}
+		printSyncReport(report)
+	},
+}
+
+func init() {
+	syncCmd.Flags().IntVar(&syncOpts.from, "from", 0, "first message to scan")
+	syncCmd.Flags().IntVar(&syncOpts.to, "to", 0, "last message to scan (default: until the end of the channel)")
+	syncCmd.Flags().BoolVar(&syncOpts.fix, "fix", false, "create the media of document messages without one (or set SYNC__FIX)")
+	syncCmd.Flags().BoolVar(&syncOpts.prune, "prune", false, "delete the media whose message is gone (or set SYNC__PRUNE)")
+	syncCmd.Flags().BoolVar(&syncOpts.json, "json", false, "print the report as json")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// buildSyncOptions returns the sync options of the config.
+func buildSyncOptions() reconcile.Options {
+	sCfg := config.Config().SyncConfig
+	return reconcile.Options{
+		MaxGap:  sCfg.MaxGap,
+		Fix:     sCfg.Fix,
+		Prune:   sCfg.Prune,
+		Limiter: rate.NewLimiter(rate.Limit(sCfg.Rate), 1),
+	}
+}
+
+// printSyncReport writes a report for humans to stdout.
+func printSyncReport(report *reconcile.Report) {
+	fmt.Printf("scanned messages %d-%d in %s: %d messages, %d documents, %d media\n",
+		report.From, report.To, report.FinishedAt.Sub(report.StartedAt).Round(time.Second), report.Messages, report.Documents, report.Media)
+	for _, kind := range []reconcile.IssueEnum{reconcile.MISSINGMEDIAIssue, reconcile.MESSAGEGONEIssue, reconcile.FILECHANGEDIssue} {
+		fmt.Printf("%s: %d\n", kind, report.Count(kind))
+	}
+	for _, i := range report.Issues {
+		line := fmt.Sprintf("%-13s message %d, file %d (%s)", i.Kind, i.MessageID, i.FileID, i.FileName)
+		if i.MediaID != nil {
+			line += fmt.Sprintf(", media %s", i.MediaID.Hex())
+		}
+		switch {
+		case i.Fixed:
+			line += ": fixed"
+		case i.Error != "":
+			line += ": " + i.Error
+		}
+		fmt.Println(line)
+	}
+}
+
+// syncTask runs the channel sync every interval until ctx is done. Failed runs are logged and retried at the
+// next interval.
+func syncTask(ctx context.Context, reconciler *reconcile.Reconciler, interval time.Duration) error {
+	ll := logrus.WithField("at", "syncTask")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		report, err := reconciler.Run(ctx, buildSyncOptions())
+		if err != nil {
+			ll.WithError(err).Error("can not sync channel")
+			continue
+		}
+		ll.Infof("channel synced: %d missing media, %d gone messages, %d changed files",
+			report.Count(reconcile.MISSINGMEDIAIssue), report.Count(reconcile.MESSAGEGONEIssue), report.Count(reconcile.FILECHANGEDIssue))
+		for _, i := range report.Issues {
+			if !i.Fixed {
+				ll.Warnf("%s: message %d, file %d (%s)", i.Kind, i.MessageID, i.FileID, i.FileName)
+			}
+		}
+	}
+}
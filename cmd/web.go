@@ -6,15 +6,25 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/amirdaaee/TGMon/internal/auth"
 	"github.com/amirdaaee/TGMon/internal/config"
 	"github.com/amirdaaee/TGMon/internal/db"
+	"github.com/amirdaaee/TGMon/internal/dlna"
+	"github.com/amirdaaee/TGMon/internal/events"
 	"github.com/amirdaaee/TGMon/internal/facade"
 	"github.com/amirdaaee/TGMon/internal/filesystem"
+	"github.com/amirdaaee/TGMon/internal/health"
+	"github.com/amirdaaee/TGMon/internal/reconcile"
+	"github.com/amirdaaee/TGMon/internal/s3"
 	"github.com/amirdaaee/TGMon/internal/stash"
 	"github.com/amirdaaee/TGMon/internal/stream"
 	"github.com/amirdaaee/TGMon/internal/types"
@@ -25,6 +35,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 )
 
 var webCmd = &cobra.Command{
@@ -46,18 +57,73 @@ var webCmd = &cobra.Command{
 			logrus.WithError(err).Fatal("can not build worker pool")
 		}
 		// ...
-		mediafacade := buildMediaFacade(dbContainer, wp)
-		jobReqFacade := buildJobReqFacade(dbContainer)
-		jobResFacade := buildJobResFacade(dbContainer)
+		hCfg := config.Config().HttpConfig
+		bus := events.NewBus(hCfg.EventBacklog)
+		mediaObserver := events.NewMediaObserver(bus)
+		wp.OnStateChange(events.NewWorkerStateListener(bus))
+		// ...
+		fsRoot := filesystem.NewMediaFS(dbContainer, wp, config.Config().FuseConfig.Library)
+		jobReqFacade := buildJobReqFacade(dbContainer, events.NewJobReqObserver(bus))
+		mediafacade := buildMediaFacade(dbContainer, wp, jobReqFacade, []facade.IMediaCacheInvalidator{fsRoot}, mediaObserver)
+		jobResFacade := buildJobResFacade(dbContainer, jobReqFacade, events.NewJobResObserver(bus))
+		tagFacade := buildTagFacade(dbContainer)
+		playlistFacade := buildPlaylistFacade(dbContainer)
+		userFacade := buildUserFacade(dbContainer)
+		apiKeyFacade := buildApiKeyFacade(dbContainer)
+		progressFacade := buildWatchProgressFacade(dbContainer)
 		ll.Info("media facade built")
 		// ...
+		authenticator, err := buildAuthenticator(dbContainer)
+		if err != nil {
+			logrus.WithError(err).Fatal("can not build authenticator")
+		}
+		if err := seedAdmin(userFacade); err != nil {
+			logrus.WithError(err).Fatal("can not seed admin user")
+		}
+		// ...
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		errG, ctx := errgroup.WithContext(ctx)
 		// ...
+		checker := buildHealthChecker(dbContainer, wp)
 
 		// ...
-		webStopper, err := webServerHandler(dbContainer, mediafacade, wp, jobReqFacade, jobResFacade, errG)
+		errG.Go(func() error {
+			return mediaObserver.Watch(ctx, dbContainer.GetMongoContainer().GetMediaFileCollection(), hCfg.EventPoll)
+		})
+		if interval := config.Config().SyncConfig.Interval; interval > 0 {
+			reconciler := reconcile.NewReconciler(mediafacade, wp)
+			errG.Go(func() error {
+				return syncTask(ctx, reconciler, interval)
+			})
+		}
+		if interval := config.Config().GCConfig.Interval; interval > 0 {
+			collector := reconcile.NewObjectCollector(mediafacade, dbContainer.GetMinioContainer().GetMinioClient())
+			errG.Go(func() error {
+				return gcTask(ctx, collector, interval)
+			})
+		}
+		var sceneMapper *stash.SceneMapper
+		if sCfg := config.Config().StashRedirectorConfig; sCfg.Enabled {
+			stashCl := stash.NewStashQlClient(sCfg.StashEndpoint, sCfg.StashApiKey)
+			sceneMapper = stash.NewSceneMapper(stashCl, mediafacade, wp)
+			if sCfg.SyncInterval > 0 {
+				syncer := stash.NewSyncer(stashCl, sceneMapper, mediafacade, tagFacade, stash.SyncOptions{
+					LibraryPath: stashLibraryPath(),
+					MinioClient: dbContainer.GetMinioContainer().GetMinioClient(),
+					PresignTTL:  config.Config().HttpConfig.AssetPresignTTL,
+					Interval:    sCfg.SyncInterval,
+					Attempts:    sCfg.SyncAttempts,
+					PullTags:    sCfg.SyncTags,
+				})
+				errG.Go(func() error {
+					return syncer.Run(ctx, bus)
+				})
+			}
+		}
+		dlnaSrv := buildDlnaServer(mediafacade, tagFacade, authenticator, dbContainer.GetMinioContainer().GetMinioClient())
+		davFS := filesystem.NewDavFS(fsRoot)
+		webStopper, err := webServerHandler(dbContainer, mediafacade, wp, jobReqFacade, jobResFacade, tagFacade, playlistFacade, userFacade, apiKeyFacade, progressFacade, authenticator, bus, sceneMapper, dlnaSrv, davFS, checker, errG)
 		if err != nil {
 			logrus.WithError(err).Fatal("can not start web server")
 		}
@@ -69,7 +135,7 @@ var webCmd = &cobra.Command{
 			}
 		}()
 		// ...
-		fuseStopper, err := fuseServerHandler(dbContainer, wp, errG)
+		fuseStopper, err := fuseServerHandler(fsRoot, checker, errG)
 		if err != nil {
 			logrus.WithError(err).Fatal("can not start fuse server")
 		}
@@ -81,6 +147,15 @@ var webCmd = &cobra.Command{
 			}
 		}()
 		// ...
+		dlnaStopper, err := dlnaServerHandler(dlnaSrv, errG)
+		if err != nil {
+			logrus.WithError(err).Fatal("can not start dlna server")
+		}
+		s3Stopper, err := s3ServerHandler(buildS3Gateway(davFS, authenticator), errG)
+		if err != nil {
+			logrus.WithError(err).Fatal("can not start s3 gateway")
+		}
+		// ...
 		errG.Go(func() error {
 			sigChan := make(chan os.Signal, 1)
 			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -93,6 +168,12 @@ var webCmd = &cobra.Command{
 		})
 		errG.Go(func() error {
 			<-ctx.Done()
+			// stop receiving traffic before the servers stop
+			checker.Shutdown()
+			if d := config.Config().HealthConfig.ShutdownDelay; d > 0 {
+				ll.Infof("not ready, waiting %s before stopping", d)
+				time.Sleep(d)
+			}
 			if webStopper != nil {
 				if err := webStopper(); err != nil {
 					logrus.Error(err)
@@ -103,6 +184,16 @@ var webCmd = &cobra.Command{
 					logrus.Error(err)
 				}
 			}
+			if dlnaStopper != nil {
+				if err := dlnaStopper(); err != nil {
+					logrus.Error(err)
+				}
+			}
+			if s3Stopper != nil {
+				if err := s3Stopper(); err != nil {
+					logrus.Error(err)
+				}
+			}
 			return nil
 		})
 		// ...
@@ -120,10 +211,9 @@ func init() {
 
 type Stopper func() error
 
-func webServerHandler(dbContainer db.IDbContainer, mediafacade facade.IFacade[types.MediaFileDoc], wp stream.IWorkerPool, jobReqFacade facade.IFacade[types.JobReqDoc], jobResFacade facade.IFacade[types.JobResDoc], errG *errgroup.Group) (Stopper, error) {
+func webServerHandler(dbContainer db.IDbContainer, mediafacade facade.IFacade[types.MediaFileDoc], wp stream.IWorkerPool, jobReqFacade facade.IFacade[types.JobReqDoc], jobResFacade facade.IFacade[types.JobResDoc], tagFacade facade.IFacade[types.TagDoc], playlistFacade facade.IFacade[types.PlaylistDoc], userFacade facade.IFacade[types.UserDoc], apiKeyFacade facade.IFacade[types.ApiKeyDoc], progressFacade facade.IFacade[types.WatchProgressDoc], authenticator auth.IAuthenticator, bus events.IBus, sceneMapper *stash.SceneMapper, dlnaSrv *dlna.Server, davFS *filesystem.DavFS, checker *health.Checker, errG *errgroup.Group) (Stopper, error) {
 	ll := logrus.WithField("at", "webServerHandler")
 	hCfg := config.Config().HttpConfig
-	sCfg := config.Config().StashRedirectorConfig
 	g := gin.Default()
 	coresCfg := cors.DefaultConfig()
 	if len(hCfg.CoresAllowed) > 0 {
@@ -131,50 +221,142 @@ func webServerHandler(dbContainer db.IDbContainer, mediafacade facade.IFacade[ty
 	} else {
 		coresCfg.AllowAllOrigins = true
 	}
-	coresCfg.AddAllowHeaders("Authorization")
+	coresCfg.AddAllowHeaders("Authorization", "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset")
+	coresCfg.AddExposeHeaders("Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Length", "Upload-Offset")
 	g.Use(cors.New(coresCfg))
 	streamHandler := web.NewStreamHandler(dbContainer, mediafacade, wp)
 	mediaHandler := web.MediaHandler{DBContainer: dbContainer}
 	jobReqHandler := web.JobReqHandler{}
 	jobResHandler := web.JobResHandler{}
+	tagHandler := web.TagHandler{}
+	playlistHandler := web.PlaylistHandler{}
+	var streamSigner auth.IStreamSigner
+	if hCfg.StreamAuth {
+		streamSigner = authenticator
+	}
+	playlistM3UHandler := web.PlaylistM3UApiHandler{
+		PlaylistFacade: playlistFacade,
+		MediaFacade:    mediafacade,
+		PublicUrl:      hCfg.PublicUrl,
+		StreamSigner:   streamSigner,
+	}
 	infoHandler := web.InfoApiHandler{
 		MediaFacade: mediafacade,
 	}
+	statsHandler := web.StatsApiHandler{
+		MediaFacade:  mediafacade,
+		JobReqFacade: jobReqFacade,
+		StreamPool:   wp,
+		CacheTTL:     hCfg.StatsCacheTTL,
+	}
+	userHandler := web.UserHandler{}
+	apiKeyHandler := web.ApiKeyHandler{
+		Authenticator: authenticator,
+	}
 	loginHandler := web.LoginApiHandler{
-		UserName: hCfg.UserName,
-		UserPass: hCfg.UserPass,
-		Token:    hCfg.ApiToken,
+		Authenticator: authenticator,
 	}
-	sessionHandler := web.SessionApiHandler{
-		Token: hCfg.ApiToken,
+	sessionHandler := web.SessionApiHandler{}
+	logoutHandler := web.LogoutApiHandler{
+		Authenticator: authenticator,
 	}
 	randomMediaHandler := web.RandomMediaApiHandler{
 		MediaFacade: mediafacade,
 	}
+	mediaArchiveHandler := web.MediaArchiveApiHandler{
+		DBContainer: dbContainer,
+		MediaFacade: mediafacade,
+		StreamPool:  wp,
+	}
+	mediaExportHandler := web.MediaExportApiHandler{
+		DBContainer:  dbContainer,
+		MediaFacade:  mediafacade,
+		PublicUrl:    hCfg.PublicUrl,
+		MinioClient:  dbContainer.GetMinioContainer().GetMinioClient(),
+		PresignTTL:   hCfg.AssetPresignTTL,
+		StreamSigner: streamSigner,
+	}
+	mediaBulkHandler := web.MediaBulkApiHandler{
+		DBContainer:  dbContainer,
+		MediaFacade:  mediafacade,
+		JobReqFacade: jobReqFacade,
+		Limiter:      rate.NewLimiter(rate.Limit(hCfg.BulkRate), 1),
+	}
+	mediaImportHandler := web.MediaImportApiHandler{
+		MediaFacade: mediafacade,
+		WorkerPool:  wp,
+		ChannelID:   config.Config().TelegramConfig.ChannelID,
+		Limiter:     rate.NewLimiter(rate.Limit(hCfg.BulkRate), 1),
+	}
+	eventsHandler := web.EventsApiHandler{
+		Bus: bus,
+	}
+	watchProgressHandler := web.WatchProgressApiHandler{
+		ProgressFacade: progressFacade,
+		MediaFacade:    mediafacade,
+	}
+	continueWatchingHandler := web.ContinueWatchingApiHandler{
+		ProgressFacade: progressFacade,
+		MediaFacade:    mediafacade,
+	}
 
 	hndlrs := web.HandlerContainer{
-		MediaHandler:       web.NewCRDApiHandler(&mediaHandler, mediafacade, "media"),
-		JobReqHandler:      web.NewCRDApiHandler(&jobReqHandler, jobReqFacade, "jobReq"),
-		JobResHandler:      web.NewCRDApiHandler(&jobResHandler, jobResFacade, "jobRes"),
-		InfoHandler:        web.NewApiHandler(&infoHandler, "info"),
-		LoginHandler:       web.NewApiHandler(&loginHandler, "auth/login"),
-		SessionHandler:     web.NewApiHandler(&sessionHandler, "auth/session"),
-		RandomMediaHandler: web.NewApiHandler(&randomMediaHandler, "media/random"),
-	}
-	if sCfg.Enabled {
-		stachCl := stash.NewStashQlClient(sCfg.StashEndpoint, sCfg.StashApiKey)
+		MediaHandler:            web.NewCRDApiHandler(&mediaHandler, mediafacade, "media"),
+		JobReqHandler:           web.NewCRDApiHandler(&jobReqHandler, jobReqFacade, "jobReq"),
+		JobResHandler:           web.NewCRDApiHandler(&jobResHandler, jobResFacade, "jobRes"),
+		TagHandler:              web.NewCRDApiHandler(&tagHandler, tagFacade, "tag"),
+		PlaylistHandler:         web.NewCRDApiHandler(&playlistHandler, playlistFacade, "playlist"),
+		UserHandler:             web.NewCRDApiHandler(&userHandler, userFacade, "user"),
+		ApiKeyHandler:           web.NewCRDApiHandler(&apiKeyHandler, apiKeyFacade, "apikey"),
+		PlaylistM3UHandler:      web.NewApiHandler(&playlistM3UHandler, "playlist"),
+		InfoHandler:             web.NewApiHandler(&infoHandler, "info"),
+		StatsHandler:            web.NewApiHandler(&statsHandler, "stats"),
+		LoginHandler:            web.NewApiHandler(&loginHandler, "auth/login"),
+		SessionHandler:          web.NewApiHandler(&sessionHandler, "auth/session"),
+		LogoutHandler:           web.NewApiHandler(&logoutHandler, "auth/logout"),
+		RandomMediaHandler:      web.NewApiHandler(&randomMediaHandler, "media/random"),
+		MediaArchiveHandler:     web.NewApiHandler(&mediaArchiveHandler, "media/archive"),
+		MediaExportHandler:      web.NewApiHandler(&mediaExportHandler, "media/export"),
+		EventsHandler:           web.NewApiHandler(&eventsHandler, "events"),
+		WatchProgressHandler:    web.NewApiHandler(&watchProgressHandler, "media"),
+		ContinueWatchingHandler: web.NewApiHandler(&continueWatchingHandler, "media/continue"),
+		MediaBulkHandler:        web.NewApiHandler(&mediaBulkHandler, "media/bulk"),
+		MediaImportHandler:      web.NewApiHandler(&mediaImportHandler, "media/import"),
+		MediaAssetHandlers:      web.NewMediaAssetHandlers(mediafacade, dbContainer.GetMinioContainer().GetMinioClient(), hCfg.AssetRedirect, hCfg.AssetPresignTTL),
+		SubtitleHandler:         &web.SubtitleHandler{MediaFacade: mediafacade, MinioClient: dbContainer.GetMinioContainer().GetMinioClient()},
+		StashScraperHandler:     &web.StashScraperHandler{MediaFacade: mediafacade, TagFacade: tagFacade, PublicUrl: hCfg.PublicUrl, MinioClient: dbContainer.GetMinioContainer().GetMinioClient(), PresignTTL: hCfg.AssetPresignTTL},
+		HealthHandler:           &web.HealthHandler{Checker: checker},
+	}
+	if sceneMapper != nil {
 		stashVTTRedirectorHandler := web.StashVTTRedirectorApiHandler{
-			MinioUrl:    sCfg.MinioUrl,
-			StashCl:     stachCl,
-			MediaFacade: mediafacade,
+			MinioClient: dbContainer.GetMinioContainer().GetMinioClient(),
+			PresignTTL:  hCfg.AssetPresignTTL,
+			Mapper:      sceneMapper,
 		}
 		stashCoverRedirectorHandler := web.StashCoverRedirectorApiHandler{
 			StashVTTRedirectorApiHandler: stashVTTRedirectorHandler,
 		}
+		stashMappingHandler := web.StashMappingApiHandler{Mapper: sceneMapper, LibraryPath: stashLibraryPath()}
 		hndlrs.StashVTTRedirectorHandler = web.NewApiHandler(&stashVTTRedirectorHandler, "")
 		hndlrs.StashCoverRedirectorHandler = web.NewApiHandler(&stashCoverRedirectorHandler, "")
+		hndlrs.StashMappingHandler = web.NewApiHandler(&stashMappingHandler, "stash/mapping")
+	}
+	uploadHandler, err := web.NewUploadHandler(wp, mediafacade, tagFacade, hCfg.UploadDir, hCfg.UploadMaxSize, hCfg.UploadTTL)
+	if err != nil {
+		return nil, fmt.Errorf("can not create upload handler: %w", err)
+	}
+	hndlrs.UploadHandler = uploadHandler
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	errG.Go(func() error {
+		return uploadHandler.Run(sweepCtx)
+	})
+	if hCfg.Dav {
+		hndlrs.DavHandler = web.NewDavHandler(davFS)
+	}
+	web.RegisterRoutes(g, streamHandler, hndlrs, authenticator, hCfg.StreamAuth, hCfg.Swagger)
+	if dlnaSrv != nil {
+		dlnaSrv.RegisterRoutes(g.Group("/dlna"))
 	}
-	web.RegisterRoutes(g, streamHandler, hndlrs, hCfg.ApiToken, hCfg.Swagger)
 	ll.Warn("starting server")
 	srv := &http.Server{
 		Addr:    hCfg.ListenAddr,
@@ -188,6 +370,7 @@ func webServerHandler(dbContainer db.IDbContainer, mediafacade facade.IFacade[ty
 		return nil
 	})
 	return func() error {
+		stopSweep()
 		if err := srv.Shutdown(context.TODO()); err != nil {
 			return fmt.Errorf("can not shutdown server: %w", err)
 		}
@@ -196,11 +379,38 @@ func webServerHandler(dbContainer db.IDbContainer, mediafacade facade.IFacade[ty
 	}, nil
 }
 
-func fuseServerHandler(dbContainer db.IDbContainer, wp stream.IWorkerPool, errG *errgroup.Group) (Stopper, error) {
+// stashLibraryPath returns the path Stash sees the FUSE filesystem at.
+func stashLibraryPath() string {
+	if p := config.Config().StashRedirectorConfig.LibraryPath; p != "" {
+		return p
+	}
+	return config.Config().FuseConfig.MediaDir
+}
+
+// seedAdmin creates the first admin user from HTTP__USER_NAME and HTTP__USER_PASS when there are no users yet.
+func seedAdmin(userFacade facade.IFacade[types.UserDoc]) error {
+	ll := logrus.WithField("at", "seedAdmin")
+	hCfg := config.Config().HttpConfig
+	if hCfg.UserName == "" || hCfg.UserPass == "" {
+		return nil
+	}
+	created, err := facade.SeedAdmin(context.Background(), userFacade, hCfg.UserName, hCfg.UserPass)
+	if err != nil {
+		return err
+	}
+	if created {
+		ll.Warnf("admin user %s created", hCfg.UserName)
+	}
+	return nil
+}
+
+func fuseServerHandler(fsRoot *filesystem.MediaFS, checker *health.Checker, errG *errgroup.Group) (Stopper, error) {
 	ll := logrus.WithField("at", "fuseServerHandler")
 	fCfg := config.Config().FuseConfig
 	if fCfg.Enabled {
 		var fuseSrv *fuse.Server
+		var mounted atomic.Bool
+		checker.Add("fuse", health.MountCheck(fCfg.MediaDir, mounted.Load))
 		errG.Go(func() error {
 			ll.Info("fuse config enabled")
 			mountDir := fCfg.MediaDir
@@ -209,11 +419,12 @@ func fuseServerHandler(dbContainer db.IDbContainer, wp stream.IWorkerPool, errG
 				Debug:      fCfg.Debug,
 			}
 			ll.Info("starting fuse server")
-			server, err := filesystem.MountWithOptions(mountDir, dbContainer, wp, opts)
+			server, err := filesystem.MountMediaFS(mountDir, fsRoot, opts)
 			if err != nil {
 				return fmt.Errorf("can not mount filesystem: %w", err)
 			}
 			fuseSrv = server
+			mounted.Store(true)
 			ll.Info("fuse server started")
 			return nil
 		})
@@ -222,6 +433,7 @@ func fuseServerHandler(dbContainer db.IDbContainer, wp stream.IWorkerPool, errG
 				ll.Warn("fuse server instance is nil")
 				return nil
 			}
+			mounted.Store(false)
 			if err := fuseSrv.Unmount(); err != nil {
 				return fmt.Errorf("can not unmount filesystem: %w", err)
 			}
@@ -231,3 +443,62 @@ func fuseServerHandler(dbContainer db.IDbContainer, wp stream.IWorkerPool, errG
 	}
 	return nil, nil
 }
+
+func dlnaServerHandler(dlnaSrv *dlna.Server, errG *errgroup.Group) (Stopper, error) {
+	ll := logrus.WithField("at", "dlnaServerHandler")
+	if dlnaSrv == nil {
+		return nil, nil
+	}
+	cfg := config.Config()
+	_, portStr, err := net.SplitHostPort(cfg.HttpConfig.ListenAddr)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse listen address: %w", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse listen port: %w", err)
+	}
+	baseUrl := cfg.DlnaConfig.PublicUrl
+	if baseUrl == "" {
+		baseUrl = cfg.HttpConfig.PublicUrl
+	}
+	announcer := dlna.NewAnnouncer(dlnaSrv.UDN(), baseUrl, port, cfg.DlnaConfig.NotifyInterval)
+	ctx, cancel := context.WithCancel(context.Background())
+	errG.Go(func() error {
+		ll.Info("starting dlna announcer")
+		if err := announcer.Run(ctx); err != nil {
+			return fmt.Errorf("error running dlna announcer: %w", err)
+		}
+		return nil
+	})
+	return func() error {
+		cancel()
+		ll.Info("dlna server stopped")
+		return nil
+	}, nil
+}
+
+func s3ServerHandler(gw *s3.Gateway, errG *errgroup.Group) (Stopper, error) {
+	ll := logrus.WithField("at", "s3ServerHandler")
+	if gw == nil {
+		return nil, nil
+	}
+	srv := &http.Server{
+		Addr:    config.Config().S3Config.ListenAddr,
+		Handler: gw,
+	}
+	errG.Go(func() error {
+		ll.Warnf("starting s3 gateway on %s", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("error running s3 gateway: %w", err)
+		}
+		return nil
+	})
+	return func() error {
+		if err := srv.Shutdown(context.TODO()); err != nil {
+			return fmt.Errorf("can not shutdown s3 gateway: %w", err)
+		}
+		ll.Info("s3 gateway stopped")
+		return nil
+	}, nil
+}
@@ -15,9 +15,158 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/apikey/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikey"
+                ],
+                "summary": "List api keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.ApiKeyDoc"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "The plain key and its s3 gateway credentials are only returned by this call.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikey"
+                ],
+                "summary": "Create api key",
+                "parameters": [
+                    {
+                        "description": "Api Key Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.ApiKeyCreateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.ApiKeyCreateResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/apikey/{id}/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikey"
+                ],
+                "summary": "Read api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Api Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiKeyDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikey"
+                ],
+                "summary": "Revoke api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Api Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
         "/api/auth/login/": {
             "post": {
-                "description": "Authenticate user and return a token",
+                "description": "Authenticate user and return a session token",
                 "consumes": [
                     "application/json"
                 ],
@@ -42,6 +191,34 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/web.LoginPostResType"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/logout/": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke the current session token",
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -63,6 +240,58 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/web.LoginPostResType"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/events/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Server-sent events for media, job and worker changes. Send ` + "`" + `Last-Event-ID` + "`" + ` (or ` + "`" + `lastEventId` + "`" + `) to resume from the backlog.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "summary": "Event stream",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only send events of these types",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event ID",
+                        "name": "lastEventId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -84,6 +313,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/web.InfoGetResType"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -111,6 +346,12 @@ const docTemplate = `{
                                 "$ref": "#/definitions/types.JobReqDoc"
                             }
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             },
@@ -144,6 +385,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/types.JobReqDoc"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -177,6 +424,62 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mark a job request as claimed (or release it) so other workers skip it. Claiming a request which is\nalready claimed fails with a conflict, so a single worker gets it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobReq"
+                ],
+                "summary": "Claim job request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Claim state",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.JobReqUpdateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.JobReqDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -215,6 +518,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/types.JobResDoc"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
@@ -239,6 +548,22 @@ const docTemplate = `{
                         "description": "page",
                         "name": "page",
                         "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "only media having all of these tag IDs",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only media the authenticated user has (or has not) watched",
+                        "name": "watched",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -247,282 +572,3151 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/web.MediaListResType"
                         }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
                     }
                 }
             }
         },
-        "/api/media/random/": {
-            "get": {
+        "/api/media/archive/": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "produces": [
+                "description": "Streams an uncompressed zip of the media with the given IDs (in order), or of the media matching the filter\n(oldest first), at most 1000 media. The size of the archive is sent up front as Content-Length.",
+                "consumes": [
                     "application/json"
                 ],
-                "summary": "Get random media",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Download media archive",
+                "parameters": [
+                    {
+                        "description": "Media to archive",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaArchiveReqType"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
                         "schema": {
-                            "$ref": "#/definitions/web.RandomMediaGetResType"
+                            "$ref": "#/definitions/web.HttpErr"
                         }
                     }
                 }
             }
         },
-        "/api/media/{id}/": {
-            "get": {
+        "/api/media/bulk/": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Deletes, tags, untags or requests a job for the media with the given IDs, or the media matching the filter.\nMedia are processed one at a time and throttled (HTTP__BULK_RATE per second); the result of each is reported.\nWith DryRun, reports what would be done without changing anything. At most 1000 media per request.\nDeleting requires the media:delete scope for api keys.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "media"
                 ],
-                "summary": "Read media",
+                "summary": "Bulk media operation",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Media ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                        "description": "Bulk operation",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaBulkReqType"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/web.MediaReadResType"
+                            "$ref": "#/definitions/web.MediaBulkResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/continue/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Media the authenticated user started but did not finish, most recently played first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Continue watching",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.ContinueWatchingGetResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/export/{format}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Exports the media matching the filters, newest first: ` + "`" + `m3u8` + "`" + ` is a playlist of stream urls, ` + "`" + `rss` + "`" + ` and ` + "`" + `atom` + "`" + `\nare feeds of the recently added media with their thumbnails, ` + "`" + `json` + "`" + ` and ` + "`" + `csv` + "`" + ` dump the metadata.\nFor feed readers, the token can be passed in the ` + "`" + `token` + "`" + ` query parameter. With stream auth, stream urls\nare signed for their media and expire, the token is never included in them.",
+                "produces": [
+                    "audio/x-mpegurl",
+                    "application/rss+xml",
+                    "application/atom+xml",
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Export media",
+                "parameters": [
+                    {
+                        "enum": [
+                            "m3u8",
+                            "rss",
+                            "atom",
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "export format",
+                        "name": "format",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "only media having all of these tag IDs",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only media the authenticated user has (or has not) watched",
+                        "name": "watched",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.MediaFileDoc"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/import/": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates the media of documents already in the storage channel, given by t.me/c/\u003cchannel\u003e/\u003cmessage\u003e links,\nmessage IDs, or an inclusive range of message IDs (From, To). Messages are resolved one at a time and\nthrottled (HTTP__BULK_RATE per second). Messages already imported, or whose file is already a media, are reported as SKIPPED.\nAt most 1000 messages per request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Import channel messages",
+                "parameters": [
+                    {
+                        "description": "Messages to import",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaImportReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaImportResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/random/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get random media",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.RandomMediaGetResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/upload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Uploads a file to the storage channel and adds it as a media. The file is streamed to telegram while it is\nreceived, so the form fields must come before the file. Duration (seconds), Width and Height are sent as\nvideo attributes of video files; Tags are names of tags to add to the media, created when missing.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Upload media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File name (defaults to the name of the file)",
+                        "name": "FileName",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Mime type (defaults to the content type of the file)",
+                        "name": "MimeType",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Duration in seconds",
+                        "name": "Duration",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Video width",
+                        "name": "Width",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Video height",
+                        "name": "Height",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated tag names",
+                        "name": "Tags",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "File",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.MediaFileDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/upload/files": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a tus upload of Upload-Length bytes. Upload-Metadata takes the same fields as the multipart upload\n(filename is required, filetype is the mime type). The upload url is returned in the Location header. Uploads\nare only visible to the user who created them (and to their api keys).",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Create a resumable upload",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Size of the file in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated keys and base64 encoded values",
+                        "name": "Upload-Metadata",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
                         }
                     }
                 }
             },
+            "options": {
+                "description": "Returns the tus versions and extensions supported by the server, and the maximum size of uploads, in the\nTus-Version, Tus-Extension and Tus-Max-Size headers.",
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get the capabilities of resumable uploads",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/api/media/upload/files/{id}": {
             "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Cancel a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get the offset of a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Appends the body at Upload-Offset, which must be the current offset of the upload. Once all bytes are\nreceived, the file is sent to telegram and the created media is returned. If sending the file or creating\nits media fails, a PATCH at the end of the upload (with an empty body) retries it; a file already sent is\nnot sent again.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "media"
                 ],
-                "summary": "Delete media",
+                "summary": "Upload a chunk of a resumable upload",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Media ID",
+                        "description": "Upload ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.MediaFileDoc"
+                        }
+                    },
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Read media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaReadResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Delete media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Update media",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.MediaUpdateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.MediaFileDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/progress": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Playback progress of the authenticated user on the media. Api keys act as the user who created them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get watch progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WatchProgressDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report the playback position (in seconds) and/or set the watched flag of the media for the authenticated user.\nReaching the end of the media marks it as watched and counts a play. Setting the watched flag clears the\nposition, unless one is given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Report watch progress",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Progress",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.ProgressPutReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WatchProgressDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/sprite": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the asset from minio with caching headers (etag and last-modified), or redirects to a short-lived\npresigned url when HTTP__ASSET_REDIRECT is set. Vtt files are always streamed, with the sprite url rewritten.\nThe token can be passed in the ` + "`" + `token` + "`" + ` query parameter, and is passed along to the sprite url of vtt files.",
+                "produces": [
+                    "image/jpeg",
+                    "text/vtt"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get media thumbnail, sprite or vtt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/subtitles": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Attaches a srt, vtt or ass file to the media as the track of Language, replacing the track of that\nlanguage if there is one. Srt files are converted to vtt.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Add subtitle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Language label (e.g. en)",
+                        "name": "Language",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Subtitle file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.MediaSubtitle"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/subtitles/{subtitle}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "text/vtt",
+                    "text/x-ssa"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get subtitle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitle",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Delete subtitle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Subtitle ID",
+                        "name": "subtitle",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/thumbnail": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the asset from minio with caching headers (etag and last-modified), or redirects to a short-lived\npresigned url when HTTP__ASSET_REDIRECT is set. Vtt files are always streamed, with the sprite url rewritten.\nThe token can be passed in the ` + "`" + `token` + "`" + ` query parameter, and is passed along to the sprite url of vtt files.",
+                "produces": [
+                    "image/jpeg",
+                    "text/vtt"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get media thumbnail, sprite or vtt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/media/{id}/vtt": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Streams the asset from minio with caching headers (etag and last-modified), or redirects to a short-lived\npresigned url when HTTP__ASSET_REDIRECT is set. Vtt files are always streamed, with the sprite url rewritten.\nThe token can be passed in the ` + "`" + `token` + "`" + ` query parameter, and is passed along to the sprite url of vtt files.",
+                "produces": [
+                    "image/jpeg",
+                    "text/vtt"
+                ],
+                "tags": [
+                    "media"
+                ],
+                "summary": "Get media thumbnail, sprite or vtt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "307": {
+                        "description": "Temporary Redirect"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/playlist/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "List playlists",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.PlaylistDoc"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Create playlist",
+                "parameters": [
+                    {
+                        "description": "Playlist Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.PlaylistCreateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PlaylistDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/playlist/{id}/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Read playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PlaylistDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Delete playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Update playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.PlaylistUpdateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.PlaylistDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/playlist/{id}/m3u": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "audio/x-mpegurl"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "Export playlist as M3U",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "M3U playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stash/mapping/": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the Stash scenes under the library path and maps each to its media, by oshash fingerprint, stored\nscene ID or the media ID in the file name. With Hash, the oshash of media that do not have one is\ncomputed first, reading the head and tail of each file from Telegram.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stash"
+                ],
+                "summary": "Rebuild Stash mapping",
+                "parameters": [
+                    {
+                        "description": "Rebuild options",
+                        "name": "data",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/web.StashMappingReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/stash.MappingReport"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stash/scraper/TGMon.yml": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the Stash scraper definition of TGMon, to be saved in the scrapers directory of Stash. The api\nkey in its headers is to be filled in.",
+                "produces": [
+                    "application/yaml"
+                ],
+                "tags": [
+                    "stash"
+                ],
+                "summary": "Scraper config",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stash/scraper/scene": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the metadata of a media for Stash. The media is looked up by ID, by the media ID in the url, by\noshash, by the media ID in the file name, then by exact name, of the given parameters.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stash"
+                ],
+                "summary": "Scrape scene",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Media ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Url of the media",
+                        "name": "url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Oshash of the file",
+                        "name": "oshash",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the file in the FUSE filesystem",
+                        "name": "filename",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the media",
+                        "name": "title",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.StashScrapedSceneType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stash/scraper/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the metadata of the media whose name or file name contains name, for Stash.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "stash"
+                ],
+                "summary": "Search scenes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Part of the name",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/web.StashScrapedSceneType"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stats/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Totals and breakdowns of the media library, job queue counts and per-worker traffic.\nLibrary figures are cached, for 30 seconds by default (HTTP__STATS_CACHE_TTL).",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Library statistics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/web.StatsGetResType"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tag/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TagDoc"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Create tag",
+                "parameters": [
+                    {
+                        "description": "Tag Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.TagCreateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TagDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tag/{id}/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Read tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TagDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Delete tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tag"
+                ],
+                "summary": "Update tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.TagUpdateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TagDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.UserDoc"
+                            }
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Create user",
+                "parameters": [
+                    {
+                        "description": "User Data",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.UserCreateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/{id}/": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Read user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the role or password of a user. Changing the password revokes all of the user's sessions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "data",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/web.UserUpdateReqType"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserDoc"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/web.HttpErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Succeeds as long as the web server is serving requests.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.LiveResType"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks mongo, the minio bucket, the connected workers and the fuse mount, with the detail of each.\nFails with 503 when a check fails, and from the start of a graceful shutdown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "events.Event": {
+            "type": "object",
+            "properties": {
+                "Data": {},
+                "ID": {
+                    "type": "integer"
+                },
+                "Time": {
+                    "type": "string"
+                },
+                "Type": {
+                    "$ref": "#/definitions/events.EventTypeEnum"
+                }
+            }
+        },
+        "events.EventTypeEnum": {
+            "type": "string",
+            "enum": [
+                "media.created",
+                "media.updated",
+                "media.deleted",
+                "job.requested",
+                "job.claimed",
+                "job.completed",
+                "job.failed",
+                "worker.state"
+            ],
+            "x-enum-varnames": [
+                "MEDIACREATEDEventType",
+                "MEDIAUPDATEDEventType",
+                "MEDIADELETEDEventType",
+                "JOBREQUESTEDEventType",
+                "JOBCLAIMEDEventType",
+                "JOBCOMPLETEDEventType",
+                "JOBFAILEDEventType",
+                "WORKERSTATEEventType"
+            ]
+        },
+        "facade.BulkItemResult": {
+            "type": "object",
+            "properties": {
+                "Error": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Status": {
+                    "$ref": "#/definitions/facade.BulkStatusEnum"
+                }
+            }
+        },
+        "facade.BulkStatusEnum": {
+            "type": "string",
+            "enum": [
+                "DONE",
+                "SKIPPED",
+                "FAILED"
+            ],
+            "x-enum-comments": {
+                "DONEBulkStatus": "applied, or would be applied in a dry run",
+                "SKIPPEDBulkStatus": "nothing to do for the document"
+            },
+            "x-enum-varnames": [
+                "DONEBulkStatus",
+                "SKIPPEDBulkStatus",
+                "FAILEDBulkStatus"
+            ]
+        },
+        "facade.ImportItemResult": {
+            "type": "object",
+            "properties": {
+                "Error": {
+                    "type": "string"
+                },
+                "MediaID": {
+                    "description": "the created media",
+                    "type": "string"
+                },
+                "MessageID": {
+                    "type": "integer"
+                },
+                "Status": {
+                    "$ref": "#/definitions/facade.BulkStatusEnum"
+                }
+            }
+        },
+        "health.ComponentReport": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/health.StatusEnum"
+                }
+            }
+        },
+        "health.LiveResType": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "$ref": "#/definitions/health.StatusEnum"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.ComponentReport"
+                    }
+                },
+                "ready": {
+                    "type": "boolean"
+                },
+                "shuttingDown": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "health.StatusEnum": {
+            "type": "string",
+            "enum": [
+                "OK",
+                "FAIL"
+            ],
+            "x-enum-varnames": [
+                "OKStatus",
+                "FAILStatus"
+            ]
+        },
+        "stash.MappingReport": {
+            "type": "object",
+            "properties": {
+                "Failed": {
+                    "description": "scenes or media that failed to be processed",
+                    "type": "integer"
+                },
+                "Hashed": {
+                    "description": "media whose missing oshash was computed",
+                    "type": "integer"
+                },
+                "Mapped": {
+                    "description": "scenes mapped to a media",
+                    "type": "integer"
+                },
+                "Scenes": {
+                    "description": "scenes found under the library path",
+                    "type": "integer"
+                },
+                "Unmatched": {
+                    "description": "IDs of the scenes no media matched",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "stream.WorkerStateEnum": {
+            "type": "string",
+            "enum": [
+                "READY",
+                "FLOOD_WAIT"
+            ],
+            "x-enum-varnames": [
+                "READYWorkerState",
+                "FLOODWAITWorkerState"
+            ]
+        },
+        "stream.WorkerTraffic": {
+            "type": "object",
+            "properties": {
+                "Bytes": {
+                    "type": "integer"
+                },
+                "Chunks": {
+                    "type": "integer"
+                },
+                "State": {
+                    "$ref": "#/definitions/stream.WorkerStateEnum"
+                },
+                "Worker": {
+                    "description": "pool index, as reported to state listeners",
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ApiKeyDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "CreatedBy": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "ExpiresAt": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Key": {
+                    "description": "plain key, only set right after creation",
+                    "type": "string"
+                },
+                "LastUsedAt": {
+                    "type": "string"
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "Prefix": {
+                    "description": "first characters of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "Scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ApiKeyScopeEnum"
+                    }
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ApiKeyScopeEnum": {
+            "type": "string",
+            "enum": [
+                "media:read",
+                "media:write",
+                "media:delete",
+                "jobs:claim",
+                "jobs:submit",
+                "stream"
+            ],
+            "x-enum-varnames": [
+                "MEDIAREADApiKeyScope",
+                "MEDIAWRITEApiKeyScope",
+                "MEDIADELETEApiKeyScope",
+                "JOBSCLAIMApiKeyScope",
+                "JOBSSUBMITApiKeyScope",
+                "STREAMApiKeyScope"
+            ]
+        },
+        "types.JobReqDoc": {
+            "type": "object",
+            "properties": {
+                "ClaimedAt": {
+                    "description": "set by the worker processing the job",
+                    "type": "string"
+                },
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "MediaID": {
+                    "type": "string"
+                },
+                "Type": {
+                    "$ref": "#/definitions/types.JobTypeEnum"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.JobResDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "JobReqID": {
+                    "type": "string"
+                },
+                "Sprite": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "Thumbnail": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                },
+                "Vtt": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "types.JobTypeEnum": {
+            "type": "string",
+            "enum": [
+                "THUMBNAIL",
+                "SPRITE"
+            ],
+            "x-enum-varnames": [
+                "THUMBNAILJobType",
+                "SPRITEJobType"
+            ]
+        },
+        "types.MediaFileDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "Description": {
+                    "type": "string"
+                },
+                "Fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "MessageID": {
+                    "type": "integer"
+                },
+                "Meta": {
+                    "$ref": "#/definitions/types.MediaFileMeta"
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "OsHash": {
+                    "description": "hash Stash identifies the file by",
+                    "type": "string"
+                },
+                "Sprite": {
+                    "type": "string"
+                },
+                "StashSceneID": {
+                    "description": "scene of the file in Stash",
+                    "type": "string"
+                },
+                "Subtitles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.MediaSubtitle"
+                    }
+                },
+                "Tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Thumbnail": {
+                    "type": "string"
+                },
+                "ThumbnailSize": {
+                    "description": "bytes; 0 for thumbnails stored before sizes were recorded",
+                    "type": "integer"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                },
+                "Vtt": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.MediaFileMeta": {
+            "type": "object",
+            "properties": {
+                "Duration": {
+                    "type": "number"
+                },
+                "FileID": {
+                    "type": "integer"
+                },
+                "FileName": {
+                    "type": "string"
+                },
+                "FileSize": {
+                    "type": "integer"
+                },
+                "MimeType": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.MediaSubtitle": {
+            "type": "object",
+            "properties": {
+                "File": {
+                    "description": "object name in minio",
+                    "type": "string"
+                },
+                "Format": {
+                    "$ref": "#/definitions/types.SubtitleFormatEnum"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Language": {
+                    "description": "label of the track (e.g. en), one track per language",
+                    "type": "string"
+                },
+                "Size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.PlaylistDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "Description": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.SubtitleFormatEnum": {
+            "type": "string",
+            "enum": [
+                "vtt",
+                "ass"
+            ],
+            "x-enum-comments": {
+                "VTTSubtitleFormat": "srt files are converted to vtt"
+            },
+            "x-enum-varnames": [
+                "VTTSubtitleFormat",
+                "ASSSubtitleFormat"
+            ]
+        },
+        "types.TagDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.UserDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "FailedLogins": {
+                    "type": "integer"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "LockedUntil": {
+                    "type": "string"
+                },
+                "Role": {
+                    "$ref": "#/definitions/types.UserRoleEnum"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                },
+                "Username": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.UserRoleEnum": {
+            "type": "string",
+            "enum": [
+                "ADMIN",
+                "EDITOR",
+                "VIEWER"
+            ],
+            "x-enum-varnames": [
+                "ADMINUserRole",
+                "EDITORUserRole",
+                "VIEWERUserRole"
+            ]
+        },
+        "types.WatchProgressDoc": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "Finished": {
+                    "description": "Position is at the end of the media, so there is nothing to resume",
+                    "type": "boolean"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "LastPlayedAt": {
+                    "type": "string"
+                },
+                "MediaID": {
+                    "type": "string"
+                },
+                "PlayCount": {
+                    "type": "integer"
+                },
+                "Position": {
+                    "description": "last reported position, in seconds",
+                    "type": "number"
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                },
+                "UserID": {
+                    "type": "string"
+                },
+                "Watched": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "web.ApiKeyCreateReqType": {
+            "type": "object",
+            "required": [
+                "Name",
+                "Scopes"
+            ],
+            "properties": {
+                "ExpiresAt": {
+                    "type": "string"
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "Scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ApiKeyScopeEnum"
+                    }
+                }
+            }
+        },
+        "web.ApiKeyCreateResType": {
+            "type": "object",
+            "properties": {
+                "CreatedAt": {
+                    "type": "string"
+                },
+                "CreatedBy": {
+                    "type": "string"
+                },
+                "DeletedAt": {
+                    "type": "string"
+                },
+                "ExpiresAt": {
+                    "type": "string"
+                },
+                "ID": {
+                    "type": "string"
+                },
+                "Key": {
+                    "description": "plain key, only set right after creation",
+                    "type": "string"
+                },
+                "LastUsedAt": {
+                    "type": "string"
+                },
+                "Name": {
+                    "type": "string"
+                },
+                "Prefix": {
+                    "description": "first characters of the key, to tell keys apart",
+                    "type": "string"
+                },
+                "S3AccessKeyID": {
+                    "type": "string"
+                },
+                "S3SecretAccessKey": {
+                    "type": "string"
+                },
+                "Scopes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ApiKeyScopeEnum"
+                    }
+                },
+                "UpdatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.ContinueWatchingGetResType": {
+            "type": "object",
+            "properties": {
+                "Items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.ContinueWatchingItemType"
+                    }
+                }
+            }
+        },
+        "web.ContinueWatchingItemType": {
+            "type": "object",
+            "properties": {
+                "Media": {
+                    "$ref": "#/definitions/types.MediaFileDoc"
+                },
+                "Progress": {
+                    "$ref": "#/definitions/types.WatchProgressDoc"
+                }
+            }
+        },
+        "web.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "BAD_REQUEST",
+                "UNAUTHORIZED",
+                "FORBIDDEN",
+                "NOT_FOUND",
+                "METHOD_NOT_ALLOWED",
+                "CONFLICT",
+                "TOO_MANY_REQUESTS",
+                "INTERNAL",
+                "UNAVAILABLE",
+                "INVALID_DOCUMENT",
+                "INVALID_UPDATE",
+                "UPDATE_CONFLICT",
+                "MULTIPLE_DOCUMENTS",
+                "FILE_EXISTS",
+                "TAG_EXISTS",
+                "USER_EXISTS",
+                "LAST_ADMIN",
+                "INVALID_CREDENTIALS",
+                "USER_LOCKED",
+                "WEAK_PASSWORD",
+                "INVALID_TOKEN",
+                "SESSION_EXPIRED",
+                "API_KEY_EXPIRED",
+                "STREAM_URL_EXPIRED",
+                "NO_THUMBNAIL",
+                "MESSAGE_NOT_FOUND",
+                "NOT_DOCUMENT",
+                "NO_WORKER",
+                "FLOOD_WAIT"
+            ],
+            "x-enum-varnames": [
+                "BADREQUESTErrorCode",
+                "UNAUTHORIZEDErrorCode",
+                "FORBIDDENErrorCode",
+                "NOTFOUNDErrorCode",
+                "METHODNOTALLOWEDErrorCode",
+                "CONFLICTErrorCode",
+                "TOOMANYREQUESTSErrorCode",
+                "INTERNALErrorCode",
+                "UNAVAILABLEErrorCode",
+                "INVALIDDOCUMENTErrorCode",
+                "INVALIDUPDATEErrorCode",
+                "UPDATECONFLICTErrorCode",
+                "MULTIPLEDOCUMENTSErrorCode",
+                "FILEEXISTSErrorCode",
+                "TAGEXISTSErrorCode",
+                "USEREXISTSErrorCode",
+                "LASTADMINErrorCode",
+                "INVALIDCREDENTIALSErrorCode",
+                "USERLOCKEDErrorCode",
+                "WEAKPASSWORDErrorCode",
+                "INVALIDTOKENErrorCode",
+                "SESSIONEXPIREDErrorCode",
+                "APIKEYEXPIREDErrorCode",
+                "STREAMURLEXPIREDErrorCode",
+                "NOTHUMBNAILErrorCode",
+                "MESSAGENOTFOUNDErrorCode",
+                "NOTDOCUMENTErrorCode",
+                "NOWORKERErrorCode",
+                "FLOODWAITErrorCode"
+            ]
+        },
+        "web.HttpErr": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/web.ErrorCode"
+                },
+                "msg": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "description": "also sent as the X-Request-ID header",
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "web.InfoGetResType": {
+            "type": "object",
+            "properties": {
+                "MediaCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "web.JobReqUpdateReqType": {
+            "type": "object",
+            "properties": {
+                "Claimed": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "web.LoginPostReqType": {
+            "type": "object",
+            "required": [
+                "Password",
+                "Username"
+            ],
+            "properties": {
+                "Password": {
+                    "type": "string"
+                },
+                "Username": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.LoginPostResType": {
+            "type": "object",
+            "properties": {
+                "ExpiresAt": {
+                    "description": "nil for the static api token",
+                    "type": "string"
+                },
+                "Token": {
+                    "type": "string"
+                },
+                "User": {
+                    "$ref": "#/definitions/types.UserDoc"
+                }
+            }
+        },
+        "web.MediaArchiveReqType": {
+            "type": "object",
+            "properties": {
+                "IDs": {
+                    "description": "media to archive, in order; when empty, the media matching Tags and Watched",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Name": {
+                    "description": "name of the zip file, without extension",
+                    "type": "string"
+                },
+                "Tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Watched": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "web.MediaBulkActionEnum": {
+            "type": "string",
+            "enum": [
+                "DELETE",
+                "TAG",
+                "UNTAG",
+                "REQUEST_JOB"
+            ],
+            "x-enum-varnames": [
+                "DELETEMediaBulkAction",
+                "TAGMediaBulkAction",
+                "UNTAGMediaBulkAction",
+                "REQUESTJOBMediaBulkAction"
+            ]
+        },
+        "web.MediaBulkReqType": {
+            "type": "object",
+            "required": [
+                "Action"
+            ],
+            "properties": {
+                "Action": {
+                    "enum": [
+                        "DELETE",
+                        "TAG",
+                        "UNTAG",
+                        "REQUEST_JOB"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/web.MediaBulkActionEnum"
+                        }
+                    ]
+                },
+                "DryRun": {
+                    "type": "boolean"
+                },
+                "IDs": {
+                    "description": "media to apply to; when empty, the media matching Tags and Watched",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "JobType": {
+                    "description": "for REQUEST_JOB",
+                    "enum": [
+                        "THUMBNAIL",
+                        "SPRITE"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.JobTypeEnum"
+                        }
+                    ]
+                },
+                "TagIDs": {
+                    "description": "tags to add or remove, for TAG and UNTAG",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "Tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "Watched": {
+                    "type": "boolean"
                 }
             }
-        }
-    },
-    "definitions": {
-        "types.JobReqDoc": {
+        },
+        "web.MediaBulkResType": {
             "type": "object",
             "properties": {
-                "CreatedAt": {
-                    "type": "string"
+                "Done": {
+                    "type": "integer"
                 },
-                "DeletedAt": {
-                    "type": "string"
+                "DryRun": {
+                    "type": "boolean"
                 },
-                "ID": {
-                    "type": "string"
+                "Failed": {
+                    "type": "integer"
                 },
-                "MediaID": {
-                    "type": "string"
+                "Items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/facade.BulkItemResult"
+                    }
                 },
-                "Type": {
-                    "$ref": "#/definitions/types.JobTypeEnum"
+                "Skipped": {
+                    "type": "integer"
                 },
-                "UpdatedAt": {
-                    "type": "string"
+                "Total": {
+                    "type": "integer"
                 }
             }
         },
-        "types.JobResDoc": {
+        "web.MediaImportReqType": {
             "type": "object",
             "properties": {
-                "CreatedAt": {
-                    "type": "string"
-                },
-                "DeletedAt": {
-                    "type": "string"
-                },
-                "ID": {
-                    "type": "string"
-                },
-                "JobReqID": {
-                    "type": "string"
+                "From": {
+                    "description": "first message ID of a range",
+                    "type": "integer"
                 },
-                "Sprite": {
+                "Links": {
+                    "description": "t.me/c/\u003cchannel\u003e/\u003cmessage\u003e links to messages of the storage channel",
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "type": "string"
                     }
                 },
-                "Thumbnail": {
+                "MessageIDs": {
                     "type": "array",
                     "items": {
                         "type": "integer"
                     }
                 },
-                "UpdatedAt": {
-                    "type": "string"
+                "To": {
+                    "description": "last message ID of a range, inclusive",
+                    "type": "integer"
+                }
+            }
+        },
+        "web.MediaImportResType": {
+            "type": "object",
+            "properties": {
+                "Done": {
+                    "type": "integer"
                 },
-                "Vtt": {
+                "Failed": {
+                    "type": "integer"
+                },
+                "Items": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/facade.ImportItemResult"
                     }
+                },
+                "Skipped": {
+                    "type": "integer"
+                },
+                "Total": {
+                    "type": "integer"
                 }
             }
         },
-        "types.JobTypeEnum": {
-            "type": "string",
-            "enum": [
-                "THUMBNAIL",
-                "SPRITE"
-            ],
-            "x-enum-varnames": [
-                "THUMBNAILJobType",
-                "SPRITEJobType"
-            ]
+        "web.MediaListResType": {
+            "type": "object",
+            "properties": {
+                "Media": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.MediaFileDoc"
+                    }
+                },
+                "Total": {
+                    "type": "integer"
+                }
+            }
         },
-        "types.MediaFileDoc": {
+        "web.MediaReadResType": {
             "type": "object",
             "properties": {
-                "CreatedAt": {
-                    "type": "string"
+                "Media": {
+                    "$ref": "#/definitions/types.MediaFileDoc"
                 },
-                "DeletedAt": {
+                "nextID": {
                     "type": "string"
                 },
-                "ID": {
+                "pervID": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.MediaUpdateReqType": {
+            "type": "object",
+            "properties": {
+                "Description": {
                     "type": "string"
                 },
-                "MessageID": {
-                    "type": "integer"
-                },
-                "Meta": {
-                    "$ref": "#/definitions/types.MediaFileMeta"
+                "Fields": {
+                    "description": "replaces all custom fields of the media",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "Sprite": {
+                "Name": {
                     "type": "string"
                 },
-                "Thumbnail": {
+                "Tags": {
+                    "description": "replaces all tags of the media",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "web.PlaylistCreateReqType": {
+            "type": "object",
+            "required": [
+                "Name"
+            ],
+            "properties": {
+                "Description": {
                     "type": "string"
                 },
-                "UpdatedAt": {
-                    "type": "string"
+                "Items": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "Vtt": {
+                "Name": {
                     "type": "string"
                 }
             }
         },
-        "types.MediaFileMeta": {
+        "web.PlaylistUpdateReqType": {
             "type": "object",
             "properties": {
-                "Duration": {
-                    "type": "number"
+                "Description": {
+                    "type": "string"
                 },
-                "FileID": {
-                    "type": "integer"
+                "Items": {
+                    "description": "replaces all items of the playlist",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "FileName": {
+                "Name": {
                     "type": "string"
+                }
+            }
+        },
+        "web.ProgressPutReqType": {
+            "type": "object",
+            "properties": {
+                "Position": {
+                    "description": "seconds",
+                    "type": "number"
                 },
-                "FileSize": {
-                    "type": "integer"
-                },
-                "MimeType": {
+                "Watched": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "web.RandomMediaGetResType": {
+            "type": "object",
+            "properties": {
+                "MediaID": {
                     "type": "string"
                 }
             }
         },
-        "web.InfoGetResType": {
+        "web.StashMappingReqType": {
             "type": "object",
             "properties": {
-                "MediaCount": {
-                    "type": "integer"
+                "Hash": {
+                    "description": "compute the oshash of media that do not have one first",
+                    "type": "boolean"
                 }
             }
         },
-        "web.LoginPostReqType": {
+        "web.StashScrapedSceneType": {
             "type": "object",
-            "required": [
-                "Password",
-                "Username"
-            ],
             "properties": {
-                "Password": {
+                "code": {
                     "type": "string"
                 },
-                "Username": {
+                "date": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.StashScrapedTagType"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "web.LoginPostResType": {
+        "web.StashScrapedTagType": {
             "type": "object",
             "properties": {
-                "Token": {
+                "name": {
                     "type": "string"
                 }
             }
         },
-        "web.MediaListResType": {
+        "web.StatsBucketType": {
             "type": "object",
             "properties": {
-                "Media": {
+                "Bytes": {
+                    "type": "integer"
+                },
+                "Count": {
+                    "type": "integer"
+                },
+                "Name": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.StatsGetResType": {
+            "type": "object",
+            "properties": {
+                "AddedPerDay": {
+                    "description": "last 30 days, named YYYY-MM-DD (UTC); days without media are omitted",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.MediaFileDoc"
+                        "$ref": "#/definitions/web.StatsBucketType"
                     }
                 },
-                "Total": {
+                "AddedPerWeek": {
+                    "description": "last 12 ISO weeks, named YYYY-Www",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.StatsBucketType"
+                    }
+                },
+                "ClaimedJobs": {
+                    "type": "integer"
+                },
+                "FailedJobs": {
+                    "description": "claimed over an hour ago without a result",
+                    "type": "integer"
+                },
+                "GeneratedAt": {
+                    "type": "string"
+                },
+                "MediaCount": {
+                    "type": "integer"
+                },
+                "MimeTypes": {
+                    "description": "most common first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.StatsBucketType"
+                    }
+                },
+                "MissingSprite": {
+                    "type": "integer"
+                },
+                "MissingThumbnail": {
+                    "type": "integer"
+                },
+                "PendingJobs": {
+                    "type": "integer"
+                },
+                "SizeBuckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/web.StatsBucketType"
+                    }
+                },
+                "TotalBytes": {
                     "type": "integer"
+                },
+                "TotalDuration": {
+                    "description": "seconds",
+                    "type": "number"
+                },
+                "Workers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stream.WorkerTraffic"
+                    }
                 }
             }
         },
-        "web.MediaReadResType": {
+        "web.TagCreateReqType": {
             "type": "object",
+            "required": [
+                "Name"
+            ],
             "properties": {
-                "Media": {
-                    "$ref": "#/definitions/types.MediaFileDoc"
-                },
-                "nextID": {
+                "Name": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.TagUpdateReqType": {
+            "type": "object",
+            "properties": {
+                "Name": {
+                    "type": "string"
+                }
+            }
+        },
+        "web.UserCreateReqType": {
+            "type": "object",
+            "required": [
+                "Password",
+                "Role",
+                "Username"
+            ],
+            "properties": {
+                "Password": {
                     "type": "string"
                 },
-                "pervID": {
+                "Role": {
+                    "$ref": "#/definitions/types.UserRoleEnum"
+                },
+                "Username": {
                     "type": "string"
                 }
             }
         },
-        "web.RandomMediaGetResType": {
+        "web.UserUpdateReqType": {
             "type": "object",
             "properties": {
-                "MediaID": {
+                "Password": {
                     "type": "string"
+                },
+                "Role": {
+                    "$ref": "#/definitions/types.UserRoleEnum"
                 }
             }
         }
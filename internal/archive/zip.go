@@ -0,0 +1,191 @@
+// Package archive builds zip archives on the fly from files streamed one after another.
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// utf8Flag marks zip entry names as utf-8.
+const utf8Flag = 0x800
+
+// dataDescriptorFlag makes the crc of an entry follow its content, so it is computed while streaming.
+const dataDescriptorFlag = 0x8
+
+// Sizes of the zip records written by archive/zip, see Size.
+const (
+	uint16max           = 1<<16 - 1
+	uint32max           = 1<<32 - 1
+	fileHeaderLen       = 30
+	dataDescriptorLen   = 16
+	dataDescriptor64Len = 24
+	directoryHeaderLen  = 46
+	zip64ExtraHeaderLen = 4
+	directoryEndLen     = 22
+	directory64EndLen   = 56
+	directory64LocLen   = 20
+)
+
+// Entry is a file of an archive.
+type Entry struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+	// Open returns the content of the file. It is called right before the file is written, and must return
+	// at least Size bytes.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Zip is a zip archive in store mode (no compression). Since the content is stored as is and crc values are
+// written after it, the size of the archive is known before any content is read.
+type Zip struct {
+	entries []Entry
+}
+
+// Size returns the size of the archive in bytes. It follows the layout of archive/zip: each entry is a local header
+// (without zip64 extra, as sizes are in the data descriptor), the content and a data descriptor, then comes the
+// central directory, with zip64 extras for sizes and offsets reaching 4GiB, and the end records.
+func (z *Zip) Size() (int64, error) {
+	var offset int64
+	var dirSize int64
+	zip64 := false
+	for _, e := range z.entries {
+		if len(e.Name) > uint16max {
+			return 0, fmt.Errorf("name of %.32s... is too long", e.Name)
+		}
+		name := int64(len(e.Name))
+		dirSize += directoryHeaderLen + name
+		if extra := zip64Extra(e.Size, offset); extra > 0 {
+			zip64 = true
+			dirSize += extra
+		}
+		offset += fileHeaderLen + name + e.Size
+		if e.Size > uint32max {
+			offset += dataDescriptor64Len
+		} else {
+			offset += dataDescriptorLen
+		}
+	}
+	size := offset + dirSize + directoryEndLen
+	if zip64 || len(z.entries) >= uint16max || dirSize >= uint32max || offset >= uint32max {
+		size += directory64EndLen + directory64LocLen
+	}
+	return size, nil
+}
+
+// Write streams the archive to w, reading the entries one after another.
+func (z *Zip) Write(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, e := range z.entries {
+		fh := z.header(e)
+		fw, err := zw.CreateRaw(fh)
+		if err != nil {
+			return err
+		}
+		crc, err := z.copyEntry(ctx, fw, e)
+		if err != nil {
+			return fmt.Errorf("can not write %s: %w", e.Name, err)
+		}
+		// the data descriptor and the central directory are written from fh once the next entry starts
+		fh.CRC32 = crc
+	}
+	return zw.Close()
+}
+
+// copyEntry writes the content of the entry and returns its crc.
+func (z *Zip) copyEntry(ctx context.Context, w io.Writer, e Entry) (uint32, error) {
+	r, err := e.Open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	h := crc32.NewIEEE()
+	n, err := io.CopyN(io.MultiWriter(w, h), r, e.Size)
+	if err != nil {
+		return 0, fmt.Errorf("read %d of %d bytes: %w", n, e.Size, err)
+	}
+	return h.Sum32(), nil
+}
+
+// header returns the zip header of the entry. The crc is filled in once the content is written.
+func (z *Zip) header(e Entry) *zip.FileHeader {
+	fh := &zip.FileHeader{
+		Name:               e.Name,
+		Method:             zip.Store,
+		Flags:              utf8Flag | dataDescriptorFlag,
+		CompressedSize64:   uint64(e.Size),
+		UncompressedSize64: uint64(e.Size),
+	}
+	fh.ModifiedDate, fh.ModifiedTime = msDosTime(e.Modified)
+	return fh
+}
+
+// NewZip creates an archive of the entries. Names are made safe to extract and unique within the archive.
+func NewZip(entries []Entry) *Zip {
+	z := &Zip{entries: make([]Entry, len(entries))}
+	seen := map[string]bool{}
+	for i, e := range entries {
+		e.Name = uniqueName(sanitizeName(e.Name), seen)
+		z.entries[i] = e
+	}
+	return z
+}
+
+// sanitizeName keeps entries at the root of the archive.
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		name = "file"
+	}
+	return name
+}
+
+// uniqueName appends a counter to names already in the archive, ignoring case.
+func uniqueName(name string, seen map[string]bool) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 2; seen[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	seen[strings.ToLower(unique)] = true
+	return unique
+}
+
+// msDosTime converts t to the MS-DOS date and time of zip headers, which can not go before 1980.
+func msDosTime(t time.Time) (uint16, uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date := uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm := uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, tm
+}
+
+// zip64Extra returns the size of the zip64 extra field of the central directory header of an entry, which holds
+// the sizes and the offset reaching 4GiB.
+func zip64Extra(size int64, offset int64) int64 {
+	if size < uint32max && offset < uint32max {
+		return 0
+	}
+	extra := int64(zip64ExtraHeaderLen)
+	if size >= uint32max {
+		extra += 16 // uncompressed and compressed sizes
+	}
+	if offset >= uint32max {
+		extra += 8
+	}
+	return extra
+}
@@ -0,0 +1,112 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/archive"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// zeroReader returns zeros forever.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+var _ = Describe("Zip", func() {
+	modified := time.Date(2025, 3, 4, 10, 20, 30, 0, time.UTC)
+	entry := func(name string, content string) archive.Entry {
+		return archive.Entry{
+			Name:     name,
+			Size:     int64(len(content)),
+			Modified: modified,
+			Open: func(ctx context.Context) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+		}
+	}
+	It("should stream an archive of the announced size", func() {
+		z := archive.NewZip([]archive.Entry{
+			entry("a.mp4", "first file"),
+			entry("a.mp4", "same name"),
+			entry("../dir/b.mkv", ""),
+			entry("فیلم.mp4", "utf-8 name"),
+		})
+		size, err := z.Size()
+		Expect(err).ToNot(HaveOccurred())
+		var buf bytes.Buffer
+		Expect(z.Write(context.Background(), &buf)).To(Succeed())
+		Expect(int64(buf.Len())).To(Equal(size))
+		// ...
+		r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), size)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.File).To(HaveLen(4))
+		names := []string{}
+		contents := []string{}
+		for _, f := range r.File {
+			Expect(f.Method).To(Equal(zip.Store))
+			Expect(f.Modified.Equal(modified)).To(BeTrue())
+			names = append(names, f.Name)
+			rc, err := f.Open()
+			Expect(err).ToNot(HaveOccurred())
+			content, err := io.ReadAll(rc) // fails on crc mismatch
+			Expect(err).ToNot(HaveOccurred())
+			contents = append(contents, string(content))
+		}
+		Expect(names).To(Equal([]string{"a.mp4", "a (2).mp4", "_dir_b.mkv", "فیلم.mp4"}))
+		Expect(contents).To(Equal([]string{"first file", "same name", "", "utf-8 name"}))
+	})
+	zeros := func(name string, size int64) archive.Entry {
+		return archive.Entry{Name: name, Size: size, Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(zeroReader{}), nil
+		}}
+	}
+	DescribeTable("should announce the size of zip64 archives", func(entries func() []archive.Entry) {
+		z := archive.NewZip(entries())
+		size, err := z.Size()
+		Expect(err).ToNot(HaveOccurred())
+		cw := &countingWriter{}
+		Expect(z.Write(context.Background(), cw)).To(Succeed())
+		Expect(cw.n).To(Equal(size))
+	},
+		Entry("with a file over 4GiB", func() []archive.Entry {
+			return []archive.Entry{entry("small.mp4", "small"), zeros("big.mkv", int64(1)<<32+10), entry("after.mp4", "after")}
+		}),
+		Entry("with a file of exactly 4GiB-1", func() []archive.Entry {
+			return []archive.Entry{zeros("edge.mkv", 1<<32-1), entry("after.mp4", "after")}
+		}),
+		Entry("with many files", func() []archive.Entry {
+			res := []archive.Entry{}
+			for i := range 1 << 16 {
+				res = append(res, entry(fmt.Sprintf("%d.txt", i), "x"))
+			}
+			return res
+		}),
+	)
+	It("should fail on short content", func() {
+		z := archive.NewZip([]archive.Entry{{Name: "short.mp4", Size: 100, Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("short")), nil
+		}}})
+		err := z.Write(context.Background(), io.Discard)
+		Expect(errors.Is(err, io.EOF)).To(BeTrue())
+	})
+})
+
+type countingWriter struct {
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ApiKeyPrefix marks a token as an api key rather than a session token.
+const ApiKeyPrefix = "tgm_"
+
+// apiKeyDisplayLen is the number of leading key characters kept in ApiKeyDoc.Prefix.
+const apiKeyDisplayLen = len(ApiKeyPrefix) + 6
+
+// NewApiKey generates a random api key and returns it along with its hash and display prefix.
+func NewApiKey() (key string, hash string, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("can not generate api key: %w", err)
+	}
+	key = ApiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, HashApiKey(key), key[:apiKeyDisplayLen], nil
+}
+
+// HashApiKey returns the hash under which an api key is stored.
+// Keys are random and long, so a fast hash is enough to protect them at rest.
+func HashApiKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// IsApiKey reports whether the token looks like an api key.
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+// S3Secret derives the s3 secret access key of an api key from its hash.
+func S3Secret(secret []byte, hash string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("s3:" + hash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
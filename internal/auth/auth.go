@@ -0,0 +1,304 @@
+// Package auth provides user authentication with hashed passwords, login lockout and signed session tokens.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// lastUsedResolution is how stale ApiKeyDoc.LastUsedAt may get before it is written again.
+const lastUsedResolution = time.Minute
+
+// dummyHash is compared against when the user does not exist, so unknown usernames take as long as wrong passwords.
+const dummyHash = "$2a$10$C4NbbZrXv4UMLFWbGTybCe4j1Yh3M3h62fVWLoCxEBelMZFhDCxeu"
+
+// Config holds the settings of an Authenticator.
+type Config struct {
+	// Secret is the key used to sign session tokens.
+	Secret []byte
+	// SessionTTL is the lifetime of a session created by Login.
+	SessionTTL time.Duration
+	// MaxLoginFailures is the number of consecutive failed logins after which a user is locked.
+	MaxLoginFailures int
+	// LockoutDuration is how long a user stays locked.
+	LockoutDuration time.Duration
+	// StaticToken, when set, is accepted as an admin token. Kept for clients that predate user accounts.
+	StaticToken string
+	// StreamUrlTTL is the lifetime of stream urls signed by SignStream.
+	StreamUrlTTL time.Duration
+}
+
+// Principal is the authenticated identity of a request. Either User or ApiKey is set.
+type Principal struct {
+	User *types.UserDoc
+	// Session is the login session of the principal. It is nil for the static token.
+	Session *types.SessionDoc
+	// ApiKey is the api key the request was made with. Api keys are limited to their scopes.
+	ApiKey *types.ApiKeyDoc
+}
+
+// IStreamSigner signs the stream urls of media handed out in playlists and feeds, so they can be played without
+// a token. A signature grants access to the stream of a single media until it expires.
+type IStreamSigner interface {
+	// SignStream returns the query parameters (`expires` and `signature`) of a signed stream url of a media.
+	SignStream(mediaID bson.ObjectID) url.Values
+	// VerifyStream checks the signature and expiry of a stream url of a media.
+	VerifyStream(mediaID bson.ObjectID, expires string, signature string) error
+}
+
+// IAuthenticator defines user login, token verification and logout.
+//
+//go:generate mockgen -source=auth.go -destination=../../mocks/auth/auth.go -package=mocks
+type IAuthenticator interface {
+	IStreamSigner
+	// Login checks the credentials and creates a new session. It returns the signed session token.
+	Login(ctx context.Context, username string, password string) (string, *Principal, error)
+	// Authenticate verifies a token and returns the principal it belongs to.
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+	// Logout revokes the session of the principal.
+	Logout(ctx context.Context, p *Principal) error
+	// S3Credentials returns the access key id and secret access key of an api key for the s3 gateway.
+	S3Credentials(key *types.ApiKeyDoc) (string, string)
+	// AuthenticateS3 finds the api key of an s3 access key id and returns it along with its secret access key.
+	AuthenticateS3(ctx context.Context, accessKeyID string) (*Principal, string, error)
+}
+
+// Authenticator implements IAuthenticator backed by the user and session collections.
+type Authenticator struct {
+	container db.IDbContainer
+	cfg       Config
+	signer    *tokenSigner
+	now       func() time.Time
+}
+
+var _ IAuthenticator = (*Authenticator)(nil)
+
+// Login checks the credentials, tracks failed attempts and issues a session token on success.
+func (a *Authenticator) Login(ctx context.Context, username string, password string) (string, *Principal, error) {
+	ll := a.getLogger("Login")
+	users, err := a.users().Finder().Filter(bsonx.NewD().Add(types.UserDoc__UsernameField, username).Build()).Find(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("can not find user: %w", err)
+	}
+	if len(users) == 0 {
+		_, _ = CheckPassword(dummyHash, password) //nolint:errcheck
+		return "", nil, ErrInvalidCredentials
+	}
+	user := users[0]
+	now := a.now()
+	if now.Before(user.LockedUntil) {
+		return "", nil, ErrUserLocked
+	}
+	ok, err := CheckPassword(user.PasswordHash, password)
+	if err != nil {
+		return "", nil, err
+	}
+	if !ok {
+		if err := a.registerFailure(ctx, user, now); err != nil {
+			ll.WithError(err).Error("can not register login failure")
+		}
+		return "", nil, ErrInvalidCredentials
+	}
+	if user.FailedLogins > 0 {
+		if _, err := a.users().Updater().Filter(query.Id(user.ID)).Updates(update.Set(types.UserDoc__FailedLoginsField, 0)).UpdateOne(ctx); err != nil {
+			ll.WithError(err).Error("can not reset login failures")
+		}
+		user.FailedLogins = 0
+	}
+	session := &types.SessionDoc{UserID: user.ID, ExpiresAt: now.Add(a.cfg.SessionTTL)}
+	if _, err := a.sessions().Creator().InsertOne(ctx, session); err != nil {
+		return "", nil, fmt.Errorf("can not create session: %w", err)
+	}
+	ll.Infof("user %s logged in", user.Username)
+	return a.signer.Sign(session.ID, session.ExpiresAt), &Principal{User: user, Session: session}, nil
+}
+
+// Authenticate verifies the token signature and expiry, then checks the session is not revoked and the user still exists.
+// Api keys are looked up by their hash instead.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if a.cfg.StaticToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.cfg.StaticToken)) == 1 {
+		return &Principal{User: &types.UserDoc{Username: "api-token", Role: types.ADMINUserRole}}, nil
+	}
+	if IsApiKey(token) {
+		return a.authenticateApiKey(ctx, token)
+	}
+	now := a.now()
+	sid, err := a.signer.Verify(token, now)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := a.sessions().Finder().Filter(query.Id(sid)).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not find session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, ErrSessionExpired
+	}
+	session := sessions[0]
+	if session.Revoked || !now.Before(session.ExpiresAt) {
+		return nil, ErrSessionExpired
+	}
+	users, err := a.users().Finder().Filter(query.Id(session.UserID)).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not find user: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, ErrSessionExpired
+	}
+	return &Principal{User: users[0], Session: session}, nil
+}
+
+// Logout revokes the session of the principal. It is a no-op for the static token.
+func (a *Authenticator) Logout(ctx context.Context, p *Principal) error {
+	if p == nil || p.Session == nil {
+		return nil
+	}
+	if _, err := a.sessions().Updater().Filter(query.Id(p.Session.ID)).Updates(update.Set(types.SessionDoc__RevokedField, true)).UpdateOne(ctx); err != nil {
+		return fmt.Errorf("can not revoke session: %w", err)
+	}
+	return nil
+}
+
+// S3Credentials returns the s3 credentials of an api key: its id as the access key id, and a secret derived from
+// the key hash and the session secret. SigV4 needs the secret on both sides, so it is derived rather than stored,
+// which also means changing the session secret changes the s3 secrets.
+func (a *Authenticator) S3Credentials(key *types.ApiKeyDoc) (string, string) {
+	return key.ID.Hex(), S3Secret(a.cfg.Secret, key.Hash)
+}
+
+// AuthenticateS3 finds the api key of an s3 access key id, checks its expiry and records its usage.
+// The caller verifies the request signature with the returned secret access key.
+func (a *Authenticator) AuthenticateS3(ctx context.Context, accessKeyID string) (*Principal, string, error) {
+	id, err := bson.ObjectIDFromHex(accessKeyID)
+	if err != nil {
+		return nil, "", ErrInvalidToken
+	}
+	p, err := a.findApiKey(ctx, query.Id(id))
+	if err != nil {
+		return nil, "", err
+	}
+	_, secret := a.S3Credentials(p.ApiKey)
+	return p, secret, nil
+}
+
+// SignStream signs the stream url of a media for StreamUrlTTL.
+func (a *Authenticator) SignStream(mediaID bson.ObjectID) url.Values {
+	expiresAt := a.now().Add(a.cfg.StreamUrlTTL)
+	return url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {a.signer.SignStream(mediaID, expiresAt)},
+	}
+}
+
+// VerifyStream checks the signature and expiry of the stream url of a media.
+func (a *Authenticator) VerifyStream(mediaID bson.ObjectID, expires string, signature string) error {
+	return a.signer.VerifyStream(mediaID, expires, signature, a.now())
+}
+
+// authenticateApiKey finds the api key by its hash.
+func (a *Authenticator) authenticateApiKey(ctx context.Context, token string) (*Principal, error) {
+	return a.findApiKey(ctx, bsonx.NewD().Add(types.ApiKeyDoc__HashField, HashApiKey(token)).Build())
+}
+
+// findApiKey finds the api key matching the filter, checks its expiry and its creator, and records its usage.
+func (a *Authenticator) findApiKey(ctx context.Context, filter bson.D) (*Principal, error) {
+	ll := a.getLogger("findApiKey")
+	keys, err := a.apiKeys().Finder().Filter(filter).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not find api key: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, ErrInvalidToken
+	}
+	key := keys[0]
+	now := a.now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrApiKeyExpired
+	}
+	if err := a.checkApiKeyCreator(ctx, key); err != nil {
+		return nil, err
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		if _, err := a.apiKeys().Updater().Filter(query.Id(key.ID)).Updates(update.Set(types.ApiKeyDoc__LastUsedAtField, now)).UpdateOne(ctx); err != nil {
+			ll.WithError(err).Error("can not update api key last usage")
+		}
+		key.LastUsedAt = &now
+	}
+	return &Principal{ApiKey: key}, nil
+}
+
+// checkApiKeyCreator rejects keys whose creator was deleted or is no longer an admin, as only admins hold api keys.
+// Keys created with the static token have no creator.
+func (a *Authenticator) checkApiKeyCreator(ctx context.Context, key *types.ApiKeyDoc) error {
+	if key.CreatedBy.IsZero() {
+		return nil
+	}
+	users, err := a.users().Finder().Filter(query.Id(key.CreatedBy)).Find(ctx)
+	if err != nil {
+		return fmt.Errorf("can not find api key creator: %w", err)
+	}
+	if len(users) == 0 || users[0].Role != types.ADMINUserRole {
+		a.getLogger("checkApiKeyCreator").Warnf("api key %s rejected: its creator is not an admin anymore", key.Prefix)
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// registerFailure increments the failed login counter of the user and locks it once the limit is reached.
+func (a *Authenticator) registerFailure(ctx context.Context, user *types.UserDoc, now time.Time) error {
+	ll := a.getLogger("registerFailure")
+	failures := user.FailedLogins + 1
+	fields := bson.D{{Key: types.UserDoc__FailedLoginsField, Value: failures}}
+	if a.cfg.MaxLoginFailures > 0 && failures >= a.cfg.MaxLoginFailures {
+		fields = bson.D{
+			{Key: types.UserDoc__FailedLoginsField, Value: 0},
+			{Key: types.UserDoc__LockedUntilField, Value: now.Add(a.cfg.LockoutDuration)},
+		}
+		ll.Warnf("user %s locked after %d failed logins", user.Username, failures)
+	}
+	if _, err := a.users().Updater().Filter(query.Id(user.ID)).Updates(bson.D{{Key: "$set", Value: fields}}).UpdateOne(ctx); err != nil {
+		return fmt.Errorf("can not update user: %w", err)
+	}
+	return nil
+}
+
+func (a *Authenticator) users() mngo.ICollection[types.UserDoc] {
+	return a.container.GetMongoContainer().GetUserCollection()
+}
+
+func (a *Authenticator) apiKeys() mngo.ICollection[types.ApiKeyDoc] {
+	return a.container.GetMongoContainer().GetApiKeyCollection()
+}
+
+func (a *Authenticator) sessions() mngo.ICollection[types.SessionDoc] {
+	return a.container.GetMongoContainer().GetSessionCollection()
+}
+
+// getLogger returns a logrus.Entry for the given function name, tagged with the struct type.
+func (a *Authenticator) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.AuthModule).WithField("func", fmt.Sprintf("%T.%s", a, fn))
+}
+
+// NewAuthenticator creates a new Authenticator with the provided database container and config.
+func NewAuthenticator(container db.IDbContainer, cfg Config) *Authenticator {
+	return &Authenticator{
+		container: container,
+		cfg:       cfg,
+		signer:    &tokenSigner{secret: cfg.Secret},
+		now:       time.Now,
+	}
+}
@@ -0,0 +1,15 @@
+package auth_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAuth(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Auth Suite")
+}
@@ -0,0 +1,326 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mDb "github.com/amirdaaee/TGMon/mocks/db"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Password", func() {
+	It("should hash and check password", func() {
+		h, err := auth.HashPassword("correct horse")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(h).ToNot(Equal("correct horse"))
+		ok, err := auth.CheckPassword(h, "correct horse")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		ok, err = auth.CheckPassword(h, "wrong horse")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+	It("should reject short password", func() {
+		_, err := auth.HashPassword("short")
+		Expect(err).To(MatchError(auth.ErrWeakPassword))
+	})
+})
+
+var _ = Describe("Authenticator", func() {
+	const password = "secret-password"
+	var (
+		ctrl            *gomock.Controller
+		mockContainer   *mDb.MockIDbContainer
+		mockUserFinder  *mMongoX.MockIFinder[types.UserDoc]
+		mockUserUpdater *mMongoX.MockIUpdater[types.UserDoc]
+		mockSessFinder  *mMongoX.MockIFinder[types.SessionDoc]
+		mockSessCreator *mMongoX.MockICreator[types.SessionDoc]
+		mockKeyFinder   *mMongoX.MockIFinder[types.ApiKeyDoc]
+		mockKeyUpdater  *mMongoX.MockIUpdater[types.ApiKeyDoc]
+		authenticator   *auth.Authenticator
+		user            *types.UserDoc
+		cfg             auth.Config
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockUserFinder = mMongoX.NewMockIFinder[types.UserDoc](ctrl)
+		mockUserUpdater = mMongoX.NewMockIUpdater[types.UserDoc](ctrl)
+		mockSessFinder = mMongoX.NewMockIFinder[types.SessionDoc](ctrl)
+		mockSessCreator = mMongoX.NewMockICreator[types.SessionDoc](ctrl)
+		mockKeyFinder = mMongoX.NewMockIFinder[types.ApiKeyDoc](ctrl)
+		mockKeyUpdater = mMongoX.NewMockIUpdater[types.ApiKeyDoc](ctrl)
+		keyColl := mMongo.NewMockICollection[types.ApiKeyDoc](ctrl)
+		keyColl.EXPECT().Finder().Return(mockKeyFinder).AnyTimes()
+		keyColl.EXPECT().Updater().Return(mockKeyUpdater).AnyTimes()
+		userColl := mMongo.NewMockICollection[types.UserDoc](ctrl)
+		userColl.EXPECT().Finder().Return(mockUserFinder).AnyTimes()
+		userColl.EXPECT().Updater().Return(mockUserUpdater).AnyTimes()
+		sessColl := mMongo.NewMockICollection[types.SessionDoc](ctrl)
+		sessColl.EXPECT().Finder().Return(mockSessFinder).AnyTimes()
+		sessColl.EXPECT().Creator().Return(mockSessCreator).AnyTimes()
+		mockMongoContainer := mMongo.NewMockIMongoContainer(ctrl)
+		mockMongoContainer.EXPECT().GetUserCollection().Return(userColl).AnyTimes()
+		mockMongoContainer.EXPECT().GetSessionCollection().Return(sessColl).AnyTimes()
+		mockMongoContainer.EXPECT().GetApiKeyCollection().Return(keyColl).AnyTimes()
+		mockContainer = mDb.NewMockIDbContainer(ctrl)
+		mockContainer.EXPECT().GetMongoContainer().Return(mockMongoContainer).AnyTimes()
+		mockUserFinder.EXPECT().Filter(gomock.Any()).Return(mockUserFinder).AnyTimes()
+		mockUserUpdater.EXPECT().Filter(gomock.Any()).Return(mockUserUpdater).AnyTimes()
+		mockSessFinder.EXPECT().Filter(gomock.Any()).Return(mockSessFinder).AnyTimes()
+		// ...
+		hash, err := auth.HashPassword(password)
+		Expect(err).ToNot(HaveOccurred())
+		user = &types.UserDoc{Username: "admin", PasswordHash: hash, Role: types.ADMINUserRole}
+		user.ID = bson.NewObjectID()
+		cfg = auth.Config{
+			Secret:           []byte("test-secret"),
+			SessionTTL:       time.Hour,
+			MaxLoginFailures: 3,
+			LockoutDuration:  time.Minute,
+			StaticToken:      "static-token",
+		}
+		authenticator = auth.NewAuthenticator(mockContainer, cfg)
+	})
+	Describe("Login", func() {
+		type testCase struct {
+			userFound       bool
+			password        string
+			failedLogins    int
+			locked          bool
+			expectErr       error
+			expectLockout   bool
+			expectFailCount bool
+		}
+		DescribeTable("", func(tc testCase) {
+			ctx := context.Background()
+			found := []*types.UserDoc{}
+			if tc.userFound {
+				user.FailedLogins = tc.failedLogins
+				if tc.locked {
+					user.LockedUntil = time.Now().Add(time.Minute)
+				}
+				found = append(found, user)
+			}
+			mockUserFinder.EXPECT().Find(ctx).Return(found, nil)
+			if tc.expectFailCount {
+				mockUserUpdater.EXPECT().Updates(gomock.Any()).DoAndReturn(func(u any) any {
+					set := u.(bson.D)[0].Value.(bson.D)
+					if tc.expectLockout {
+						Expect(set).To(ContainElement(HaveField("Key", types.UserDoc__LockedUntilField)))
+					} else {
+						Expect(set).To(Equal(bson.D{{Key: types.UserDoc__FailedLoginsField, Value: tc.failedLogins + 1}}))
+					}
+					return mockUserUpdater
+				})
+				mockUserUpdater.EXPECT().UpdateOne(ctx).Return(&mongo.UpdateResult{}, nil)
+			}
+			if tc.expectErr == nil {
+				mockSessCreator.EXPECT().InsertOne(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *types.SessionDoc, _ ...any) (*mongo.InsertOneResult, error) {
+					s.ID = bson.NewObjectID()
+					return &mongo.InsertOneResult{}, nil
+				})
+			}
+			token, p, err := authenticator.Login(ctx, "admin", tc.password)
+			if tc.expectErr != nil {
+				Expect(err).To(MatchError(tc.expectErr))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).ToNot(BeEmpty())
+			Expect(p.User).To(Equal(user))
+			Expect(p.Session.UserID).To(Equal(user.ID))
+		},
+			Entry("should login with correct password", testCase{userFound: true, password: password}),
+			Entry("should reject unknown user", testCase{password: password, expectErr: auth.ErrInvalidCredentials}),
+			Entry("should count failed login", testCase{userFound: true, password: "wrong", expectErr: auth.ErrInvalidCredentials, expectFailCount: true}),
+			Entry("should lock user after too many failures", testCase{userFound: true, password: "wrong", failedLogins: 2, expectErr: auth.ErrInvalidCredentials, expectFailCount: true, expectLockout: true}),
+			Entry("should reject locked user even with correct password", testCase{userFound: true, password: password, locked: true, expectErr: auth.ErrUserLocked}),
+		)
+	})
+	Describe("Authenticate", func() {
+		var token string
+		var session *types.SessionDoc
+		BeforeEach(func() {
+			ctx := context.Background()
+			mockUserFinder.EXPECT().Find(ctx).Return([]*types.UserDoc{user}, nil)
+			mockSessCreator.EXPECT().InsertOne(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, s *types.SessionDoc, _ ...any) (*mongo.InsertOneResult, error) {
+				s.ID = bson.NewObjectID()
+				session = s
+				return &mongo.InsertOneResult{}, nil
+			})
+			var err error
+			token, _, err = authenticator.Login(ctx, "admin", password)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should accept valid session token", func() {
+			ctx := context.Background()
+			mockSessFinder.EXPECT().Find(ctx).Return([]*types.SessionDoc{session}, nil)
+			mockUserFinder.EXPECT().Find(ctx).Return([]*types.UserDoc{user}, nil)
+			p, err := authenticator.Authenticate(ctx, token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.User).To(Equal(user))
+		})
+		It("should reject revoked session", func() {
+			ctx := context.Background()
+			session.Revoked = true
+			mockSessFinder.EXPECT().Find(ctx).Return([]*types.SessionDoc{session}, nil)
+			_, err := authenticator.Authenticate(ctx, token)
+			Expect(err).To(MatchError(auth.ErrSessionExpired))
+		})
+		It("should reject tampered token", func() {
+			_, err := authenticator.Authenticate(context.Background(), token+"x")
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+		It("should reject token signed with another secret", func() {
+			cfg.Secret = []byte("other-secret")
+			_, err := auth.NewAuthenticator(mockContainer, cfg).Authenticate(context.Background(), token)
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+		It("should accept static token as admin", func() {
+			p, err := authenticator.Authenticate(context.Background(), "static-token")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.User.Role).To(Equal(types.ADMINUserRole))
+			Expect(p.Session).To(BeNil())
+		})
+	})
+	Describe("Authenticate api key", func() {
+		newUser := func(role types.UserRoleEnum) *types.UserDoc {
+			u := &types.UserDoc{Username: "creator", Role: role}
+			u.ID = bson.NewObjectID()
+			return u
+		}
+		type testCase struct {
+			found        bool
+			expired      bool
+			recentlyUsed bool
+			creator      *types.UserDoc // nil for keys without creator
+			creatorGone  bool
+			expectErr    error
+			expectWrite  bool
+		}
+		DescribeTable("", func(tc testCase) {
+			ctx := context.Background()
+			key, hash, prefix, err := auth.NewApiKey()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(auth.IsApiKey(key)).To(BeTrue())
+			Expect(key).To(HavePrefix(prefix))
+			doc := &types.ApiKeyDoc{Hash: hash, Scopes: []types.ApiKeyScopeEnum{types.MEDIAREADApiKeyScope}}
+			if tc.expired {
+				exp := time.Now().Add(-time.Minute)
+				doc.ExpiresAt = &exp
+			}
+			if tc.recentlyUsed {
+				used := time.Now()
+				doc.LastUsedAt = &used
+			}
+			found := []*types.ApiKeyDoc{}
+			if tc.found {
+				found = append(found, doc)
+			}
+			if tc.creator != nil {
+				doc.CreatedBy = tc.creator.ID
+				creators := []*types.UserDoc{tc.creator}
+				if tc.creatorGone {
+					creators = nil
+				}
+				mockUserFinder.EXPECT().Find(ctx).Return(creators, nil)
+			}
+			mockKeyFinder.EXPECT().Filter(bson.D{{Key: types.ApiKeyDoc__HashField, Value: hash}}).Return(mockKeyFinder)
+			mockKeyFinder.EXPECT().Find(ctx).Return(found, nil)
+			if tc.expectWrite {
+				mockKeyUpdater.EXPECT().Filter(gomock.Any()).Return(mockKeyUpdater)
+				mockKeyUpdater.EXPECT().Updates(gomock.Any()).Return(mockKeyUpdater)
+				mockKeyUpdater.EXPECT().UpdateOne(ctx).Return(&mongo.UpdateResult{}, nil)
+			}
+			p, err := authenticator.Authenticate(ctx, key)
+			if tc.expectErr != nil {
+				Expect(err).To(MatchError(tc.expectErr))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.User).To(BeNil())
+			Expect(p.ApiKey).To(Equal(doc))
+			Expect(p.ApiKey.LastUsedAt).ToNot(BeNil())
+		},
+			Entry("should accept key and record usage", testCase{found: true, expectWrite: true}),
+			Entry("should not record usage again right away", testCase{found: true, recentlyUsed: true}),
+			Entry("should reject unknown key", testCase{expectErr: auth.ErrInvalidToken}),
+			Entry("should reject expired key", testCase{found: true, expired: true, expectErr: auth.ErrApiKeyExpired}),
+			Entry("should accept key of an admin", testCase{found: true, recentlyUsed: true, creator: newUser(types.ADMINUserRole)}),
+			Entry("should reject key of a deleted admin", testCase{found: true, creator: newUser(types.ADMINUserRole), creatorGone: true, expectErr: auth.ErrInvalidToken}),
+			Entry("should reject key of a demoted admin", testCase{found: true, creator: newUser(types.EDITORUserRole), expectErr: auth.ErrInvalidToken}),
+		)
+	})
+	Describe("Authenticate s3", func() {
+		var doc *types.ApiKeyDoc
+		BeforeEach(func() {
+			_, hash, _, err := auth.NewApiKey()
+			Expect(err).ToNot(HaveOccurred())
+			used := time.Now()
+			doc = &types.ApiKeyDoc{Hash: hash, LastUsedAt: &used}
+			doc.ID = bson.NewObjectID()
+		})
+		It("should find the key by its access key id and return its secret", func() {
+			ctx := context.Background()
+			accessKeyID, secret := authenticator.S3Credentials(doc)
+			Expect(accessKeyID).To(Equal(doc.ID.Hex()))
+			Expect(secret).To(Equal(auth.S3Secret(cfg.Secret, doc.Hash)))
+			mockKeyFinder.EXPECT().Filter(bson.D{{Key: "_id", Value: doc.ID}}).Return(mockKeyFinder)
+			mockKeyFinder.EXPECT().Find(ctx).Return([]*types.ApiKeyDoc{doc}, nil)
+			p, gotSecret, err := authenticator.AuthenticateS3(ctx, accessKeyID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.ApiKey).To(Equal(doc))
+			Expect(gotSecret).To(Equal(secret))
+		})
+		It("should derive different secrets for different session secrets", func() {
+			Expect(auth.S3Secret([]byte("a"), doc.Hash)).ToNot(Equal(auth.S3Secret([]byte("b"), doc.Hash)))
+		})
+		It("should reject malformed access key id", func() {
+			_, _, err := authenticator.AuthenticateS3(context.Background(), "not-an-id")
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+	})
+	Describe("SignStream", func() {
+		var mediaID bson.ObjectID
+		BeforeEach(func() {
+			cfg.StreamUrlTTL = time.Hour
+			authenticator = auth.NewAuthenticator(mockContainer, cfg)
+			mediaID = bson.NewObjectID()
+		})
+		It("should verify a signed stream url of the media", func() {
+			q := authenticator.SignStream(mediaID)
+			Expect(q.Get("token")).To(BeEmpty())
+			Expect(authenticator.VerifyStream(mediaID, q.Get("expires"), q.Get("signature"))).To(Succeed())
+		})
+		It("should reject the signature for another media", func() {
+			q := authenticator.SignStream(mediaID)
+			Expect(authenticator.VerifyStream(bson.NewObjectID(), q.Get("expires"), q.Get("signature"))).To(MatchError(auth.ErrInvalidToken))
+		})
+		It("should reject an extended expiry", func() {
+			q := authenticator.SignStream(mediaID)
+			expires := strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10)
+			Expect(authenticator.VerifyStream(mediaID, expires, q.Get("signature"))).To(MatchError(auth.ErrInvalidToken))
+		})
+		It("should reject an expired url", func() {
+			cfg.StreamUrlTTL = -time.Minute
+			q := auth.NewAuthenticator(mockContainer, cfg).SignStream(mediaID)
+			Expect(authenticator.VerifyStream(mediaID, q.Get("expires"), q.Get("signature"))).To(MatchError(auth.ErrStreamUrlExpired))
+		})
+		It("should not accept a stream signature as a session token", func() {
+			q := authenticator.SignStream(mediaID)
+			token := fmt.Sprintf("%s.%s.%s", mediaID.Hex(), q.Get("expires"), q.Get("signature"))
+			_, err := authenticator.Authenticate(context.Background(), token)
+			Expect(err).To(MatchError(auth.ErrInvalidToken))
+		})
+	})
+})
@@ -0,0 +1,11 @@
+package auth
+
+import "errors"
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+var ErrUserLocked = errors.New("user is temporarily locked due to repeated login failures")
+var ErrInvalidToken = errors.New("invalid token")
+var ErrSessionExpired = errors.New("session expired or revoked")
+var ErrWeakPassword = errors.New("password is too short")
+var ErrApiKeyExpired = errors.New("api key expired")
+var ErrStreamUrlExpired = errors.New("stream url expired")
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordMinLen is the minimum accepted length of a user password.
+const PasswordMinLen = 8
+
+// HashPassword validates the password and returns its bcrypt hash.
+func HashPassword(password string) (string, error) {
+	if len(password) < PasswordMinLen {
+		return "", fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, PasswordMinLen)
+	}
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("can not hash password: %w", err)
+	}
+	return string(h), nil
+}
+
+// CheckPassword reports whether password matches the given bcrypt hash.
+func CheckPassword(hash string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, fmt.Errorf("can not check password: %w", err)
+}
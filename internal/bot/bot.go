@@ -2,6 +2,7 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/amirdaaee/TGMon/internal/log"
@@ -22,6 +23,15 @@ func (b *Bot) Start() error {
 	return b.cl.GetClient().Idle()
 }
 
+// Ping checks that the bot is connected to telegram.
+func (b *Bot) Ping(ctx context.Context) error {
+	cl := b.cl.GetClient()
+	if cl == nil {
+		return fmt.Errorf("client is not connected")
+	}
+	return cl.Ping(ctx)
+}
+
 // getLogger returns a logger entry with function context for the Bot.
 func (b *Bot) getLogger(fn string) *logrus.Entry {
 	return log.GetLogger(log.BotModule).WithField("func", fmt.Sprintf("%T.%s", b, fn))
@@ -1,7 +1,19 @@
 // Package bot provides error types and helpers for the bot module.
 package bot
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoWorker indicates that there is no worker to read the forwarded document.
+var ErrNoWorker = errors.New("no available worker")
+
+// ErrNoMedia indicates that a reply does not refer to a media.
+var ErrNoMedia = errors.New("message does not refer to a media")
+
+// ErrNotDocument indicates that a message does not carry a document.
+var ErrNotDocument = errors.New("message is not a document")
 
 // BotError represents an error in the bot package with an optional wrapped error.
 type BotError struct {
@@ -3,11 +3,15 @@ package bot
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"strings"
 
 	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/celestix/gotgproto/ext"
 	tgTypes "github.com/celestix/gotgproto/types"
 	"github.com/gotd/td/tg"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // forward forwards a message from one chat to another and returns the new message.
@@ -48,3 +52,82 @@ func forward(ctx *ext.Context, u *ext.Update, targetID int64) (*tgTypes.Message,
 	ll.Debugf("got forwarded message: %+v", m)
 	return m, nil
 }
+
+// hashtagRe matches telegram style hashtags (e.g. #some_tag).
+var hashtagRe = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// parseHashtags returns the hashtags found in the given text, without the leading '#'.
+func parseHashtags(text string) []string {
+	matches := hashtagRe.FindAllStringSubmatch(text, -1)
+	tags := make([]string, 0, len(matches))
+	for _, m := range matches {
+		tags = append(tags, m[1])
+	}
+	return tags
+}
+
+// successMediaPrefix starts the line of success messages holding the media ID.
+const successMediaPrefix = "id: "
+
+// successMediaRe matches the media ID line of success messages.
+var successMediaRe = regexp.MustCompile(`(?m)^` + successMediaPrefix + `([0-9a-f]{24})$`)
+
+// parseSuccessMediaID returns the media ID of a success message.
+func parseSuccessMediaID(text string) (bson.ObjectID, bool) {
+	m := successMediaRe.FindStringSubmatch(text)
+	if m == nil {
+		return bson.ObjectID{}, false
+	}
+	id, err := bson.ObjectIDFromHex(m[1])
+	return id, err == nil
+}
+
+// subtitleExts are the extensions of the subtitle files attached to media.
+var subtitleExts = map[string]bool{".srt": true, ".vtt": true, ".ass": true, ".ssa": true}
+
+// subtitleDoc returns the document of a message if it is a subtitle file.
+func subtitleDoc(msg *tgTypes.Message) (*tg.Document, bool) {
+	media, ok := msg.Media.(*tg.MessageMediaDocument)
+	if !ok {
+		return nil, false
+	}
+	doc, ok := media.Document.(*tg.Document)
+	if !ok {
+		return nil, false
+	}
+	for _, attr := range doc.Attributes {
+		if v, ok := attr.(*tg.DocumentAttributeFilename); ok {
+			return doc, subtitleExts[strings.ToLower(path.Ext(v.FileName))]
+		}
+	}
+	return nil, false
+}
+
+// subtitleLanguage returns the language of a subtitle: the caption of its message, or the second extension of its
+// file name (e.g. en of movie.en.srt).
+func subtitleLanguage(caption string, fileName string) string {
+	if caption = strings.TrimSpace(caption); caption != "" {
+		return caption
+	}
+	name := strings.TrimSuffix(fileName, path.Ext(fileName))
+	return strings.TrimPrefix(path.Ext(name), ".")
+}
+
+// repliedMessage returns the message the message of an update replies to.
+func repliedMessage(ctx *ext.Context, u *ext.Update) (*tgTypes.Message, error) {
+	if u.EffectiveMessage.ReplyToMessage != nil {
+		return u.EffectiveMessage.ReplyToMessage, nil
+	}
+	header, ok := u.EffectiveMessage.ReplyTo.(*tg.MessageReplyHeader)
+	if !ok || header.ReplyToMsgID == 0 {
+		return nil, NewBotError("message is not a reply", nil)
+	}
+	msgs, err := ctx.GetMessages(u.EffectiveChat().GetID(), []tg.InputMessageClass{&tg.InputMessageID{ID: header.ReplyToMsgID}})
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, NewBotError("replied message not found", nil)
+	}
+	return tgTypes.ConstructMessage(msgs[0]), nil
+}
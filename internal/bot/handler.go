@@ -2,8 +2,10 @@
 package bot
 
 import (
+	"bytes"
 	"fmt"
 
+	"github.com/amirdaaee/TGMon/internal/db/minio"
 	"github.com/amirdaaee/TGMon/internal/facade"
 	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/amirdaaee/TGMon/internal/stream"
@@ -28,6 +30,8 @@ type IHandler interface {
 type handler struct {
 	channelID       int64
 	mediaFacade     facade.IFacade[types.MediaFileDoc]
+	tagFacade       facade.IFacade[types.TagDoc]
+	minioClient     minio.IMinioClient
 	workerContainer stream.IWorkerPool
 }
 
@@ -44,15 +48,20 @@ func (h *handler) Register(b *Bot) {
 }
 
 // handleDoc processes incoming media messages from users, forwards them, and stores metadata.
+// Hashtags in the message caption are added as tags of the media.
+// Subtitle documents sent as a reply to a success message are attached to its media instead.
 func (h *handler) handleDoc(ctx *ext.Context, u *ext.Update) error {
 	ll := h.getLogger("handleDoc")
 	ll.Debug("new message received")
 	if !u.EffectiveChat().IsAUser() {
 		return NewBotError("message is not from a user", nil)
 	}
+	if doc, ok := subtitleDoc(u.EffectiveMessage); ok && u.EffectiveMessage.ReplyTo != nil {
+		return h.handleSubtitle(ctx, u, doc)
+	}
 	worker := h.workerContainer.GetNextWorker()
 	if worker == nil {
-		return NewBotError("no available worker", nil)
+		return NewBotError("can not handle message", ErrNoWorker)
 	}
 	// Forward message and process result
 	fwMsg, err := forward(ctx, u, h.channelID)
@@ -69,6 +78,15 @@ func (h *handler) handleDoc(ctx *ext.Context, u *ext.Update) error {
 	if err != nil {
 		return NewBotError("can not build media file doc", err)
 	}
+	// Tag media using hashtags of the message caption
+	if tags := parseHashtags(u.EffectiveMessage.Text); len(tags) > 0 {
+		tagIDs, err := facade.EnsureTags(ctx, h.tagFacade, tags)
+		if err != nil {
+			ll.WithError(err).Error("can not tag media file doc")
+		} else {
+			docDoc.Tags = tagIDs
+		}
+	}
 	d, err := h.mediaFacade.CreateOne(ctx, &docDoc)
 	if err != nil {
 		return NewBotError("can not create media file doc", err)
@@ -82,25 +100,59 @@ func (h *handler) handleDoc(ctx *ext.Context, u *ext.Update) error {
 
 // buildMediaFileDoc creates a MediaFileDoc from a document and message ID.
 func (h *handler) buildMediaFileDoc(newDoc any, msgID int) (types.MediaFileDoc, error) {
-	docMeta := types.MediaFileMeta{}
 	doc, ok := newDoc.(*tg.Document)
 	if !ok {
-		return types.MediaFileDoc{}, NewBotError(fmt.Sprintf("newDoc is not a *tg.Document: %T", newDoc), nil)
+		return types.MediaFileDoc{}, NewBotError(fmt.Sprintf("newDoc is %T", newDoc), ErrNotDocument)
 	}
-	if err := docMeta.FillFromDocument(doc); err != nil {
+	mediaDoc, err := types.NewMediaFileDoc(doc, msgID)
+	if err != nil {
 		return types.MediaFileDoc{}, NewBotError("can not fill document meta", err)
 	}
-	return types.MediaFileDoc{
-		Meta:      docMeta,
-		MessageID: msgID,
-	}, nil
+	return mediaDoc, nil
+}
+
+// handleSubtitle attaches a subtitle document to the media of the success message it replies to. The language is
+// the caption of the message, or taken from the file name (e.g. movie.en.srt).
+func (h *handler) handleSubtitle(ctx *ext.Context, u *ext.Update, doc *tg.Document) error {
+	ll := h.getLogger("handleSubtitle")
+	replied, err := repliedMessage(ctx, u)
+	if err != nil {
+		return NewBotError("can not get replied message", err)
+	}
+	mediaID, ok := parseSuccessMediaID(replied.Text)
+	if !ok {
+		return NewBotError("reply to a success message to add a subtitle", ErrNoMedia)
+	}
+	meta := types.MediaFileMeta{}
+	if err := meta.FillFromDocument(doc); err != nil {
+		return NewBotError("can not fill document meta", err)
+	}
+	language := subtitleLanguage(u.EffectiveMessage.Text, meta.FileName)
+	if doc.Size > facade.SubtitleMaxSize {
+		return NewBotError(fmt.Sprintf("subtitle is larger than %d bytes", facade.SubtitleMaxSize), nil)
+	}
+	var buf bytes.Buffer
+	if _, err := ctx.DownloadMedia(u.EffectiveMessage.Media, ext.DownloadOutputStream{Writer: &buf}, nil); err != nil {
+		return NewBotError("can not download subtitle", err)
+	}
+	sub, err := facade.AddSubtitle(ctx, h.mediaFacade, h.minioClient, mediaID, language, meta.FileName, buf.Bytes())
+	if err != nil {
+		return NewBotError("can not add subtitle", err)
+	}
+	ll.Infof("subtitle %s added to media %s", sub.Language, mediaID.Hex())
+	m := fmt.Sprintf("ok: subtitle %s (%s)", sub.Language, sub.Format)
+	if _, err := ctx.Reply(u, ext.ReplyTextString(m), &ext.ReplyOpts{ReplyToMessageId: u.EffectiveMessage.ID}); err != nil {
+		ll.WithError(err).Error("can not send success message")
+	}
+	return nil
 }
 
 // sendSuccessMsg sends a confirmation message to the user after successful processing.
+// The message holds the media ID, so replies to it can refer to the media.
 func (h *handler) sendSuccessMsg(ctx *ext.Context, u *ext.Update, doc *types.MediaFileDoc) error {
 	ll := h.getLogger("sendSuccessMsg")
 	ll.Debugf("sending success message")
-	m := fmt.Sprintf("ok: %s (%d)", doc.Meta.FileName, doc.Meta.FileID)
+	m := fmt.Sprintf("ok: %s (%d)\n%s%s", doc.Meta.FileName, doc.Meta.FileID, successMediaPrefix, doc.ID.Hex())
 	if _, err := ctx.Reply(u, ext.ReplyTextString(m), &ext.ReplyOpts{ReplyToMessageId: u.EffectiveMessage.ID}); err != nil {
 		return NewBotError("failed to send success message", err)
 	}
@@ -121,15 +173,23 @@ var _ IHandler = (*handler)(nil)
 
 // NewHandler creates a new handler instance with the given dependencies.
 // Returns an error if any dependency is nil.
-func NewHandler(mediaFacade facade.IFacade[types.MediaFileDoc], channelID int64, wp stream.IWorkerPool) (IHandler, error) {
+func NewHandler(mediaFacade facade.IFacade[types.MediaFileDoc], tagFacade facade.IFacade[types.TagDoc], minioClient minio.IMinioClient, channelID int64, wp stream.IWorkerPool) (IHandler, error) {
 	if mediaFacade == nil {
 		return nil, NewBotError("mediaFacade cannot be nil", nil)
 	}
+	if tagFacade == nil {
+		return nil, NewBotError("tagFacade cannot be nil", nil)
+	}
+	if minioClient == nil {
+		return nil, NewBotError("minioClient cannot be nil", nil)
+	}
 	if wp == nil {
 		return nil, NewBotError("workerContainer cannot be nil", nil)
 	}
 	return &handler{
 		mediaFacade:     mediaFacade,
+		tagFacade:       tagFacade,
+		minioClient:     minioClient,
 		channelID:       channelID,
 		workerContainer: wp,
 	}, nil
@@ -1,12 +1,33 @@
 package config
 
+import "time"
+
 type HttpConfigType struct {
-	UserName     string   `env:"USER_NAME,required"`
-	UserPass     string   `env:"USER_PASS,required"`
-	ApiToken     string   `env:"API_TOKEN,required"`
-	Swagger      bool     `env:"SWAGGER" envDefault:"false"`
-	CoresAllowed []string `env:"CORES_ALLOWED_ORIGINS"`
-	ListenAddr   string   `env:"LISTEN_ADDR" envDefault:":8080"`
+	UserName        string        `env:"USER_NAME"` // seeds the first admin user when there are no users
+	UserPass        string        `env:"USER_PASS"`
+	ApiToken        string        `env:"API_TOKEN"` // deprecated: static admin token, prefer user sessions
+	Swagger         bool          `env:"SWAGGER" envDefault:"false"`
+	CoresAllowed    []string      `env:"CORES_ALLOWED_ORIGINS"`
+	ListenAddr      string        `env:"LISTEN_ADDR" envDefault:":8080"`
+	PublicUrl       string        `env:"PUBLIC_URL"`
+	StreamAuth      bool          `env:"STREAM_AUTH" envDefault:"false"`          // require a token (header or `token` query parameter) or a url signature for /stream
+	EventBacklog    int           `env:"EVENT_BACKLOG" envDefault:"256"`          // number of events kept for resuming /api/events
+	EventPoll       time.Duration `env:"EVENT_POLL" envDefault:"5s"`              // interval to look for media added by other processes
+	Dav             bool          `env:"DAV" envDefault:"true"`                   // serve the media read-only over WebDAV at /dav/
+	StatsCacheTTL   time.Duration `env:"STATS_CACHE_TTL" envDefault:"30s"`        // how long /api/stats reuses its aggregations
+	AssetRedirect   bool          `env:"ASSET_REDIRECT" envDefault:"false"`       // redirect thumbnail and sprite requests to presigned minio urls
+	AssetPresignTTL time.Duration `env:"ASSET_PRESIGN_TTL" envDefault:"15m"`      // validity of presigned asset urls, also handed out in feeds, dlna listings and to stash
+	BulkRate        float64       `env:"BULK_RATE" envDefault:"10"`               // media per second applied by bulk operations
+	UploadDir       string        `env:"UPLOAD_DIR" envDefault:"uploads"`         // where chunks of resumable uploads are kept until complete
+	UploadMaxSize   int64         `env:"UPLOAD_MAX_SIZE" envDefault:"2097152000"` // telegram limit for bots (2000MiB)
+	UploadTTL       time.Duration `env:"UPLOAD_TTL" envDefault:"24h"`             // how long unfinished resumable uploads are kept
+}
+type AuthConfigType struct {
+	SessionSecret    string        `env:"SESSION_SECRET"`
+	SessionTTL       time.Duration `env:"SESSION_TTL" envDefault:"168h"`
+	MaxLoginFailures int           `env:"MAX_LOGIN_FAILURES" envDefault:"5"`
+	LockoutDuration  time.Duration `env:"LOCKOUT_DURATION" envDefault:"15m"`
+	StreamUrlTTL     time.Duration `env:"STREAM_URL_TTL" envDefault:"168h"` // validity of signed stream urls in playlists, feeds and dlna listings
 }
 type TelegramConfigType struct {
 	AppID           int      `env:"APP_ID,required"`
@@ -34,6 +55,7 @@ type FuseConfigType struct {
 	AllowOther bool   `env:"ALLOW_OTHER" envDefault:"true"`
 	Debug      bool   `env:"DEBUG" envDefault:"false"`
 	MediaDir   string `env:"MEDIA_DIR" envDefault:"/tgmon-data/media"`
+	Library    bool   `env:"LIBRARY" envDefault:"false"`
 }
 type RuntimeConfigType struct {
 	LogLevel       string `env:"LOG_LEVEL" envDefault:"warning"`
@@ -41,17 +63,57 @@ type RuntimeConfigType struct {
 	StreamBuffSize int    `env:"STREAM_BUFF_SIZE" envDefault:"8388608"`
 }
 type StashRedirectorConfigType struct {
-	Enabled       bool   `env:"ENABLED" envDefault:"true"`
-	MinioUrl      string `env:"MINIO_URL" envDefault:""`
-	StashEndpoint string `env:"STASH_ENDPOINT" envDefault:""`
-	StashApiKey   string `env:"STASH_API_KEY" envDefault:""`
+	Enabled       bool          `env:"ENABLED" envDefault:"true"`
+	StashEndpoint string        `env:"STASH_ENDPOINT" envDefault:""`
+	StashApiKey   string        `env:"STASH_API_KEY" envDefault:""`
+	LibraryPath   string        `env:"LIBRARY_PATH"`                  // path Stash sees the FUSE filesystem at; defaults to FUSE__MEDIA_DIR
+	SyncInterval  time.Duration `env:"SYNC_INTERVAL" envDefault:"0"`  // how often media changes are pushed to Stash; 0 disables the sync
+	SyncAttempts  int           `env:"SYNC_ATTEMPTS" envDefault:"30"` // intervals a new media waits for Stash to scan it
+	SyncTags      bool          `env:"SYNC_TAGS" envDefault:"false"`  // pull tag changes made in Stash back into TGMon
+}
+type DlnaConfigType struct {
+	Enabled        bool          `env:"ENABLED" envDefault:"false"`
+	FriendlyName   string        `env:"FRIENDLY_NAME" envDefault:"TGMon"`
+	PublicUrl      string        `env:"PUBLIC_URL"`                 // url announced to renderers; defaults to HTTP__PUBLIC_URL or the local address
+	LanOnly        bool          `env:"LAN_ONLY" envDefault:"true"` // only answer renderers on private networks
+	NotifyInterval time.Duration `env:"NOTIFY_INTERVAL" envDefault:"10m"`
+}
+type S3ConfigType struct {
+	Enabled    bool   `env:"ENABLED" envDefault:"false"`
+	ListenAddr string `env:"LISTEN_ADDR" envDefault:":9000"`
+	Bucket     string `env:"BUCKET" envDefault:"tgmon"`
+	Region     string `env:"REGION" envDefault:"us-east-1"`
+}
+type HealthConfigType struct {
+	MinWorkers    int           `env:"MIN_WORKERS" envDefault:"1"`     // ready workers required for readiness
+	Timeout       time.Duration `env:"TIMEOUT" envDefault:"5s"`        // time given to each readiness check
+	BotListenAddr string        `env:"BOT_LISTEN_ADDR"`                // serves /healthz and /readyz of the bot process when set
+	ShutdownDelay time.Duration `env:"SHUTDOWN_DELAY" envDefault:"5s"` // time not ready before the servers stop on shutdown
+}
+type SyncConfigType struct {
+	Interval time.Duration `env:"INTERVAL" envDefault:"0"`   // runs the channel sync in the web process this often; 0 disables it
+	MaxGap   int           `env:"MAX_GAP" envDefault:"1000"` // missing messages in a row after the last media that end the scan
+	Rate     float64       `env:"RATE" envDefault:"2"`       // requests per second to telegram (100 messages each)
+	Fix      bool          `env:"FIX" envDefault:"false"`    // create the media of document messages without one
+	Prune    bool          `env:"PRUNE" envDefault:"false"`  // delete the media whose message is gone
+}
+type GCConfigType struct {
+	Interval    time.Duration `env:"INTERVAL" envDefault:"0"`       // runs the minio garbage collection in the web process this often; 0 disables it
+	Action      string        `env:"ACTION" envDefault:"REPORT"`    // REPORT, QUARANTINE or DELETE orphan objects
+	GracePeriod time.Duration `env:"GRACE_PERIOD" envDefault:"24h"` // orphan objects modified more recently are kept
 }
 type ConfigType struct {
 	TelegramConfig        TelegramConfigType        `envPrefix:"TELEGRAM__"`
 	HttpConfig            HttpConfigType            `envPrefix:"HTTP__"`
+	AuthConfig            AuthConfigType            `envPrefix:"AUTH__"`
 	MinioConfig           MinioConfigType           `envPrefix:"MINIO__"`
 	MongoDBConfig         MongoDBConfigType         `envPrefix:"MONGODB__"`
 	FuseConfig            FuseConfigType            `envPrefix:"FUSE__"`
 	RuntimeConfig         RuntimeConfigType         `envPrefix:"RUNTIME__"`
 	StashRedirectorConfig StashRedirectorConfigType `envPrefix:"STASH_REDIRECTOR__"`
+	DlnaConfig            DlnaConfigType            `envPrefix:"DLNA__"`
+	S3Config              S3ConfigType              `envPrefix:"S3__"`
+	HealthConfig          HealthConfigType          `envPrefix:"HEALTH__"`
+	SyncConfig            SyncConfigType            `envPrefix:"SYNC__"`
+	GCConfig              GCConfigType              `envPrefix:"GC__"`
 }
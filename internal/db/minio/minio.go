@@ -5,13 +5,22 @@ package minio
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 )
 
+// ErrFileNotFound is returned when a file does not exist in the bucket.
+var ErrFileNotFound = errors.New("file not found")
+
+// noSuchKeyCode is the error code of requests on missing objects.
+const noSuchKeyCode = "NoSuchKey"
+
 // IMinioCl defines the interface for MinIO client operations.
 // This interface wraps the essential MinIO operations needed for object storage management.
 // It is designed to be mockable for testing purposes.
@@ -33,6 +42,22 @@ type IMinioCl interface {
 	// RemoveObject deletes an object from the specified bucket.
 	// Returns an error if the deletion fails.
 	RemoveObject(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) error
+
+	// GetObject returns a reader of an object from the specified bucket.
+	// Errors of the request are returned by the first read, not by GetObject.
+	GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error)
+
+	// StatObject returns the metadata of an object from the specified bucket.
+	StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+
+	// PresignedGetObject returns a url to download an object without credentials, valid for expires.
+	PresignedGetObject(ctx context.Context, bucketName string, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error)
+
+	// ListObjects lists the objects of the specified bucket. Errors are sent as objects with Err set.
+	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
+
+	// CopyObject copies an object, within or across buckets.
+	CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error)
 }
 
 // IMinioClient defines the high-level interface for MinIO client operations.
@@ -56,6 +81,25 @@ type IMinioClient interface {
 	// FileRm removes a file from the bucket.
 	// The removal is forced, meaning it will delete the file even if it has versioning enabled.
 	FileRm(ctx context.Context, fileName string) error
+
+	// Ping checks that the configured bucket is reachable and exists.
+	Ping(ctx context.Context) error
+
+	// FileGet returns a reader of a file of the bucket. The reader can seek, to serve ranges of the file.
+	FileGet(ctx context.Context, fileName string) (io.ReadSeekCloser, error)
+
+	// FileStat returns the metadata of a file of the bucket.
+	// Returns ErrFileNotFound if the file does not exist.
+	FileStat(ctx context.Context, fileName string) (minio.ObjectInfo, error)
+
+	// PresignedGet returns a url to download a file of the bucket without credentials, valid for expires.
+	PresignedGet(ctx context.Context, fileName string, expires time.Duration) (*url.URL, error)
+
+	// FileList returns the metadata of all files of the bucket whose name starts with prefix.
+	FileList(ctx context.Context, prefix string) ([]minio.ObjectInfo, error)
+
+	// FileMove renames a file of the bucket.
+	FileMove(ctx context.Context, fileName string, newName string) error
 }
 
 // MinioClient implements the IMinioClient interface and provides high-level file operations
@@ -138,6 +182,76 @@ func (cl *MinioClient) FileRm(ctx context.Context, fileName string) error {
 	return nil
 }
 
+// Ping checks that the configured bucket is reachable and exists.
+// Unlike CreateBucket, a missing bucket is an error rather than created.
+func (cl *MinioClient) Ping(ctx context.Context) error {
+	exists, err := cl.BucketExists(ctx, cl.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket existence for bucket '%s': %w", cl.bucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket '%s' does not exist", cl.bucket)
+	}
+	return nil
+}
+
+// FileGet returns a reader of a file of the configured bucket.
+// The object is fetched lazily, so a missing file is reported by the first read; use FileStat to check for it.
+func (cl *MinioClient) FileGet(ctx context.Context, fileName string) (io.ReadSeekCloser, error) {
+	obj, err := cl.GetObject(ctx, cl.bucket, fileName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file '%s' from bucket '%s': %w", fileName, cl.bucket, err)
+	}
+	return obj, nil
+}
+
+// FileStat returns the metadata (size, content type, etag and modification time) of a file of the configured bucket.
+// Returns ErrFileNotFound if the file does not exist.
+func (cl *MinioClient) FileStat(ctx context.Context, fileName string) (minio.ObjectInfo, error) {
+	info, err := cl.StatObject(ctx, cl.bucket, fileName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == noSuchKeyCode {
+			err = fmt.Errorf("%w: %w", ErrFileNotFound, err)
+		}
+		return info, fmt.Errorf("failed to stat file '%s' from bucket '%s': %w", fileName, cl.bucket, err)
+	}
+	return info, nil
+}
+
+// PresignedGet returns a url to download a file of the configured bucket without credentials, valid for expires.
+// The url is signed for the endpoint of the client, so it must be reachable by whoever uses the url.
+func (cl *MinioClient) PresignedGet(ctx context.Context, fileName string, expires time.Duration) (*url.URL, error) {
+	u, err := cl.PresignedGetObject(ctx, cl.bucket, fileName, expires, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign file '%s' from bucket '%s': %w", fileName, cl.bucket, err)
+	}
+	return u, nil
+}
+
+// FileList returns the metadata of all files of the configured bucket whose name starts with prefix, including those
+// in "directories" of the prefix.
+func (cl *MinioClient) FileList(ctx context.Context, prefix string) ([]minio.ObjectInfo, error) {
+	res := []minio.ObjectInfo{}
+	for obj := range cl.ListObjects(ctx, cl.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list files of bucket '%s': %w", cl.bucket, obj.Err)
+		}
+		res = append(res, obj)
+	}
+	return res, nil
+}
+
+// FileMove renames a file of the configured bucket, by copying it then removing the original.
+// The original is kept if the copy fails.
+func (cl *MinioClient) FileMove(ctx context.Context, fileName string, newName string) error {
+	dst := minio.CopyDestOptions{Bucket: cl.bucket, Object: newName}
+	src := minio.CopySrcOptions{Bucket: cl.bucket, Object: fileName}
+	if _, err := cl.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to copy file '%s' to '%s' in bucket '%s': %w", fileName, newName, cl.bucket, err)
+	}
+	return cl.FileRm(ctx, fileName)
+}
+
 var _ IMinioClient = (*MinioClient)(nil)
 
 // NewMinioClient creates a new MinioClient instance with the specified low-level client and bucket name.
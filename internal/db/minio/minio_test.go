@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
+	"time"
 
 	mnio "github.com/minio/minio-go/v7"
 
@@ -270,5 +272,129 @@ var _ = Describe("Minio", func() {
 				})
 			}
 		})
+		Describe("Ping", Label("Ping"), func() {
+			type testCase struct {
+				description           string
+				tType                 TestCaseType
+				bucketName            string
+				expectBucketExistsRes bool  // returned result from minio.BucketExist
+				expectBucketExistsErr error // returned error from minio.BucketExist
+				expectErr             bool  // whether or not expect failure
+			}
+			// ...
+			tests := []testCase{
+				{
+					description:           "existing bucket",
+					tType:                 HAPPY_PATH,
+					bucketName:            "mock_bucket",
+					expectBucketExistsRes: true,
+				},
+				{
+					description:           "missing bucket",
+					tType:                 FAILURE,
+					bucketName:            "mock_bucket",
+					expectBucketExistsRes: false,
+					expectErr:             true,
+				},
+				{
+					description:           "error calling BucketExists",
+					tType:                 FAILURE,
+					bucketName:            "mock_bucket",
+					expectBucketExistsErr: fmt.Errorf("mock BucketExists err"),
+					expectErr:             true,
+				},
+			}
+			// ...
+			for _, tc := range tests {
+				tc := tc
+				It(tc.description, Label(string(tc.tType)), func() {
+					// Arrange
+					mockMinio.EXPECT().BucketExists(gomock.Any(), tc.bucketName).Return(tc.expectBucketExistsRes, tc.expectBucketExistsErr)
+					// Act
+					err := mnioCl.Ping(testContext)
+					// Assert
+					if tc.expectErr {
+						Expect(err).To(HaveOccurred())
+					} else {
+						Expect(err).NotTo(HaveOccurred())
+					}
+				})
+			}
+		})
+		Describe("FileStat", Label("FileStat"), func() {
+			It("returns the object info", Label(string(HAPPY_PATH)), func() {
+				mockMinio.EXPECT().StatObject(gomock.Any(), testBucketName, "test.file", gomock.Any()).Return(mnio.ObjectInfo{Key: "test.file", Size: 10}, nil)
+				info, err := mnioCl.FileStat(testContext, "test.file")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Size).To(BeEquivalentTo(10))
+			})
+			It("returns ErrFileNotFound for missing files", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().StatObject(gomock.Any(), testBucketName, "test.file", gomock.Any()).Return(mnio.ObjectInfo{}, mnio.ErrorResponse{Code: "NoSuchKey"})
+				_, err := mnioCl.FileStat(testContext, "test.file")
+				Expect(err).To(MatchError(minio.ErrFileNotFound))
+			})
+			It("returns other errors as is", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().StatObject(gomock.Any(), testBucketName, "test.file", gomock.Any()).Return(mnio.ObjectInfo{}, fmt.Errorf("mock StatObject error"))
+				_, err := mnioCl.FileStat(testContext, "test.file")
+				Expect(err).To(HaveOccurred())
+				Expect(err).NotTo(MatchError(minio.ErrFileNotFound))
+			})
+		})
+		Describe("FileGet", Label("FileGet"), func() {
+			It("returns error if GetObject fails", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().GetObject(gomock.Any(), testBucketName, "test.file", gomock.Any()).Return(nil, fmt.Errorf("mock GetObject error"))
+				_, err := mnioCl.FileGet(testContext, "test.file")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Describe("PresignedGet", Label("PresignedGet"), func() {
+			It("returns the presigned url", Label(string(HAPPY_PATH)), func() {
+				u, _ := url.Parse("http://minio/mock_bucket/test.file?X-Amz-Signature=sig")
+				mockMinio.EXPECT().PresignedGetObject(gomock.Any(), testBucketName, "test.file", time.Minute, gomock.Any()).Return(u, nil)
+				res, err := mnioCl.PresignedGet(testContext, "test.file", time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(Equal(u))
+			})
+			It("returns error if PresignedGetObject fails", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().PresignedGetObject(gomock.Any(), testBucketName, "test.file", time.Minute, gomock.Any()).Return(nil, fmt.Errorf("mock PresignedGetObject error"))
+				_, err := mnioCl.PresignedGet(testContext, "test.file", time.Minute)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Describe("FileList", Label("FileList"), func() {
+			listOf := func(objs ...mnio.ObjectInfo) <-chan mnio.ObjectInfo {
+				ch := make(chan mnio.ObjectInfo, len(objs))
+				for _, o := range objs {
+					ch <- o
+				}
+				close(ch)
+				return ch
+			}
+			It("returns all objects of the prefix", Label(string(HAPPY_PATH)), func() {
+				mockMinio.EXPECT().ListObjects(gomock.Any(), testBucketName, mnio.ListObjectsOptions{Prefix: "test", Recursive: true}).Return(listOf(mnio.ObjectInfo{Key: "test.a"}, mnio.ObjectInfo{Key: "test.b"}))
+				res, err := mnioCl.FileList(testContext, "test")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res).To(HaveLen(2))
+				Expect(res[1].Key).To(Equal("test.b"))
+			})
+			It("returns the error of the listing", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().ListObjects(gomock.Any(), testBucketName, gomock.Any()).Return(listOf(mnio.ObjectInfo{Key: "test.a"}, mnio.ObjectInfo{Err: fmt.Errorf("mock ListObjects error")}))
+				_, err := mnioCl.FileList(testContext, "")
+				Expect(err).To(MatchError(ContainSubstring("mock ListObjects error")))
+			})
+		})
+		Describe("FileMove", Label("FileMove"), func() {
+			It("copies then removes the file", Label(string(HAPPY_PATH)), func() {
+				gomock.InOrder(
+					mockMinio.EXPECT().CopyObject(gomock.Any(), mnio.CopyDestOptions{Bucket: testBucketName, Object: "new.file"}, mnio.CopySrcOptions{Bucket: testBucketName, Object: "test.file"}).Return(mnio.UploadInfo{}, nil),
+					mockMinio.EXPECT().RemoveObject(gomock.Any(), testBucketName, "test.file", gomock.Any()).Return(nil),
+				)
+				Expect(mnioCl.FileMove(testContext, "test.file", "new.file")).To(Succeed())
+			})
+			It("keeps the file if the copy fails", Label(string(FAILURE)), func() {
+				mockMinio.EXPECT().CopyObject(gomock.Any(), gomock.Any(), gomock.Any()).Return(mnio.UploadInfo{}, fmt.Errorf("mock CopyObject error"))
+				Expect(mnioCl.FileMove(testContext, "test.file", "new.file")).NotTo(Succeed())
+			})
+		})
 	})
 })
@@ -12,9 +12,15 @@ import (
 type CollectionNameType string
 
 const (
-	FILE_COLLECTION_NAME   CollectionNameType = "files"
-	JOBREQ_COLLECTION_NAME CollectionNameType = "job"
-	JOBRES_COLLECTION_NAME CollectionNameType = "jobres"
+	FILE_COLLECTION_NAME     CollectionNameType = "files"
+	JOBREQ_COLLECTION_NAME   CollectionNameType = "job"
+	JOBRES_COLLECTION_NAME   CollectionNameType = "jobres"
+	TAG_COLLECTION_NAME      CollectionNameType = "tag"
+	PLAYLIST_COLLECTION_NAME CollectionNameType = "playlist"
+	USER_COLLECTION_NAME     CollectionNameType = "user"
+	SESSION_COLLECTION_NAME  CollectionNameType = "session"
+	APIKEY_COLLECTION_NAME   CollectionNameType = "apikey"
+	PROGRESS_COLLECTION_NAME CollectionNameType = "progress"
 )
 
 // ICollection defines the interface for MongoDB collection operations.
@@ -25,7 +31,7 @@ const (
 //go:generate mockgen -source=collection.go -destination=../../../mocks/db/mongo/collection.go -package=mocks
 type ICollection[T any] interface {
 	// Aggregator returns an aggregator for building and executing aggregation pipelines.
-	Aggregator() aggregator.IAggregator[T]
+	Aggregator() IAggregator[T]
 
 	// Creator returns a creator for inserting documents into the collection.
 	Creator() creator.ICreator[T]
@@ -40,6 +46,14 @@ type ICollection[T any] interface {
 	Updater() updater.IUpdater[T]
 }
 
+// IAggregator builds and executes aggregation pipelines. It adds Pipeline to the aggregator interface of go-mongox,
+// which only exposes it on the concrete type.
+type IAggregator[T any] interface {
+	aggregator.IAggregator[T]
+	// Pipeline sets the aggregation pipeline to execute.
+	Pipeline(pipeline any) IAggregator[T]
+}
+
 // Collection implements the ICollection interface and provides MongoDB collection operations.
 // It wraps a go-mongox Collection to provide a consistent interface for database operations.
 // The struct is generic and can work with any document type that implements IMongoDoc.
@@ -52,8 +66,8 @@ var _ ICollection[any] = (*Collection[any])(nil)
 
 // Aggregator returns an aggregator instance for building and executing aggregation pipelines.
 // This provides access to MongoDB's aggregation framework through the go-mongox library.
-func (c *Collection[T]) Aggregator() aggregator.IAggregator[T] {
-	return c.xColl.Aggregator()
+func (c *Collection[T]) Aggregator() IAggregator[T] {
+	return &Aggregator[T]{Aggregator: c.xColl.Aggregator()}
 }
 
 // Creator returns a creator instance for inserting new documents into the collection.
@@ -79,3 +93,17 @@ func (c *Collection[T]) Finder() finder.IFinder[T] {
 func (c *Collection[T]) Updater() updater.IUpdater[T] {
 	return c.xColl.Updater()
 }
+
+// Aggregator implements the IAggregator interface by wrapping a go-mongox Aggregator.
+type Aggregator[T any] struct {
+	*aggregator.Aggregator[T]
+}
+
+// Compile-time check to ensure Aggregator implements IAggregator
+var _ IAggregator[any] = (*Aggregator[any])(nil)
+
+// Pipeline sets the aggregation pipeline to execute.
+func (a *Aggregator[T]) Pipeline(pipeline any) IAggregator[T] {
+	a.Aggregator.Pipeline(pipeline)
+	return a
+}
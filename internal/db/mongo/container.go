@@ -7,6 +7,7 @@ import (
 	"github.com/amirdaaee/TGMon/internal/types"
 	"github.com/chenmingyong0423/go-mongox/v2"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
@@ -22,6 +23,12 @@ type IMongoContainer interface {
 	GetJobReqCollection() ICollection[types.JobReqDoc]
 	GetJobResCollection() ICollection[types.JobResDoc]
 	GetMediaFileCollection() ICollection[types.MediaFileDoc]
+	GetTagCollection() ICollection[types.TagDoc]
+	GetPlaylistCollection() ICollection[types.PlaylistDoc]
+	GetUserCollection() ICollection[types.UserDoc]
+	GetSessionCollection() ICollection[types.SessionDoc]
+	GetApiKeyCollection() ICollection[types.ApiKeyDoc]
+	GetWatchProgressCollection() ICollection[types.WatchProgressDoc]
 }
 
 // MongoContainer implements the IMongoContainer interface and holds references to the MongoDB client, database, and helper structs.
@@ -59,8 +66,68 @@ func (c *MongoContainer) GetMediaFileCollection() ICollection[types.MediaFileDoc
 	return &Collection[types.MediaFileDoc]{xColl: xCol}
 }
 
+// GetTagCollection returns the collection for tag documents.
+func (c *MongoContainer) GetTagCollection() ICollection[types.TagDoc] {
+	xCol := mongox.NewCollection[types.TagDoc](c.db.Database, string(TAG_COLLECTION_NAME))
+	return &Collection[types.TagDoc]{xColl: xCol}
+}
+
+// GetPlaylistCollection returns the collection for playlist documents.
+func (c *MongoContainer) GetPlaylistCollection() ICollection[types.PlaylistDoc] {
+	xCol := mongox.NewCollection[types.PlaylistDoc](c.db.Database, string(PLAYLIST_COLLECTION_NAME))
+	return &Collection[types.PlaylistDoc]{xColl: xCol}
+}
+
+// GetUserCollection returns the collection for user documents.
+func (c *MongoContainer) GetUserCollection() ICollection[types.UserDoc] {
+	xCol := mongox.NewCollection[types.UserDoc](c.db.Database, string(USER_COLLECTION_NAME))
+	return &Collection[types.UserDoc]{xColl: xCol}
+}
+
+// GetSessionCollection returns the collection for session documents.
+func (c *MongoContainer) GetSessionCollection() ICollection[types.SessionDoc] {
+	xCol := mongox.NewCollection[types.SessionDoc](c.db.Database, string(SESSION_COLLECTION_NAME))
+	return &Collection[types.SessionDoc]{xColl: xCol}
+}
+
+// GetApiKeyCollection returns the collection for api key documents.
+func (c *MongoContainer) GetApiKeyCollection() ICollection[types.ApiKeyDoc] {
+	xCol := mongox.NewCollection[types.ApiKeyDoc](c.db.Database, string(APIKEY_COLLECTION_NAME))
+	return &Collection[types.ApiKeyDoc]{xColl: xCol}
+}
+
+// GetWatchProgressCollection returns the collection for watch progress documents.
+func (c *MongoContainer) GetWatchProgressCollection() ICollection[types.WatchProgressDoc] {
+	xCol := mongox.NewCollection[types.WatchProgressDoc](c.db.Database, string(PROGRESS_COLLECTION_NAME))
+	return &Collection[types.WatchProgressDoc]{xColl: xCol}
+}
+
 var _ IMongoContainer = (*MongoContainer)(nil)
 
+// indexes are the indexes NewMongoContainer creates, by collection.
+var indexes = map[CollectionNameType][]mongo.IndexModel{
+	PROGRESS_COLLECTION_NAME: {{
+		// a single progress per user and media; checking for one before inserting races with concurrent reports
+		Keys:    bson.D{{Key: types.WatchProgressDoc__UserIDField, Value: 1}, {Key: types.WatchProgressDoc__MediaIDField, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}},
+	TAG_COLLECTION_NAME: {{
+		// tags are looked up and created by name, concurrently by the bot, the scraper and bulk edits
+		Keys:    bson.D{{Key: types.TagDoc__NameField, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}},
+}
+
+// ensureIndexes creates the indexes of the collections, if they do not exist.
+func ensureIndexes(ctx context.Context, db *mongo.Database) error {
+	for coll, models := range indexes {
+		if _, err := db.Collection(string(coll)).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("error creating indexes of %s: %w", coll, err)
+		}
+	}
+	return nil
+}
+
 // MongoContainerConfig holds configuration for connecting to a MongoDB instance.
 type MongoContainerConfig struct {
 	// Endpoint is the MongoDB server URI
@@ -106,6 +173,10 @@ func NewMongoContainer(ctx context.Context, config MongoContainerConfig, ping bo
 	}
 
 	// ...
+	if err := ensureIndexes(ctx, cl.Database(config.DbName)); err != nil {
+		// e.g. duplicates stored before the index existed; they have to be removed by hand
+		logrus.WithError(err).Error("Failed to create indexes")
+	}
 	mCl := MongoClient{
 		xCl: mongox.NewClient(cl, &mongox.Config{}),
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/chenmingyong0423/go-mongox/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 )
 
 // IMongoClient defines the interface for MongoDB client operations.
@@ -19,6 +20,9 @@ type IMongoClient interface {
 	// NewDatabase creates a new database instance with the specified name.
 	// Returns an IDatabase interface for database-level operations.
 	NewDatabase(string) IDatabase
+
+	// Ping checks that the primary of the MongoDB deployment is reachable.
+	Ping(context.Context) error
 }
 
 // MongoClient implements the IMongoClient interface and wraps a go-mongox client.
@@ -49,6 +53,12 @@ func (c *MongoClient) NewDatabase(name string) IDatabase {
 	return &Database{Database: c.xCl.NewDatabase(name)}
 }
 
+// Ping checks that the primary of the MongoDB deployment is reachable.
+// It is used by readiness checks, unlike the ping done once when connecting.
+func (c *MongoClient) Ping(ctx context.Context) error {
+	return c.xCl.Client().Ping(ctx, readpref.Primary())
+}
+
 var _ IMongoClient = (*MongoClient)(nil)
 
 // IDatabase defines the interface for MongoDB database operations.
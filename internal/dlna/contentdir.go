@@ -0,0 +1,379 @@
+package dlna
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	rootID      = "0"
+	dateRootID  = "date"
+	typeRootID  = "type"
+	tagRootID   = "tag"
+	createdAtDb = "created_at"
+	// dlnaFlags marks resources as streamable with background transfer, as in DLNA 1.5.
+	dlnaFlags = "01700000000000000000000000000000"
+)
+
+// leafDepth is the number of id segments of containers holding media, per top level container.
+var leafDepth = map[string]int{dateRootID: 3, typeRootID: 2, tagRootID: 2}
+
+// mediaKind is a container of the "by type" tree.
+type mediaKind struct {
+	ID     string
+	Title  string
+	Filter bson.D
+}
+
+var mediaKinds = []mediaKind{
+	{ID: "video", Title: "Video", Filter: mimeFilter("^video/")},
+	{ID: "audio", Title: "Audio", Filter: mimeFilter("^audio/")},
+	{ID: "image", Title: "Images", Filter: mimeFilter("^image/")},
+	{ID: "other", Title: "Other", Filter: bson.D{{Key: types.MediaFileDoc__MimeTypeField, Value: bson.D{{Key: "$not", Value: bson.Regex{Pattern: "^(video|audio|image)/"}}}}}},
+}
+
+// object is a container or an item of the directory.
+type object struct {
+	ID         string
+	ParentID   string
+	Title      string
+	ChildCount int64
+	Media      *types.MediaFileDoc // set for items
+}
+
+// container is a resolved container. Containers either list sub containers or hold the media matching filter.
+type container struct {
+	object
+	children []object
+	filter   bson.D
+}
+
+// ResourceUrls holds what is needed to build urls handed out to renderers.
+type ResourceUrls struct {
+	BaseUrl      string
+	StreamSigner auth.IStreamSigner
+	MinioClient  minio.IMinioClient // presigns thumbnail urls; thumbnails are omitted when nil
+	PresignTTL   time.Duration
+}
+
+// ContentDirectory serves the media library as a UPnP ContentDirectory tree:
+//
+//	0
+//	├── date/<year>/<month>
+//	├── type/<video|audio|image|other>
+//	└── tag/<tag id>
+//
+// Items are addressed as "<container id>/<media id>", so their parent can be derived from their id.
+type ContentDirectory struct {
+	mediaFac facade.IFacade[types.MediaFileDoc]
+	tagFac   facade.IFacade[types.TagDoc]
+}
+
+// Browse implements the ContentDirectory Browse action. It returns the DIDL-Lite result, the number of
+// returned objects and the total number of matches.
+func (cd *ContentDirectory) Browse(ctx context.Context, objectID string, browseFlag string, start int64, count int64, urls ResourceUrls) (string, int, int64, error) {
+	switch browseFlag {
+	case "BrowseMetadata":
+		obj, err := cd.getObject(ctx, objectID)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		didl, err := renderDIDL(ctx, []object{*obj}, urls)
+		return didl, 1, 1, err
+	case "BrowseDirectChildren":
+		objs, total, err := cd.getChildren(ctx, objectID, start, count)
+		if err != nil {
+			return "", 0, 0, err
+		}
+		didl, err := renderDIDL(ctx, objs, urls)
+		return didl, len(objs), total, err
+	default:
+		return "", 0, 0, &upnpError{Code: upnpErrInvalidArgs, Desc: fmt.Sprintf("invalid browse flag %s", browseFlag)}
+	}
+}
+
+// getObject returns the container or item with the given id.
+func (cd *ContentDirectory) getObject(ctx context.Context, id string) (*object, error) {
+	segments := strings.Split(id, "/")
+	if depth, ok := leafDepth[segments[0]]; ok && len(segments) == depth+1 {
+		parentID := strings.Join(segments[:depth], "/")
+		mediaID, err := bson.ObjectIDFromHex(segments[depth])
+		if err != nil {
+			return nil, noSuchObject(id)
+		}
+		media, err := cd.mediaFac.GetCollection().Finder().Filter(query.Id(mediaID)).Find(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can not find media: %w", err)
+		}
+		if len(media) == 0 {
+			return nil, noSuchObject(id)
+		}
+		return mediaObject(parentID, media[0]), nil
+	}
+	c, err := cd.getContainer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &c.object, nil
+}
+
+// getChildren returns a page of the children of the container and their total number. A count of 0 returns all of them.
+func (cd *ContentDirectory) getChildren(ctx context.Context, id string, start int64, count int64) ([]object, int64, error) {
+	c, err := cd.getContainer(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.filter == nil {
+		total := int64(len(c.children))
+		end := total
+		if count > 0 {
+			end = min(start+count, total)
+		}
+		if start >= end {
+			return []object{}, total, nil
+		}
+		return c.children[start:end], total, nil
+	}
+	fnd := cd.mediaFac.GetCollection().Finder().Filter(c.filter).Sort(bsonx.NewD().Add(createdAtDb, -1).Build()).Skip(start)
+	if count > 0 {
+		fnd = fnd.Limit(count)
+	}
+	media, err := fnd.Find(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can not find media: %w", err)
+	}
+	objs := make([]object, len(media))
+	for i, m := range media {
+		objs[i] = *mediaObject(id, m)
+	}
+	return objs, c.ChildCount, nil
+}
+
+// getContainer resolves the container with the given id.
+func (cd *ContentDirectory) getContainer(ctx context.Context, id string) (*container, error) {
+	segments := strings.Split(id, "/")
+	switch segments[0] {
+	case rootID:
+		if len(segments) == 1 {
+			return cd.getRoot(ctx)
+		}
+	case dateRootID:
+		return cd.getDateContainer(ctx, segments[1:])
+	case typeRootID:
+		return cd.getTypeContainer(ctx, segments[1:])
+	case tagRootID:
+		return cd.getTagContainer(ctx, segments[1:])
+	}
+	return nil, noSuchObject(id)
+}
+
+func (cd *ContentDirectory) getRoot(ctx context.Context) (*container, error) {
+	c := &container{object: object{ID: rootID, ParentID: "-1", Title: "TGMon"}}
+	for _, id := range []string{dateRootID, typeRootID, tagRootID} {
+		child, err := cd.getContainer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		c.children = append(c.children, child.object)
+	}
+	c.ChildCount = int64(len(c.children))
+	return c, nil
+}
+
+func (cd *ContentDirectory) getDateContainer(ctx context.Context, path []string) (*container, error) {
+	months, err := cd.getMonths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch len(path) {
+	case 0:
+		c := &container{object: object{ID: dateRootID, ParentID: rootID, Title: "By date"}}
+		years := map[int]int64{}
+		for _, m := range months {
+			years[m.Year()]++
+		}
+		keys := make([]int, 0, len(years))
+		for y := range years {
+			keys = append(keys, y)
+		}
+		slices.Sort(keys)
+		slices.Reverse(keys)
+		for _, y := range keys {
+			c.children = append(c.children, object{ID: fmt.Sprintf("%s/%d", dateRootID, y), ParentID: dateRootID, Title: strconv.Itoa(y), ChildCount: years[y]})
+		}
+		c.ChildCount = int64(len(c.children))
+		return c, nil
+	case 1:
+		year, err := strconv.Atoi(path[0])
+		if err != nil {
+			break
+		}
+		id := fmt.Sprintf("%s/%d", dateRootID, year)
+		c := &container{object: object{ID: id, ParentID: dateRootID, Title: path[0]}}
+		for _, m := range sortedMonths(months) {
+			if m.Year() == year {
+				c.children = append(c.children, object{ID: fmt.Sprintf("%s/%02d", id, m.Month()), ParentID: id, Title: m.Format("January 2006"), ChildCount: m.count})
+			}
+		}
+		if len(c.children) == 0 {
+			break
+		}
+		c.ChildCount = int64(len(c.children))
+		return c, nil
+	case 2:
+		m, ok := months[strings.Join(path, "/")]
+		if !ok {
+			break
+		}
+		parentID := fmt.Sprintf("%s/%d", dateRootID, m.Year())
+		c := &container{
+			object: object{ID: fmt.Sprintf("%s/%02d", parentID, m.Month()), ParentID: parentID, Title: m.Format("January 2006"), ChildCount: m.count},
+			filter: query.NewBuilder().Gte(createdAtDb, m.Time).Lt(createdAtDb, m.AddDate(0, 1, 0)).Build(),
+		}
+		return c, nil
+	}
+	return nil, noSuchObject(strings.Join(append([]string{dateRootID}, path...), "/"))
+}
+
+func (cd *ContentDirectory) getTypeContainer(ctx context.Context, path []string) (*container, error) {
+	switch len(path) {
+	case 0:
+		c := &container{object: object{ID: typeRootID, ParentID: rootID, Title: "By type"}}
+		for _, k := range mediaKinds {
+			n, err := cd.mediaFac.GetCollection().Finder().Filter(k.Filter).Count(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("can not count %s media: %w", k.ID, err)
+			}
+			if n > 0 {
+				c.children = append(c.children, object{ID: typeRootID + "/" + k.ID, ParentID: typeRootID, Title: k.Title, ChildCount: n})
+			}
+		}
+		c.ChildCount = int64(len(c.children))
+		return c, nil
+	case 1:
+		for _, k := range mediaKinds {
+			if k.ID != path[0] {
+				continue
+			}
+			n, err := cd.mediaFac.GetCollection().Finder().Filter(k.Filter).Count(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("can not count %s media: %w", k.ID, err)
+			}
+			return &container{object: object{ID: typeRootID + "/" + k.ID, ParentID: typeRootID, Title: k.Title, ChildCount: n}, filter: k.Filter}, nil
+		}
+	}
+	return nil, noSuchObject(strings.Join(append([]string{typeRootID}, path...), "/"))
+}
+
+func (cd *ContentDirectory) getTagContainer(ctx context.Context, path []string) (*container, error) {
+	switch len(path) {
+	case 0:
+		tags, err := cd.tagFac.GetCollection().Finder().Sort(bsonx.NewD().Add(types.TagDoc__NameField, 1).Build()).Find(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can not find tags: %w", err)
+		}
+		c := &container{object: object{ID: tagRootID, ParentID: rootID, Title: "By tag"}}
+		for _, t := range tags {
+			tc, err := cd.tagContainer(ctx, t)
+			if err != nil {
+				return nil, err
+			}
+			c.children = append(c.children, tc.object)
+		}
+		c.ChildCount = int64(len(c.children))
+		return c, nil
+	case 1:
+		tagID, err := bson.ObjectIDFromHex(path[0])
+		if err != nil {
+			break
+		}
+		tags, err := cd.tagFac.GetCollection().Finder().Filter(query.Id(tagID)).Find(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can not find tag: %w", err)
+		}
+		if len(tags) == 0 {
+			break
+		}
+		return cd.tagContainer(ctx, tags[0])
+	}
+	return nil, noSuchObject(strings.Join(append([]string{tagRootID}, path...), "/"))
+}
+
+// tagContainer returns the container of media having the tag.
+func (cd *ContentDirectory) tagContainer(ctx context.Context, tag *types.TagDoc) (*container, error) {
+	filter := bsonx.NewD().Add(types.MediaFileDoc__TagsField, tag.ID).Build()
+	n, err := cd.mediaFac.GetCollection().Finder().Filter(filter).Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not count media of tag %s: %w", tag.Name, err)
+	}
+	return &container{object: object{ID: tagRootID + "/" + tag.ID.Hex(), ParentID: tagRootID, Title: tag.Name, ChildCount: n}, filter: filter}, nil
+}
+
+// month is a calendar month holding media, in UTC.
+type month struct {
+	time.Time
+	count int64
+}
+
+func (m month) key() string {
+	return m.Format("2006/01")
+}
+
+// getMonths returns the months media were added in, keyed by "yyyy/mm".
+func (cd *ContentDirectory) getMonths(ctx context.Context) (map[string]month, error) {
+	docs, err := cd.mediaFac.GetCollection().Finder().Find(ctx, options.Find().SetProjection(bsonx.NewD().Add(createdAtDb, 1).Build()))
+	if err != nil {
+		return nil, fmt.Errorf("can not find media dates: %w", err)
+	}
+	res := map[string]month{}
+	for _, d := range docs {
+		t := d.CreatedAt.UTC()
+		m := month{Time: time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)}
+		m.count = res[m.key()].count + 1
+		res[m.key()] = m
+	}
+	return res, nil
+}
+
+// sortedMonths returns the months newest first.
+func sortedMonths(months map[string]month) []month {
+	res := make([]month, 0, len(months))
+	for _, m := range months {
+		res = append(res, m)
+	}
+	slices.SortFunc(res, func(a, b month) int {
+		return b.Compare(a.Time)
+	})
+	return res
+}
+
+// mediaObject returns the item of the media in the given container.
+func mediaObject(parentID string, media *types.MediaFileDoc) *object {
+	return &object{ID: parentID + "/" + media.ID.Hex(), ParentID: parentID, Title: media.DisplayName(), Media: media}
+}
+
+func mimeFilter(pattern string) bson.D {
+	return bson.D{{Key: types.MediaFileDoc__MimeTypeField, Value: bson.Regex{Pattern: pattern}}}
+}
+
+func noSuchObject(id string) error {
+	return &upnpError{Code: upnpErrNoSuchObject, Desc: fmt.Sprintf("no such object: %s", id)}
+}
+
+// NewContentDirectory creates a ContentDirectory of the media library.
+func NewContentDirectory(mediaFac facade.IFacade[types.MediaFileDoc], tagFac facade.IFacade[types.TagDoc]) *ContentDirectory {
+	return &ContentDirectory{mediaFac: mediaFac, tagFac: tagFac}
+}
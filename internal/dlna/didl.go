@@ -0,0 +1,151 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/log"
+)
+
+type didlLite struct {
+	XMLName    xml.Name        `xml:"DIDL-Lite"`
+	Xmlns      string          `xml:"xmlns,attr"`
+	XmlnsDC    string          `xml:"xmlns:dc,attr"`
+	XmlnsUpnp  string          `xml:"xmlns:upnp,attr"`
+	XmlnsDlna  string          `xml:"xmlns:dlna,attr"`
+	Containers []didlContainer `xml:"container"`
+	Items      []didlItem      `xml:"item"`
+}
+type didlContainer struct {
+	ID         string `xml:"id,attr"`
+	ParentID   string `xml:"parentID,attr"`
+	Restricted string `xml:"restricted,attr"`
+	ChildCount int64  `xml:"childCount,attr"`
+	Title      string `xml:"dc:title"`
+	Class      string `xml:"upnp:class"`
+}
+type didlItem struct {
+	ID          string         `xml:"id,attr"`
+	ParentID    string         `xml:"parentID,attr"`
+	Restricted  string         `xml:"restricted,attr"`
+	Title       string         `xml:"dc:title"`
+	Class       string         `xml:"upnp:class"`
+	Date        string         `xml:"dc:date,omitempty"`
+	AlbumArtURI *didlAlbumArt  `xml:"upnp:albumArtURI,omitempty"`
+	Res         []didlResource `xml:"res"`
+}
+type didlAlbumArt struct {
+	ProfileID string `xml:"dlna:profileID,attr"`
+	URI       string `xml:",chardata"`
+}
+type didlResource struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	Duration     string `xml:"duration,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+// renderDIDL renders objects as a DIDL-Lite document.
+func renderDIDL(ctx context.Context, objs []object, urls ResourceUrls) (string, error) {
+	doc := didlLite{
+		Xmlns:     "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/",
+		XmlnsDC:   "http://purl.org/dc/elements/1.1/",
+		XmlnsUpnp: "urn:schemas-upnp-org:metadata-1-0/upnp/",
+		XmlnsDlna: "urn:schemas-dlna-org:metadata-1-0/",
+	}
+	for _, o := range objs {
+		if o.Media == nil {
+			doc.Containers = append(doc.Containers, didlContainer{
+				ID:         o.ID,
+				ParentID:   o.ParentID,
+				Restricted: "1",
+				ChildCount: o.ChildCount,
+				Title:      o.Title,
+				Class:      "object.container.storageFolder",
+			})
+			continue
+		}
+		doc.Items = append(doc.Items, mediaItem(ctx, o, urls))
+	}
+	b, err := xml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("can not marshal DIDL-Lite: %w", err)
+	}
+	return string(b), nil
+}
+
+// mediaItem returns the DIDL item of a media object, with its stream and thumbnail resources. Thumbnails are
+// presigned minio urls, as renderers can not authenticate to the asset endpoints.
+func mediaItem(ctx context.Context, o object, urls ResourceUrls) didlItem {
+	m := o.Media
+	mime := m.Meta.MimeType
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+	streamUrl := fmt.Sprintf("%s/stream/%s", urls.BaseUrl, m.ID.Hex())
+	if urls.StreamSigner != nil {
+		streamUrl += "?" + urls.StreamSigner.SignStream(m.ID).Encode()
+	}
+	item := didlItem{
+		ID:         o.ID,
+		ParentID:   o.ParentID,
+		Restricted: "1",
+		Title:      o.Title,
+		Class:      upnpClass(mime),
+		Res: []didlResource{{
+			ProtocolInfo: fmt.Sprintf("http-get:*:%s:DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=%s", mime, dlnaFlags),
+			Size:         m.Meta.FileSize,
+			Duration:     formatDuration(m.Meta.Duration),
+			URL:          streamUrl,
+		}},
+	}
+	if !m.CreatedAt.IsZero() {
+		item.Date = m.CreatedAt.UTC().Format("2006-01-02")
+	}
+	if urls.MinioClient == nil || m.Thumbnail == "" {
+		return item
+	}
+	u, err := urls.MinioClient.PresignedGet(ctx, m.Thumbnail, urls.PresignTTL)
+	if err != nil {
+		log.GetLogger(log.DLNAModule).WithError(err).Warnf("can not presign thumbnail of media %s", m.ID.Hex())
+		return item
+	}
+	thumbUrl := u.String()
+	item.AlbumArtURI = &didlAlbumArt{ProfileID: "JPEG_TN", URI: thumbUrl}
+	item.Res = append(item.Res, didlResource{
+		ProtocolInfo: "http-get:*:image/jpeg:DLNA.ORG_PN=JPEG_TN;DLNA.ORG_OP=00;DLNA.ORG_CI=1",
+		URL:          thumbUrl,
+	})
+	return item
+}
+
+// upnpClass returns the UPnP class of an item with the given mime type.
+func upnpClass(mime string) string {
+	switch {
+	case strings.HasPrefix(mime, "video/"):
+		return "object.item.videoItem"
+	case strings.HasPrefix(mime, "audio/"):
+		return "object.item.audioItem.musicTrack"
+	case strings.HasPrefix(mime, "image/"):
+		return "object.item.imageItem.photo"
+	}
+	return "object.item"
+}
+
+// formatDuration formats seconds as H:MM:SS.mmm, or returns an empty string for unknown durations.
+func formatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	d := time.Duration(seconds * float64(time.Second))
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%d:%02d:%02d.%03d", h, m, s, d/time.Millisecond)
+}
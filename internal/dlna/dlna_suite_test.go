@@ -0,0 +1,17 @@
+package dlna_test
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDlna(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	gin.SetMode(gin.TestMode)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dlna Suite")
+}
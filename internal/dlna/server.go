@@ -0,0 +1,179 @@
+// Package dlna provides a DLNA/UPnP media server exposing the media library to renderers such as smart TVs.
+package dlna
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Config holds the settings of the media server.
+type Config struct {
+	// FriendlyName is the name renderers show for the server.
+	FriendlyName string
+	// BaseUrl is the url renderers reach the web server at. When empty, it is taken from each request.
+	BaseUrl string
+	// StreamSigner, when set, signs the stream url of each media for servers requiring stream authentication.
+	StreamSigner auth.IStreamSigner
+	// MinioClient presigns the urls of thumbnails, valid for PresignTTL. Thumbnails are omitted when nil.
+	MinioClient minio.IMinioClient
+	PresignTTL  time.Duration
+	// LanOnly rejects requests which do not come directly from a private network, as anyone browsing the library
+	// gets playable urls of its media.
+	LanOnly bool
+}
+
+// Server serves the device description and the UPnP services of the media server.
+type Server struct {
+	cfg        Config
+	udn        string
+	contentDir *ContentDirectory
+}
+
+// RegisterRoutes registers the description, control and event routes under r, which must be mounted at /dlna.
+func (s *Server) RegisterRoutes(r *gin.RouterGroup) {
+	if s.cfg.LanOnly {
+		r.Use(lanOnlyMiddleware)
+	}
+	r.GET("/rootDesc.xml", func(g *gin.Context) {
+		s.writeXML(g, http.StatusOK, deviceDescription(s.udn, s.cfg.FriendlyName))
+	})
+	for _, svc := range services {
+		scpd := svc.SCPD
+		r.GET(fmt.Sprintf("/%s.xml", svc.Name), func(g *gin.Context) {
+			s.writeXML(g, http.StatusOK, scpd)
+		})
+		r.Handle("SUBSCRIBE", "/event/"+svc.Name, s.subscribe)
+		r.Handle("UNSUBSCRIBE", "/event/"+svc.Name, func(g *gin.Context) { g.Status(http.StatusOK) })
+	}
+	r.POST("/control/ContentDirectory", s.contentDirectoryControl)
+	r.POST("/control/ConnectionManager", s.connectionManagerControl)
+}
+
+// UDN returns the unique device name of the server.
+func (s *Server) UDN() string {
+	return s.udn
+}
+
+func (s *Server) contentDirectoryControl(g *gin.Context) {
+	ll := s.getLogger("contentDirectoryControl")
+	action, err := parseSoapAction(g.Request.Body)
+	if err != nil {
+		s.writeFault(g, &upnpError{Code: upnpErrInvalidArgs, Desc: err.Error()})
+		return
+	}
+	ll.Debugf("action %s: %v", action.Name, action.Args)
+	switch action.Name {
+	case "Browse":
+		start, _ := strconv.ParseInt(action.Args["StartingIndex"], 10, 64)
+		count, _ := strconv.ParseInt(action.Args["RequestedCount"], 10, 64)
+		urls := ResourceUrls{BaseUrl: s.baseUrl(g), StreamSigner: s.cfg.StreamSigner, MinioClient: s.cfg.MinioClient, PresignTTL: s.cfg.PresignTTL}
+		didl, n, total, err := s.contentDir.Browse(g.Request.Context(), action.Args["ObjectID"], action.Args["BrowseFlag"], max(start, 0), max(count, 0), urls)
+		if err != nil {
+			ll.WithError(err).Warn("can not browse")
+			s.writeFault(g, err)
+			return
+		}
+		s.writeXML(g, http.StatusOK, soapResponse(contentDirectoryType, action.Name, []soapArg{
+			{Name: "Result", Value: didl},
+			{Name: "NumberReturned", Value: strconv.Itoa(n)},
+			{Name: "TotalMatches", Value: strconv.FormatInt(total, 10)},
+			{Name: "UpdateID", Value: "1"},
+		}))
+	case "GetSearchCapabilities":
+		s.writeXML(g, http.StatusOK, soapResponse(contentDirectoryType, action.Name, []soapArg{{Name: "SearchCaps"}}))
+	case "GetSortCapabilities":
+		s.writeXML(g, http.StatusOK, soapResponse(contentDirectoryType, action.Name, []soapArg{{Name: "SortCaps"}}))
+	case "GetSystemUpdateID":
+		s.writeXML(g, http.StatusOK, soapResponse(contentDirectoryType, action.Name, []soapArg{{Name: "Id", Value: "1"}}))
+	default:
+		s.writeFault(g, &upnpError{Code: upnpErrInvalidAction, Desc: fmt.Sprintf("invalid action %s", action.Name)})
+	}
+}
+
+func (s *Server) connectionManagerControl(g *gin.Context) {
+	action, err := parseSoapAction(g.Request.Body)
+	if err != nil {
+		s.writeFault(g, &upnpError{Code: upnpErrInvalidArgs, Desc: err.Error()})
+		return
+	}
+	switch action.Name {
+	case "GetProtocolInfo":
+		s.writeXML(g, http.StatusOK, soapResponse(connectionManagerType, action.Name, []soapArg{{Name: "Source", Value: "http-get:*:*:*"}, {Name: "Sink"}}))
+	case "GetCurrentConnectionIDs":
+		s.writeXML(g, http.StatusOK, soapResponse(connectionManagerType, action.Name, []soapArg{{Name: "ConnectionIDs", Value: "0"}}))
+	default:
+		s.writeFault(g, &upnpError{Code: upnpErrInvalidAction, Desc: fmt.Sprintf("invalid action %s", action.Name)})
+	}
+}
+
+// subscribe accepts event subscriptions so that control points do not give up on the server. No events are sent.
+func (s *Server) subscribe(g *gin.Context) {
+	sid := g.GetHeader("SID")
+	if sid == "" {
+		sid = "uuid:" + uuid.NewString()
+	}
+	g.Header("SID", sid)
+	g.Header("TIMEOUT", fmt.Sprintf("Second-%d", ssdpMaxAge))
+	g.Status(http.StatusOK)
+}
+
+func (s *Server) writeXML(g *gin.Context, status int, body string) {
+	g.Header("EXT", "")
+	g.Header("Server", serverHeader)
+	g.Data(status, `text/xml; charset="utf-8"`, []byte(body))
+}
+
+func (s *Server) writeFault(g *gin.Context, err error) {
+	var uErr *upnpError
+	if !errors.As(err, &uErr) {
+		uErr = &upnpError{Code: upnpErrActionFailed, Desc: err.Error()}
+	}
+	s.writeXML(g, http.StatusInternalServerError, soapFault(uErr))
+}
+
+// baseUrl returns the configured base url or derives it from the request.
+func (s *Server) baseUrl(g *gin.Context) string {
+	if s.cfg.BaseUrl != "" {
+		return strings.TrimSuffix(s.cfg.BaseUrl, "/")
+	}
+	return "http://" + g.Request.Host
+}
+
+// lanOnlyMiddleware rejects requests from outside private networks. Requests forwarded by a proxy are rejected too,
+// as the proxy is usually what exposes the server publicly.
+func lanOnlyMiddleware(g *gin.Context) {
+	ip := net.ParseIP(g.RemoteIP())
+	forwarded := g.GetHeader("X-Forwarded-For") != "" || g.GetHeader("Forwarded") != ""
+	if ip == nil || forwarded || !(ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+		g.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	g.Next()
+}
+
+func (s *Server) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.DLNAModule).WithField("func", fmt.Sprintf("%T.%s", s, fn))
+}
+
+// NewServer creates a media server for the library. Its UDN is derived from the friendly name and the
+// host name, so renderers recognize it across restarts.
+func NewServer(cfg Config, mediaFac facade.IFacade[types.MediaFileDoc], tagFac facade.IFacade[types.TagDoc]) *Server {
+	host, _ := os.Hostname()
+	udn := "uuid:" + uuid.NewSHA1(uuid.NameSpaceOID, []byte("tgmon/"+host+"/"+cfg.FriendlyName)).String()
+	return &Server{cfg: cfg, udn: udn, contentDir: NewContentDirectory(mediaFac, tagFac)}
+}
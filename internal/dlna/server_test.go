@@ -0,0 +1,162 @@
+package dlna_test
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/dlna"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mAuth "github.com/amirdaaee/TGMon/mocks/auth"
+	mMinio "github.com/amirdaaee/TGMon/mocks/db/minio"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		ctrl        *gomock.Controller
+		mediaFinder *mMongoX.MockIFinder[types.MediaFileDoc]
+		tagFinder   *mMongoX.MockIFinder[types.TagDoc]
+		engine      *gin.Engine
+		media       []*types.MediaFileDoc
+		tag         *types.TagDoc
+	)
+	newMedia := func(createdAt time.Time, mime string) *types.MediaFileDoc {
+		m := &types.MediaFileDoc{Name: "mock", Meta: types.MediaFileMeta{MimeType: mime, FileSize: 1024, Duration: 3723.5}, Thumbnail: "thumb.jpg"}
+		m.ID = bson.NewObjectID()
+		m.CreatedAt = createdAt
+		return m
+	}
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		media = []*types.MediaFileDoc{
+			newMedia(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), "video/mp4"),
+			newMedia(time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC), "video/mp4"),
+		}
+		tag = &types.TagDoc{Name: "mock-tag"}
+		tag.ID = bson.NewObjectID()
+		mediaFinder = mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mediaFinder.EXPECT().Filter(gomock.Any()).Return(mediaFinder).AnyTimes()
+		mediaFinder.EXPECT().Sort(gomock.Any()).Return(mediaFinder).AnyTimes()
+		mediaFinder.EXPECT().Skip(gomock.Any()).Return(mediaFinder).AnyTimes()
+		mediaFinder.EXPECT().Limit(gomock.Any()).Return(mediaFinder).AnyTimes()
+		mediaFinder.EXPECT().Count(gomock.Any()).Return(int64(2), nil).AnyTimes()
+		mediaFinder.EXPECT().Find(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, _ ...any) ([]*types.MediaFileDoc, error) {
+			return media, nil
+		}).AnyTimes()
+		tagFinder = mMongoX.NewMockIFinder[types.TagDoc](ctrl)
+		tagFinder.EXPECT().Filter(gomock.Any()).Return(tagFinder).AnyTimes()
+		tagFinder.EXPECT().Sort(gomock.Any()).Return(tagFinder).AnyTimes()
+		tagFinder.EXPECT().Find(gomock.Any()).Return([]*types.TagDoc{tag}, nil).AnyTimes()
+		mediaColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mediaColl.EXPECT().Finder().Return(mediaFinder).AnyTimes()
+		tagColl := mMongo.NewMockICollection[types.TagDoc](ctrl)
+		tagColl.EXPECT().Finder().Return(tagFinder).AnyTimes()
+		mediaFac := mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mediaFac.EXPECT().GetCollection().Return(mediaColl).AnyTimes()
+		tagFac := mFacade.NewMockIFacade[types.TagDoc](ctrl)
+		tagFac.EXPECT().GetCollection().Return(tagColl).AnyTimes()
+		signer := mAuth.NewMockIStreamSigner(ctrl)
+		signer.EXPECT().SignStream(gomock.Any()).DoAndReturn(func(mediaID bson.ObjectID) url.Values {
+			return url.Values{"expires": {"123"}, "signature": {"sig-" + mediaID.Hex()}}
+		}).AnyTimes()
+		minioCl := mMinio.NewMockIMinioClient(ctrl)
+		minioCl.EXPECT().PresignedGet(gomock.Any(), gomock.Any(), time.Minute).DoAndReturn(func(ctx context.Context, fileName string, expires time.Duration) (*url.URL, error) {
+			return url.Parse("http://minio/bucket/" + fileName + "?X-Amz-Signature=sig")
+		}).AnyTimes()
+		srv := dlna.NewServer(dlna.Config{FriendlyName: "mock", BaseUrl: "http://tgmon", StreamSigner: signer, MinioClient: minioCl, PresignTTL: time.Minute, LanOnly: true}, mediaFac, tagFac)
+		engine = gin.New()
+		srv.RegisterRoutes(engine.Group("/dlna"))
+	})
+	browse := func(objectID string, flag string) (int, string) {
+		body := fmt.Sprintf(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+			`<u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1"><ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag>`+
+			`<Filter>*</Filter><StartingIndex>0</StartingIndex><RequestedCount>0</RequestedCount><SortCriteria></SortCriteria></u:Browse></s:Body></s:Envelope>`, objectID, flag)
+		req := httptest.NewRequest(http.MethodPost, "/dlna/control/ContentDirectory", strings.NewReader(body))
+		req.RemoteAddr = "192.168.1.10:41000"
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec.Code, html.UnescapeString(rec.Body.String())
+	}
+	It("should serve the device description", func() {
+		req := httptest.NewRequest(http.MethodGet, "/dlna/rootDesc.xml", nil)
+		req.RemoteAddr = "192.168.1.10:41000"
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("urn:schemas-upnp-org:device:MediaServer:1"))
+		Expect(rec.Body.String()).To(ContainSubstring("<friendlyName>mock</friendlyName>"))
+	})
+	It("should list root containers", func() {
+		code, res := browse("0", "BrowseDirectChildren")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res).To(ContainSubstring(`<container id="date" parentID="0" restricted="1" childCount="2">`))
+		Expect(res).To(ContainSubstring(`<container id="type" parentID="0" restricted="1" childCount="4">`))
+		Expect(res).To(ContainSubstring(`<container id="tag" parentID="0" restricted="1" childCount="1">`))
+		Expect(res).To(ContainSubstring("<TotalMatches>3</TotalMatches>"))
+	})
+	It("should list months of a year", func() {
+		code, res := browse("date/2025", "BrowseDirectChildren")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res).To(ContainSubstring(`<container id="date/2025/01" parentID="date/2025" restricted="1" childCount="1"><dc:title>January 2025</dc:title>`))
+		Expect(res).ToNot(ContainSubstring("date/2024/05"))
+	})
+	It("should list tags", func() {
+		code, res := browse("tag", "BrowseDirectChildren")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res).To(ContainSubstring(fmt.Sprintf(`<container id="tag/%s" parentID="tag" restricted="1" childCount="2"><dc:title>mock-tag</dc:title>`, tag.ID.Hex())))
+	})
+	It("should describe media items", func() {
+		m := media[0]
+		code, res := browse("type/video/"+m.ID.Hex(), "BrowseMetadata")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(res).To(ContainSubstring(fmt.Sprintf(`<item id="type/video/%s" parentID="type/video" restricted="1">`, m.ID.Hex())))
+		Expect(res).To(ContainSubstring("<upnp:class>object.item.videoItem</upnp:class>"))
+		Expect(res).To(ContainSubstring(`protocolInfo="http-get:*:video/mp4:DLNA.ORG_OP=01;`))
+		Expect(res).To(ContainSubstring(`duration="1:02:03.500"`))
+		Expect(res).To(ContainSubstring(fmt.Sprintf("http://tgmon/stream/%s?expires=123&amp;signature=sig-%s</res>", m.ID.Hex(), m.ID.Hex())))
+		Expect(res).To(ContainSubstring(`<upnp:albumArtURI dlna:profileID="JPEG_TN">http://minio/bucket/thumb.jpg?X-Amz-Signature=sig</upnp:albumArtURI>`))
+	})
+	It("should list media of a container", func() {
+		code, res := browse("type/video", "BrowseDirectChildren")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(strings.Count(res, "<item ")).To(Equal(2))
+		Expect(res).To(ContainSubstring("<NumberReturned>2</NumberReturned>"))
+	})
+	DescribeTable("lan only", func(remoteAddr string, header string, expected int) {
+		req := httptest.NewRequest(http.MethodGet, "/dlna/rootDesc.xml", nil)
+		req.RemoteAddr = remoteAddr
+		if header != "" {
+			req.Header.Set("X-Forwarded-For", header)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(expected))
+	},
+		Entry("should answer private addresses", "10.0.0.5:41000", "", http.StatusOK),
+		Entry("should answer loopback", "127.0.0.1:41000", "", http.StatusOK),
+		Entry("should reject public addresses", "203.0.113.7:41000", "", http.StatusForbidden),
+		Entry("should reject forwarded requests", "10.0.0.5:41000", "203.0.113.7", http.StatusForbidden),
+	)
+	DescribeTable("errors", func(objectID string, flag string, code string) {
+		status, res := browse(objectID, flag)
+		Expect(status).To(Equal(http.StatusInternalServerError))
+		Expect(res).To(ContainSubstring("<errorCode>" + code + "</errorCode>"))
+	},
+		Entry("should reject unknown objects", "nope", "BrowseDirectChildren", "701"),
+		Entry("should reject unknown types", "type/nope", "BrowseDirectChildren", "701"),
+		Entry("should reject unknown browse flags", "0", "Nope", "402"),
+	)
+})
@@ -0,0 +1,107 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UPnP error codes returned in SOAP faults.
+const (
+	upnpErrInvalidAction = 401
+	upnpErrInvalidArgs   = 402
+	upnpErrActionFailed  = 501
+	upnpErrNoSuchObject  = 701
+)
+
+// soapAction is a decoded SOAP action call.
+type soapAction struct {
+	Name string
+	Args map[string]string
+}
+
+// soapArg is an output argument of a SOAP action response. Responses keep the argument order of the SCPD.
+type soapArg struct {
+	Name  string
+	Value string
+}
+
+// upnpError is an action failure reported to the control point as a SOAP fault.
+type upnpError struct {
+	Code int
+	Desc string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("upnp error %d: %s", e.Code, e.Desc)
+}
+
+// parseSoapAction decodes the first element of the SOAP body as the action and its children as arguments.
+func parseSoapAction(r io.Reader) (*soapAction, error) {
+	dec := xml.NewDecoder(r)
+	inBody := false
+	var action *soapAction
+	var argName string
+	var argValue strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not parse soap request: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case !inBody:
+				inBody = t.Name.Local == "Body"
+			case action == nil:
+				action = &soapAction{Name: t.Name.Local, Args: map[string]string{}}
+			case argName == "":
+				argName = t.Name.Local
+				argValue.Reset()
+			}
+		case xml.CharData:
+			if argName != "" {
+				argValue.Write(t)
+			}
+		case xml.EndElement:
+			if argName != "" && t.Name.Local == argName {
+				action.Args[argName] = argValue.String()
+				argName = ""
+			} else if action != nil && t.Name.Local == action.Name {
+				return action, nil
+			}
+		}
+	}
+	if action == nil {
+		return nil, errors.New("soap request has no action")
+	}
+	return action, nil
+}
+
+// soapResponse renders the response envelope of an action of the given service type.
+func soapResponse(serviceType string, action string, args []soapArg) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&b, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, a := range args {
+		fmt.Fprintf(&b, "<%s>%s</%s>", a.Name, xmlEscape(a.Value), a.Name)
+	}
+	fmt.Fprintf(&b, `</u:%sResponse>`, action)
+	b.WriteString(`</s:Body></s:Envelope>`)
+	return b.String()
+}
+
+// soapFault renders a SOAP fault envelope for the given UPnP error.
+func soapFault(e *upnpError) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`+
+		`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, e.Code, xmlEscape(e.Desc))
+}
@@ -0,0 +1,171 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ssdpAddr     = "239.255.255.250:1900"
+	ssdpMaxAge   = 1800
+	serverHeader = "Linux/1.0 UPnP/1.0 TGMon/1.0"
+	descPath     = "/dlna/rootDesc.xml"
+)
+
+// ssdpTarget is a notification type of the device and its unique service name.
+type ssdpTarget struct {
+	NT  string
+	USN string
+}
+
+// Announcer advertises the media server over SSDP and answers discovery requests.
+type Announcer struct {
+	udn      string
+	baseUrl  string
+	port     int
+	interval time.Duration
+}
+
+// Run announces the device until ctx is done, then sends byebye notifications.
+func (a *Announcer) Run(ctx context.Context) error {
+	ll := a.getLogger("Run")
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return fmt.Errorf("can not resolve ssdp address: %w", err)
+	}
+	listener, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("can not join ssdp group: %w", err)
+	}
+	defer listener.Close() //nolint:errcheck
+	sender, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return fmt.Errorf("can not open ssdp socket: %w", err)
+	}
+	defer sender.Close() //nolint:errcheck
+	go a.serve(listener, sender)
+	a.notify(sender, group, "ssdp:alive")
+	ll.Info("dlna server announced")
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.notify(sender, group, "ssdp:byebye")
+			ll.Info("dlna server unannounced")
+			return nil
+		case <-ticker.C:
+			a.notify(sender, group, "ssdp:alive")
+		}
+	}
+}
+
+// serve answers M-SEARCH requests until the listener is closed.
+func (a *Announcer) serve(listener *net.UDPConn, sender *net.UDPConn) {
+	ll := a.getLogger("serve")
+	buf := make([]byte, 2048)
+	for {
+		n, remote, err := listener.ReadFromUDP(buf)
+		if err != nil {
+			ll.WithError(err).Debug("ssdp listener closed")
+			return
+		}
+		st, ok := parseSearch(buf[:n])
+		if !ok {
+			continue
+		}
+		location := a.location(remote)
+		for _, t := range a.match(st) {
+			msg := fmt.Sprintf("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=%d\r\nDATE: %s\r\nEXT:\r\nLOCATION: %s\r\nSERVER: %s\r\nST: %s\r\nUSN: %s\r\n\r\n",
+				ssdpMaxAge, time.Now().UTC().Format(http.TimeFormat), location, serverHeader, t.NT, t.USN)
+			if _, err := sender.WriteToUDP([]byte(msg), remote); err != nil {
+				ll.WithError(err).Warn("can not answer ssdp search")
+			}
+		}
+	}
+}
+
+// notify sends a NOTIFY message with the given sub type for every target.
+func (a *Announcer) notify(sender *net.UDPConn, group *net.UDPAddr, nts string) {
+	ll := a.getLogger("notify")
+	location := a.location(group)
+	for _, t := range a.targets() {
+		msg := fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %s\r\nCACHE-CONTROL: max-age=%d\r\nLOCATION: %s\r\nNT: %s\r\nNTS: %s\r\nSERVER: %s\r\nUSN: %s\r\n\r\n",
+			ssdpAddr, ssdpMaxAge, location, t.NT, nts, serverHeader, t.USN)
+		if _, err := sender.WriteToUDP([]byte(msg), group); err != nil {
+			ll.WithError(err).Warn("can not send ssdp notify")
+		}
+	}
+}
+
+// targets returns all notification types of the device.
+func (a *Announcer) targets() []ssdpTarget {
+	res := []ssdpTarget{
+		{NT: "upnp:rootdevice", USN: a.udn + "::upnp:rootdevice"},
+		{NT: a.udn, USN: a.udn},
+		{NT: mediaServerType, USN: a.udn + "::" + mediaServerType},
+	}
+	for _, s := range services {
+		res = append(res, ssdpTarget{NT: s.Type, USN: a.udn + "::" + s.Type})
+	}
+	return res
+}
+
+// match returns the targets answering a search for st.
+func (a *Announcer) match(st string) []ssdpTarget {
+	if st == "ssdp:all" {
+		return a.targets()
+	}
+	for _, t := range a.targets() {
+		if t.NT == st {
+			return []ssdpTarget{t}
+		}
+	}
+	return nil
+}
+
+// location returns the device description url reachable from remote.
+func (a *Announcer) location(remote *net.UDPAddr) string {
+	if a.baseUrl != "" {
+		return a.baseUrl + descPath
+	}
+	host := "127.0.0.1"
+	if conn, err := net.DialUDP("udp4", nil, remote); err == nil {
+		host = conn.LocalAddr().(*net.UDPAddr).IP.String()
+		conn.Close() //nolint:errcheck
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(host, strconv.Itoa(a.port)), descPath)
+}
+
+func (a *Announcer) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.DLNAModule).WithField("func", fmt.Sprintf("%T.%s", a, fn))
+}
+
+// parseSearch returns the search target of an SSDP M-SEARCH discovery request.
+func parseSearch(msg []byte) (string, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(msg)))
+	if err != nil || req.Method != "M-SEARCH" {
+		return "", false
+	}
+	if strings.Trim(req.Header.Get("MAN"), `"`) != "ssdp:discover" {
+		return "", false
+	}
+	st := req.Header.Get("ST")
+	return st, st != ""
+}
+
+// NewAnnouncer creates an Announcer for the device. Without baseUrl, the local address facing each
+// control point is announced with the given http port.
+func NewAnnouncer(udn string, baseUrl string, port int, interval time.Duration) *Announcer {
+	return &Announcer{udn: udn, baseUrl: strings.TrimSuffix(baseUrl, "/"), port: port, interval: interval}
+}
@@ -0,0 +1,118 @@
+// Package events provides an in-process event bus for library and job changes.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/sirupsen/logrus"
+)
+
+type EventTypeEnum string
+
+const (
+	MEDIACREATEDEventType EventTypeEnum = "media.created"
+	MEDIAUPDATEDEventType EventTypeEnum = "media.updated"
+	MEDIADELETEDEventType EventTypeEnum = "media.deleted"
+	JOBREQUESTEDEventType EventTypeEnum = "job.requested"
+	JOBCLAIMEDEventType   EventTypeEnum = "job.claimed"
+	JOBCOMPLETEDEventType EventTypeEnum = "job.completed"
+	JOBFAILEDEventType    EventTypeEnum = "job.failed"
+	WORKERSTATEEventType  EventTypeEnum = "worker.state"
+)
+
+// subscriberBuffer is the number of events buffered per subscriber. Subscribers falling further behind are dropped
+// and are expected to resume from the backlog.
+const subscriberBuffer = 64
+
+// Event is a single change published on the bus. IDs increase monotonically within a process.
+type Event struct {
+	ID   uint64
+	Type EventTypeEnum
+	Time time.Time
+	Data any
+}
+
+// IBus publishes events to subscribers and keeps a short backlog for resuming.
+//
+//go:generate mockgen -source=bus.go -destination=../../mocks/events/bus.go -package=mocks
+type IBus interface {
+	// Publish sends an event to all subscribers. It never blocks.
+	Publish(typ EventTypeEnum, data any)
+	// Subscribe returns the backlog events after lastID and a channel for new events.
+	// The channel is closed when cancel is called or the subscriber falls behind.
+	Subscribe(lastID uint64) (backlog []Event, ch <-chan Event, cancel func())
+}
+
+// Bus implements IBus with a fixed size in-memory backlog.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	backlog     []Event
+	backlogSize int
+	subs        map[chan Event]struct{}
+}
+
+var _ IBus = (*Bus)(nil)
+
+// Publish appends the event to the backlog and sends it to all subscribers.
+func (b *Bus) Publish(typ EventTypeEnum, data any) {
+	ll := b.getLogger("Publish")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Time: time.Now(), Data: data}
+	b.backlog = append(b.backlog, e)
+	if len(b.backlog) > b.backlogSize {
+		b.backlog = b.backlog[len(b.backlog)-b.backlogSize:]
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			ll.Warn("subscriber is too slow, dropping it")
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. A lastID of 0 skips the backlog; a lastID ahead of the bus
+// (e.g. from before a restart) replays the whole backlog.
+func (b *Bus) Subscribe(lastID uint64) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	backlog := []Event{}
+	if lastID > 0 {
+		if lastID > b.lastID {
+			lastID = 0
+		}
+		for _, e := range b.backlog {
+			if e.ID > lastID {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+	ch := make(chan Event, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return backlog, ch, cancel
+}
+
+func (b *Bus) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.EventModule).WithField("func", fmt.Sprintf("%T.%s", b, fn))
+}
+
+// NewBus creates a Bus keeping the last backlogSize events.
+func NewBus(backlogSize int) *Bus {
+	return &Bus{backlogSize: backlogSize, subs: map[chan Event]struct{}{}}
+}
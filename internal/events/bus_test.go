@@ -0,0 +1,62 @@
+package events_test
+
+import (
+	"github.com/amirdaaee/TGMon/internal/events"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bus", func() {
+	var bus *events.Bus
+	BeforeEach(func() {
+		bus = events.NewBus(3)
+	})
+	ids := func(evs []events.Event) []uint64 {
+		res := []uint64{}
+		for _, e := range evs {
+			res = append(res, e.ID)
+		}
+		return res
+	}
+	It("should send published events to subscribers", func() {
+		backlog, ch, cancel := bus.Subscribe(0)
+		defer cancel()
+		Expect(backlog).To(BeEmpty())
+		bus.Publish(events.MEDIACREATEDEventType, "x")
+		var e events.Event
+		Expect(ch).To(Receive(&e))
+		Expect(e.ID).To(Equal(uint64(1)))
+		Expect(e.Type).To(Equal(events.MEDIACREATEDEventType))
+		Expect(e.Data).To(Equal("x"))
+	})
+	DescribeTable("backlog", func(published int, lastID uint64, expected []uint64) {
+		for range published {
+			bus.Publish(events.MEDIAUPDATEDEventType, nil)
+		}
+		backlog, _, cancel := bus.Subscribe(lastID)
+		defer cancel()
+		Expect(ids(backlog)).To(Equal(expected))
+	},
+		Entry("should skip backlog without last id", 2, uint64(0), []uint64{}),
+		Entry("should resume after last id", 3, uint64(1), []uint64{2, 3}),
+		Entry("should keep only the last events", 5, uint64(1), []uint64{3, 4, 5}),
+		Entry("should replay everything for unknown last id", 2, uint64(10), []uint64{1, 2}),
+	)
+	It("should drop slow subscribers", func() {
+		_, ch, cancel := bus.Subscribe(0)
+		defer cancel()
+		for range 100 {
+			bus.Publish(events.MEDIAUPDATEDEventType, nil)
+		}
+		Eventually(func() bool {
+			_, ok := <-ch
+			return ok
+		}).Should(BeFalse())
+	})
+	It("should close channel on cancel", func() {
+		_, ch, cancel := bus.Subscribe(0)
+		cancel()
+		cancel()
+		Expect(ch).To(BeClosed())
+	})
+})
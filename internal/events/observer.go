@@ -0,0 +1,168 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// mediaSeenMax bounds the set of media IDs remembered to avoid publishing media.created twice.
+const mediaSeenMax = 1024
+
+// JobEventData is the payload of job events.
+type JobEventData struct {
+	JobReqID bson.ObjectID
+	MediaID  *bson.ObjectID    `json:",omitempty"`
+	Type     types.JobTypeEnum `json:",omitempty"`
+	Error    string            `json:",omitempty"`
+}
+
+// WorkerStateEventData is the payload of worker.state events.
+type WorkerStateEventData struct {
+	Worker int
+	State  stream.WorkerStateEnum
+}
+
+// MediaObserver publishes media changes made through the media facade.
+// Media inserted by other processes, such as the bot, are picked up by Watch.
+type MediaObserver struct {
+	bus    IBus
+	mu     sync.Mutex
+	seen   map[bson.ObjectID]struct{}
+	lastID bson.ObjectID
+}
+
+var _ facade.IFacadeObserver[types.MediaFileDoc] = (*MediaObserver)(nil)
+
+func (o *MediaObserver) OnCreated(doc *types.MediaFileDoc) {
+	o.publishCreated(doc)
+}
+func (o *MediaObserver) OnUpdated(doc *types.MediaFileDoc) {
+	o.bus.Publish(MEDIAUPDATEDEventType, doc)
+}
+func (o *MediaObserver) OnDeleted(doc *types.MediaFileDoc) {
+	o.bus.Publish(MEDIADELETEDEventType, doc)
+}
+func (o *MediaObserver) OnCreateFailed(doc *types.MediaFileDoc, err error) {}
+
+// Watch polls the media collection for documents newer than the last published one until ctx is done.
+func (o *MediaObserver) Watch(ctx context.Context, coll mngo.ICollection[types.MediaFileDoc], interval time.Duration) error {
+	ll := o.getLogger("Watch")
+	latest, err := coll.Finder().Sort(bson.D{{Key: "_id", Value: -1}}).Limit(1).Find(ctx)
+	if err != nil {
+		return fmt.Errorf("can not find latest media: %w", err)
+	}
+	if len(latest) > 0 {
+		o.advance(latest[0].ID)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		o.mu.Lock()
+		lastID := o.lastID
+		o.mu.Unlock()
+		docs, err := coll.Finder().Filter(query.Gt("_id", lastID)).Sort(bson.D{{Key: "_id", Value: 1}}).Find(ctx)
+		if err != nil {
+			ll.WithError(err).Error("can not find new media")
+			continue
+		}
+		for _, doc := range docs {
+			o.publishCreated(doc)
+		}
+	}
+}
+
+// publishCreated publishes media.created once per media.
+func (o *MediaObserver) publishCreated(doc *types.MediaFileDoc) {
+	o.mu.Lock()
+	if _, ok := o.seen[doc.ID]; ok {
+		o.mu.Unlock()
+		return
+	}
+	if len(o.seen) >= mediaSeenMax {
+		o.seen = map[bson.ObjectID]struct{}{}
+	}
+	o.seen[doc.ID] = struct{}{}
+	o.mu.Unlock()
+	o.advance(doc.ID)
+	o.bus.Publish(MEDIACREATEDEventType, doc)
+}
+
+// advance moves the watch cursor forward to id.
+func (o *MediaObserver) advance(id bson.ObjectID) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if bytes.Compare(id[:], o.lastID[:]) > 0 {
+		o.lastID = id
+	}
+}
+
+func (o *MediaObserver) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.EventModule).WithField("func", fmt.Sprintf("%T.%s", o, fn))
+}
+
+// NewMediaObserver creates a MediaObserver publishing to bus.
+func NewMediaObserver(bus IBus) *MediaObserver {
+	return &MediaObserver{bus: bus, seen: map[bson.ObjectID]struct{}{}}
+}
+
+// JobReqObserver publishes job.requested and job.claimed events.
+type JobReqObserver struct {
+	facade.NopObserver[types.JobReqDoc]
+	bus IBus
+}
+
+func (o *JobReqObserver) OnCreated(doc *types.JobReqDoc) {
+	o.bus.Publish(JOBREQUESTEDEventType, JobEventData{JobReqID: doc.ID, MediaID: &doc.MediaID, Type: doc.Type})
+}
+func (o *JobReqObserver) OnUpdated(doc *types.JobReqDoc) {
+	if doc.ClaimedAt != nil {
+		o.bus.Publish(JOBCLAIMEDEventType, JobEventData{JobReqID: doc.ID, MediaID: &doc.MediaID, Type: doc.Type})
+	}
+}
+
+// NewJobReqObserver creates a JobReqObserver publishing to bus.
+func NewJobReqObserver(bus IBus) *JobReqObserver {
+	return &JobReqObserver{bus: bus}
+}
+
+// JobResObserver publishes job.completed and job.failed events.
+type JobResObserver struct {
+	facade.NopObserver[types.JobResDoc]
+	bus IBus
+}
+
+func (o *JobResObserver) OnCreated(doc *types.JobResDoc) {
+	o.bus.Publish(JOBCOMPLETEDEventType, JobEventData{JobReqID: doc.JobReqID})
+}
+func (o *JobResObserver) OnCreateFailed(doc *types.JobResDoc, err error) {
+	o.bus.Publish(JOBFAILEDEventType, JobEventData{JobReqID: doc.JobReqID, Error: err.Error()})
+}
+
+// NewJobResObserver creates a JobResObserver publishing to bus.
+func NewJobResObserver(bus IBus) *JobResObserver {
+	return &JobResObserver{bus: bus}
+}
+
+// NewWorkerStateListener returns a stream.WorkerStateListener publishing worker.state events to bus.
+func NewWorkerStateListener(bus IBus) stream.WorkerStateListener {
+	return func(worker int, state stream.WorkerStateEnum) {
+		bus.Publish(WORKERSTATEEventType, WorkerStateEventData{Worker: worker, State: state})
+	}
+}
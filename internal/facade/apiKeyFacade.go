@@ -0,0 +1,100 @@
+// Package facade provides CRUD logic for api key documents.
+package facade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// apiKeyNameMaxLen is the maximum length of an api key name.
+const apiKeyNameMaxLen = 64
+
+// ApiKeyCrud implements ICrud for ApiKeyDoc, providing CRUD hooks and collection access.
+type ApiKeyCrud struct {
+	container db.IDbContainer
+}
+
+var _ ICrud[types.ApiKeyDoc] = (*ApiKeyCrud)(nil)
+
+// PreCreate validates the name, scopes and expiry of an ApiKeyDoc and generates its key.
+// The plain key is only available on the returned document.
+func (crd *ApiKeyCrud) PreCreate(ctx context.Context, doc *types.ApiKeyDoc) error {
+	if doc == nil {
+		return fmt.Errorf("ApiKeyDoc is nil")
+	}
+	doc.Name = strings.TrimSpace(doc.Name)
+	if doc.Name == "" {
+		return fmt.Errorf("%w: api key name is empty", ErrInvalidDocument)
+	}
+	if len(doc.Name) > apiKeyNameMaxLen {
+		return fmt.Errorf("%w: api key name is longer than %d characters", ErrInvalidDocument, apiKeyNameMaxLen)
+	}
+	if len(doc.Scopes) == 0 {
+		return fmt.Errorf("%w: api key has no scopes", ErrInvalidDocument)
+	}
+	scopes := []types.ApiKeyScopeEnum{}
+	seen := map[types.ApiKeyScopeEnum]bool{}
+	for _, s := range doc.Scopes {
+		if !s.IsValid() {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidDocument, s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	doc.Scopes = scopes
+	if doc.ExpiresAt != nil && !doc.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: api key expiry is in the past", ErrInvalidDocument)
+	}
+	key, hash, prefix, err := auth.NewApiKey()
+	if err != nil {
+		return err
+	}
+	doc.Key, doc.Hash, doc.Prefix = key, hash, prefix
+	doc.LastUsedAt = nil
+	return nil
+}
+
+// PostCreate is a post-create hook for ApiKeyDoc. No-op in this implementation.
+func (crd *ApiKeyCrud) PostCreate(ctx context.Context, doc *types.ApiKeyDoc) error {
+	return nil
+}
+
+// PreDelete is a pre-delete hook for ApiKeyDoc. No-op in this implementation.
+func (crd *ApiKeyCrud) PreDelete(ctx context.Context, doc *types.ApiKeyDoc) error {
+	return nil
+}
+
+// PostDelete is a post-delete hook for ApiKeyDoc. No-op in this implementation.
+func (crd *ApiKeyCrud) PostDelete(ctx context.Context, doc *types.ApiKeyDoc) error {
+	return nil
+}
+
+// PreUpdate rejects all updates; api keys are revoked and recreated instead.
+func (crd *ApiKeyCrud) PreUpdate(ctx context.Context, doc *types.ApiKeyDoc, fields bson.D) error {
+	return fmt.Errorf("%w: api keys can not be edited", ErrInvalidUpdate)
+}
+
+// PostUpdate is a post-update hook for ApiKeyDoc. No-op in this implementation.
+func (crd *ApiKeyCrud) PostUpdate(ctx context.Context, doc *types.ApiKeyDoc) error {
+	return nil
+}
+
+// GetCollection returns the ApiKey collection from the database container.
+func (crd *ApiKeyCrud) GetCollection() mngo.ICollection[types.ApiKeyDoc] {
+	return crd.container.GetMongoContainer().GetApiKeyCollection()
+}
+
+// NewApiKeyCrud creates a new ApiKeyCrud with the provided database container.
+func NewApiKeyCrud(container db.IDbContainer) ICrud[types.ApiKeyDoc] {
+	return &ApiKeyCrud{container: container}
+}
@@ -0,0 +1,60 @@
+package facade_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mDb "github.com/amirdaaee/TGMon/mocks/db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("ApiKeyCrud", func() {
+	var crd facade.ICrud[types.ApiKeyDoc]
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		crd = facade.NewApiKeyCrud(mDb.NewMockIDbContainer(ctrl))
+	})
+	Describe("PreCreate", func() {
+		type testCase struct {
+			doc       *types.ApiKeyDoc
+			expectErr bool
+		}
+		past := time.Now().Add(-time.Hour)
+		DescribeTable("", func(tc testCase) {
+			err := crd.PreCreate(context.Background(), tc.doc)
+			if tc.expectErr {
+				Expect(err).To(MatchError(facade.ErrInvalidDocument))
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tc.doc.Key).ToNot(BeEmpty())
+			Expect(tc.doc.Hash).ToNot(BeEmpty())
+			Expect(tc.doc.Key).To(HavePrefix(tc.doc.Prefix))
+			Expect(tc.doc.Scopes).To(Equal([]types.ApiKeyScopeEnum{types.MEDIAREADApiKeyScope, types.STREAMApiKeyScope}))
+		},
+			Entry("should generate key and dedupe scopes", testCase{
+				doc: &types.ApiKeyDoc{Name: "sprites", Scopes: []types.ApiKeyScopeEnum{types.MEDIAREADApiKeyScope, types.STREAMApiKeyScope, types.MEDIAREADApiKeyScope}},
+			}),
+			Entry("should reject empty name", testCase{
+				doc:       &types.ApiKeyDoc{Scopes: []types.ApiKeyScopeEnum{types.MEDIAREADApiKeyScope}},
+				expectErr: true,
+			}),
+			Entry("should reject missing scopes", testCase{
+				doc:       &types.ApiKeyDoc{Name: "sprites"},
+				expectErr: true,
+			}),
+			Entry("should reject unknown scope", testCase{
+				doc:       &types.ApiKeyDoc{Name: "sprites", Scopes: []types.ApiKeyScopeEnum{"media:everything"}},
+				expectErr: true,
+			}),
+			Entry("should reject expiry in the past", testCase{
+				doc:       &types.ApiKeyDoc{Name: "sprites", Scopes: []types.ApiKeyScopeEnum{types.MEDIAREADApiKeyScope}, ExpiresAt: &past},
+				expectErr: true,
+			}),
+		)
+	})
+})
@@ -0,0 +1,168 @@
+// Package facade provides bulk operations on media documents.
+package facade
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/time/rate"
+)
+
+type BulkStatusEnum string
+
+const (
+	DONEBulkStatus    BulkStatusEnum = "DONE"    // applied, or would be applied in a dry run
+	SKIPPEDBulkStatus BulkStatusEnum = "SKIPPED" // nothing to do for the document
+	FAILEDBulkStatus  BulkStatusEnum = "FAILED"
+)
+
+// BulkItemResult is the outcome of a bulk operation on a single document.
+type BulkItemResult struct {
+	ID     bson.ObjectID
+	Status BulkStatusEnum
+	Error  string
+}
+
+// BulkOptions controls how a bulk operation is applied.
+type BulkOptions struct {
+	// DryRun reports what would be done without changing anything.
+	DryRun bool
+	// Limiter throttles the documents applied one after another, and so the side effects of their hooks
+	// (e.g. removing files from minio). Nil applies them without limit.
+	Limiter *rate.Limiter
+}
+
+// bulkApplyFunc applies an operation to the document at index i, returning whether there was anything to do.
+type bulkApplyFunc func(ctx context.Context, i int, dryRun bool) (BulkStatusEnum, error)
+
+// runBulk applies an operation to the documents one at a time, through the facade so their hooks run. A failed
+// document does not stop the others; once ctx is done, the remaining documents fail with its error.
+func runBulk(ctx context.Context, ids []bson.ObjectID, opts BulkOptions, apply bulkApplyFunc) []BulkItemResult {
+	res := make([]BulkItemResult, len(ids))
+	for i, id := range ids {
+		res[i].ID = id
+		res[i].Status, res[i].Error = applyBulkItem(ctx, i, opts, apply)
+	}
+	return res
+}
+
+// applyBulkItem applies an operation to the document at index i once ctx and the limiter allow it, returning its
+// status and error message.
+func applyBulkItem(ctx context.Context, i int, opts BulkOptions, apply bulkApplyFunc) (BulkStatusEnum, string) {
+	if err := ctx.Err(); err != nil {
+		return FAILEDBulkStatus, err.Error()
+	}
+	if !opts.DryRun && opts.Limiter != nil {
+		if err := opts.Limiter.Wait(ctx); err != nil {
+			return FAILEDBulkStatus, err.Error()
+		}
+	}
+	status, err := apply(ctx, i, opts.DryRun)
+	if err != nil {
+		return FAILEDBulkStatus, err.Error()
+	}
+	return status, ""
+}
+
+// mediaIDs returns the IDs of the media.
+func mediaIDs(media []*types.MediaFileDoc) []bson.ObjectID {
+	ids := make([]bson.ObjectID, len(media))
+	for i, m := range media {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// BulkDeleteMedia deletes the media one at a time, running the delete hooks of each.
+func BulkDeleteMedia(ctx context.Context, fac IFacade[types.MediaFileDoc], media []*types.MediaFileDoc, opts BulkOptions) []BulkItemResult {
+	return runBulk(ctx, mediaIDs(media), opts, func(ctx context.Context, i int, dryRun bool) (BulkStatusEnum, error) {
+		if dryRun {
+			return DONEBulkStatus, nil
+		}
+		if _, err := fac.DeleteOne(ctx, query.Id(media[i].ID)); err != nil {
+			return FAILEDBulkStatus, err
+		}
+		return DONEBulkStatus, nil
+	})
+}
+
+// BulkTagMedia adds the tags to (or with untag, removes them from) the media one at a time. Media that already have
+// (or do not have) all the tags are skipped. Added tags must exist in tagColl.
+func BulkTagMedia(ctx context.Context, fac IFacade[types.MediaFileDoc], tagColl mngo.ICollection[types.TagDoc], media []*types.MediaFileDoc, tags []bson.ObjectID, untag bool, opts BulkOptions) ([]BulkItemResult, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: no tags given", ErrInvalidUpdate)
+	}
+	if !untag {
+		if err := ensureIDsExist(ctx, tagColl, tags); err != nil {
+			return nil, fmt.Errorf("%w: invalid tags: %w", ErrInvalidUpdate, err)
+		}
+	}
+	res := runBulk(ctx, mediaIDs(media), opts, func(ctx context.Context, i int, dryRun bool) (BulkStatusEnum, error) {
+		newTags := retag(media[i].Tags, tags, untag)
+		if len(newTags) == len(media[i].Tags) {
+			return SKIPPEDBulkStatus, nil
+		}
+		if dryRun {
+			return DONEBulkStatus, nil
+		}
+		if _, err := fac.UpdateOne(ctx, query.Id(media[i].ID), bson.D{{Key: types.MediaFileDoc__TagsField, Value: newTags}}); err != nil {
+			return FAILEDBulkStatus, err
+		}
+		return DONEBulkStatus, nil
+	})
+	return res, nil
+}
+
+// retag returns current with the tags added, or removed with untag. The order of current is kept.
+func retag(current []bson.ObjectID, tags []bson.ObjectID, untag bool) []bson.ObjectID {
+	res := make([]bson.ObjectID, 0, len(current)+len(tags))
+	for _, t := range current {
+		if !untag || !slices.Contains(tags, t) {
+			res = append(res, t)
+		}
+	}
+	if !untag {
+		for _, t := range tags {
+			if !slices.Contains(res, t) {
+				res = append(res, t)
+			}
+		}
+	}
+	return res
+}
+
+// BulkRequestJobs requests a job of the given type for the media one at a time. Media with a pending (not yet
+// claimed) job of the type are skipped.
+func BulkRequestJobs(ctx context.Context, fac IFacade[types.JobReqDoc], media []*types.MediaFileDoc, jobType types.JobTypeEnum, opts BulkOptions) ([]BulkItemResult, error) {
+	if jobType != types.THUMBNAILJobType && jobType != types.SPRITEJobType {
+		return nil, fmt.Errorf("%w: unknown job type (%s)", ErrInvalidDocument, jobType)
+	}
+	res := runBulk(ctx, mediaIDs(media), opts, func(ctx context.Context, i int, dryRun bool) (BulkStatusEnum, error) {
+		filter := bsonx.NewD().
+			Add(types.JobReqDoc__MediaIDField, media[i].ID).
+			Add(types.JobReqDoc__TypeField, jobType).
+			Add(types.JobReqDoc__ClaimedAtField, nil).
+			Build()
+		n, err := fac.GetCollection().Finder().Filter(filter).Count(ctx)
+		if err != nil {
+			return FAILEDBulkStatus, fmt.Errorf("error counting pending jobs: %w", err)
+		}
+		if n > 0 {
+			return SKIPPEDBulkStatus, nil
+		}
+		if dryRun {
+			return DONEBulkStatus, nil
+		}
+		if _, err := fac.CreateOne(ctx, &types.JobReqDoc{MediaID: media[i].ID, Type: jobType}); err != nil {
+			return FAILEDBulkStatus, err
+		}
+		return DONEBulkStatus, nil
+	})
+	return res, nil
+}
@@ -0,0 +1,147 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Bulk", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		testContext  context.Context
+		media        []*types.MediaFileDoc
+	)
+	newMedia := func(tags ...bson.ObjectID) *types.MediaFileDoc {
+		m := &types.MediaFileDoc{Tags: tags}
+		m.ID = bson.NewObjectID()
+		return m
+	}
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+	})
+	Describe("BulkDeleteMedia", func() {
+		BeforeEach(func() {
+			media = []*types.MediaFileDoc{newMedia(), newMedia(), newMedia()}
+		})
+		It("should delete every media and report failures per item", func() {
+			mockMediaFac.EXPECT().DeleteOne(testContext, gomock.Any()).Return(nil, nil).Times(2)
+			mockMediaFac.EXPECT().DeleteOne(testContext, gomock.Any()).Return(nil, fmt.Errorf("mock delete error"))
+			res := facade.BulkDeleteMedia(testContext, mockMediaFac, media, facade.BulkOptions{})
+			Expect(res).To(HaveLen(3))
+			Expect(res[0]).To(Equal(facade.BulkItemResult{ID: media[0].ID, Status: facade.DONEBulkStatus}))
+			Expect(res[1].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[2].Status).To(Equal(facade.FAILEDBulkStatus))
+			Expect(res[2].Error).To(ContainSubstring("mock delete error"))
+		})
+		It("should not delete anything in a dry run", func() {
+			res := facade.BulkDeleteMedia(testContext, mockMediaFac, media, facade.BulkOptions{DryRun: true})
+			Expect(res).To(HaveLen(3))
+			for _, r := range res {
+				Expect(r.Status).To(Equal(facade.DONEBulkStatus))
+			}
+		})
+		It("should fail the remaining media once the context is done", func() {
+			ctx, cancel := context.WithCancel(testContext)
+			mockMediaFac.EXPECT().DeleteOne(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D) (*types.MediaFileDoc, error) {
+				cancel()
+				return nil, nil
+			})
+			res := facade.BulkDeleteMedia(ctx, mockMediaFac, media, facade.BulkOptions{})
+			Expect(res[0].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[1].Status).To(Equal(facade.FAILEDBulkStatus))
+			Expect(res[2].Error).To(Equal(context.Canceled.Error()))
+		})
+	})
+	Describe("BulkTagMedia", func() {
+		var (
+			mockTagFinder *mMongoX.MockIFinder[types.TagDoc]
+			mockTagColl   *mMongo.MockICollection[types.TagDoc]
+			tagA, tagB    bson.ObjectID
+		)
+		BeforeEach(func() {
+			tagA, tagB = bson.NewObjectID(), bson.NewObjectID()
+			mockTagFinder = mMongoX.NewMockIFinder[types.TagDoc](ctrl)
+			mockTagFinder.EXPECT().Filter(gomock.Any()).Return(mockTagFinder).AnyTimes()
+			mockTagColl = mMongo.NewMockICollection[types.TagDoc](ctrl)
+			mockTagColl.EXPECT().Finder().Return(mockTagFinder).AnyTimes()
+			media = []*types.MediaFileDoc{newMedia(), newMedia(tagA), newMedia(tagB, tagA)}
+		})
+		It("should add the tags to media missing them", func() {
+			mockTagFinder.EXPECT().Count(testContext).Return(int64(1), nil)
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), bson.D{{Key: types.MediaFileDoc__TagsField, Value: []bson.ObjectID{tagA}}}).Return(nil, nil)
+			res, err := facade.BulkTagMedia(testContext, mockMediaFac, mockTagColl, media, []bson.ObjectID{tagA}, false, facade.BulkOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res[0].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[1].Status).To(Equal(facade.SKIPPEDBulkStatus))
+			Expect(res[2].Status).To(Equal(facade.SKIPPEDBulkStatus))
+		})
+		It("should remove the tags from media having them", func() {
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), bson.D{{Key: types.MediaFileDoc__TagsField, Value: []bson.ObjectID{}}}).Return(nil, nil)
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), bson.D{{Key: types.MediaFileDoc__TagsField, Value: []bson.ObjectID{tagB}}}).Return(nil, nil)
+			res, err := facade.BulkTagMedia(testContext, mockMediaFac, mockTagColl, media, []bson.ObjectID{tagA}, true, facade.BulkOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res[0].Status).To(Equal(facade.SKIPPEDBulkStatus))
+			Expect(res[1].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[2].Status).To(Equal(facade.DONEBulkStatus))
+		})
+		It("should report without updating in a dry run", func() {
+			mockTagFinder.EXPECT().Count(testContext).Return(int64(1), nil)
+			res, err := facade.BulkTagMedia(testContext, mockMediaFac, mockTagColl, media, []bson.ObjectID{tagB}, false, facade.BulkOptions{DryRun: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res[0].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[1].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[2].Status).To(Equal(facade.SKIPPEDBulkStatus))
+		})
+		It("should refuse tags that do not exist", func() {
+			mockTagFinder.EXPECT().Count(testContext).Return(int64(0), nil)
+			_, err := facade.BulkTagMedia(testContext, mockMediaFac, mockTagColl, media, []bson.ObjectID{tagA}, false, facade.BulkOptions{})
+			Expect(err).To(MatchError(facade.ErrInvalidUpdate))
+		})
+	})
+	Describe("BulkRequestJobs", func() {
+		var (
+			mockJobFac    *mFacade.MockIFacade[types.JobReqDoc]
+			mockJobFinder *mMongoX.MockIFinder[types.JobReqDoc]
+			mockJobColl   *mMongo.MockICollection[types.JobReqDoc]
+		)
+		BeforeEach(func() {
+			mockJobFinder = mMongoX.NewMockIFinder[types.JobReqDoc](ctrl)
+			mockJobFinder.EXPECT().Filter(gomock.Any()).Return(mockJobFinder).AnyTimes()
+			mockJobColl = mMongo.NewMockICollection[types.JobReqDoc](ctrl)
+			mockJobColl.EXPECT().Finder().Return(mockJobFinder).AnyTimes()
+			mockJobFac = mFacade.NewMockIFacade[types.JobReqDoc](ctrl)
+			mockJobFac.EXPECT().GetCollection().Return(mockJobColl).AnyTimes()
+			media = []*types.MediaFileDoc{newMedia(), newMedia()}
+		})
+		It("should request jobs for media without a pending one", func() {
+			mockJobFinder.EXPECT().Count(testContext).Return(int64(0), nil)
+			mockJobFinder.EXPECT().Count(testContext).Return(int64(1), nil)
+			mockJobFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.JobReqDoc) (*types.JobReqDoc, error) {
+				Expect(doc.MediaID).To(Equal(media[0].ID))
+				Expect(doc.Type).To(Equal(types.SPRITEJobType))
+				return doc, nil
+			})
+			res, err := facade.BulkRequestJobs(testContext, mockJobFac, media, types.SPRITEJobType, facade.BulkOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res[0].Status).To(Equal(facade.DONEBulkStatus))
+			Expect(res[1].Status).To(Equal(facade.SKIPPEDBulkStatus))
+		})
+		It("should refuse unknown job types", func() {
+			_, err := facade.BulkRequestJobs(testContext, mockJobFac, media, "mock", facade.BulkOptions{})
+			Expect(err).To(MatchError(facade.ErrInvalidDocument))
+		})
+	})
+})
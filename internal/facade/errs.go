@@ -5,3 +5,9 @@ import "errors"
 var ErrFileAlreadyExists = errors.New("file already exists")
 var ErrNoDocumentsFound = errors.New("no documents found")
 var ErrMultipleDocumentsFound = errors.New("multiple documents found")
+var ErrInvalidUpdate = errors.New("invalid update")
+var ErrUpdateConflict = errors.New("document no longer matches the update")
+var ErrInvalidDocument = errors.New("invalid document")
+var ErrTagAlreadyExists = errors.New("tag already exists")
+var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrLastAdmin = errors.New("at least one admin user is required")
@@ -3,12 +3,16 @@ package facade
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
 	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // ICrud defines hooks and collection access for CRUD operations on type T.
@@ -18,7 +22,9 @@ type ICrud[T any] interface {
 	PreCreate(ctx context.Context, doc *T) error
 	PostCreate(ctx context.Context, doc *T) error // errors in post handlers won't affect main transaction (see docs)
 	PreDelete(ctx context.Context, doc *T) error
-	PostDelete(ctx context.Context, doc *T) error // errors in post handlers won't affect main transaction (see docs)
+	PostDelete(ctx context.Context, doc *T) error               // errors in post handlers won't affect main transaction (see docs)
+	PreUpdate(ctx context.Context, doc *T, fields bson.D) error // may rewrite elements of fields in place (e.g. to hash values)
+	PostUpdate(ctx context.Context, doc *T) error               // errors in post handlers won't affect main transaction (see docs)
 	GetCollection() mngo.ICollection[T]
 }
 
@@ -28,13 +34,31 @@ type ICrud[T any] interface {
 type IFacade[T any] interface {
 	CreateOne(ctx context.Context, doc *T) (*T, error)
 	DeleteOne(ctx context.Context, filter bson.D) (*T, error)
+	UpdateOne(ctx context.Context, filter bson.D, fields bson.D) (*T, error)
 	GetCRD() ICrud[T]
 	GetCollection() mngo.ICollection[T]
 }
 
+// IFacadeObserver is notified of changes made through a BaseFacade, after the post hooks ran.
+type IFacadeObserver[T any] interface {
+	OnCreated(doc *T)
+	OnUpdated(doc *T)
+	OnDeleted(doc *T)
+	OnCreateFailed(doc *T, err error)
+}
+
+// NopObserver implements IFacadeObserver with no-op methods. Embed it to observe only some changes.
+type NopObserver[T any] struct{}
+
+func (NopObserver[T]) OnCreated(doc *T)                 {}
+func (NopObserver[T]) OnUpdated(doc *T)                 {}
+func (NopObserver[T]) OnDeleted(doc *T)                 {}
+func (NopObserver[T]) OnCreateFailed(doc *T, err error) {}
+
 // BaseFacade provides a generic implementation of IFacade for type T.
 type BaseFacade[T any] struct {
-	crd ICrud[T]
+	crd       ICrud[T]
+	observers []IFacadeObserver[T]
 }
 
 var _ IFacade[any] = (*BaseFacade[any])(nil)
@@ -47,10 +71,14 @@ func (f *BaseFacade[T]) CreateOne(ctx context.Context, doc *T) (*T, error) {
 		return nil, fmt.Errorf("document is nil")
 	}
 	if err := f.crd.PreCreate(ctx, doc); err != nil {
-		return nil, fmt.Errorf("error pre-creating hook: %w", err)
+		err = fmt.Errorf("error pre-creating hook: %w", err)
+		f.notify(func(o IFacadeObserver[T]) { o.OnCreateFailed(doc, err) })
+		return nil, err
 	}
 	if _, err := f.GetCollection().Creator().InsertOne(ctx, doc); err != nil {
-		return nil, fmt.Errorf("error creating document: %w", err)
+		err = fmt.Errorf("error creating document: %w", err)
+		f.notify(func(o IFacadeObserver[T]) { o.OnCreateFailed(doc, err) })
+		return nil, err
 	}
 	// PostCreate runs in a goroutine; errors are logged but not returned.
 	postCtx := context.Background()
@@ -60,6 +88,7 @@ func (f *BaseFacade[T]) CreateOne(ctx context.Context, doc *T) (*T, error) {
 		} else {
 			ll.Info("document post-creating hook completed")
 		}
+		f.notify(func(o IFacadeObserver[T]) { o.OnCreated(doc) })
 	}()
 	return doc, nil
 }
@@ -96,10 +125,70 @@ func (f *BaseFacade[T]) DeleteOne(ctx context.Context, filter bson.D) (*T, error
 		} else {
 			ll.Info("document post-deleting hook completed")
 		}
+		f.notify(func(o IFacadeObserver[T]) { o.OnDeleted(doc) })
 	}()
 	return doc, nil
 }
 
+// UpdateOne sets the given fields on a single document matching the filter after running pre-update hooks and returns the updated document. Post-update hooks run in a goroutine; errors are logged but not returned.
+// The update is applied to the document the hooks validated, only if it still matches the filter; otherwise ErrUpdateConflict is returned.
+func (f *BaseFacade[T]) UpdateOne(ctx context.Context, filter bson.D, fields bson.D) (*T, error) {
+	ll := f.getLogger("UpdateOne")
+	ll.Info("Updating document")
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidUpdate)
+	}
+	fnd := f.GetCollection().Finder().Filter(filter)
+	c, err := fnd.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error counting existing documents: %w", err)
+	}
+	if c == 0 {
+		return nil, ErrNoDocumentsFound
+	} else if c > 1 {
+		return nil, ErrMultipleDocumentsFound
+	}
+	doc, err := fnd.FindOne(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding document to update: %w", err)
+	}
+	if err := f.crd.PreUpdate(ctx, doc, fields); err != nil {
+		return nil, fmt.Errorf("error pre-updating hook: %w", err)
+	}
+	updated, err := f.GetCollection().Finder().Filter(docFilter(doc, filter)).Updates(bson.D{{Key: "$set", Value: fields}}).
+		FindOneAndUpdate(ctx, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: document changed before the update", ErrUpdateConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error updating document: %w", err)
+	}
+	// PostUpdate runs in a goroutine; errors are logged but not returned.
+	postCtx := context.Background()
+	go func() {
+		if err := f.crd.PostUpdate(postCtx, updated); err != nil {
+			ll.WithError(err).Error("error in post-updating hook")
+		} else {
+			ll.Info("document post-updating hook completed")
+		}
+		f.notify(func(o IFacadeObserver[T]) { o.OnUpdated(updated) })
+	}()
+	return updated, nil
+}
+
+// docFilter restricts filter to doc, for documents with an id (embedding mongox.Model). DefaultId returns the id of
+// fetched documents as is.
+func docFilter(doc any, filter bson.D) bson.D {
+	m, ok := doc.(interface{ DefaultId() bson.ObjectID })
+	if !ok {
+		return filter
+	}
+	if len(filter) == 0 {
+		return query.Id(m.DefaultId())
+	}
+	return bson.D{{Key: "$and", Value: bson.A{query.Id(m.DefaultId()), filter}}}
+}
+
 // GetCRD returns the underlying CRUD implementation for type T.
 func (f *BaseFacade[T]) GetCRD() ICrud[T] {
 	return f.crd
@@ -110,12 +199,38 @@ func (f *BaseFacade[T]) GetCollection() mngo.ICollection[T] {
 	return f.crd.GetCollection()
 }
 
+// notify calls fn for every observer of the facade.
+func (f *BaseFacade[T]) notify(fn func(o IFacadeObserver[T])) {
+	for _, o := range f.observers {
+		fn(o)
+	}
+}
+
 // getLogger returns a logrus.Entry for the given function name, tagged with the struct type.
 func (f *BaseFacade[T]) getLogger(fn string) *logrus.Entry {
 	return log.GetLogger(log.FacadeModule).WithField("func", fmt.Sprintf("%T.%s", f, fn))
 }
 
-// NewFacade returns a new BaseFacade for the given CRD implementation.
-func NewFacade[T any](crd ICrud[T]) IFacade[T] {
-	return &BaseFacade[T]{crd: crd}
+// NewFacade returns a new BaseFacade for the given CRD implementation, notifying the given observers of changes.
+func NewFacade[T any](crd ICrud[T], observers ...IFacadeObserver[T]) IFacade[T] {
+	return &BaseFacade[T]{crd: crd, observers: observers}
+}
+
+// ensureIDsExist returns an error wrapping ErrNoDocumentsFound if any of the given IDs is missing from the collection.
+func ensureIDsExist[T any](ctx context.Context, coll mngo.ICollection[T], ids []bson.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	unique := map[bson.ObjectID]struct{}{}
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+	n, err := coll.Finder().Filter(query.In("_id", ids...)).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("error counting documents: %w", err)
+	}
+	if n != int64(len(unique)) {
+		return fmt.Errorf("%w: %d of %d referenced documents exist", ErrNoDocumentsFound, n, len(unique))
+	}
+	return nil
 }
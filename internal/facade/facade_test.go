@@ -25,6 +25,7 @@ var _ = Describe("BaseFacade", func() {
 		mockCreator        *mMongoX.MockICreator[testDoc]
 		mockDeleter        *mMongoX.MockIDeleter[testDoc]
 		mockFinder         *mMongoX.MockIFinder[testDoc]
+		mockUpdater        *mMongoX.MockIUpdater[testDoc]
 		mockCrud           *mFacade.MockICrud[testDoc]
 		mockContainer      *mDb.MockIDbContainer
 		mockCollection     *mMongo.MockICollection[testDoc]
@@ -44,10 +45,12 @@ var _ = Describe("BaseFacade", func() {
 		mockCreator = mMongoX.NewMockICreator[testDoc](ctrl)
 		mockDeleter = mMongoX.NewMockIDeleter[testDoc](ctrl)
 		mockFinder = mMongoX.NewMockIFinder[testDoc](ctrl)
+		mockUpdater = mMongoX.NewMockIUpdater[testDoc](ctrl)
 		mockCollection = mMongo.NewMockICollection[testDoc](ctrl)
 		mockCollection.EXPECT().Creator().Return(mockCreator).AnyTimes()
 		mockCollection.EXPECT().Deleter().Return(mockDeleter).AnyTimes()
 		mockCollection.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockCollection.EXPECT().Updater().Return(mockUpdater).AnyTimes()
 		// ...
 		mockCrud = mFacade.NewMockICrud[testDoc](ctrl)
 		mockCrud.EXPECT().GetCollection().Return(mockCollection).AnyTimes()
@@ -142,6 +145,32 @@ var _ = Describe("BaseFacade", func() {
 			}),
 		)
 	})
+	Describe("Observers", func() {
+		var mockObserver *mFacade.MockIFacadeObserver[testDoc]
+		BeforeEach(func() {
+			mockObserver = mFacade.NewMockIFacadeObserver[testDoc](ctrl)
+		})
+		It("should notify created after post create hook", func() {
+			created := make(chan struct{})
+			mockCrud.EXPECT().PreCreate(testContext, tDoc).Return(nil)
+			mockCreator.EXPECT().InsertOne(testContext, tDoc).Return(&mongo.InsertOneResult{}, nil)
+			mockCrud.EXPECT().PostCreate(gomock.Any(), tDoc).Return(nil)
+			mockObserver.EXPECT().OnCreated(tDoc).Do(func(*testDoc) { close(created) })
+			fac = facade.NewFacade(mockCrud, mockObserver)
+			_, err := fac.CreateOne(testContext, tDoc)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(created).WithTimeout(1 * time.Second).Should(BeClosed())
+		})
+		It("should notify create failed on pre create error", func() {
+			mockCrud.EXPECT().PreCreate(testContext, tDoc).Return(fmt.Errorf("mock pre create error"))
+			mockObserver.EXPECT().OnCreateFailed(tDoc, gomock.Any()).Do(func(_ *testDoc, err error) {
+				Expect(err).To(MatchError(ContainSubstring("mock pre create error")))
+			})
+			fac = facade.NewFacade(mockCrud, mockObserver)
+			_, err := fac.CreateOne(testContext, tDoc)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 	Describe("DeleteOne", func() {
 		type testCase struct {
 			nilQuery       bool
@@ -308,4 +337,161 @@ var _ = Describe("BaseFacade", func() {
 			}),
 		)
 	})
+	Describe("UpdateOne", func() {
+		type testCase struct {
+			emptyFields    bool
+			findOneErr     bool
+			conflict       bool
+			expectPreErr   bool
+			expectPostErr  bool
+			expectDbErr    bool
+			expectCountErr bool
+			expectErr      bool
+			dbCall         bool
+			countCall      bool
+			findCall       bool
+			preCall        bool
+			postCall       bool
+			count          int64
+		}
+		DescribeTable("", func(tc testCase) {
+			ctx := testContext
+			query := testQ
+			fields := bson.D{{Key: "Name", Value: "mock"}}
+			if tc.emptyFields {
+				fields = bson.D{}
+			}
+			if tc.countCall {
+				mockFinder.EXPECT().Filter(gomock.AssignableToTypeOf(query)).Return(mockFinder).AnyTimes()
+				mockFinder.EXPECT().Count(ctx).DoAndReturn(func(ctx context.Context, _ ...any) (int64, error) {
+					if tc.expectCountErr {
+						return 0, fmt.Errorf("mock count error")
+					}
+					return tc.count, nil
+				})
+			}
+			if tc.findCall {
+				if tc.findOneErr {
+					mockFinder.EXPECT().FindOne(ctx).Return(nil, fmt.Errorf("mock findOne error"))
+				} else {
+					mockFinder.EXPECT().FindOne(ctx).Return(tDoc, nil)
+				}
+			}
+			if tc.dbCall {
+				mockFinder.EXPECT().Updates(bson.D{{Key: "$set", Value: fields}}).Return(mockFinder)
+				mockFinder.EXPECT().FindOneAndUpdate(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, _ ...any) (*testDoc, error) {
+					if tc.expectDbErr {
+						return nil, fmt.Errorf("mock update error")
+					}
+					if tc.conflict {
+						return nil, mongo.ErrNoDocuments
+					}
+					return tDoc, nil
+				})
+			}
+			if tc.preCall {
+				mockCrud.EXPECT().PreUpdate(ctx, gomock.Any(), fields).DoAndReturn(func(ctx context.Context, doc *testDoc, fields bson.D) error {
+					if tc.expectPreErr {
+						return fmt.Errorf("mock pre update error")
+					}
+					return nil
+				})
+			}
+			postCalled := make(chan struct{})
+			if tc.postCall {
+				mockCrud.EXPECT().PostUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, doc *testDoc) error {
+					defer close(postCalled)
+					if tc.expectPostErr {
+						return fmt.Errorf("mock post update error")
+					}
+					return nil
+				})
+			} else {
+				close(postCalled)
+			}
+			fac = facade.NewFacade(mockCrud)
+			res, err := fac.UpdateOne(ctx, query, fields)
+			if tc.expectErr {
+				Expect(err).To(HaveOccurred())
+				Expect(res).To(BeNil())
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).ToNot(BeNil())
+			}
+			if tc.conflict {
+				Expect(err).To(MatchError(facade.ErrUpdateConflict))
+			}
+			Eventually(postCalled).WithTimeout(1 * time.Second).Should(BeClosed())
+		},
+			Entry("should update a single document", testCase{
+				countCall: true,
+				findCall:  true,
+				preCall:   true,
+				dbCall:    true,
+				postCall:  true,
+				count:     1,
+			}),
+			Entry("should not update with no fields", testCase{
+				emptyFields: true,
+				expectErr:   true,
+			}),
+			Entry("should not update when no document found", testCase{
+				countCall: true,
+				count:     0,
+				expectErr: true,
+			}),
+			Entry("should not update when multiple documents found", testCase{
+				countCall: true,
+				count:     2,
+				expectErr: true,
+			}),
+			Entry("should not update with count error", testCase{
+				countCall:      true,
+				expectCountErr: true,
+				expectErr:      true,
+			}),
+			Entry("should not update with FindOne returning error", testCase{
+				countCall:  true,
+				findCall:   true,
+				findOneErr: true,
+				count:      1,
+				expectErr:  true,
+			}),
+			Entry("should not update with pre error", testCase{
+				countCall:    true,
+				findCall:     true,
+				preCall:      true,
+				count:        1,
+				expectPreErr: true,
+				expectErr:    true,
+			}),
+			Entry("should not update with update error", testCase{
+				countCall:   true,
+				findCall:    true,
+				preCall:     true,
+				dbCall:      true,
+				count:       1,
+				expectDbErr: true,
+				expectErr:   true,
+			}),
+			Entry("should not update when the document changed after it was checked", testCase{
+				countCall: true,
+				findCall:  true,
+				preCall:   true,
+				dbCall:    true,
+				conflict:  true,
+				count:     1,
+				expectErr: true,
+			}),
+			Entry("should update with post error", testCase{
+				countCall:     true,
+				findCall:      true,
+				preCall:       true,
+				dbCall:        true,
+				postCall:      true,
+				count:         1,
+				expectPostErr: true,
+			}),
+		)
+	})
 })
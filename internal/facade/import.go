@@ -0,0 +1,81 @@
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ImportItemResult is the outcome of importing a single channel message.
+type ImportItemResult struct {
+	MessageID int
+	MediaID   *bson.ObjectID // the created media
+	Status    BulkStatusEnum
+	Error     string
+}
+
+// ImportMessages creates the media of documents already posted in the storage channel, one message at a time, like
+// the bot does for forwarded messages. Messages already imported, or whose file is already a media, are skipped
+// with the reason in their error, whatever the duplicate setting of the facade; messages without a document fail.
+func ImportMessages(ctx context.Context, fac IFacade[types.MediaFileDoc], wp stream.IWorkerPool, msgIDs []int, opts BulkOptions) []ImportItemResult {
+	res := make([]ImportItemResult, len(msgIDs))
+	for i, msgID := range msgIDs {
+		res[i].MessageID = msgID
+		var dupErr error
+		res[i].Status, res[i].Error = applyBulkItem(ctx, i, opts, func(ctx context.Context, i int, dryRun bool) (BulkStatusEnum, error) {
+			media, err := importMessage(ctx, fac, wp, msgID)
+			if errors.Is(err, ErrFileAlreadyExists) {
+				dupErr = err
+				return SKIPPEDBulkStatus, nil
+			}
+			if err != nil {
+				return FAILEDBulkStatus, err
+			}
+			res[i].MediaID = &media.ID
+			return DONEBulkStatus, nil
+		})
+		if dupErr != nil {
+			res[i].Error = dupErr.Error()
+		}
+	}
+	return res
+}
+
+// importMessage creates the media of the document of a channel message.
+func importMessage(ctx context.Context, fac IFacade[types.MediaFileDoc], wp stream.IWorkerPool, msgID int) (*types.MediaFileDoc, error) {
+	if err := checkImported(ctx, fac, bson.D{{Key: types.MediaFileDoc__MessageIDField, Value: msgID}}); err != nil {
+		return nil, fmt.Errorf("message %d: %w", msgID, err)
+	}
+	worker := wp.GetNextWorker()
+	if worker == nil {
+		return nil, stream.ErrNoWorker
+	}
+	doc, err := worker.GetDoc(ctx, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("can not get document of message: %w", err)
+	}
+	media, err := types.NewMediaFileDoc(doc, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("can not fill document meta: %w", err)
+	}
+	if err := checkImported(ctx, fac, bson.D{{Key: types.MediaFileDoc__FileIDField, Value: media.Meta.FileID}}); err != nil {
+		return nil, fmt.Errorf("file %d: %w", media.Meta.FileID, err)
+	}
+	return fac.CreateOne(ctx, &media)
+}
+
+// checkImported returns ErrFileAlreadyExists when a media matches filter.
+func checkImported(ctx context.Context, fac IFacade[types.MediaFileDoc], filter bson.D) error {
+	n, err := fac.GetCollection().Finder().Filter(filter).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("can not check existing media: %w", err)
+	}
+	if n > 0 {
+		return ErrFileAlreadyExists
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package facade_test
+
+import (
+	"context"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mStream "github.com/amirdaaee/TGMon/mocks/stream"
+	"github.com/chenmingyong0423/go-mongox/v2/finder"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/gotd/td/tg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Import", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		mockWP       *mStream.MockIWorkerPool
+		mockWorker   *mStream.MockIWorker
+		mockFinder   *mMongoX.MockIFinder[types.MediaFileDoc]
+		testContext  context.Context
+		existing     []bson.D
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mockWorker = mStream.NewMockIWorker(ctrl)
+		mockWP = mStream.NewMockIWorkerPool(ctrl)
+		mockWP.EXPECT().GetNextWorker().Return(mockWorker).AnyTimes()
+		existing = nil
+		var filter bson.D
+		mockFinder = mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mockFinder.EXPECT().Filter(gomock.Any()).DoAndReturn(func(f any) finder.IFinder[types.MediaFileDoc] {
+			filter = f.(bson.D)
+			return mockFinder
+		}).AnyTimes()
+		mockFinder.EXPECT().Count(testContext).DoAndReturn(func(ctx context.Context, _ ...options.Lister[options.CountOptions]) (int64, error) {
+			for _, e := range existing {
+				if e[0] == filter[0] {
+					return 1, nil
+				}
+			}
+			return 0, nil
+		}).AnyTimes()
+		mockColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mockColl.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockMediaFac.EXPECT().GetCollection().Return(mockColl).AnyTimes()
+	})
+	Describe("ImportMessages", func() {
+		It("should create the media of every message and report duplicates and failures per message", func() {
+			mockWorker.EXPECT().GetDoc(testContext, 10).Return(&tg.Document{ID: 100, Size: 5, Attributes: []tg.DocumentAttributeClass{&tg.DocumentAttributeFilename{FileName: "mock.mkv"}}}, nil)
+			mockWorker.EXPECT().GetDoc(testContext, 11).Return(&tg.Document{ID: 101}, nil)
+			mockWorker.EXPECT().GetDoc(testContext, 12).Return(nil, stream.ErrMessageNotFound)
+			existing = []bson.D{
+				{{Key: types.MediaFileDoc__FileIDField, Value: int64(101)}},
+				{{Key: types.MediaFileDoc__MessageIDField, Value: 13}},
+			}
+			mediaID := bson.NewObjectID()
+			mockMediaFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.MediaFileDoc) (*types.MediaFileDoc, error) {
+				Expect(doc.MessageID).To(Equal(10))
+				Expect(doc.Meta.FileID).To(Equal(int64(100)))
+				Expect(doc.Meta.FileName).To(Equal("mock.mkv"))
+				doc.ID = mediaID
+				return doc, nil
+			})
+			res := facade.ImportMessages(testContext, mockMediaFac, mockWP, []int{10, 11, 12, 13}, facade.BulkOptions{})
+			Expect(res).To(HaveLen(4))
+			Expect(res[0]).To(Equal(facade.ImportItemResult{MessageID: 10, MediaID: &mediaID, Status: facade.DONEBulkStatus}))
+			Expect(res[1].Status).To(Equal(facade.SKIPPEDBulkStatus))
+			Expect(res[1].Error).To(ContainSubstring(facade.ErrFileAlreadyExists.Error()))
+			Expect(res[2].Status).To(Equal(facade.FAILEDBulkStatus))
+			Expect(res[2].Error).To(ContainSubstring(stream.ErrMessageNotFound.Error()))
+			Expect(res[3].Status).To(Equal(facade.SKIPPEDBulkStatus))
+			Expect(res[3].Error).To(ContainSubstring("message 13"))
+		})
+	})
+})
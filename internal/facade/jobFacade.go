@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/amirdaaee/TGMon/internal/db"
 	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
@@ -28,6 +29,7 @@ func (crd *JobReqCrud) PreCreate(ctx context.Context, doc *types.JobReqDoc) erro
 	if doc == nil {
 		return fmt.Errorf("JobReqDoc is nil")
 	}
+	doc.ClaimedAt = nil
 	// TODO: duplicated check (stub)
 	return nil
 }
@@ -47,6 +49,26 @@ func (crd *JobReqCrud) PostDelete(ctx context.Context, doc *types.JobReqDoc) err
 	return nil
 }
 
+// PreUpdate validates the fields to be set on a JobReqDoc. Only ClaimedAt is editable.
+func (crd *JobReqCrud) PreUpdate(ctx context.Context, doc *types.JobReqDoc, fields bson.D) error {
+	for _, f := range fields {
+		if f.Key != types.JobReqDoc__ClaimedAtField {
+			return fmt.Errorf("%w: field %s is not editable", ErrInvalidUpdate, f.Key)
+		}
+		switch f.Value.(type) {
+		case nil, time.Time:
+		default:
+			return fmt.Errorf("%w: %s must be a time or null", ErrInvalidUpdate, f.Key)
+		}
+	}
+	return nil
+}
+
+// PostUpdate is a post-update hook for JobReqDoc. No-op in this implementation.
+func (crd *JobReqCrud) PostUpdate(ctx context.Context, doc *types.JobReqDoc) error {
+	return nil
+}
+
 // GetCollection returns the JobReq collection from the database container.
 func (crd *JobReqCrud) GetCollection() mngo.ICollection[types.JobReqDoc] {
 	return crd.container.GetMongoContainer().GetJobReqCollection()
@@ -107,6 +129,16 @@ func (crd *JobResCrud) PostDelete(ctx context.Context, doc *types.JobResDoc) err
 	return nil
 }
 
+// PreUpdate is a pre-update hook for JobResDoc. No-op in this implementation.
+func (crd *JobResCrud) PreUpdate(ctx context.Context, doc *types.JobResDoc, fields bson.D) error {
+	return nil
+}
+
+// PostUpdate is a post-update hook for JobResDoc. No-op in this implementation.
+func (crd *JobResCrud) PostUpdate(ctx context.Context, doc *types.JobResDoc) error {
+	return nil
+}
+
 // GetCollection returns the JobRes collection from the database container.
 func (crd *JobResCrud) GetCollection() mngo.ICollection[types.JobResDoc] {
 	return crd.container.GetMongoContainer().GetJobResCollection()
@@ -131,9 +163,9 @@ func (crd *JobResCrud) getJobRequest(ctx context.Context, doc *types.JobResDoc)
 	}
 
 	if len(jobReqD) == 0 {
-		return nil, fmt.Errorf("job req doc not found")
+		return nil, fmt.Errorf("%w: job req doc %s", ErrNoDocumentsFound, doc.JobReqID.Hex())
 	} else if len(jobReqD) > 1 {
-		return nil, fmt.Errorf("multiple job req docs found")
+		return nil, fmt.Errorf("%w: job req doc %s", ErrMultipleDocumentsFound, doc.JobReqID.Hex())
 	}
 
 	return jobReqD[0], nil
@@ -173,18 +205,19 @@ func (crd *JobResCrud) processJobResult(ctx context.Context, doc *types.JobResDo
 			ll.Debugf("vtt file added to minio: %s", fname)
 		}
 	}
-	return crd.getUpdateField(jobReq.Type, fileName)
+	return crd.getUpdateField(jobReq.Type, fileName, int64(len(doc.Thumbnail)))
 }
 
-// getUpdateField returns the BSON update field for the given job type and file name.
-func (crd *JobResCrud) getUpdateField(jobType types.JobTypeEnum, fileName string) ([]bson.D, error) {
+// getUpdateField returns the BSON update field for the given job type and file name. thumbSize is the size of the
+// thumbnail of thumbnail jobs.
+func (crd *JobResCrud) getUpdateField(jobType types.JobTypeEnum, fileName string, thumbSize int64) ([]bson.D, error) {
 	switch jobType {
 	case types.THUMBNAILJobType:
-		return []bson.D{update.Set(types.MediaFileDoc__ThumbnailField, crd.thumbFileName(fileName))}, nil
+		return []bson.D{update.Set(types.MediaFileDoc__ThumbnailField, crd.thumbFileName(fileName)), update.Set(types.MediaFileDoc__ThumbnailSizeField, thumbSize)}, nil
 	case types.SPRITEJobType:
 		return []bson.D{update.Set(types.MediaFileDoc__SpriteField, crd.spriteFileName(fileName)), update.Set(types.MediaFileDoc__VttField, crd.vttFileName(fileName))}, nil
 	default:
-		return nil, fmt.Errorf("unknown job type: %s", jobType)
+		return nil, fmt.Errorf("%w: unknown job type (%s)", ErrInvalidDocument, jobType)
 	}
 }
 
@@ -209,7 +242,7 @@ func (crd *JobResCrud) spriteFileName(s string) string {
 }
 
 // NewJobResCrud creates a new JobResCrud with the provided database container.
-func NewJobResCrud(container db.IDbContainer) ICrud[types.JobResDoc] {
-	jobReqFacade := NewFacade(NewJobReqCrud(container))
+// Completed job requests are removed through jobReqFacade.
+func NewJobResCrud(container db.IDbContainer, jobReqFacade IFacade[types.JobReqDoc]) ICrud[types.JobResDoc] {
 	return &JobResCrud{container: container, jReqFac: jobReqFacade}
 }
@@ -0,0 +1,43 @@
+package facade_test
+
+import (
+	"context"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("JobResCrud", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockFinder  *mMongoX.MockIFinder[types.JobReqDoc]
+		mockJobFac  *mFacade.MockIFacade[types.JobReqDoc]
+		testContext context.Context
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockFinder = mMongoX.NewMockIFinder[types.JobReqDoc](ctrl)
+		mockFinder.EXPECT().Filter(gomock.Any()).Return(mockFinder).AnyTimes()
+		mockColl := mMongo.NewMockICollection[types.JobReqDoc](ctrl)
+		mockColl.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockJobFac = mFacade.NewMockIFacade[types.JobReqDoc](ctrl)
+		mockJobFac.EXPECT().GetCollection().Return(mockColl).AnyTimes()
+	})
+	DescribeTable("PreCreate with a bad job request", func(found []*types.JobReqDoc, expectErr error) {
+		mockFinder.EXPECT().Find(testContext).Return(found, nil)
+		crd := facade.NewJobResCrud(nil, mockJobFac)
+		err := crd.PreCreate(testContext, &types.JobResDoc{JobReqID: bson.NewObjectID()})
+		Expect(err).To(MatchError(expectErr))
+	},
+		Entry("missing", []*types.JobReqDoc{}, facade.ErrNoDocumentsFound),
+		Entry("duplicated", []*types.JobReqDoc{{}, {}}, facade.ErrMultipleDocumentsFound),
+	)
+})
@@ -4,6 +4,7 @@ package facade
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/amirdaaee/TGMon/internal/db"
@@ -17,14 +18,32 @@ import (
 	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// limits applied to user editable media fields.
+const (
+	mediaNameMaxLen        = 255
+	mediaDescriptionMaxLen = 4096
+	mediaFieldsMaxCount    = 64
+	mediaFieldKeyMaxLen    = 64
+	mediaFieldValueMaxLen  = 1024
+)
+
+// IMediaCacheInvalidator is implemented by components holding a cached view of media documents (e.g. the FUSE filesystem)
+// that must be dropped when media documents change.
+type IMediaCacheInvalidator interface {
+	InvalidateMediaCache()
+}
+
 // MediaCrud implements ICrud for MediaFileDoc, providing CRUD hooks and collection access.
 type MediaCrud struct {
 	dbContainer     db.IDbContainer
 	jReqFac         IFacade[types.JobReqDoc]
 	workerContainer stream.IWorkerPool
 	keepDup         bool
+	osHash          bool
+	invalidators    []IMediaCacheInvalidator
 }
 
 var _ ICrud[types.MediaFileDoc] = (*MediaCrud)(nil)
@@ -46,7 +65,7 @@ func (crd *MediaCrud) PreCreate(ctx context.Context, doc *types.MediaFileDoc) er
 	return nil
 }
 
-// PostCreate creates a sprite job request after creating a media file. Returns an error if the document is nil or job creation fails.
+// PostCreate creates a sprite job request, and sets the initial thumbnail and, if enabled, the oshash after creating a media file. Returns an error if the document is nil or job creation fails.
 func (crd *MediaCrud) PostCreate(ctx context.Context, doc *types.MediaFileDoc) error {
 	ll := crd.getLogger("PostCreate")
 	if doc == nil {
@@ -67,6 +86,16 @@ func (crd *MediaCrud) PostCreate(ctx context.Context, doc *types.MediaFileDoc) e
 		}
 		ll.Info("initial thumbnail set")
 	}()
+	if !crd.osHash {
+		return nil
+	}
+	go func() {
+		if _, err := SetMediaOsHash(newCtx, crd.GetCollection(), crd.workerContainer, doc); err != nil {
+			ll.WithError(err).Error("failed to set oshash")
+			return
+		}
+		ll.Info("oshash set")
+	}()
 	return nil
 }
 
@@ -75,7 +104,7 @@ func (crd *MediaCrud) PreDelete(ctx context.Context, doc *types.MediaFileDoc) er
 	return nil
 }
 
-// PostDelete deletes orphaned jobs and files after deleting a media file. Retries file deletion up to 3 times. Logs errors but does not return them.
+// PostDelete deletes orphaned jobs, playlist memberships, watch progress and files after deleting a media file. Retries file deletion up to 3 times. Logs errors but does not return them.
 func (crd *MediaCrud) PostDelete(ctx context.Context, doc *types.MediaFileDoc) error {
 	ll := crd.getLogger("PostDelete")
 	if doc == nil {
@@ -87,7 +116,23 @@ func (crd *MediaCrud) PostDelete(ctx context.Context, doc *types.MediaFileDoc) e
 	} else if dl.DeletedCount > 0 {
 		ll.Infof("deleted %d orphaned jobs", dl.DeletedCount)
 	}
-	for _, fn := range []string{doc.Vtt, doc.Thumbnail} {
+	pq := bsonx.NewD().Add(types.PlaylistDoc__ItemsField, doc.ID).Build()
+	if up, err := crd.dbContainer.GetMongoContainer().GetPlaylistCollection().Updater().Filter(pq).Updates(update.Pull(types.PlaylistDoc__ItemsField, doc.ID)).UpdateMany(ctx); err != nil {
+		ll.WithError(err).Error("failed to remove media from playlists")
+	} else if up.ModifiedCount > 0 {
+		ll.Infof("media removed from %d playlists", up.ModifiedCount)
+	}
+	wq := bsonx.NewD().Add(types.WatchProgressDoc__MediaIDField, doc.ID).Build()
+	if dl, err := crd.dbContainer.GetMongoContainer().GetWatchProgressCollection().Deleter().Filter(wq).DeleteMany(ctx); err != nil {
+		ll.WithError(err).Error("failed to delete watch progress")
+	} else if dl.DeletedCount > 0 {
+		ll.Infof("deleted %d watch progress", dl.DeletedCount)
+	}
+	files := []string{doc.Vtt, doc.Sprite, doc.Thumbnail}
+	for _, sub := range doc.Subtitles {
+		files = append(files, sub.File)
+	}
+	for _, fn := range files {
 		if fn != "" {
 			var lastErr error
 			for i := 0; i < 3; i++ {
@@ -104,6 +149,35 @@ func (crd *MediaCrud) PostDelete(ctx context.Context, doc *types.MediaFileDoc) e
 			}
 		}
 	}
+	crd.invalidateCaches()
+	return nil
+}
+
+// PreUpdate validates the fields to be set on a MediaFileDoc. Only Name, Description, Fields, Tags and Subtitles are
+// editable; subtitles are set by AddSubtitle and RemoveSubtitle, along with their files.
+func (crd *MediaCrud) PreUpdate(ctx context.Context, doc *types.MediaFileDoc, fields bson.D) error {
+	if doc == nil {
+		return fmt.Errorf("MediaFileDoc is nil")
+	}
+	for _, f := range fields {
+		if err := validateMediaField(f); err != nil {
+			return err
+		}
+		if f.Key == types.MediaFileDoc__TagsField {
+			if err := ensureIDsExist(ctx, crd.dbContainer.GetMongoContainer().GetTagCollection(), f.Value.([]bson.ObjectID)); err != nil {
+				return fmt.Errorf("%w: invalid tags: %w", ErrInvalidUpdate, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PostUpdate invalidates cached media listings after updating a media file.
+func (crd *MediaCrud) PostUpdate(ctx context.Context, doc *types.MediaFileDoc) error {
+	if doc == nil {
+		return fmt.Errorf("MediaFileDoc is nil")
+	}
+	crd.invalidateCaches()
 	return nil
 }
 
@@ -112,6 +186,13 @@ func (crd *MediaCrud) GetCollection() mngo.ICollection[types.MediaFileDoc] {
 	return crd.dbContainer.GetMongoContainer().GetMediaFileCollection()
 }
 
+// invalidateCaches notifies all registered invalidators that media documents have changed.
+func (crd *MediaCrud) invalidateCaches() {
+	for _, inv := range crd.invalidators {
+		inv.InvalidateMediaCache()
+	}
+}
+
 // getMinioClient returns the Minio client from the database container.
 func (crd *MediaCrud) getMinioClient() minio.IMinioClient {
 	return crd.dbContainer.GetMinioContainer().GetMinioClient()
@@ -123,9 +204,12 @@ func (crd *MediaCrud) getLogger(fn string) *logrus.Entry {
 }
 
 // NewMediaCrud creates a new MediaCrud with the provided database container.
-func NewMediaCrud(dbContainer db.IDbContainer, workerContainer stream.IWorkerPool, keepDup bool) ICrud[types.MediaFileDoc] {
-	jobReqFacade := NewFacade(NewJobReqCrud(dbContainer))
-	return &MediaCrud{dbContainer: dbContainer, jReqFac: jobReqFacade, workerContainer: workerContainer, keepDup: keepDup}
+// Sprite jobs for new media are requested through jobReqFacade.
+// With osHash, the oshash of new media is computed for Stash; the media of an existing library are backfilled by the
+// mapping rebuild.
+// Invalidators are notified whenever a media document is updated or deleted.
+func NewMediaCrud(dbContainer db.IDbContainer, workerContainer stream.IWorkerPool, jobReqFacade IFacade[types.JobReqDoc], keepDup bool, osHash bool, invalidators ...IMediaCacheInvalidator) ICrud[types.MediaFileDoc] {
+	return &MediaCrud{dbContainer: dbContainer, jReqFac: jobReqFacade, workerContainer: workerContainer, keepDup: keepDup, osHash: osHash, invalidators: invalidators}
 }
 
 // ...
@@ -138,7 +222,11 @@ func setMediaThumbnail(ctx context.Context, crd *MediaCrud, doc *types.MediaFile
 	if err := crd.dbContainer.GetMinioContainer().GetMinioClient().FileAdd(ctx, fname, thumb); err != nil {
 		return fmt.Errorf("failed to add thumbnail to minio: %w", err)
 	}
-	if _, err := crd.GetCollection().Updater().Filter(query.Id(doc.ID)).Updates(update.Set(types.MediaFileDoc__ThumbnailField, fname)).UpdateOne(ctx); err != nil {
+	fields := bson.D{
+		{Key: types.MediaFileDoc__ThumbnailField, Value: fname},
+		{Key: types.MediaFileDoc__ThumbnailSizeField, Value: int64(len(thumb))},
+	}
+	if _, err := crd.GetCollection().Updater().Filter(query.Id(doc.ID)).Updates(bson.D{{Key: "$set", Value: fields}}).UpdateOne(ctx); err != nil {
 		return fmt.Errorf("failed to update thumbnail in db: %w", err)
 	}
 	return nil
@@ -151,3 +239,55 @@ func setSpriteJob(ctx context.Context, crd *MediaCrud, doc *types.MediaFileDoc)
 	})
 	return err
 }
+
+// validateMediaField checks that a single field of a media update is editable and holds a valid value.
+func validateMediaField(f bson.E) error {
+	switch f.Key {
+	case types.MediaFileDoc__NameField:
+		v, ok := f.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a string", ErrInvalidUpdate, f.Key)
+		}
+		if strings.TrimSpace(v) != v {
+			return fmt.Errorf("%w: %s can not have leading or trailing spaces", ErrInvalidUpdate, f.Key)
+		}
+		if len(v) > mediaNameMaxLen {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidUpdate, f.Key, mediaNameMaxLen)
+		}
+	case types.MediaFileDoc__DescriptionField:
+		v, ok := f.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a string", ErrInvalidUpdate, f.Key)
+		}
+		if len(v) > mediaDescriptionMaxLen {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidUpdate, f.Key, mediaDescriptionMaxLen)
+		}
+	case types.MediaFileDoc__FieldsField:
+		v, ok := f.Value.(map[string]string)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a map of strings", ErrInvalidUpdate, f.Key)
+		}
+		if len(v) > mediaFieldsMaxCount {
+			return fmt.Errorf("%w: %s can not have more than %d entries", ErrInvalidUpdate, f.Key, mediaFieldsMaxCount)
+		}
+		for k, val := range v {
+			if k == "" || len(k) > mediaFieldKeyMaxLen || strings.ContainsAny(k, ".$") {
+				return fmt.Errorf("%w: invalid custom field name (%s)", ErrInvalidUpdate, k)
+			}
+			if len(val) > mediaFieldValueMaxLen {
+				return fmt.Errorf("%w: custom field %s is longer than %d characters", ErrInvalidUpdate, k, mediaFieldValueMaxLen)
+			}
+		}
+	case types.MediaFileDoc__TagsField:
+		if _, ok := f.Value.([]bson.ObjectID); !ok {
+			return fmt.Errorf("%w: %s must be a list of tag IDs", ErrInvalidUpdate, f.Key)
+		}
+	case types.MediaFileDoc__SubtitlesField:
+		if _, ok := f.Value.([]types.MediaSubtitle); !ok {
+			return fmt.Errorf("%w: %s must be a list of subtitles", ErrInvalidUpdate, f.Key)
+		}
+	default:
+		return fmt.Errorf("%w: field %s is not editable", ErrInvalidUpdate, f.Key)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package facade_test
+
+import (
+	"context"
+	"strings"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mDb "github.com/amirdaaee/TGMon/mocks/db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("MediaCrud", func() {
+	var (
+		ctrl          *gomock.Controller
+		mockContainer *mDb.MockIDbContainer
+		crd           facade.ICrud[types.MediaFileDoc]
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockContainer = mDb.NewMockIDbContainer(ctrl)
+		crd = facade.NewMediaCrud(mockContainer, nil, nil, false, false)
+	})
+	Describe("PreUpdate", func() {
+		type testCase struct {
+			fields    bson.D
+			expectErr bool
+		}
+		DescribeTable("", func(tc testCase) {
+			err := crd.PreUpdate(context.Background(), &types.MediaFileDoc{}, tc.fields)
+			if tc.expectErr {
+				Expect(err).To(MatchError(facade.ErrInvalidUpdate))
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+		},
+			Entry("should accept editable fields", testCase{
+				fields: bson.D{
+					{Key: types.MediaFileDoc__NameField, Value: "new name"},
+					{Key: types.MediaFileDoc__DescriptionField, Value: "some description"},
+					{Key: types.MediaFileDoc__FieldsField, Value: map[string]string{"season": "1"}},
+				},
+			}),
+			Entry("should reject non-editable field", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__FileIDField, Value: int64(1)}},
+				expectErr: true,
+			}),
+			Entry("should reject name with wrong type", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__NameField, Value: 1}},
+				expectErr: true,
+			}),
+			Entry("should reject name with surrounding spaces", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__NameField, Value: " name "}},
+				expectErr: true,
+			}),
+			Entry("should reject too long name", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__NameField, Value: strings.Repeat("a", 256)}},
+				expectErr: true,
+			}),
+			Entry("should reject too long description", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__DescriptionField, Value: strings.Repeat("a", 4097)}},
+				expectErr: true,
+			}),
+			Entry("should reject custom field with invalid key", testCase{
+				fields:    bson.D{{Key: types.MediaFileDoc__FieldsField, Value: map[string]string{"a.b": "1"}}},
+				expectErr: true,
+			}),
+		)
+	})
+})
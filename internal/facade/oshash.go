@@ -0,0 +1,77 @@
+package facade
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+)
+
+// osHashChunkSize is the size of the head and tail of a file hashed by its oshash.
+const osHashChunkSize = 64 * 1024
+
+// ComputeOsHash returns the oshash of a media, the hash Stash identifies files by: the size of the file plus the sum
+// of the little-endian uint64 words of its first and last 64KB. The head and tail are read through the stream pool.
+func ComputeOsHash(ctx context.Context, wp stream.IWorkerPool, media *types.MediaFileDoc) (string, error) {
+	size := media.Meta.FileSize
+	if size <= 0 {
+		return "", errors.New("can not hash an empty file")
+	}
+	chunk := min(int64(osHashChunkSize), size)
+	head, err := readMediaRange(ctx, wp, media.MessageID, 0, chunk)
+	if err != nil {
+		return "", fmt.Errorf("can not read head: %w", err)
+	}
+	tail, err := readMediaRange(ctx, wp, media.MessageID, size-chunk, chunk)
+	if err != nil {
+		return "", fmt.Errorf("can not read tail: %w", err)
+	}
+	return osHash(size, head, tail), nil
+}
+
+// SetMediaOsHash computes the oshash of a media and stores it in coll.
+func SetMediaOsHash(ctx context.Context, coll mngo.ICollection[types.MediaFileDoc], wp stream.IWorkerPool, media *types.MediaFileDoc) (string, error) {
+	hash, err := ComputeOsHash(ctx, wp, media)
+	if err != nil {
+		return "", err
+	}
+	if _, err := coll.Updater().Filter(query.Id(media.ID)).Updates(update.Set(types.MediaFileDoc__OsHashField, hash)).UpdateOne(ctx); err != nil {
+		return "", fmt.Errorf("failed to update oshash in db: %w", err)
+	}
+	return hash, nil
+}
+
+// readMediaRange reads length bytes of the file of a message from offset.
+func readMediaRange(ctx context.Context, wp stream.IWorkerPool, msgID int, offset int64, length int64) ([]byte, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	streamer, err := wp.Stream(streamCtx, msgID, offset, offset+length-1)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(streamer, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// osHash sums the size of a file and the little-endian uint64 words of its head and tail.
+func osHash(size int64, head []byte, tail []byte) string {
+	data := append(append([]byte{}, head...), tail...)
+	words := make([]uint64, len(data)/8)
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, words) //nolint:errcheck // reading from memory
+	sum := uint64(size)
+	for _, w := range words {
+		sum += w
+	}
+	return fmt.Sprintf("%016x", sum)
+}
@@ -0,0 +1,66 @@
+package facade_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mStream "github.com/amirdaaee/TGMon/mocks/stream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+// memStreamer streams a slice of a file from memory.
+type memStreamer struct {
+	*bytes.Reader
+}
+
+func (s memStreamer) GetBuffer() *bufio.Reader {
+	return nil
+}
+
+var _ = Describe("OsHash", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockWP      *mStream.MockIWorkerPool
+		testContext context.Context
+		file        []byte
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockWP = mStream.NewMockIWorkerPool(ctrl)
+		mockWP.EXPECT().Stream(gomock.Any(), 7, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msgID int, offset int64, end int64) (stream.IStreamer, error) {
+			return memStreamer{bytes.NewReader(file[offset : end+1])}, nil
+		}).AnyTimes()
+	})
+	Describe("ComputeOsHash", func() {
+		It("should hash the head and tail of large files", func() {
+			file = make([]byte, 200*1024)
+			binary.LittleEndian.PutUint64(file[0:], 1)
+			binary.LittleEndian.PutUint64(file[100*1024:], 1<<40) // neither in the head nor in the tail
+			binary.LittleEndian.PutUint64(file[len(file)-8:], 2)
+			hash, err := facade.ComputeOsHash(testContext, mockWP, &types.MediaFileDoc{MessageID: 7, Meta: types.MediaFileMeta{FileSize: int64(len(file))}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(fmt.Sprintf("%016x", len(file)+3)))
+		})
+		It("should hash small files as both head and tail", func() {
+			file = make([]byte, 16)
+			binary.LittleEndian.PutUint64(file[0:], 5)
+			binary.LittleEndian.PutUint64(file[8:], 6)
+			hash, err := facade.ComputeOsHash(testContext, mockWP, &types.MediaFileDoc{MessageID: 7, Meta: types.MediaFileMeta{FileSize: 16}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(fmt.Sprintf("%016x", 16+2*11)))
+		})
+		It("should fail on empty files", func() {
+			_, err := facade.ComputeOsHash(testContext, mockWP, &types.MediaFileDoc{MessageID: 7})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
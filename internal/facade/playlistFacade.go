@@ -0,0 +1,140 @@
+// Package facade provides CRUD logic for playlist documents.
+package facade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// limits applied to playlist documents.
+const (
+	playlistNameMaxLen        = 255
+	playlistDescriptionMaxLen = 4096
+	playlistItemsMaxCount     = 10000
+)
+
+// PlaylistCrud implements ICrud for PlaylistDoc, providing CRUD hooks and collection access.
+type PlaylistCrud struct {
+	container db.IDbContainer
+}
+
+var _ ICrud[types.PlaylistDoc] = (*PlaylistCrud)(nil)
+
+// PreCreate validates a PlaylistDoc and checks that all of its items exist before creating it.
+func (crd *PlaylistCrud) PreCreate(ctx context.Context, doc *types.PlaylistDoc) error {
+	if doc == nil {
+		return fmt.Errorf("PlaylistDoc is nil")
+	}
+	doc.Name = strings.TrimSpace(doc.Name)
+	if doc.Items == nil {
+		doc.Items = []bson.ObjectID{}
+	}
+	for _, f := range []bson.E{
+		{Key: types.PlaylistDoc__NameField, Value: doc.Name},
+		{Key: types.PlaylistDoc__DescriptionField, Value: doc.Description},
+		{Key: types.PlaylistDoc__ItemsField, Value: doc.Items},
+	} {
+		if err := validatePlaylistField(f); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+		}
+	}
+	if err := ensureIDsExist(ctx, crd.getMediaCollection(), doc.Items); err != nil {
+		return fmt.Errorf("%w: invalid playlist items: %w", ErrInvalidDocument, err)
+	}
+	return nil
+}
+
+// PostCreate is a post-create hook for PlaylistDoc. No-op in this implementation.
+func (crd *PlaylistCrud) PostCreate(ctx context.Context, doc *types.PlaylistDoc) error {
+	return nil
+}
+
+// PreDelete is a pre-delete hook for PlaylistDoc. No-op in this implementation.
+func (crd *PlaylistCrud) PreDelete(ctx context.Context, doc *types.PlaylistDoc) error {
+	return nil
+}
+
+// PostDelete is a post-delete hook for PlaylistDoc. No-op in this implementation.
+func (crd *PlaylistCrud) PostDelete(ctx context.Context, doc *types.PlaylistDoc) error {
+	return nil
+}
+
+// PreUpdate validates the fields to be set on a PlaylistDoc. Name, Description and Items are editable.
+func (crd *PlaylistCrud) PreUpdate(ctx context.Context, doc *types.PlaylistDoc, fields bson.D) error {
+	if doc == nil {
+		return fmt.Errorf("PlaylistDoc is nil")
+	}
+	for _, f := range fields {
+		if err := validatePlaylistField(f); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+		}
+		if f.Key == types.PlaylistDoc__ItemsField {
+			if err := ensureIDsExist(ctx, crd.getMediaCollection(), f.Value.([]bson.ObjectID)); err != nil {
+				return fmt.Errorf("%w: invalid playlist items: %w", ErrInvalidUpdate, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PostUpdate is a post-update hook for PlaylistDoc. No-op in this implementation.
+func (crd *PlaylistCrud) PostUpdate(ctx context.Context, doc *types.PlaylistDoc) error {
+	return nil
+}
+
+// GetCollection returns the Playlist collection from the database container.
+func (crd *PlaylistCrud) GetCollection() mngo.ICollection[types.PlaylistDoc] {
+	return crd.container.GetMongoContainer().GetPlaylistCollection()
+}
+
+// getMediaCollection returns the MediaFile collection from the database container.
+func (crd *PlaylistCrud) getMediaCollection() mngo.ICollection[types.MediaFileDoc] {
+	return crd.container.GetMongoContainer().GetMediaFileCollection()
+}
+
+// NewPlaylistCrud creates a new PlaylistCrud with the provided database container.
+func NewPlaylistCrud(container db.IDbContainer) ICrud[types.PlaylistDoc] {
+	return &PlaylistCrud{container: container}
+}
+
+// validatePlaylistField checks that a single field of a playlist holds a valid value.
+func validatePlaylistField(f bson.E) error {
+	switch f.Key {
+	case types.PlaylistDoc__NameField:
+		v, ok := f.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", f.Key)
+		}
+		if v == "" || strings.TrimSpace(v) != v {
+			return fmt.Errorf("%s can not be empty or have leading or trailing spaces", f.Key)
+		}
+		if len(v) > playlistNameMaxLen {
+			return fmt.Errorf("%s is longer than %d characters", f.Key, playlistNameMaxLen)
+		}
+	case types.PlaylistDoc__DescriptionField:
+		v, ok := f.Value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", f.Key)
+		}
+		if len(v) > playlistDescriptionMaxLen {
+			return fmt.Errorf("%s is longer than %d characters", f.Key, playlistDescriptionMaxLen)
+		}
+	case types.PlaylistDoc__ItemsField:
+		v, ok := f.Value.([]bson.ObjectID)
+		if !ok {
+			return fmt.Errorf("%s must be a list of media IDs", f.Key)
+		}
+		if len(v) > playlistItemsMaxCount {
+			return fmt.Errorf("%s can not have more than %d entries", f.Key, playlistItemsMaxCount)
+		}
+	default:
+		return fmt.Errorf("field %s is not editable", f.Key)
+	}
+	return nil
+}
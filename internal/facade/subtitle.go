@@ -0,0 +1,148 @@
+package facade
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SubtitleMaxSize is the largest subtitle file accepted.
+const SubtitleMaxSize = 5 << 20
+
+// subtitleLanguageRe matches the language labels of subtitles. They end up in the names of sidecar files, so they
+// are kept to letters, digits, dashes and underscores.
+var subtitleLanguageRe = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,32}$`)
+
+// srtTimingRe matches the timing line of a srt cue, whose milliseconds are separated by a comma.
+var srtTimingRe = regexp.MustCompile(`^(\d+:\d{2}:\d{2}),(\d{3})\s*-->\s*(\d+:\d{2}:\d{2}),(\d{3})(.*)$`)
+
+// AddSubtitle stores a subtitle file as a track of the media with the given language, replacing the track of that
+// language if there is one. The format is taken from the extension of fileName: srt files are converted to vtt,
+// vtt and ass files are stored as they are.
+func AddSubtitle(ctx context.Context, fac IFacade[types.MediaFileDoc], minioClient minio.IMinioClient, mediaID bson.ObjectID, language string, fileName string, data []byte) (*types.MediaSubtitle, error) {
+	if !subtitleLanguageRe.MatchString(language) {
+		return nil, fmt.Errorf("%w: invalid subtitle language (%s)", ErrInvalidDocument, language)
+	}
+	format, data, err := convertSubtitle(fileName, data)
+	if err != nil {
+		return nil, err
+	}
+	media, err := fac.GetCollection().Finder().Filter(query.Id(mediaID)).FindOne(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub := types.MediaSubtitle{ID: bson.NewObjectID(), Language: language, Format: format, Size: int64(len(data))}
+	sub.File = fmt.Sprintf("%s_SUBTITLE_%s.%s", mediaID.Hex(), sub.ID.Hex(), format)
+	if err := minioClient.FileAdd(ctx, sub.File, data); err != nil {
+		return nil, fmt.Errorf("failed to add subtitle file to minio: %w", err)
+	}
+	subs := []types.MediaSubtitle{}
+	replaced := []string{}
+	for _, s := range media.Subtitles {
+		if s.Language == language {
+			replaced = append(replaced, s.File)
+			continue
+		}
+		subs = append(subs, s)
+	}
+	subs = append(subs, sub)
+	if _, err := fac.UpdateOne(ctx, query.Id(mediaID), bson.D{{Key: types.MediaFileDoc__SubtitlesField, Value: subs}}); err != nil {
+		removeSubtitleFiles(ctx, minioClient, sub.File)
+		return nil, err
+	}
+	removeSubtitleFiles(ctx, minioClient, replaced...)
+	return &sub, nil
+}
+
+// RemoveSubtitle removes a subtitle track of a media and its file.
+func RemoveSubtitle(ctx context.Context, fac IFacade[types.MediaFileDoc], minioClient minio.IMinioClient, mediaID bson.ObjectID, subtitleID bson.ObjectID) error {
+	media, err := fac.GetCollection().Finder().Filter(query.Id(mediaID)).FindOne(ctx)
+	if err != nil {
+		return err
+	}
+	sub := media.Subtitle(subtitleID)
+	if sub == nil {
+		return fmt.Errorf("%w: subtitle %s", ErrNoDocumentsFound, subtitleID.Hex())
+	}
+	subs := []types.MediaSubtitle{}
+	for _, s := range media.Subtitles {
+		if s.ID != subtitleID {
+			subs = append(subs, s)
+		}
+	}
+	if _, err := fac.UpdateOne(ctx, query.Id(mediaID), bson.D{{Key: types.MediaFileDoc__SubtitlesField, Value: subs}}); err != nil {
+		return err
+	}
+	removeSubtitleFiles(ctx, minioClient, sub.File)
+	return nil
+}
+
+// removeSubtitleFiles removes subtitle files no media references anymore. Failures are logged, as the files are
+// left to the garbage collector.
+func removeSubtitleFiles(ctx context.Context, minioClient minio.IMinioClient, files ...string) {
+	for _, f := range files {
+		if err := minioClient.FileRm(ctx, f); err != nil {
+			log.GetLogger(log.FacadeModule).WithField("func", "removeSubtitleFiles").WithError(err).Warnf("can not remove subtitle file %s", f)
+		}
+	}
+}
+
+// convertSubtitle returns the format of a subtitle file by its extension, and its content in that format.
+func convertSubtitle(fileName string, data []byte) (types.SubtitleFormatEnum, []byte, error) {
+	if len(data) > SubtitleMaxSize {
+		return "", nil, fmt.Errorf("%w: subtitle is larger than %d bytes", ErrInvalidDocument, SubtitleMaxSize)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return "", nil, fmt.Errorf("%w: subtitle is not utf-8 encoded", ErrInvalidDocument)
+	}
+	switch ext := strings.ToLower(path.Ext(fileName)); ext {
+	case ".srt":
+		vtt, err := srtToVtt(data)
+		return types.VTTSubtitleFormat, vtt, err
+	case ".vtt":
+		if !bytes.HasPrefix(data, []byte("WEBVTT")) {
+			return "", nil, fmt.Errorf("%w: vtt subtitle does not start with WEBVTT", ErrInvalidDocument)
+		}
+		return types.VTTSubtitleFormat, data, nil
+	case ".ass", ".ssa":
+		if !bytes.Contains(data, []byte("[Script Info]")) {
+			return "", nil, fmt.Errorf("%w: ass subtitle has no [Script Info] section", ErrInvalidDocument)
+		}
+		return types.ASSSubtitleFormat, data, nil
+	default:
+		return "", nil, fmt.Errorf("%w: unsupported subtitle format (%s)", ErrInvalidDocument, ext)
+	}
+}
+
+// srtToVtt converts a srt subtitle to WebVTT: the header is added, and the milliseconds of timings are separated by
+// a dot. Cue numbers are kept as cue identifiers.
+func srtToVtt(data []byte) ([]byte, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	cues := 0
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if m := srtTimingRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			line = fmt.Sprintf("%s.%s --> %s.%s%s", m[1], m[2], m[3], m[4], m[5])
+			cues++
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if cues == 0 {
+		return nil, fmt.Errorf("%w: srt subtitle has no cues", ErrInvalidDocument)
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,102 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMinio "github.com/amirdaaee/TGMon/mocks/db/minio"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Subtitle", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		mockFinder   *mMongoX.MockIFinder[types.MediaFileDoc]
+		mockMinio    *mMinio.MockIMinioClient
+		testContext  context.Context
+		media        *types.MediaFileDoc
+		oldSub       types.MediaSubtitle
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockFinder = mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mockFinder.EXPECT().Filter(gomock.Any()).Return(mockFinder).AnyTimes()
+		mockColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mockColl.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mockMediaFac.EXPECT().GetCollection().Return(mockColl).AnyTimes()
+		mockMinio = mMinio.NewMockIMinioClient(ctrl)
+		oldSub = types.MediaSubtitle{ID: bson.NewObjectID(), Language: "en", Format: types.VTTSubtitleFormat, File: "old.vtt"}
+		media = &types.MediaFileDoc{Subtitles: []types.MediaSubtitle{oldSub}}
+		media.ID = bson.NewObjectID()
+	})
+	Describe("AddSubtitle", func() {
+		It("should convert srt to vtt and replace the track of the language", func() {
+			srt := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nWorld\r\n"
+			vtt := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.500\nHello\n\n2\n00:00:03.000 --> 00:00:04.000\nWorld\n"
+			mockFinder.EXPECT().FindOne(testContext).Return(media, nil)
+			mockMinio.EXPECT().FileAdd(testContext, gomock.Any(), []byte(vtt)).Return(nil)
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.MediaFileDoc, error) {
+				Expect(fields).To(HaveLen(1))
+				Expect(fields[0].Key).To(Equal(types.MediaFileDoc__SubtitlesField))
+				subs := fields[0].Value.([]types.MediaSubtitle)
+				Expect(subs).To(HaveLen(1))
+				Expect(subs[0].ID).ToNot(Equal(oldSub.ID))
+				return media, nil
+			})
+			mockMinio.EXPECT().FileRm(testContext, "old.vtt").Return(nil)
+			sub, err := facade.AddSubtitle(testContext, mockMediaFac, mockMinio, media.ID, "en", "mock.SRT", []byte(srt))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sub.Language).To(Equal("en"))
+			Expect(sub.Format).To(Equal(types.VTTSubtitleFormat))
+			Expect(sub.Size).To(Equal(int64(len(vtt))))
+			Expect(sub.File).To(HavePrefix(media.ID.Hex()))
+			Expect(sub.File).To(HaveSuffix(".vtt"))
+		})
+		It("should keep the tracks of other languages and remove the file when the update fails", func() {
+			mockFinder.EXPECT().FindOne(testContext).Return(media, nil)
+			mockMinio.EXPECT().FileAdd(testContext, gomock.Any(), gomock.Any()).Return(nil)
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.MediaFileDoc, error) {
+				Expect(fields[0].Value).To(HaveLen(2))
+				return nil, fmt.Errorf("mock err")
+			})
+			mockMinio.EXPECT().FileRm(testContext, gomock.Not("old.vtt")).Return(nil)
+			_, err := facade.AddSubtitle(testContext, mockMediaFac, mockMinio, media.ID, "fa", "mock.ass", []byte("[Script Info]\nTitle: mock\n"))
+			Expect(err).To(HaveOccurred())
+		})
+		DescribeTable("should reject invalid subtitles",
+			func(language string, fileName string, data string) {
+				_, err := facade.AddSubtitle(testContext, mockMediaFac, mockMinio, media.ID, language, fileName, []byte(data))
+				Expect(err).To(MatchError(facade.ErrInvalidDocument))
+			},
+			Entry("invalid language", "../en", "mock.vtt", "WEBVTT\n"),
+			Entry("unsupported format", "en", "mock.sub", "mock"),
+			Entry("vtt without header", "en", "mock.vtt", "mock"),
+			Entry("srt without cues", "en", "mock.srt", "mock"),
+			Entry("not utf-8", "en", "mock.vtt", "WEBVTT\n\xff"),
+		)
+	})
+	Describe("RemoveSubtitle", func() {
+		It("should remove the track and its file", func() {
+			mockFinder.EXPECT().FindOne(testContext).Return(media, nil)
+			mockMediaFac.EXPECT().UpdateOne(testContext, gomock.Any(), bson.D{{Key: types.MediaFileDoc__SubtitlesField, Value: []types.MediaSubtitle{}}}).Return(media, nil)
+			mockMinio.EXPECT().FileRm(testContext, "old.vtt").Return(nil)
+			Expect(facade.RemoveSubtitle(testContext, mockMediaFac, mockMinio, media.ID, oldSub.ID)).To(Succeed())
+		})
+		It("should fail on unknown tracks", func() {
+			mockFinder.EXPECT().FindOne(testContext).Return(media, nil)
+			err := facade.RemoveSubtitle(testContext, mockMediaFac, mockMinio, media.ID, bson.NewObjectID())
+			Expect(err).To(MatchError(facade.ErrNoDocumentsFound))
+		})
+	})
+})
@@ -0,0 +1,182 @@
+// Package facade provides CRUD logic for tag documents.
+package facade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// tagNameMaxLen is the maximum length of a tag name.
+const tagNameMaxLen = 64
+
+// TagCrud implements ICrud for TagDoc, providing CRUD hooks and collection access.
+type TagCrud struct {
+	container db.IDbContainer
+}
+
+var _ ICrud[types.TagDoc] = (*TagCrud)(nil)
+
+// PreCreate validates the tag name and checks for duplicates before creating a TagDoc.
+func (crd *TagCrud) PreCreate(ctx context.Context, doc *types.TagDoc) error {
+	if doc == nil {
+		return fmt.Errorf("TagDoc is nil")
+	}
+	doc.Name = strings.TrimSpace(doc.Name)
+	if err := validateTagName(doc.Name); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+	return crd.checkDuplicate(ctx, doc.Name)
+}
+
+// PostCreate is a post-create hook for TagDoc. No-op in this implementation.
+func (crd *TagCrud) PostCreate(ctx context.Context, doc *types.TagDoc) error {
+	return nil
+}
+
+// PreDelete is a pre-delete hook for TagDoc. No-op in this implementation.
+func (crd *TagCrud) PreDelete(ctx context.Context, doc *types.TagDoc) error {
+	return nil
+}
+
+// PostDelete removes the deleted tag from all media documents.
+func (crd *TagCrud) PostDelete(ctx context.Context, doc *types.TagDoc) error {
+	ll := crd.getLogger("PostDelete")
+	if doc == nil {
+		return fmt.Errorf("TagDoc is nil")
+	}
+	res, err := crd.container.GetMongoContainer().GetMediaFileCollection().Updater().
+		Filter(bsonx.NewD().Add(types.MediaFileDoc__TagsField, doc.ID).Build()).
+		Updates(update.Pull(types.MediaFileDoc__TagsField, doc.ID)).
+		UpdateMany(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag from media: %w", err)
+	}
+	ll.Infof("tag removed from %d media", res.ModifiedCount)
+	return nil
+}
+
+// PreUpdate validates the fields to be set on a TagDoc. Only Name is editable and must stay unique.
+func (crd *TagCrud) PreUpdate(ctx context.Context, doc *types.TagDoc, fields bson.D) error {
+	if doc == nil {
+		return fmt.Errorf("TagDoc is nil")
+	}
+	for _, f := range fields {
+		if f.Key != types.TagDoc__NameField {
+			return fmt.Errorf("%w: field %s is not editable", ErrInvalidUpdate, f.Key)
+		}
+		name, ok := f.Value.(string)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a string", ErrInvalidUpdate, f.Key)
+		}
+		if err := validateTagName(name); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+		}
+		if name == doc.Name {
+			continue
+		}
+		if err := crd.checkDuplicate(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostUpdate is a post-update hook for TagDoc. No-op in this implementation.
+func (crd *TagCrud) PostUpdate(ctx context.Context, doc *types.TagDoc) error {
+	return nil
+}
+
+// GetCollection returns the Tag collection from the database container.
+func (crd *TagCrud) GetCollection() mngo.ICollection[types.TagDoc] {
+	return crd.container.GetMongoContainer().GetTagCollection()
+}
+
+// checkDuplicate returns ErrTagAlreadyExists if a tag with the given name exists.
+func (crd *TagCrud) checkDuplicate(ctx context.Context, name string) error {
+	n, err := crd.GetCollection().Finder().Filter(bsonx.NewD().Add(types.TagDoc__NameField, name).Build()).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %s", ErrTagAlreadyExists, name)
+	}
+	return nil
+}
+
+// getLogger returns a logrus.Entry for the given function name, tagged with the struct type.
+func (crd *TagCrud) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.FacadeModule).WithField("func", fmt.Sprintf("%T.%s", crd, fn))
+}
+
+// NewTagCrud creates a new TagCrud with the provided database container.
+func NewTagCrud(container db.IDbContainer) ICrud[types.TagDoc] {
+	return &TagCrud{container: container}
+}
+
+// EnsureTags returns the IDs of the tags with the given names, creating the missing ones.
+// Empty and duplicated names are ignored. A tag created concurrently between the lookup and the creation is looked up
+// again.
+func EnsureTags(ctx context.Context, tagFac IFacade[types.TagDoc], names []string) ([]bson.ObjectID, error) {
+	ids := []bson.ObjectID{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tag, err := findTag(ctx, tagFac, name)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			tag, err = tagFac.CreateOne(ctx, &types.TagDoc{Name: name})
+			if errors.Is(err, ErrTagAlreadyExists) || mongo.IsDuplicateKeyError(err) {
+				tag, err = findTag(ctx, tagFac, name)
+				if err == nil && tag == nil {
+					err = fmt.Errorf("%w: tag %s", ErrNoDocumentsFound, name)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to create tag %s: %w", name, err)
+			}
+		}
+		ids = append(ids, tag.ID)
+	}
+	return ids, nil
+}
+
+// findTag returns the tag with the given name, or nil if there is none.
+func findTag(ctx context.Context, tagFac IFacade[types.TagDoc], name string) (*types.TagDoc, error) {
+	tags, err := tagFac.GetCollection().Finder().Filter(bsonx.NewD().Add(types.TagDoc__NameField, name).Build()).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tag %s: %w", name, err)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags[0], nil
+}
+
+// validateTagName checks that a tag name is not empty and not too long.
+func validateTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tag name is empty")
+	}
+	if len(name) > tagNameMaxLen {
+		return fmt.Errorf("tag name is longer than %d characters", tagNameMaxLen)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("EnsureTags", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockFinder     *mMongoX.MockIFinder[types.TagDoc]
+		mockCollection *mMongo.MockICollection[types.TagDoc]
+		mockFac        *mFacade.MockIFacade[types.TagDoc]
+		testContext    context.Context
+		existingID     bson.ObjectID
+		createdID      bson.ObjectID
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		existingID = bson.NewObjectID()
+		createdID = bson.NewObjectID()
+		mockFinder = mMongoX.NewMockIFinder[types.TagDoc](ctrl)
+		mockFinder.EXPECT().Filter(gomock.Any()).Return(mockFinder).AnyTimes()
+		mockCollection = mMongo.NewMockICollection[types.TagDoc](ctrl)
+		mockCollection.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockFac = mFacade.NewMockIFacade[types.TagDoc](ctrl)
+		mockFac.EXPECT().GetCollection().Return(mockCollection).AnyTimes()
+	})
+	type testCase struct {
+		names       []string
+		existing    bool
+		findErr     bool
+		createCall  bool
+		createErr   bool
+		raceErr     error // returned by the creation, the tag being found afterwards
+		expectIDs   func() []bson.ObjectID
+		expectErr   bool
+		expectFinds int
+	}
+	DescribeTable("", func(tc testCase) {
+		finds := 0
+		mockFinder.EXPECT().Find(testContext).DoAndReturn(func(ctx context.Context, _ ...any) ([]*types.TagDoc, error) {
+			finds++
+			if tc.findErr {
+				return nil, fmt.Errorf("mock find error")
+			}
+			if tc.existing || (tc.raceErr != nil && finds > 1) {
+				t := &types.TagDoc{Name: "mock"}
+				t.ID = existingID
+				return []*types.TagDoc{t}, nil
+			}
+			return []*types.TagDoc{}, nil
+		}).Times(tc.expectFinds)
+		if tc.createCall {
+			mockFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.TagDoc) (*types.TagDoc, error) {
+				if tc.createErr {
+					return nil, fmt.Errorf("mock create error")
+				}
+				if tc.raceErr != nil {
+					return nil, tc.raceErr
+				}
+				doc.ID = createdID
+				return doc, nil
+			})
+		}
+		ids, err := facade.EnsureTags(testContext, mockFac, tc.names)
+		if tc.expectErr {
+			Expect(err).To(HaveOccurred())
+		} else {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ids).To(Equal(tc.expectIDs()))
+		}
+	},
+		Entry("should return existing tag", testCase{
+			names:       []string{"mock"},
+			existing:    true,
+			expectFinds: 1,
+			expectIDs:   func() []bson.ObjectID { return []bson.ObjectID{existingID} },
+		}),
+		Entry("should create missing tag", testCase{
+			names:       []string{"mock"},
+			createCall:  true,
+			expectFinds: 1,
+			expectIDs:   func() []bson.ObjectID { return []bson.ObjectID{createdID} },
+		}),
+		Entry("should ignore empty and duplicated names", testCase{
+			names:       []string{"mock", " ", "mock "},
+			existing:    true,
+			expectFinds: 1,
+			expectIDs:   func() []bson.ObjectID { return []bson.ObjectID{existingID} },
+		}),
+		Entry("should fail with find error", testCase{
+			names:       []string{"mock"},
+			findErr:     true,
+			expectFinds: 1,
+			expectErr:   true,
+		}),
+		Entry("should find tag created concurrently", testCase{
+			names:       []string{"mock"},
+			createCall:  true,
+			raceErr:     fmt.Errorf("%w: mock", facade.ErrTagAlreadyExists),
+			expectFinds: 2,
+			expectIDs:   func() []bson.ObjectID { return []bson.ObjectID{existingID} },
+		}),
+		Entry("should find tag created concurrently despite the duplicate check", testCase{
+			names:       []string{"mock"},
+			createCall:  true,
+			raceErr:     fmt.Errorf("error creating document: %w", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}),
+			expectFinds: 2,
+			expectIDs:   func() []bson.ObjectID { return []bson.ObjectID{existingID} },
+		}),
+		Entry("should fail with create error", testCase{
+			names:       []string{"mock"},
+			createCall:  true,
+			createErr:   true,
+			expectFinds: 1,
+			expectErr:   true,
+		}),
+	)
+})
@@ -0,0 +1,213 @@
+// Package facade provides CRUD logic for user documents.
+package facade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// usernameMaxLen is the maximum length of a username.
+const usernameMaxLen = 64
+
+// UserCrud implements ICrud for UserDoc, providing CRUD hooks and collection access.
+type UserCrud struct {
+	container db.IDbContainer
+	// passwordHashes holds the password hashes set by PreUpdate by user ID, so PostUpdate revokes the sessions of
+	// users whose new password was actually stored.
+	passwordHashes sync.Map
+}
+
+var _ ICrud[types.UserDoc] = (*UserCrud)(nil)
+
+// PreCreate validates the username and role, checks for duplicates and hashes the password before creating a UserDoc.
+func (crd *UserCrud) PreCreate(ctx context.Context, doc *types.UserDoc) error {
+	if doc == nil {
+		return fmt.Errorf("UserDoc is nil")
+	}
+	doc.Username = strings.TrimSpace(doc.Username)
+	if doc.Username == "" {
+		return fmt.Errorf("%w: username is empty", ErrInvalidDocument)
+	}
+	if len(doc.Username) > usernameMaxLen {
+		return fmt.Errorf("%w: username is longer than %d characters", ErrInvalidDocument, usernameMaxLen)
+	}
+	if !doc.Role.IsValid() {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidDocument, doc.Role)
+	}
+	n, err := crd.GetCollection().Finder().Filter(bsonx.NewD().Add(types.UserDoc__UsernameField, doc.Username).Build()).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %w: %s", ErrInvalidDocument, ErrUserAlreadyExists, doc.Username)
+	}
+	hash, err := auth.HashPassword(doc.Password)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+	doc.PasswordHash = hash
+	doc.Password = ""
+	doc.FailedLogins = 0
+	return nil
+}
+
+// PostCreate is a post-create hook for UserDoc. No-op in this implementation.
+func (crd *UserCrud) PostCreate(ctx context.Context, doc *types.UserDoc) error {
+	return nil
+}
+
+// PreDelete refuses to delete the last admin user.
+func (crd *UserCrud) PreDelete(ctx context.Context, doc *types.UserDoc) error {
+	if doc == nil {
+		return fmt.Errorf("UserDoc is nil")
+	}
+	if doc.Role == types.ADMINUserRole {
+		return crd.checkNotLastAdmin(ctx)
+	}
+	return nil
+}
+
+// PostDelete removes all sessions, api keys and watch progress of the deleted user.
+func (crd *UserCrud) PostDelete(ctx context.Context, doc *types.UserDoc) error {
+	ll := crd.getLogger("PostDelete")
+	if doc == nil {
+		return fmt.Errorf("UserDoc is nil")
+	}
+	res, err := crd.getSessionCollection().Deleter().Filter(bsonx.NewD().Add(types.SessionDoc__UserIDField, doc.ID).Build()).DeleteMany(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	ll.Infof("%d sessions deleted", res.DeletedCount)
+	res, err = crd.container.GetMongoContainer().GetApiKeyCollection().Deleter().Filter(bsonx.NewD().Add(types.ApiKeyDoc__CreatedByField, doc.ID).Build()).DeleteMany(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete user api keys: %w", err)
+	}
+	ll.Infof("%d api keys deleted", res.DeletedCount)
+	res, err = crd.container.GetMongoContainer().GetWatchProgressCollection().Deleter().Filter(bsonx.NewD().Add(types.WatchProgressDoc__UserIDField, doc.ID).Build()).DeleteMany(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete user watch progress: %w", err)
+	}
+	ll.Infof("%d watch progress deleted", res.DeletedCount)
+	return nil
+}
+
+// PreUpdate validates the fields to be set on a UserDoc. Role and Password are editable.
+// A Password field is replaced in place by its hash. It has no side effects: sessions are revoked by PostUpdate,
+// once the new password is stored.
+func (crd *UserCrud) PreUpdate(ctx context.Context, doc *types.UserDoc, fields bson.D) error {
+	if doc == nil {
+		return fmt.Errorf("UserDoc is nil")
+	}
+	for i, f := range fields {
+		switch f.Key {
+		case types.UserDoc__RoleField:
+			role, ok := f.Value.(types.UserRoleEnum)
+			if !ok || !role.IsValid() {
+				return fmt.Errorf("%w: unknown role %v", ErrInvalidUpdate, f.Value)
+			}
+			if doc.Role == types.ADMINUserRole && role != types.ADMINUserRole {
+				if err := crd.checkNotLastAdmin(ctx); err != nil {
+					return fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+				}
+			}
+		case types.UserDoc__PasswordField:
+			password, ok := f.Value.(string)
+			if !ok {
+				return fmt.Errorf("%w: %s must be a string", ErrInvalidUpdate, f.Key)
+			}
+			hash, err := auth.HashPassword(password)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+			}
+			fields[i] = bson.E{Key: types.UserDoc__PasswordHashField, Value: hash}
+			crd.passwordHashes.Store(doc.ID, hash)
+		default:
+			return fmt.Errorf("%w: field %s is not editable", ErrInvalidUpdate, f.Key)
+		}
+	}
+	return nil
+}
+
+// PostUpdate revokes all sessions of the user if its password was changed by the update. The hash set by PreUpdate
+// is compared to the stored one, so an update which failed after PreUpdate revokes nothing.
+func (crd *UserCrud) PostUpdate(ctx context.Context, doc *types.UserDoc) error {
+	if doc == nil {
+		return fmt.Errorf("UserDoc is nil")
+	}
+	hash, ok := crd.passwordHashes.LoadAndDelete(doc.ID)
+	if !ok || hash != doc.PasswordHash {
+		return nil
+	}
+	if err := crd.revokeSessions(ctx, doc.ID); err != nil {
+		return err
+	}
+	crd.getLogger("PostUpdate").Infof("sessions of user %s revoked after password change", doc.Username)
+	return nil
+}
+
+// GetCollection returns the User collection from the database container.
+func (crd *UserCrud) GetCollection() mngo.ICollection[types.UserDoc] {
+	return crd.container.GetMongoContainer().GetUserCollection()
+}
+
+// checkNotLastAdmin returns ErrLastAdmin if there is at most one admin user.
+func (crd *UserCrud) checkNotLastAdmin(ctx context.Context) error {
+	n, err := crd.GetCollection().Finder().Filter(bsonx.NewD().Add(types.UserDoc__RoleField, types.ADMINUserRole).Build()).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if n <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// revokeSessions marks all sessions of the user as revoked.
+func (crd *UserCrud) revokeSessions(ctx context.Context, userID bson.ObjectID) error {
+	q := bsonx.NewD().Add(types.SessionDoc__UserIDField, userID).Build()
+	if _, err := crd.getSessionCollection().Updater().Filter(q).Updates(update.Set(types.SessionDoc__RevokedField, true)).UpdateMany(ctx); err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+func (crd *UserCrud) getSessionCollection() mngo.ICollection[types.SessionDoc] {
+	return crd.container.GetMongoContainer().GetSessionCollection()
+}
+
+// getLogger returns a logrus.Entry for the given function name, tagged with the struct type.
+func (crd *UserCrud) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.FacadeModule).WithField("func", fmt.Sprintf("%T.%s", crd, fn))
+}
+
+// NewUserCrud creates a new UserCrud with the provided database container.
+func NewUserCrud(container db.IDbContainer) ICrud[types.UserDoc] {
+	return &UserCrud{container: container}
+}
+
+// SeedAdmin creates an admin user with the given credentials if there are no users yet.
+func SeedAdmin(ctx context.Context, userFac IFacade[types.UserDoc], username string, password string) (bool, error) {
+	n, err := userFac.GetCollection().Finder().Count(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to count users: %w", err)
+	}
+	if n > 0 {
+		return false, nil
+	}
+	if _, err := userFac.CreateOne(ctx, &types.UserDoc{Username: username, Password: password, Role: types.ADMINUserRole}); err != nil {
+		return false, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,153 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mDb "github.com/amirdaaee/TGMon/mocks/db"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("UserCrud", func() {
+	var (
+		ctrl          *gomock.Controller
+		mockContainer *mDb.MockIDbContainer
+		crd           facade.ICrud[types.UserDoc]
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockContainer = mDb.NewMockIDbContainer(ctrl)
+		crd = facade.NewUserCrud(mockContainer)
+	})
+	Describe("PreCreate", func() {
+		type testCase struct {
+			doc *types.UserDoc
+		}
+		DescribeTable("should reject invalid user", func(tc testCase) {
+			err := crd.PreCreate(context.Background(), tc.doc)
+			Expect(err).To(MatchError(facade.ErrInvalidDocument))
+		},
+			Entry("empty username", testCase{doc: &types.UserDoc{Username: " ", Password: "long-enough", Role: types.VIEWERUserRole}}),
+			Entry("unknown role", testCase{doc: &types.UserDoc{Username: "user", Password: "long-enough", Role: "ROOT"}}),
+		)
+	})
+	Describe("PreUpdate", func() {
+		type testCase struct {
+			fields bson.D
+		}
+		DescribeTable("should reject invalid update", func(tc testCase) {
+			doc := &types.UserDoc{Username: "user", Role: types.VIEWERUserRole}
+			err := crd.PreUpdate(context.Background(), doc, tc.fields)
+			Expect(err).To(MatchError(facade.ErrInvalidUpdate))
+		},
+			Entry("non-editable field", testCase{fields: bson.D{{Key: types.UserDoc__UsernameField, Value: "other"}}}),
+			Entry("unknown role", testCase{fields: bson.D{{Key: types.UserDoc__RoleField, Value: types.UserRoleEnum("ROOT")}}}),
+			Entry("short password", testCase{fields: bson.D{{Key: types.UserDoc__PasswordField, Value: "short"}}}),
+		)
+		It("should accept role change of non-admin user", func() {
+			doc := &types.UserDoc{Username: "user", Role: types.VIEWERUserRole}
+			err := crd.PreUpdate(context.Background(), doc, bson.D{{Key: types.UserDoc__RoleField, Value: types.EDITORUserRole}})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+	Describe("Password change", func() {
+		var (
+			mockUserFinder  *mMongoX.MockIFinder[types.UserDoc]
+			mockUserUpdater *mMongoX.MockIUpdater[types.UserDoc]
+			mockSessUpdater *mMongoX.MockIUpdater[types.SessionDoc]
+			doc             *types.UserDoc
+		)
+		BeforeEach(func() {
+			mockUserFinder = mMongoX.NewMockIFinder[types.UserDoc](ctrl)
+			mockUserUpdater = mMongoX.NewMockIUpdater[types.UserDoc](ctrl)
+			mockSessUpdater = mMongoX.NewMockIUpdater[types.SessionDoc](ctrl)
+			userColl := mMongo.NewMockICollection[types.UserDoc](ctrl)
+			userColl.EXPECT().Finder().Return(mockUserFinder).AnyTimes()
+			userColl.EXPECT().Updater().Return(mockUserUpdater).AnyTimes()
+			sessColl := mMongo.NewMockICollection[types.SessionDoc](ctrl)
+			sessColl.EXPECT().Updater().Return(mockSessUpdater).AnyTimes()
+			mockMongoContainer := mMongo.NewMockIMongoContainer(ctrl)
+			mockMongoContainer.EXPECT().GetUserCollection().Return(userColl).AnyTimes()
+			mockMongoContainer.EXPECT().GetSessionCollection().Return(sessColl).AnyTimes()
+			mockContainer.EXPECT().GetMongoContainer().Return(mockMongoContainer).AnyTimes()
+			doc = &types.UserDoc{Username: "user", Role: types.VIEWERUserRole, PasswordHash: "old-hash"}
+			doc.ID = bson.NewObjectID()
+		})
+		expectRevoke := func() {
+			mockSessUpdater.EXPECT().Filter(bson.D{{Key: types.SessionDoc__UserIDField, Value: doc.ID}}).Return(mockSessUpdater)
+			mockSessUpdater.EXPECT().Updates(gomock.Any()).Return(mockSessUpdater)
+			mockSessUpdater.EXPECT().UpdateMany(gomock.Any()).Return(&mongo.UpdateResult{}, nil)
+		}
+		It("should revoke sessions once the new password is stored", func() {
+			ctx := context.Background()
+			fields := bson.D{{Key: types.UserDoc__PasswordField, Value: "new-password"}}
+			Expect(crd.PreUpdate(ctx, doc, fields)).To(Succeed())
+			Expect(fields[0].Key).To(Equal(types.UserDoc__PasswordHashField))
+			updated := *doc
+			updated.PasswordHash = fields[0].Value.(string)
+			expectRevoke()
+			Expect(crd.PostUpdate(ctx, &updated)).To(Succeed())
+		})
+		It("should not revoke sessions when a later field fails validation", func() {
+			ctx := context.Background()
+			fields := bson.D{
+				{Key: types.UserDoc__PasswordField, Value: "new-password"},
+				{Key: types.UserDoc__UsernameField, Value: "other"},
+			}
+			Expect(crd.PreUpdate(ctx, doc, fields)).To(MatchError(facade.ErrInvalidUpdate))
+			// a later update of the user does not revoke for the rejected password either
+			Expect(crd.PostUpdate(ctx, doc)).To(Succeed())
+		})
+		It("should not revoke sessions when the update fails", func() {
+			ctx := context.Background()
+			fac := facade.NewFacade(crd)
+			filter := bson.D{{Key: "_id", Value: doc.ID}}
+			mockUserFinder.EXPECT().Filter(filter).Return(mockUserFinder).AnyTimes()
+			mockUserFinder.EXPECT().Count(ctx).Return(int64(1), nil)
+			mockUserFinder.EXPECT().FindOne(ctx).Return(doc, nil)
+			// the update is restricted to the checked document
+			mockUserFinder.EXPECT().Filter(bson.D{{Key: "$and", Value: bson.A{filter, filter}}}).Return(mockUserFinder)
+			mockUserFinder.EXPECT().Updates(gomock.Any()).Return(mockUserFinder)
+			mockUserFinder.EXPECT().FindOneAndUpdate(ctx, gomock.Any()).Return(nil, fmt.Errorf("mock update error"))
+			_, err := fac.UpdateOne(ctx, filter, bson.D{{Key: types.UserDoc__PasswordField, Value: "new-password"}})
+			Expect(err).To(HaveOccurred())
+			Expect(crd.PostUpdate(ctx, doc)).To(Succeed())
+		})
+	})
+	Describe("PostDelete", func() {
+		It("should delete the sessions, api keys and watch progress of the user", func() {
+			ctx := context.Background()
+			doc := &types.UserDoc{Username: "user", Role: types.ADMINUserRole}
+			doc.ID = bson.NewObjectID()
+			mockSessDeleter := mMongoX.NewMockIDeleter[types.SessionDoc](ctrl)
+			mockSessDeleter.EXPECT().Filter(bson.D{{Key: types.SessionDoc__UserIDField, Value: doc.ID}}).Return(mockSessDeleter)
+			mockSessDeleter.EXPECT().DeleteMany(ctx).Return(&mongo.DeleteResult{}, nil)
+			mockKeyDeleter := mMongoX.NewMockIDeleter[types.ApiKeyDoc](ctrl)
+			mockKeyDeleter.EXPECT().Filter(bson.D{{Key: types.ApiKeyDoc__CreatedByField, Value: doc.ID}}).Return(mockKeyDeleter)
+			mockKeyDeleter.EXPECT().DeleteMany(ctx).Return(&mongo.DeleteResult{DeletedCount: 2}, nil)
+			mockProgressDeleter := mMongoX.NewMockIDeleter[types.WatchProgressDoc](ctrl)
+			mockProgressDeleter.EXPECT().Filter(bson.D{{Key: types.WatchProgressDoc__UserIDField, Value: doc.ID}}).Return(mockProgressDeleter)
+			mockProgressDeleter.EXPECT().DeleteMany(ctx).Return(&mongo.DeleteResult{}, nil)
+			sessColl := mMongo.NewMockICollection[types.SessionDoc](ctrl)
+			sessColl.EXPECT().Deleter().Return(mockSessDeleter)
+			keyColl := mMongo.NewMockICollection[types.ApiKeyDoc](ctrl)
+			keyColl.EXPECT().Deleter().Return(mockKeyDeleter)
+			progressColl := mMongo.NewMockICollection[types.WatchProgressDoc](ctrl)
+			progressColl.EXPECT().Deleter().Return(mockProgressDeleter)
+			mockMongoContainer := mMongo.NewMockIMongoContainer(ctrl)
+			mockMongoContainer.EXPECT().GetSessionCollection().Return(sessColl)
+			mockMongoContainer.EXPECT().GetApiKeyCollection().Return(keyColl)
+			mockMongoContainer.EXPECT().GetWatchProgressCollection().Return(progressColl)
+			mockContainer.EXPECT().GetMongoContainer().Return(mockMongoContainer).AnyTimes()
+			Expect(crd.PostDelete(ctx, doc)).To(Succeed())
+		})
+	})
+})
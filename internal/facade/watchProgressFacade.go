@@ -0,0 +1,202 @@
+// Package facade provides CRUD logic for watch progress documents.
+package facade
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db"
+	mngo "github.com/amirdaaee/TGMon/internal/db/mongo"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// watchedThreshold is the fraction of a media's duration after which playback counts as finished.
+const watchedThreshold = 0.9
+
+// WatchProgressCrud implements ICrud for WatchProgressDoc, providing CRUD hooks and collection access.
+type WatchProgressCrud struct {
+	container db.IDbContainer
+}
+
+var _ ICrud[types.WatchProgressDoc] = (*WatchProgressCrud)(nil)
+
+// PreCreate validates a WatchProgressDoc and makes sure the user has no progress for the media yet.
+func (crd *WatchProgressCrud) PreCreate(ctx context.Context, doc *types.WatchProgressDoc) error {
+	if doc == nil {
+		return fmt.Errorf("WatchProgressDoc is nil")
+	}
+	if doc.UserID.IsZero() || doc.MediaID.IsZero() {
+		return fmt.Errorf("%w: user and media are required", ErrInvalidDocument)
+	}
+	if doc.Position < 0 || doc.PlayCount < 0 {
+		return fmt.Errorf("%w: position and play count can not be negative", ErrInvalidDocument)
+	}
+	n, err := crd.GetCollection().Finder().Filter(progressFilter(doc.UserID, doc.MediaID)).Count(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: progress already exists", ErrInvalidDocument)
+	}
+	return nil
+}
+
+// PostCreate is a post-create hook for WatchProgressDoc. No-op in this implementation.
+func (crd *WatchProgressCrud) PostCreate(ctx context.Context, doc *types.WatchProgressDoc) error {
+	return nil
+}
+
+// PreDelete is a pre-delete hook for WatchProgressDoc. No-op in this implementation.
+func (crd *WatchProgressCrud) PreDelete(ctx context.Context, doc *types.WatchProgressDoc) error {
+	return nil
+}
+
+// PostDelete is a post-delete hook for WatchProgressDoc. No-op in this implementation.
+func (crd *WatchProgressCrud) PostDelete(ctx context.Context, doc *types.WatchProgressDoc) error {
+	return nil
+}
+
+// PreUpdate validates the fields to be set on a WatchProgressDoc. Only the playback state is editable.
+func (crd *WatchProgressCrud) PreUpdate(ctx context.Context, doc *types.WatchProgressDoc, fields bson.D) error {
+	if doc == nil {
+		return fmt.Errorf("WatchProgressDoc is nil")
+	}
+	for _, f := range fields {
+		switch f.Key {
+		case types.WatchProgressDoc__PositionField:
+			v, ok := f.Value.(float64)
+			if !ok || v < 0 {
+				return fmt.Errorf("%w: %s must be a non-negative number", ErrInvalidUpdate, f.Key)
+			}
+		case types.WatchProgressDoc__PlayCountField:
+			v, ok := f.Value.(int)
+			if !ok || v < 0 {
+				return fmt.Errorf("%w: %s must be a non-negative integer", ErrInvalidUpdate, f.Key)
+			}
+		case types.WatchProgressDoc__FinishedField, types.WatchProgressDoc__WatchedField:
+			if _, ok := f.Value.(bool); !ok {
+				return fmt.Errorf("%w: %s must be a boolean", ErrInvalidUpdate, f.Key)
+			}
+		case types.WatchProgressDoc__LastPlayedAtField:
+			if _, ok := f.Value.(time.Time); !ok {
+				return fmt.Errorf("%w: %s must be a time", ErrInvalidUpdate, f.Key)
+			}
+		default:
+			return fmt.Errorf("%w: field %s is not editable", ErrInvalidUpdate, f.Key)
+		}
+	}
+	return nil
+}
+
+// PostUpdate is a post-update hook for WatchProgressDoc. No-op in this implementation.
+func (crd *WatchProgressCrud) PostUpdate(ctx context.Context, doc *types.WatchProgressDoc) error {
+	return nil
+}
+
+// GetCollection returns the WatchProgress collection from the database container.
+func (crd *WatchProgressCrud) GetCollection() mngo.ICollection[types.WatchProgressDoc] {
+	return crd.container.GetMongoContainer().GetWatchProgressCollection()
+}
+
+// NewWatchProgressCrud creates a new WatchProgressCrud with the provided database container.
+func NewWatchProgressCrud(container db.IDbContainer) ICrud[types.WatchProgressDoc] {
+	return &WatchProgressCrud{container: container}
+}
+
+// GetProgress returns the progress of the user on the media, or nil if the user never played it.
+func GetProgress(ctx context.Context, progressFac IFacade[types.WatchProgressDoc], userID bson.ObjectID, mediaID bson.ObjectID) (*types.WatchProgressDoc, error) {
+	docs, err := progressFac.GetCollection().Finder().Filter(progressFilter(userID, mediaID)).Limit(1).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find progress: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	return docs[0], nil
+}
+
+// ReportProgress records the playback position of the media for the user. Reaching the end of the media
+// marks it as watched and counts a play; a play is counted again only after playback restarted.
+// The progress of a user on a media is unique, a report racing with another creating it updates it instead.
+func ReportProgress(ctx context.Context, progressFac IFacade[types.WatchProgressDoc], userID bson.ObjectID, media *types.MediaFileDoc, position float64) (*types.WatchProgressDoc, error) {
+	if position < 0 {
+		return nil, fmt.Errorf("%w: position can not be negative", ErrInvalidDocument)
+	}
+	finished := media.Meta.Duration > 0 && position >= media.Meta.Duration*watchedThreshold
+	cur, err := GetProgress(ctx, progressFac, userID, media.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cur == nil {
+		doc := &types.WatchProgressDoc{UserID: userID, MediaID: media.ID, Position: position, Finished: finished, LastPlayedAt: now}
+		if finished {
+			doc.Watched = true
+			doc.PlayCount = 1
+		}
+		res, err := progressFac.CreateOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return ReportProgress(ctx, progressFac, userID, media, position)
+		}
+		return res, err
+	}
+	fields := bson.D{
+		{Key: types.WatchProgressDoc__PositionField, Value: position},
+		{Key: types.WatchProgressDoc__FinishedField, Value: finished},
+		{Key: types.WatchProgressDoc__LastPlayedAtField, Value: now},
+	}
+	if finished && !cur.Finished {
+		fields = append(fields,
+			bson.E{Key: types.WatchProgressDoc__WatchedField, Value: true},
+			bson.E{Key: types.WatchProgressDoc__PlayCountField, Value: cur.PlayCount + 1},
+		)
+	}
+	return progressFac.UpdateOne(ctx, query.Id(cur.ID), fields)
+}
+
+// MarkWatched sets the watched flag of the media for the user and its resume position, which is cleared if position
+// is nil. Marking an unwatched media as watched counts a play.
+func MarkWatched(ctx context.Context, progressFac IFacade[types.WatchProgressDoc], userID bson.ObjectID, mediaID bson.ObjectID, watched bool, position *float64) (*types.WatchProgressDoc, error) {
+	resume := float64(0)
+	if position != nil {
+		if *position < 0 {
+			return nil, fmt.Errorf("%w: position can not be negative", ErrInvalidDocument)
+		}
+		resume = *position
+	}
+	cur, err := GetProgress(ctx, progressFac, userID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if cur == nil {
+		doc := &types.WatchProgressDoc{UserID: userID, MediaID: mediaID, Position: resume, Watched: watched}
+		if watched {
+			doc.PlayCount = 1
+			doc.LastPlayedAt = time.Now()
+		}
+		res, err := progressFac.CreateOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return MarkWatched(ctx, progressFac, userID, mediaID, watched, position)
+		}
+		return res, err
+	}
+	fields := bson.D{
+		{Key: types.WatchProgressDoc__PositionField, Value: resume},
+		{Key: types.WatchProgressDoc__FinishedField, Value: false},
+		{Key: types.WatchProgressDoc__WatchedField, Value: watched},
+	}
+	if watched && !cur.Watched {
+		fields = append(fields, bson.E{Key: types.WatchProgressDoc__PlayCountField, Value: cur.PlayCount + 1})
+	}
+	return progressFac.UpdateOne(ctx, query.Id(cur.ID), fields)
+}
+
+// progressFilter matches the progress of a user on a media.
+func progressFilter(userID bson.ObjectID, mediaID bson.ObjectID) bson.D {
+	return bsonx.NewD().Add(types.WatchProgressDoc__UserIDField, userID).Add(types.WatchProgressDoc__MediaIDField, mediaID).Build()
+}
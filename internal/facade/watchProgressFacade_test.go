@@ -0,0 +1,206 @@
+package facade_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("WatchProgress", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockFinder     *mMongoX.MockIFinder[types.WatchProgressDoc]
+		mockCollection *mMongo.MockICollection[types.WatchProgressDoc]
+		mockFac        *mFacade.MockIFacade[types.WatchProgressDoc]
+		testContext    context.Context
+		userID         bson.ObjectID
+		media          *types.MediaFileDoc
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		userID = bson.NewObjectID()
+		media = &types.MediaFileDoc{Meta: types.MediaFileMeta{Duration: 100}}
+		media.ID = bson.NewObjectID()
+		mockFinder = mMongoX.NewMockIFinder[types.WatchProgressDoc](ctrl)
+		mockFinder.EXPECT().Filter(gomock.Any()).Return(mockFinder).AnyTimes()
+		mockFinder.EXPECT().Limit(gomock.Any()).Return(mockFinder).AnyTimes()
+		mockCollection = mMongo.NewMockICollection[types.WatchProgressDoc](ctrl)
+		mockCollection.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockFac = mFacade.NewMockIFacade[types.WatchProgressDoc](ctrl)
+		mockFac.EXPECT().GetCollection().Return(mockCollection).AnyTimes()
+	})
+	returnProgress := func(cur *types.WatchProgressDoc) {
+		mockFinder.EXPECT().Find(testContext).DoAndReturn(func(ctx context.Context, _ ...any) ([]*types.WatchProgressDoc, error) {
+			if cur == nil {
+				return []*types.WatchProgressDoc{}, nil
+			}
+			return []*types.WatchProgressDoc{cur}, nil
+		})
+	}
+	Describe("ReportProgress", func() {
+		It("should create progress on first report", func() {
+			returnProgress(nil)
+			mockFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.WatchProgressDoc) (*types.WatchProgressDoc, error) {
+				return doc, nil
+			})
+			res, err := facade.ReportProgress(testContext, mockFac, userID, media, 30)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.UserID).To(Equal(userID))
+			Expect(res.MediaID).To(Equal(media.ID))
+			Expect(res.Position).To(Equal(float64(30)))
+			Expect(res.Watched).To(BeFalse())
+			Expect(res.PlayCount).To(Equal(0))
+		})
+		It("should count a play on first report at the end", func() {
+			returnProgress(nil)
+			mockFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.WatchProgressDoc) (*types.WatchProgressDoc, error) {
+				return doc, nil
+			})
+			res, err := facade.ReportProgress(testContext, mockFac, userID, media, 95)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Finished).To(BeTrue())
+			Expect(res.Watched).To(BeTrue())
+			Expect(res.PlayCount).To(Equal(1))
+		})
+		It("should update progress created by a concurrent report", func() {
+			cur := types.WatchProgressDoc{UserID: userID, MediaID: media.ID, Position: 10}
+			cur.ID = bson.NewObjectID()
+			returnProgress(nil)
+			mockFac.EXPECT().CreateOne(testContext, gomock.Any()).Return(nil, fmt.Errorf("error creating document: %w", mongo.WriteException{
+				WriteErrors: []mongo.WriteError{{Code: 11000, Message: "E11000 duplicate key error"}},
+			}))
+			returnProgress(&cur)
+			mockFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.WatchProgressDoc, error) {
+				Expect(filter).To(Equal(query.Id(cur.ID)))
+				Expect(fields).To(ContainElement(bson.E{Key: types.WatchProgressDoc__PositionField, Value: float64(30)}))
+				cur.Position = 30
+				return &cur, nil
+			})
+			res, err := facade.ReportProgress(testContext, mockFac, userID, media, 30)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Position).To(Equal(float64(30)))
+		})
+		type testCase struct {
+			cur         types.WatchProgressDoc
+			position    float64
+			expectCount bool
+		}
+		DescribeTable("update", func(tc testCase) {
+			cur := tc.cur
+			cur.ID = bson.NewObjectID()
+			returnProgress(&cur)
+			mockFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.WatchProgressDoc, error) {
+				keys := map[string]any{}
+				for _, f := range fields {
+					keys[f.Key] = f.Value
+				}
+				Expect(keys).To(HaveKeyWithValue(types.WatchProgressDoc__PositionField, tc.position))
+				if tc.expectCount {
+					Expect(keys).To(HaveKeyWithValue(types.WatchProgressDoc__WatchedField, true))
+					Expect(keys).To(HaveKeyWithValue(types.WatchProgressDoc__PlayCountField, cur.PlayCount+1))
+				} else {
+					Expect(keys).ToNot(HaveKey(types.WatchProgressDoc__PlayCountField))
+				}
+				return &cur, nil
+			})
+			_, err := facade.ReportProgress(testContext, mockFac, userID, media, tc.position)
+			Expect(err).ToNot(HaveOccurred())
+		},
+			Entry("should update position", testCase{
+				cur:      types.WatchProgressDoc{Position: 10},
+				position: 20,
+			}),
+			Entry("should count a play when reaching the end", testCase{
+				cur:         types.WatchProgressDoc{Position: 80, PlayCount: 2},
+				position:    91,
+				expectCount: true,
+			}),
+			Entry("should not count a play twice", testCase{
+				cur:      types.WatchProgressDoc{Position: 91, Finished: true, Watched: true, PlayCount: 1},
+				position: 99,
+			}),
+			Entry("should not reset watched on rewatch", testCase{
+				cur:      types.WatchProgressDoc{Position: 91, Finished: true, Watched: true, PlayCount: 1},
+				position: 5,
+			}),
+		)
+		It("should reject negative position", func() {
+			_, err := facade.ReportProgress(testContext, mockFac, userID, media, -1)
+			Expect(err).To(MatchError(facade.ErrInvalidDocument))
+		})
+	})
+	Describe("MarkWatched", func() {
+		It("should count a play when marking as watched", func() {
+			cur := types.WatchProgressDoc{Position: 40, PlayCount: 1}
+			cur.ID = bson.NewObjectID()
+			returnProgress(&cur)
+			mockFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.WatchProgressDoc, error) {
+				Expect(fields).To(ContainElements(
+					bson.E{Key: types.WatchProgressDoc__PositionField, Value: float64(0)},
+					bson.E{Key: types.WatchProgressDoc__WatchedField, Value: true},
+					bson.E{Key: types.WatchProgressDoc__PlayCountField, Value: 2},
+				))
+				return &cur, nil
+			})
+			_, err := facade.MarkWatched(testContext, mockFac, userID, media.ID, true, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should create unwatched progress", func() {
+			returnProgress(nil)
+			mockFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.WatchProgressDoc) (*types.WatchProgressDoc, error) {
+				return doc, nil
+			})
+			res, err := facade.MarkWatched(testContext, mockFac, userID, media.ID, false, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Watched).To(BeFalse())
+			Expect(res.PlayCount).To(Equal(0))
+		})
+		It("should keep a given position", func() {
+			cur := types.WatchProgressDoc{Position: 40, Watched: true, PlayCount: 1}
+			cur.ID = bson.NewObjectID()
+			returnProgress(&cur)
+			mockFac.EXPECT().UpdateOne(testContext, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.WatchProgressDoc, error) {
+				Expect(fields).To(ContainElements(
+					bson.E{Key: types.WatchProgressDoc__PositionField, Value: float64(25)},
+					bson.E{Key: types.WatchProgressDoc__WatchedField, Value: false},
+				))
+				return &cur, nil
+			})
+			position := float64(25)
+			_, err := facade.MarkWatched(testContext, mockFac, userID, media.ID, false, &position)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should reject negative position", func() {
+			position := float64(-1)
+			_, err := facade.MarkWatched(testContext, mockFac, userID, media.ID, false, &position)
+			Expect(err).To(MatchError(facade.ErrInvalidDocument))
+		})
+	})
+	Describe("PreUpdate", func() {
+		DescribeTable("", func(fields bson.D, expectErr bool) {
+			crd := facade.NewWatchProgressCrud(nil)
+			err := crd.PreUpdate(testContext, &types.WatchProgressDoc{}, fields)
+			if expectErr {
+				Expect(err).To(MatchError(facade.ErrInvalidUpdate))
+			} else {
+				Expect(err).ToNot(HaveOccurred())
+			}
+		},
+			Entry("should allow playback state", bson.D{{Key: types.WatchProgressDoc__PositionField, Value: float64(3)}, {Key: types.WatchProgressDoc__WatchedField, Value: true}}, false),
+			Entry("should reject negative position", bson.D{{Key: types.WatchProgressDoc__PositionField, Value: float64(-3)}}, true),
+			Entry("should reject other fields", bson.D{{Key: types.WatchProgressDoc__UserIDField, Value: bson.NewObjectID()}}, true),
+		)
+	})
+})
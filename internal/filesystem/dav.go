@@ -0,0 +1,435 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/webdav"
+)
+
+// DavFS exposes the media files of a MediaFS as a read-only webdav.FileSystem.
+// It shares the media cache and file naming of the FUSE mount, so both list the same files.
+type DavFS struct {
+	root *MediaFS
+}
+
+var _ webdav.FileSystem = (*DavFS)(nil)
+
+// Mkdir is not supported, the filesystem is read-only
+func (d *DavFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+// RemoveAll is not supported, the filesystem is read-only
+func (d *DavFS) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+// Rename is not supported, the filesystem is read-only
+func (d *DavFS) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+// OpenFile opens a directory, a media file or a sidecar file for reading
+func (d *DavFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	entry, err := d.resolve(ctx, name, true)
+	if err != nil {
+		return nil, err
+	}
+	fileCtx, cancel := context.WithCancel(ctx)
+	f := &davFile{
+		info:             entry.info,
+		media:            entry.media,
+		children:         entry.children,
+		streamWorkerPool: d.root.streamWorkerPool,
+		ctx:              fileCtx,
+		cancel:           cancel,
+	}
+	if entry.sidecar != nil {
+		f.sidecar = entry.sidecar
+		f.minioClient = d.root.dbContainer.GetMinioContainer().GetMinioClient()
+	}
+	return f, nil
+}
+
+// Stat returns the file info of a directory, a media file or a sidecar file
+func (d *DavFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	entry, err := d.resolve(ctx, name, false)
+	if err != nil {
+		return nil, err
+	}
+	return entry.info, nil
+}
+
+// davEntry is a resolved webdav path: a directory, a media file, or a sidecar file of media
+type davEntry struct {
+	info     *davFileInfo
+	media    *types.MediaFileDoc
+	sidecar  *sidecar
+	children []os.FileInfo // of directories, if listed
+}
+
+// resolve returns the entry of a webdav path. The root directory lists the media files and their subtitles, or the
+// folders of the media in the library view, which list the media file and its sidecar files. With list, the
+// children of directories are resolved too.
+func (d *DavFS) resolve(ctx context.Context, name string, list bool) (*davEntry, error) {
+	mediaFiles, err := d.root.getMediaFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fileName, isRoot := d.cleanName(name)
+	if isRoot {
+		entry := &davEntry{info: d.rootInfo(mediaFiles)}
+		if list {
+			entry.children = d.rootChildren(mediaFiles)
+		}
+		return entry, nil
+	}
+	if !d.root.library {
+		if strings.Contains(fileName, "/") {
+			return nil, os.ErrNotExist
+		}
+		if media := d.root.findMedia(mediaFiles, fileName); media != nil {
+			return &davEntry{info: d.mediaInfo(media), media: media}, nil
+		}
+		if media, sc := d.root.findSidecar(mediaFiles, fileName); sc != nil {
+			return &davEntry{info: d.sidecarInfo(media, sc), media: media, sidecar: sc}, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	itemName, fileName, _ := strings.Cut(fileName, "/")
+	media := d.root.findItem(mediaFiles, itemName)
+	if media == nil || strings.Contains(fileName, "/") {
+		return nil, os.ErrNotExist
+	}
+	if fileName == "" {
+		entry := &davEntry{info: d.itemInfo(media), media: media}
+		if list {
+			sidecars, err := d.root.getSidecars(ctx, media)
+			if err != nil {
+				return nil, err
+			}
+			entry.children = []os.FileInfo{d.mediaInfo(media)}
+			for i := range sidecars {
+				entry.children = append(entry.children, d.sidecarInfo(media, &sidecars[i]))
+			}
+			d.sortInfos(entry.children)
+		}
+		return entry, nil
+	}
+	if d.root.getFilename(media) == fileName {
+		return &davEntry{info: d.mediaInfo(media), media: media}, nil
+	}
+	sidecars, err := d.root.getSidecars(ctx, media)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sidecars {
+		if sidecars[i].name == fileName {
+			return &davEntry{info: d.sidecarInfo(media, &sidecars[i]), media: media, sidecar: &sidecars[i]}, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// rootChildren returns the infos of the media files and their subtitles, or of the folders of the media in the
+// library view
+func (d *DavFS) rootChildren(mediaFiles []*types.MediaFileDoc) []os.FileInfo {
+	children := make([]os.FileInfo, 0, len(mediaFiles))
+	for _, m := range mediaFiles {
+		if d.root.library {
+			children = append(children, d.itemInfo(m))
+			continue
+		}
+		children = append(children, d.mediaInfo(m))
+		sidecars := d.root.getSubtitleSidecars(m)
+		for i := range sidecars {
+			children = append(children, d.sidecarInfo(m, &sidecars[i]))
+		}
+	}
+	d.sortInfos(children)
+	return children
+}
+
+// sortInfos sorts file infos by name
+func (d *DavFS) sortInfos(infos []os.FileInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+}
+
+// cleanName returns the file name of a webdav path, and whether the path is the root directory
+func (d *DavFS) cleanName(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	return name, name == ""
+}
+
+// rootInfo returns the info of the root directory, modified when the newest media was added
+func (d *DavFS) rootInfo(mediaFiles []*types.MediaFileDoc) *davFileInfo {
+	info := &davFileInfo{name: "/", dir: true}
+	for _, m := range mediaFiles {
+		if m.CreatedAt.After(info.modTime) {
+			info.modTime = m.CreatedAt
+		}
+	}
+	return info
+}
+
+// mediaInfo returns the info of a media file
+func (d *DavFS) mediaInfo(media *types.MediaFileDoc) *davFileInfo {
+	name := d.root.getFilename(media)
+	contentType := media.Meta.MimeType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &davFileInfo{
+		name:        name,
+		size:        media.Meta.FileSize,
+		modTime:     media.CreatedAt,
+		contentType: contentType,
+	}
+}
+
+// itemInfo returns the info of the library folder of a media
+func (d *DavFS) itemInfo(media *types.MediaFileDoc) *davFileInfo {
+	return &davFileInfo{
+		name:    d.root.getItemName(media),
+		modTime: media.UpdatedAt,
+		dir:     true,
+	}
+}
+
+// sidecarInfo returns the info of a sidecar file of a media
+func (d *DavFS) sidecarInfo(media *types.MediaFileDoc, sc *sidecar) *davFileInfo {
+	return &davFileInfo{
+		name:        sc.name,
+		size:        sc.size,
+		modTime:     media.UpdatedAt,
+		contentType: sc.contentType,
+	}
+}
+
+// NewDavFS creates a webdav filesystem serving the media files of root
+func NewDavFS(root *MediaFS) *DavFS {
+	return &DavFS{root: root}
+}
+
+// davFileInfo implements os.FileInfo, and webdav.ContentTyper so clients listing a directory
+// do not download the beginning of every file to sniff its content type.
+type davFileInfo struct {
+	name        string
+	size        int64
+	modTime     time.Time
+	dir         bool
+	contentType string
+}
+
+var _ webdav.ContentTyper = (*davFileInfo)(nil)
+
+func (fi *davFileInfo) Name() string       { return fi.name }
+func (fi *davFileInfo) Size() int64        { return fi.size }
+func (fi *davFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *davFileInfo) IsDir() bool        { return fi.dir }
+func (fi *davFileInfo) Sys() any           { return nil }
+func (fi *davFileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ContentType returns the mime type of the media file
+func (fi *davFileInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	return fi.contentType, nil
+}
+
+// davFile is an open webdav file. Media files are read sequentially from a single streamer
+// of the worker pool, which is reopened at the new offset when the file is seeked.
+// Sidecar files are read from minio, or from memory if they are generated.
+type davFile struct {
+	info             *davFileInfo
+	media            *types.MediaFileDoc
+	sidecar          *sidecar
+	minioClient      minio.IMinioClient
+	sidecarReader    io.ReadSeekCloser
+	children         []os.FileInfo
+	streamWorkerPool stream.IWorkerPool
+	ctx              context.Context
+	cancel           context.CancelFunc
+	offset           int64
+	streamer         stream.IStreamer
+	streamCancel     context.CancelFunc
+}
+
+var _ webdav.File = (*davFile)(nil)
+
+// Read reads from the current offset of the media file
+func (f *davFile) Read(p []byte) (int, error) {
+	if f.sidecar != nil {
+		r, err := f.getSidecarReader()
+		if err != nil {
+			return 0, err
+		}
+		return r.Read(p)
+	}
+	if f.media == nil {
+		return 0, fmt.Errorf("%s is a directory", f.info.name)
+	}
+	size := f.media.Meta.FileSize
+	if f.offset >= size {
+		return 0, io.EOF
+	}
+	if f.streamer == nil {
+		streamCtx, streamCancel := context.WithCancel(f.ctx)
+		streamer, err := f.streamWorkerPool.Stream(streamCtx, f.media.MessageID, f.offset, size-1)
+		if err != nil {
+			streamCancel()
+			f.getLogger("Read").WithError(err).Error("Failed to create streamer")
+			return 0, err
+		}
+		f.streamer = streamer
+		f.streamCancel = streamCancel
+	}
+	if remaining := size - f.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := f.streamer.Read(p)
+	f.offset += int64(n)
+	if errors.Is(err, io.EOF) && f.offset < size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek moves the offset of the media file, dropping the current streamer if the offset changes
+func (f *davFile) Seek(offset int64, whence int) (int64, error) {
+	if f.sidecar != nil {
+		r, err := f.getSidecarReader()
+		if err != nil {
+			return 0, err
+		}
+		return r.Seek(offset, whence)
+	}
+	if f.media == nil {
+		return 0, nil
+	}
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = f.media.Meta.FileSize + offset
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if abs < 0 {
+		return 0, fmt.Errorf("negative offset: %d", abs)
+	}
+	if abs != f.offset {
+		f.closeStreamer()
+		f.offset = abs
+	}
+	return abs, nil
+}
+
+// Readdir returns the children of the directory
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.info.dir {
+		return nil, fmt.Errorf("%s is not a directory", f.info.name)
+	}
+	if count <= 0 {
+		children := f.children
+		f.children = nil
+		return children, nil
+	}
+	if len(f.children) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.children) {
+		count = len(f.children)
+	}
+	children := f.children[:count]
+	f.children = f.children[count:]
+	return children, nil
+}
+
+// Stat returns the file info
+func (f *davFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// Write is not supported, the filesystem is read-only
+func (f *davFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// Close cancels any running stream of the file
+func (f *davFile) Close() error {
+	f.closeStreamer()
+	f.cancel()
+	if f.sidecarReader != nil {
+		return f.sidecarReader.Close()
+	}
+	return nil
+}
+
+// getSidecarReader returns the reader of the sidecar file, opening it on first use
+func (f *davFile) getSidecarReader() (io.ReadSeekCloser, error) {
+	if f.sidecarReader == nil {
+		if f.sidecar.object == "" {
+			f.sidecarReader = nopReadSeekCloser{bytes.NewReader(f.sidecar.data)}
+			return f.sidecarReader, nil
+		}
+		r, err := f.minioClient.FileGet(f.ctx, f.sidecar.object)
+		if err != nil {
+			f.getLogger("getSidecarReader").WithError(err).Errorf("Failed to get %s", f.sidecar.object)
+			return nil, err
+		}
+		f.sidecarReader = r
+	}
+	return f.sidecarReader, nil
+}
+
+func (f *davFile) closeStreamer() {
+	if f.streamCancel != nil {
+		f.streamCancel()
+	}
+	f.streamer = nil
+	f.streamCancel = nil
+}
+
+func (f *davFile) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.FuseModule).WithField("func", fmt.Sprintf("%T.%s", f, fn))
+}
+
+// nopReadSeekCloser adds a no-op Close to the reader of a generated sidecar file
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error { return nil }
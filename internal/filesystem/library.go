@@ -0,0 +1,277 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"syscall"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// suffixes of the sidecar files of the library view, appended to the item name
+const (
+	nfoSuffix    = ".nfo"
+	posterSuffix = "-poster.jpg"
+	thumbSuffix  = "-thumb.jpg"
+)
+
+// LibraryDir is the folder of a media in the library view. It holds the media file, its subtitles, an nfo with the
+// metadata of the media and the thumbnail as poster and thumb images, laid out the way Jellyfin and Kodi expect:
+//
+//	Movie-<id>/Movie-<id>.mkv
+//	Movie-<id>/Movie-<id>.en.vtt
+//	Movie-<id>/Movie-<id>.nfo
+//	Movie-<id>/Movie-<id>-poster.jpg
+//	Movie-<id>/Movie-<id>-thumb.jpg
+type LibraryDir struct {
+	fs.Inode
+	root  *MediaFS
+	media *types.MediaFileDoc
+}
+
+var _ fs.NodeReaddirer = (*LibraryDir)(nil)
+var _ fs.NodeLookuper = (*LibraryDir)(nil)
+var _ fs.NodeGetattrer = (*LibraryDir)(nil)
+
+// Getattr returns directory attributes
+func (ld *LibraryDir) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0555
+	out.Nlink = 2
+	out.Size = 4096
+	out.Mtime = uint64(ld.media.UpdatedAt.Unix())
+	out.Atime = uint64(ld.media.UpdatedAt.Unix())
+	out.Ctime = uint64(ld.media.CreatedAt.Unix())
+	return 0
+}
+
+// Readdir lists the media file and its sidecar files
+func (ld *LibraryDir) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	ll := ld.getLogger("Readdir")
+	media, sidecars, errno := ld.getFiles(ctx)
+	if errno != 0 {
+		return nil, errno
+	}
+	entries := []fuse.DirEntry{{
+		Name: ld.root.getFilename(media),
+		Mode: fuse.S_IFREG | 0444,
+		Ino:  ld.root.getInodeNumber(media.ID),
+	}}
+	for _, sc := range sidecars {
+		entries = append(entries, fuse.DirEntry{
+			Name: sc.name,
+			Mode: fuse.S_IFREG | 0444,
+			Ino:  ld.root.getInodeNumber(sc.id),
+		})
+	}
+	ll.Debugf("Returning %d entries", len(entries))
+	return fs.NewListDirStream(entries), 0
+}
+
+// Lookup finds the media file or a sidecar file by name
+func (ld *LibraryDir) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	media, sidecars, errno := ld.getFiles(ctx)
+	if errno != 0 {
+		return nil, errno
+	}
+	if ld.root.getFilename(media) == name {
+		node, stable := ld.root.mediaNode(media, out)
+		return ld.NewInode(ctx, node, stable), 0
+	}
+	for i := range sidecars {
+		if sidecars[i].name == name {
+			node, stable := ld.root.sidecarNode(media, &sidecars[i], out)
+			return ld.NewInode(ctx, node, stable), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+// getFiles returns the current version of the media of the folder and its sidecar files
+func (ld *LibraryDir) getFiles(ctx context.Context) (*types.MediaFileDoc, []sidecar, syscall.Errno) {
+	ll := ld.getLogger("getFiles")
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	mediaFiles, err := ld.root.getMediaFiles(queryCtx)
+	if err != nil {
+		ll.WithError(err).Warn("Failed to get media files")
+		return nil, nil, syscall.EIO
+	}
+	media := ld.root.findMediaByID(mediaFiles, ld.media.ID)
+	if media == nil {
+		return nil, nil, syscall.ENOENT
+	}
+	sidecars, err := ld.root.getSidecars(queryCtx, media)
+	if err != nil {
+		ll.WithError(err).Warn("Failed to get sidecar files")
+		return nil, nil, syscall.EIO
+	}
+	return media, sidecars, 0
+}
+
+func (ld *LibraryDir) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.FuseModule).WithField("func", fmt.Sprintf("%T.%s", ld, fn))
+}
+
+// nfoMovie is the Kodi movie nfo of a media, also read by Jellyfin
+type nfoMovie struct {
+	XMLName   xml.Name     `xml:"movie"`
+	Title     string       `xml:"title"`
+	Plot      string       `xml:"plot,omitempty"`
+	Runtime   int          `xml:"runtime,omitempty"` // minutes
+	DateAdded string       `xml:"dateadded"`
+	Tags      []string     `xml:"tag"`
+	UniqueID  nfoUniqueID  `xml:"uniqueid"`
+	FileInfo  *nfoFileInfo `xml:"fileinfo,omitempty"`
+}
+type nfoUniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	Value   string `xml:",chardata"`
+}
+type nfoFileInfo struct {
+	DurationInSeconds int `xml:"streamdetails>video>durationinseconds"`
+}
+
+// getLibrarySidecars returns the nfo of a media, and its thumbnail as poster and thumb if it has one
+func (mfs *MediaFS) getLibrarySidecars(ctx context.Context, media *types.MediaFileDoc) ([]sidecar, error) {
+	itemName := mfs.getItemName(media)
+	nfo, err := mfs.buildNfo(media)
+	if err != nil {
+		return nil, err
+	}
+	sidecars := []sidecar{{
+		name:        itemName + nfoSuffix,
+		id:          media.ID.String() + nfoSuffix,
+		data:        nfo,
+		size:        int64(len(nfo)),
+		contentType: "text/xml; charset=utf-8",
+	}}
+	if media.Thumbnail == "" {
+		return sidecars, nil
+	}
+	size := media.ThumbnailSize
+	if size == 0 {
+		// stored before thumbnail sizes were recorded
+		info, err := mfs.dbContainer.GetMinioContainer().GetMinioClient().FileStat(ctx, media.Thumbnail)
+		if err != nil {
+			mfs.getLogger("getLibrarySidecars").WithError(err).Debugf("Failed to stat thumbnail of %s", media.ID.Hex())
+			return sidecars, nil
+		}
+		size = info.Size
+	}
+	for _, suffix := range []string{posterSuffix, thumbSuffix} {
+		sidecars = append(sidecars, sidecar{
+			name:        itemName + suffix,
+			id:          media.ID.String() + suffix,
+			object:      media.Thumbnail,
+			size:        size,
+			contentType: "image/jpeg",
+		})
+	}
+	return sidecars, nil
+}
+
+// buildNfo returns the nfo of a media: its title, description, runtime, the date it was added and its tags
+func (mfs *MediaFS) buildNfo(media *types.MediaFileDoc) ([]byte, error) {
+	nfo := nfoMovie{
+		Title:     media.DisplayName(),
+		Plot:      media.Description,
+		DateAdded: media.CreatedAt.Format(time.DateTime),
+		Tags:      []string{},
+		UniqueID:  nfoUniqueID{Type: "tgmon", Default: true, Value: media.ID.Hex()},
+	}
+	if media.Meta.Duration > 0 {
+		nfo.Runtime = max(1, int(math.Round(media.Meta.Duration/60)))
+		nfo.FileInfo = &nfoFileInfo{DurationInSeconds: int(math.Round(media.Meta.Duration))}
+	}
+	mfs.cacheMutex.RLock()
+	for _, id := range media.Tags {
+		if name, ok := mfs.tagCache[id]; ok {
+			nfo.Tags = append(nfo.Tags, name)
+		}
+	}
+	mfs.cacheMutex.RUnlock()
+	data, err := xml.MarshalIndent(nfo, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal nfo: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// findTagNames returns the names of the tags of the given media by ID, with a single query
+func (mfs *MediaFS) findTagNames(ctx context.Context, mediaFiles []*types.MediaFileDoc) (map[bson.ObjectID]string, error) {
+	names := map[bson.ObjectID]string{}
+	ids := []bson.ObjectID{}
+	seen := map[bson.ObjectID]bool{}
+	for _, m := range mediaFiles {
+		for _, id := range m.Tags {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	tags, err := mfs.dbContainer.GetMongoContainer().GetTagCollection().Finder().Filter(query.In("_id", ids...)).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tags: %w", err)
+	}
+	for _, t := range tags {
+		names[t.ID] = t.Name
+	}
+	return names, nil
+}
+
+// findItem returns the media whose library folder is named name, or nil if there is none
+func (mfs *MediaFS) findItem(mediaFiles []*types.MediaFileDoc, name string) *types.MediaFileDoc {
+	for _, m := range mediaFiles {
+		if mfs.getItemName(m) == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// findMediaByID returns the media with the given ID, or nil if there is none
+func (mfs *MediaFS) findMediaByID(mediaFiles []*types.MediaFileDoc, id bson.ObjectID) *types.MediaFileDoc {
+	for _, m := range mediaFiles {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// lookupItem returns the node of the library folder of a media
+func (mfs *MediaFS) lookupItem(ctx context.Context, media *types.MediaFileDoc, out *fuse.EntryOut) *fs.Inode {
+	out.Mode = fuse.S_IFDIR | 0555
+	out.Mtime = uint64(media.UpdatedAt.Unix())
+	out.Atime = uint64(media.UpdatedAt.Unix())
+	out.Ctime = uint64(media.CreatedAt.Unix())
+	stable := fs.StableAttr{
+		Mode: fuse.S_IFDIR,
+		Ino:  mfs.getInodeNumber(mfs.getItemInodeID(media)),
+	}
+	return mfs.NewInode(ctx, &LibraryDir{root: mfs, media: media}, stable)
+}
+
+// getItemName returns the name of the library folder of a media: the filename of the media without extension
+func (mfs *MediaFS) getItemName(media *types.MediaFileDoc) string {
+	return mfs.getBaseFilename(media)
+}
+
+// getItemInodeID identifies the library folder of a media, for inode numbers
+func (mfs *MediaFS) getItemInodeID(media *types.MediaFileDoc) string {
+	return media.ID.String() + "/"
+}
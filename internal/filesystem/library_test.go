@@ -0,0 +1,201 @@
+package filesystem_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/filesystem"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mDb "github.com/amirdaaee/TGMon/mocks/db"
+	mMinio "github.com/amirdaaee/TGMon/mocks/db/minio"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/chenmingyong0423/go-mongox/v2/finder"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/minio/minio-go/v7"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+type nopCloser struct{ io.ReadSeeker }
+
+func (nopCloser) Close() error { return nil }
+
+var _ = Describe("Library view", func() {
+	var (
+		ctrl        *gomock.Controller
+		mockMinio   *mMinio.MockIMinioClient
+		davFS       *filesystem.DavFS
+		testContext context.Context
+		movie       *types.MediaFileDoc
+		twin        *types.MediaFileDoc // named like movie
+		legacy      *types.MediaFileDoc // thumbnail stored without its size
+		tagA, tagB  *types.TagDoc
+		tagFilter   any
+	)
+	newMedia := func(name string) *types.MediaFileDoc {
+		m := &types.MediaFileDoc{Name: name, Meta: types.MediaFileMeta{FileSize: 10, MimeType: "video/mp4"}}
+		m.ID = bson.NewObjectID()
+		m.CreatedAt = time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+		m.UpdatedAt = m.CreatedAt
+		return m
+	}
+	itemName := func(m *types.MediaFileDoc) string {
+		return m.Name + "-" + m.ID.Hex()
+	}
+	list := func(name string) []os.FileInfo {
+		f, err := davFS.OpenFile(testContext, name, os.O_RDONLY, 0)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		infos, err := f.Readdir(0)
+		Expect(err).ToNot(HaveOccurred())
+		return infos
+	}
+	names := func(infos []os.FileInfo) []string {
+		res := []string{}
+		for _, fi := range infos {
+			res = append(res, fi.Name())
+		}
+		return res
+	}
+	read := func(name string) []byte {
+		f, err := davFS.OpenFile(testContext, name, os.O_RDONLY, 0)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		Expect(err).ToNot(HaveOccurred())
+		return data
+	}
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		tagA = &types.TagDoc{Name: "action"}
+		tagA.ID = bson.NewObjectID()
+		tagB = &types.TagDoc{Name: "drama"}
+		tagB.ID = bson.NewObjectID()
+		movie = newMedia("Movie")
+		movie.Description = "mock plot"
+		movie.Meta.Duration = 5430
+		movie.Tags = []bson.ObjectID{tagA.ID, tagB.ID}
+		movie.Thumbnail = "movie.jpg"
+		movie.ThumbnailSize = 123
+		movie.Subtitles = []types.MediaSubtitle{{ID: bson.NewObjectID(), Language: "en", Format: types.VTTSubtitleFormat, File: "sub.vtt", Size: 4}}
+		twin = newMedia("Movie")
+		twin.Tags = []bson.ObjectID{tagA.ID}
+		legacy = newMedia("Legacy")
+		legacy.Thumbnail = "legacy.jpg"
+
+		mediaFinder := mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mediaFinder.EXPECT().Find(gomock.Any()).Return([]*types.MediaFileDoc{movie, twin, legacy}, nil).Times(1)
+		mediaColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mediaColl.EXPECT().Finder().Return(mediaFinder).AnyTimes()
+		tagFinder := mMongoX.NewMockIFinder[types.TagDoc](ctrl)
+		tagFinder.EXPECT().Filter(gomock.Any()).DoAndReturn(func(filter any) finder.IFinder[types.TagDoc] {
+			tagFilter = filter
+			return tagFinder
+		}).AnyTimes()
+		// tags are loaded once with the media, not per item
+		tagFinder.EXPECT().Find(gomock.Any()).Return([]*types.TagDoc{tagA, tagB}, nil).Times(1)
+		tagColl := mMongo.NewMockICollection[types.TagDoc](ctrl)
+		tagColl.EXPECT().Finder().Return(tagFinder).AnyTimes()
+		mongoContainer := mMongo.NewMockIMongoContainer(ctrl)
+		mongoContainer.EXPECT().GetMediaFileCollection().Return(mediaColl).AnyTimes()
+		mongoContainer.EXPECT().GetTagCollection().Return(tagColl).AnyTimes()
+		// only thumbnails without a recorded size are stat'ed
+		mockMinio = mMinio.NewMockIMinioClient(ctrl)
+		mockMinio.EXPECT().FileStat(gomock.Any(), "legacy.jpg").Return(minio.ObjectInfo{Size: 77}, nil).AnyTimes()
+		minioContainer := mMinio.NewMockIMinioContainer(ctrl)
+		minioContainer.EXPECT().GetMinioClient().Return(mockMinio).AnyTimes()
+		dbContainer := mDb.NewMockIDbContainer(ctrl)
+		dbContainer.EXPECT().GetMongoContainer().Return(mongoContainer).AnyTimes()
+		dbContainer.EXPECT().GetMinioContainer().Return(minioContainer).AnyTimes()
+		davFS = filesystem.NewDavFS(filesystem.NewMediaFS(dbContainer, nil, true))
+	})
+	It("should list a folder per media", func() {
+		infos := list("/")
+		Expect(names(infos)).To(ConsistOf(itemName(movie), itemName(twin), itemName(legacy)))
+		for _, fi := range infos {
+			Expect(fi.IsDir()).To(BeTrue())
+		}
+		Expect(tagFilter).To(Equal(query.In("_id", tagA.ID, tagB.ID)))
+	})
+	It("should give media with the same name their own folders", func() {
+		Expect(itemName(movie)).ToNot(Equal(itemName(twin)))
+		Expect(names(list(itemName(twin)))).To(Equal([]string{
+			itemName(twin) + ".mp4",
+			itemName(twin) + ".nfo",
+		}))
+		nfo := string(read(itemName(twin) + "/" + itemName(twin) + ".nfo"))
+		Expect(nfo).To(ContainSubstring(twin.ID.Hex()))
+		Expect(nfo).ToNot(ContainSubstring(movie.ID.Hex()))
+	})
+	It("should list the media file and its sidecar files in its folder", func() {
+		item := itemName(movie)
+		infos := list(item)
+		Expect(names(infos)).To(Equal([]string{
+			item + "-poster.jpg",
+			item + "-thumb.jpg",
+			item + ".en.vtt",
+			item + ".mp4",
+			item + ".nfo",
+		}))
+		Expect(infos[0].Size()).To(Equal(int64(123)))
+		Expect(infos[1].Size()).To(Equal(int64(123)))
+		Expect(infos[3].Size()).To(Equal(int64(10)))
+	})
+	It("should stat thumbnails stored without their size", func() {
+		item := itemName(legacy)
+		fi, err := davFS.Stat(testContext, item+"/"+item+"-poster.jpg")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fi.Size()).To(Equal(int64(77)))
+	})
+	It("should render the nfo of a media", func() {
+		item := itemName(movie)
+		data := read(item + "/" + item + ".nfo")
+		Expect(string(data)).To(HavePrefix(xml.Header))
+		var nfo struct {
+			Title     string   `xml:"title"`
+			Plot      string   `xml:"plot"`
+			Runtime   int      `xml:"runtime"`
+			DateAdded string   `xml:"dateadded"`
+			Tags      []string `xml:"tag"`
+			UniqueID  struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"uniqueid"`
+			Duration int `xml:"fileinfo>streamdetails>video>durationinseconds"`
+		}
+		Expect(xml.Unmarshal(data, &nfo)).To(Succeed())
+		Expect(nfo.Title).To(Equal("Movie"))
+		Expect(nfo.Plot).To(Equal("mock plot"))
+		Expect(nfo.Runtime).To(Equal(91))
+		Expect(nfo.Duration).To(Equal(5430))
+		Expect(nfo.DateAdded).To(Equal("2025-01-10 12:00:00"))
+		Expect(nfo.Tags).To(Equal([]string{"action", "drama"}))
+		Expect(nfo.UniqueID.Type).To(Equal("tgmon"))
+		Expect(nfo.UniqueID.Value).To(Equal(movie.ID.Hex()))
+		fi, err := davFS.Stat(testContext, item+"/"+item+".nfo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fi.Size()).To(Equal(int64(len(data))))
+	})
+	It("should serve the thumbnail as poster", func() {
+		item := itemName(movie)
+		mockMinio.EXPECT().FileGet(gomock.Any(), "movie.jpg").Return(nopCloser{bytes.NewReader([]byte("mock-jpeg"))}, nil)
+		Expect(read(item + "/" + item + "-poster.jpg")).To(Equal([]byte("mock-jpeg")))
+	})
+	It("should not list posters of media without a thumbnail", func() {
+		Expect(names(list(itemName(twin)))).ToNot(ContainElement(HaveSuffix(".jpg")))
+	})
+	It("should not find files outside of item folders", func() {
+		_, err := davFS.Stat(testContext, itemName(movie)+".mp4")
+		Expect(err).To(MatchError(os.ErrNotExist))
+		_, err = davFS.Stat(testContext, itemName(movie)+"/"+itemName(twin)+".mp4")
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+})
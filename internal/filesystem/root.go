@@ -13,12 +13,14 @@ import (
 	"unicode"
 
 	"github.com/amirdaaee/TGMon/internal/db"
+	"github.com/amirdaaee/TGMon/internal/facade"
 	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/amirdaaee/TGMon/internal/stream"
 	"github.com/amirdaaee/TGMon/internal/types"
 	"github.com/hanwen/go-fuse/v2/fs"
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // MediaFS is the root filesystem node that lists all media files
@@ -27,9 +29,11 @@ type MediaFS struct {
 	dbContainer      db.IDbContainer
 	streamWorkerPool stream.IWorkerPool
 	mediaCache       map[string]*types.MediaFileDoc
+	tagCache         map[bson.ObjectID]string // names of the tags of the cached media, in the library view
 	cacheMutex       sync.RWMutex
 	cacheExpiry      time.Time
 	cacheTTL         time.Duration
+	library          bool // arrange media in a folder per item, see LibraryDir
 }
 
 var _ fs.NodeOnAdder = (*MediaFS)(nil)
@@ -37,6 +41,7 @@ var _ fs.NodeReaddirer = (*MediaFS)(nil)
 var _ fs.NodeLookuper = (*MediaFS)(nil)
 var _ fs.NodeGetattrer = (*MediaFS)(nil)
 var _ fs.NodeOpendirer = (*MediaFS)(nil)
+var _ facade.IMediaCacheInvalidator = (*MediaFS)(nil)
 
 // OnAdd is called when the filesystem is mounted
 func (mfs *MediaFS) OnAdd(ctx context.Context) {
@@ -63,7 +68,8 @@ func (mfs *MediaFS) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.Attr
 	return 0
 }
 
-// Readdir lists all media files in the root directory
+// Readdir lists all media files and their subtitles in the root directory, or the folders of the media in the
+// library view
 func (mfs *MediaFS) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 	ll := mfs.getLogger("Readdir")
 	ll.Debug("Reading directory")
@@ -102,6 +108,14 @@ func (mfs *MediaFS) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 
 	// Create directory entries directly (avoid intermediate struct for better performance)
 	for _, media := range mediaFiles {
+		if mfs.library {
+			entries = append(entries, fuse.DirEntry{
+				Name: mfs.getItemName(media),
+				Mode: fuse.S_IFDIR | 0555,
+				Ino:  mfs.getInodeNumber(mfs.getItemInodeID(media)),
+			})
+			continue
+		}
 		filename := mfs.getFilename(media)
 		// Set Ino to match what we use in Lookup - use hash of ObjectID for uniqueness
 		ino := mfs.getInodeNumber(media.ID)
@@ -110,6 +124,13 @@ func (mfs *MediaFS) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 			Mode: fuse.S_IFREG | 0444, // Regular file, read-only
 			Ino:  ino,
 		})
+		for _, sc := range mfs.getSubtitleSidecars(media) {
+			entries = append(entries, fuse.DirEntry{
+				Name: sc.name,
+				Mode: fuse.S_IFREG | 0444,
+				Ino:  mfs.getInodeNumber(sc.id),
+			})
+		}
 	}
 
 	// Sort entries by filename for deterministic ordering
@@ -150,20 +171,30 @@ func (mfs *MediaFS) Lookup(ctx context.Context, name string, out *fuse.EntryOut)
 		return nil, syscall.EIO
 	}
 
-	// Find the media file by name
-	var media *types.MediaFileDoc
-	for _, m := range mediaFiles {
-		if mfs.getFilename(m) == name {
-			media = m
-			break
+	if mfs.library {
+		media := mfs.findItem(mediaFiles, name)
+		if media == nil {
+			ll.Debugf("Folder not found: %s", name)
+			return nil, syscall.ENOENT
 		}
+		return mfs.lookupItem(ctx, media, out), 0
 	}
-
+	media := mfs.findMedia(mediaFiles, name)
 	if media == nil {
+		if media, sc := mfs.findSidecar(mediaFiles, name); sc != nil {
+			node, stable := mfs.sidecarNode(media, sc, out)
+			return mfs.NewInode(ctx, node, stable), 0
+		}
 		ll.Debugf("File not found: %s", name)
 		return nil, syscall.ENOENT
 	}
+	node, stable := mfs.mediaNode(media, out)
+	ll.Debugf("Found file: %s (size: %d)", name, media.Meta.FileSize)
+	return mfs.NewInode(ctx, node, stable), 0
+}
 
+// mediaNode returns the node of a media file, and sets its entry attributes
+func (mfs *MediaFS) mediaNode(media *types.MediaFileDoc, out *fuse.EntryOut) (fs.InodeEmbedder, fs.StableAttr) {
 	// Create file node
 	fileNode := &MediaFile{
 		media:            media,
@@ -182,9 +213,7 @@ func (mfs *MediaFS) Lookup(ctx context.Context, name string, out *fuse.EntryOut)
 		Mode: fuse.S_IFREG,
 		Ino:  mfs.getInodeNumber(media.ID),
 	}
-
-	ll.Debugf("Found file: %s (size: %d)", name, media.Meta.FileSize)
-	return mfs.NewInode(ctx, fileNode, stable), 0
+	return fileNode, stable
 }
 
 // getMediaFiles retrieves all media files from the database, with caching
@@ -222,17 +251,112 @@ func (mfs *MediaFS) getMediaFiles(ctx context.Context) ([]*types.MediaFileDoc, e
 		return nil, fmt.Errorf("failed to find media files: %w", err)
 	}
 
+	// In the library view, the tag names of the nfo files are loaded with the media so listing the library does not
+	// query the tags of every item
+	var tagNames map[bson.ObjectID]string
+	if mfs.library {
+		if tagNames, err = mfs.findTagNames(ctx, mediaFiles); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update cache
 	mfs.mediaCache = make(map[string]*types.MediaFileDoc)
 	for _, media := range mediaFiles {
 		filename := mfs.getFilename(media)
 		mfs.mediaCache[filename] = media
 	}
+	if tagNames != nil {
+		mfs.tagCache = tagNames
+	}
 	mfs.cacheExpiry = time.Now().Add(mfs.cacheTTL)
 
 	return mediaFiles, nil
 }
 
+// findMedia returns the media file named name, or nil if there is none
+func (mfs *MediaFS) findMedia(mediaFiles []*types.MediaFileDoc, name string) *types.MediaFileDoc {
+	for _, m := range mediaFiles {
+		if mfs.getFilename(m) == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// findSidecar returns the media and the sidecar file named name, or nil if there is none
+func (mfs *MediaFS) findSidecar(mediaFiles []*types.MediaFileDoc, name string) (*types.MediaFileDoc, *sidecar) {
+	for _, m := range mediaFiles {
+		sidecars := mfs.getSubtitleSidecars(m)
+		for i := range sidecars {
+			if sidecars[i].name == name {
+				return m, &sidecars[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getSidecars returns the sidecar files of a media: its subtitles, and in the library view its nfo and posters
+func (mfs *MediaFS) getSidecars(ctx context.Context, media *types.MediaFileDoc) ([]sidecar, error) {
+	sidecars := mfs.getSubtitleSidecars(media)
+	if !mfs.library {
+		return sidecars, nil
+	}
+	library, err := mfs.getLibrarySidecars(ctx, media)
+	if err != nil {
+		return nil, err
+	}
+	return append(sidecars, library...), nil
+}
+
+// getSubtitleSidecars returns the sidecar files of the subtitles of a media
+func (mfs *MediaFS) getSubtitleSidecars(media *types.MediaFileDoc) []sidecar {
+	sidecars := make([]sidecar, 0, len(media.Subtitles))
+	for i := range media.Subtitles {
+		sub := &media.Subtitles[i]
+		contentType := "text/vtt"
+		if sub.Format == types.ASSSubtitleFormat {
+			contentType = "text/x-ssa"
+		}
+		sidecars = append(sidecars, sidecar{
+			name:        mfs.getSubtitleFilename(media, sub),
+			id:          sub.ID.String(),
+			object:      sub.File,
+			size:        sub.Size,
+			contentType: contentType,
+		})
+	}
+	return sidecars
+}
+
+// sidecarNode returns the node of a sidecar file, and sets its entry attributes
+func (mfs *MediaFS) sidecarNode(media *types.MediaFileDoc, sc *sidecar, out *fuse.EntryOut) (fs.InodeEmbedder, fs.StableAttr) {
+	fileNode := &SidecarFile{
+		media:       media,
+		sidecar:     sc,
+		minioClient: mfs.dbContainer.GetMinioContainer().GetMinioClient(),
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(sc.size)
+	out.Mtime = uint64(media.UpdatedAt.Unix())
+	out.Atime = uint64(media.UpdatedAt.Unix())
+	out.Ctime = uint64(media.CreatedAt.Unix())
+	stable := fs.StableAttr{
+		Mode: fuse.S_IFREG,
+		Ino:  mfs.getInodeNumber(sc.id),
+	}
+	return fileNode, stable
+}
+
+// InvalidateMediaCache drops the cached media list so the next directory read fetches it from the database
+func (mfs *MediaFS) InvalidateMediaCache() {
+	mfs.cacheMutex.Lock()
+	defer mfs.cacheMutex.Unlock()
+	mfs.cacheExpiry = time.Time{}
+	mfs.getLogger("InvalidateMediaCache").Debug("media cache invalidated")
+}
+
 // getInodeNumber generates a unique inode number from an ObjectID
 // Uses SHA256 hash to ensure uniqueness even if timestamps collide
 func (mfs *MediaFS) getInodeNumber(id interface{}) uint64 {
@@ -289,14 +413,23 @@ func (mfs *MediaFS) sanitizeFilename(filename string) string {
 
 // getFilename returns the filename for a media file
 func (mfs *MediaFS) getFilename(media *types.MediaFileDoc) string {
-	ext := mfs.getExtensionFromMimeType(media.Meta.MimeType)
-	if media.Meta.FileName != "" {
-		safeName := mfs.sanitizeFilename(media.Meta.FileName)
-		name := fmt.Sprintf("%s-%s%s", safeName, media.ID.Hex(), ext)
-		return name
-	}
-	// Use ID as filename with appropriate extension based on mime type
-	return fmt.Sprintf("%s%s", media.ID.Hex(), ext)
+	return mfs.getBaseFilename(media) + mfs.getExtensionFromMimeType(media.Meta.MimeType)
+}
+
+// getBaseFilename returns the filename for a media file without extension
+func (mfs *MediaFS) getBaseFilename(media *types.MediaFileDoc) string {
+	if displayName := media.DisplayName(); displayName != "" {
+		safeName := mfs.sanitizeFilename(displayName)
+		return fmt.Sprintf("%s-%s", safeName, media.ID.Hex())
+	}
+	// Use ID as filename
+	return media.ID.Hex()
+}
+
+// getSubtitleFilename returns the filename of the sidecar file of a subtitle: the filename of the media with the
+// language and format of the subtitle as extensions (e.g. Movie-<id>.en.vtt)
+func (mfs *MediaFS) getSubtitleFilename(media *types.MediaFileDoc, sub *types.MediaSubtitle) string {
+	return fmt.Sprintf("%s.%s.%s", mfs.getBaseFilename(media), sub.Language, sub.Format)
 }
 
 // getExtensionFromMimeType returns a file extension based on mime type
@@ -331,13 +464,16 @@ func (mfs *MediaFS) getLogger(fn string) *logrus.Entry {
 	return log.GetLogger(log.FuseModule).WithField("func", fmt.Sprintf("%T.%s", mfs, fn))
 }
 
-// NewMediaFS creates a new MediaFS filesystem
-func NewMediaFS(dbContainer db.IDbContainer, streamWorkerPool stream.IWorkerPool) *MediaFS {
+// NewMediaFS creates a new MediaFS filesystem. With library, media are arranged in a folder per item with nfo and
+// poster sidecar files (see LibraryDir), for media servers like Jellyfin and Kodi
+func NewMediaFS(dbContainer db.IDbContainer, streamWorkerPool stream.IWorkerPool, library bool) *MediaFS {
 	return &MediaFS{
 		dbContainer:      dbContainer,
 		streamWorkerPool: streamWorkerPool,
 		mediaCache:       make(map[string]*types.MediaFileDoc),
+		tagCache:         make(map[bson.ObjectID]string),
 		cacheTTL:         30 * time.Second, // Cache media list for 30 seconds
+		library:          library,
 	}
 }
 
@@ -353,10 +489,21 @@ type MountOptions struct {
 	AllowOther bool
 	// Debug enables FUSE debug logging
 	Debug bool
+	// Library arranges media in a folder per item, see LibraryDir
+	Library bool
 }
 
 // MountWithOptions mounts the media filesystem with custom options
 func MountWithOptions(mountPoint string, dbContainer db.IDbContainer, streamWorkerPool stream.IWorkerPool, opts *MountOptions) (*fuse.Server, error) {
+	if opts == nil {
+		opts = &MountOptions{}
+	}
+	return MountMediaFS(mountPoint, NewMediaFS(dbContainer, streamWorkerPool, opts.Library), opts)
+}
+
+// MountMediaFS mounts an already created MediaFS root with custom options.
+// This allows the caller to keep a reference to the root (e.g. for cache invalidation)
+func MountMediaFS(mountPoint string, root *MediaFS, opts *MountOptions) (*fuse.Server, error) {
 	ll := log.GetLogger(log.FuseModule).WithField("func", "Mount")
 	ll.Infof("Mounting filesystem at: %s", mountPoint)
 
@@ -394,9 +541,6 @@ func MountWithOptions(mountPoint string, dbContainer db.IDbContainer, streamWork
 		}
 	}
 
-	// Create root filesystem
-	root := NewMediaFS(dbContainer, streamWorkerPool)
-
 	// Create FUSE server
 	fuseOpts := &fs.Options{}
 	fuseOpts.Debug = opts.Debug
@@ -0,0 +1,90 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/sirupsen/logrus"
+)
+
+// sidecar is a file listed next to the file of a media: a subtitle track (e.g. Movie-<id>.en.vtt next to
+// Movie-<id>.mkv) so players like Kodi and Jellyfin pick it up, and in the library view the nfo and poster files
+type sidecar struct {
+	name        string
+	id          string // identifies the sidecar among all files, for inode numbers
+	object      string // object of the file in minio, or empty if data holds the file
+	data        []byte
+	size        int64
+	contentType string
+}
+
+// SidecarFile is the node of a sidecar file of a media
+type SidecarFile struct {
+	fs.Inode
+	media       *types.MediaFileDoc
+	sidecar     *sidecar
+	minioClient minio.IMinioClient
+}
+
+var _ fs.NodeOpener = (*SidecarFile)(nil)
+var _ fs.NodeGetattrer = (*SidecarFile)(nil)
+
+// Getattr returns file attributes
+func (sf *SidecarFile) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(sf.sidecar.size)
+	out.Mtime = uint64(sf.media.UpdatedAt.Unix())
+	out.Atime = uint64(sf.media.UpdatedAt.Unix())
+	out.Ctime = uint64(sf.media.CreatedAt.Unix())
+	return 0
+}
+
+// Open reads the whole file from minio, as sidecar files are small
+func (sf *SidecarFile) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	ll := sf.getLogger("Open")
+	if flags&fuse.O_ANYWRITE != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	if sf.sidecar.object == "" {
+		return &memoryFileHandle{data: sf.sidecar.data}, fuse.FOPEN_KEEP_CACHE, 0
+	}
+	r, err := sf.minioClient.FileGet(ctx, sf.sidecar.object)
+	if err != nil {
+		ll.WithError(err).Errorf("Failed to get %s", sf.sidecar.object)
+		return nil, 0, syscall.EIO
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		ll.WithError(err).Errorf("Failed to read %s", sf.sidecar.object)
+		return nil, 0, syscall.EIO
+	}
+	return &memoryFileHandle{data: data}, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (sf *SidecarFile) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.FuseModule).WithField("func", fmt.Sprintf("%T.%s", sf, fn))
+}
+
+// memoryFileHandle serves reads of an open sidecar file from memory
+type memoryFileHandle struct {
+	data []byte
+}
+
+var _ fs.FileReader = (*memoryFileHandle)(nil)
+
+// Read reads data from the file at the specified offset
+func (h *memoryFileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	if off >= int64(len(h.data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := min(off+int64(len(dest)), int64(len(h.data)))
+	return fuse.ReadResultData(h.data[off:end]), 0
+}
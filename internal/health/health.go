@@ -0,0 +1,153 @@
+// Package health reports the liveness and readiness of a process from checks of the components it depends on.
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/stream"
+)
+
+// CheckFunc checks a component, returning an error when it is not usable.
+type CheckFunc func(ctx context.Context) error
+
+type StatusEnum string
+
+const (
+	OKStatus   StatusEnum = "OK"
+	FAILStatus StatusEnum = "FAIL"
+)
+
+// ComponentReport is the result of the check of a component.
+type ComponentReport struct {
+	Name     string     `json:"name"`
+	Status   StatusEnum `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Duration string     `json:"duration"`
+}
+
+// Report is the readiness of a process, with the detail of every component.
+type Report struct {
+	Ready        bool              `json:"ready"`
+	ShuttingDown bool              `json:"shuttingDown"`
+	Components   []ComponentReport `json:"components"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the registered checks concurrently, each within the timeout of the checker.
+type Checker struct {
+	timeout      time.Duration
+	mu           sync.Mutex
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// Add registers the check of a component. Components are reported in the order they are added.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Shutdown makes the process not ready, so load balancers stop routing to it while it drains.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready checks all components. The process is ready when all of them are ok and it is not shutting down.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.Unlock()
+	res := Report{
+		ShuttingDown: c.shuttingDown.Load(),
+		Components:   make([]ComponentReport, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res.Components[i] = c.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+	res.Ready = !res.ShuttingDown
+	for _, r := range res.Components {
+		if r.Status != OKStatus {
+			res.Ready = false
+		}
+	}
+	return res
+}
+
+// run runs a check, turning panics and timeouts into failures.
+func (c *Checker) run(ctx context.Context, chk check) ComponentReport {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	errC := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errC <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		errC <- chk.fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errC:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := ComponentReport{Name: chk.name, Status: OKStatus, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		res.Status, res.Error = FAILStatus, err.Error()
+	}
+	return res
+}
+
+// NewChecker creates a checker giving each check at most timeout to complete.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// WorkersCheck requires at least min workers of the pool to be ready (not waiting for a flood wait).
+func WorkersCheck(wp stream.IWorkerPool, min int) CheckFunc {
+	return func(ctx context.Context) error {
+		ready := 0
+		workers := wp.Traffic()
+		for _, w := range workers {
+			if w.State == stream.READYWorkerState {
+				ready++
+			}
+		}
+		if ready < min {
+			return fmt.Errorf("%d of %d workers ready, %d required", ready, len(workers), min)
+		}
+		return nil
+	}
+}
+
+// MountCheck requires the filesystem to be mounted and dir to be served. A fuse mount whose server is gone fails
+// with "transport endpoint is not connected".
+func MountCheck(dir string, mounted func() bool) CheckFunc {
+	return func(ctx context.Context) error {
+		if !mounted() {
+			return fmt.Errorf("%s is not mounted", dir)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		return nil
+	}
+}
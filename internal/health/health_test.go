@@ -0,0 +1,115 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/health"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	mockStream "github.com/amirdaaee/TGMon/mocks/stream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	gomock "go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Checker", func() {
+	var checker *health.Checker
+	ok := func(ctx context.Context) error { return nil }
+	BeforeEach(func() {
+		checker = health.NewChecker(50 * time.Millisecond)
+	})
+	It("should be ready when all components are ok", func() {
+		checker.Add("a", ok)
+		checker.Add("b", ok)
+		res := checker.Ready(context.Background())
+		Expect(res.Ready).To(BeTrue())
+		Expect(res.StatusCode()).To(Equal(http.StatusOK))
+		Expect(res.Components).To(HaveLen(2))
+		Expect(res.Components[0].Name).To(Equal("a"))
+		Expect(res.Components[1].Status).To(Equal(health.OKStatus))
+	})
+	It("should report the failing component", func() {
+		checker.Add("a", ok)
+		checker.Add("b", func(ctx context.Context) error { return errors.New("mock err") })
+		res := checker.Ready(context.Background())
+		Expect(res.Ready).To(BeFalse())
+		Expect(res.StatusCode()).To(Equal(http.StatusServiceUnavailable))
+		Expect(res.Components[0].Status).To(Equal(health.OKStatus))
+		Expect(res.Components[1].Status).To(Equal(health.FAILStatus))
+		Expect(res.Components[1].Error).To(Equal("mock err"))
+	})
+	It("should fail checks that time out", func() {
+		checker.Add("slow", func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+		res := checker.Ready(context.Background())
+		Expect(res.Ready).To(BeFalse())
+		Expect(res.Components[0].Error).To(ContainSubstring("deadline exceeded"))
+	})
+	It("should fail checks that panic", func() {
+		checker.Add("panic", func(ctx context.Context) error { panic("mock panic") })
+		res := checker.Ready(context.Background())
+		Expect(res.Ready).To(BeFalse())
+		Expect(res.Components[0].Error).To(ContainSubstring("mock panic"))
+	})
+	It("should not be ready once shutting down", func() {
+		checker.Add("a", ok)
+		checker.Shutdown()
+		res := checker.Ready(context.Background())
+		Expect(res.Ready).To(BeFalse())
+		Expect(res.ShuttingDown).To(BeTrue())
+		Expect(res.Components[0].Status).To(Equal(health.OKStatus))
+	})
+	It("should serve the probes", func() {
+		checker.Add("a", func(ctx context.Context) error { return errors.New("mock err") })
+		h := health.NewHandler(checker)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		var res health.Report
+		Expect(json.Unmarshal(rec.Body.Bytes(), &res)).To(Succeed())
+		Expect(res.Components[0].Error).To(Equal("mock err"))
+	})
+})
+
+var _ = Describe("WorkersCheck", func() {
+	var (
+		ctrl     *gomock.Controller
+		mockPool *mockStream.MockIWorkerPool
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockPool = mockStream.NewMockIWorkerPool(ctrl)
+	})
+	workers := []stream.WorkerTraffic{
+		{Worker: 1, State: stream.READYWorkerState},
+		{Worker: 2, State: stream.FLOODWAITWorkerState},
+		{Worker: 3, State: stream.READYWorkerState},
+	}
+	It("should pass with enough ready workers", func() {
+		mockPool.EXPECT().Traffic().Return(workers)
+		Expect(health.WorkersCheck(mockPool, 2)(context.Background())).To(Succeed())
+	})
+	It("should not count workers in flood wait", func() {
+		mockPool.EXPECT().Traffic().Return(workers)
+		err := health.WorkersCheck(mockPool, 3)(context.Background())
+		Expect(err).To(MatchError("2 of 3 workers ready, 3 required"))
+	})
+})
+
+var _ = Describe("MountCheck", func() {
+	It("should fail when not mounted", func() {
+		Expect(health.MountCheck(GinkgoT().TempDir(), func() bool { return false })(context.Background())).NotTo(Succeed())
+	})
+	It("should pass when the mount is served", func() {
+		Expect(health.MountCheck(GinkgoT().TempDir(), func() bool { return true })(context.Background())).To(Succeed())
+	})
+})
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LiveResType is the body of liveness responses.
+type LiveResType struct {
+	Status StatusEnum `json:"status"`
+}
+
+// NewHandler serves /healthz and /readyz, for processes without a web server of their own.
+func NewHandler(c *Checker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, LiveResType{Status: OKStatus})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		res := c.Ready(r.Context())
+		writeJSON(w, res.StatusCode(), res)
+	})
+	return mux
+}
+
+// StatusCode is the http status of readiness responses: 503 when not ready.
+func (r Report) StatusCode() int {
+	if r.Ready {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
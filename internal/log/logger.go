@@ -7,13 +7,19 @@ import (
 type LogModule string
 
 const (
-	DBModule     LogModule = "db"
-	FacadeModule LogModule = "facade"
-	TlgModule    LogModule = "tlg"
-	BotModule    LogModule = "bot"
-	StreamModule LogModule = "stream"
-	WebModule    LogModule = "web"
-	FuseModule   LogModule = "fuse"
+	DBModule        LogModule = "db"
+	FacadeModule    LogModule = "facade"
+	TlgModule       LogModule = "tlg"
+	BotModule       LogModule = "bot"
+	StreamModule    LogModule = "stream"
+	WebModule       LogModule = "web"
+	FuseModule      LogModule = "fuse"
+	AuthModule      LogModule = "auth"
+	EventModule     LogModule = "event"
+	DLNAModule      LogModule = "dlna"
+	S3Module        LogModule = "s3"
+	ReconcileModule LogModule = "reconcile"
+	StashModule     LogModule = "stash"
 )
 
 func GetLogger(module LogModule) *logrus.Entry {
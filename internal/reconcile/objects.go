@@ -0,0 +1,196 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// QuarantinePrefix is where orphan objects are moved to when quarantined. Objects under it are not cross-referenced
+// with the media; they are purged once they have been quarantined for the grace period (moving an object resets its
+// modification time).
+const QuarantinePrefix = "quarantine/"
+
+type ObjectIssueEnum string
+
+const (
+	ORPHANObjectIssue  ObjectIssueEnum = "ORPHAN"  // object no media references
+	MISSINGObjectIssue ObjectIssueEnum = "MISSING" // media references an object that does not exist
+	EXPIREDObjectIssue ObjectIssueEnum = "EXPIRED" // object quarantined for longer than the grace period
+)
+
+type GCActionEnum string
+
+const (
+	REPORTGCAction     GCActionEnum = "REPORT"     // only report orphan and expired objects
+	QUARANTINEGCAction GCActionEnum = "QUARANTINE" // move orphan objects under QuarantinePrefix, delete expired ones
+	DELETEGCAction     GCActionEnum = "DELETE"     // delete orphan and expired objects
+)
+
+// ObjectIssue is a difference between the bucket and the media.
+type ObjectIssue struct {
+	Kind         ObjectIssueEnum
+	Object       string
+	Size         int64          `json:",omitempty"`
+	LastModified *time.Time     `json:",omitempty"`
+	MediaID      *bson.ObjectID `json:",omitempty"` // media referencing a missing object
+	Field        string         `json:",omitempty"` // field of the media referencing a missing object
+	Fixed        bool           // the orphan object was quarantined or deleted, or the expired one deleted
+	Error        string         `json:",omitempty"`
+}
+
+// GCReport is the outcome of a garbage collection.
+type GCReport struct {
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Action      GCActionEnum
+	Objects     int   // objects in the bucket, besides quarantined ones
+	Referenced  int   // objects referenced by media
+	Recent      int   // orphan objects kept as they are younger than the grace period
+	Quarantined int64 // bytes of the orphan objects quarantined, still taking space until they expire
+	Reclaimed   int64 // bytes of the orphan and expired objects deleted
+	Issues      []ObjectIssue
+}
+
+// Count returns the number of issues of a kind.
+func (r *GCReport) Count(kind ObjectIssueEnum) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// GCOptions controls a garbage collection.
+type GCOptions struct {
+	Action GCActionEnum
+	// GracePeriod keeps orphan objects modified more recently, as their media may not reference them yet (job results
+	// and thumbnails are stored before the media is updated).
+	GracePeriod time.Duration
+}
+
+// ObjectCollector finds the objects of the bucket no media references (e.g. thumbnails replaced by the ones of
+// thumbnail jobs, or files left by failed hooks), and the media referencing objects that do not exist.
+type ObjectCollector struct {
+	mediaFacade facade.IFacade[types.MediaFileDoc]
+	minioClient minio.IMinioClient
+}
+
+// Run lists the bucket, cross-references it with the thumbnail, sprite, vtt and subtitles of every media, and applies the action
+// of opts to the orphan objects. Quarantined objects past the grace period are deleted unless the action is
+// REPORTGCAction. Missing objects are only reported.
+func (c *ObjectCollector) Run(ctx context.Context, opts GCOptions) (*GCReport, error) {
+	ll := c.getLogger("Run")
+	if opts.Action == "" {
+		opts.Action = REPORTGCAction
+	}
+	switch opts.Action {
+	case REPORTGCAction, QUARANTINEGCAction, DELETEGCAction:
+	default:
+		return nil, fmt.Errorf("unknown action (%s)", opts.Action)
+	}
+	report := &GCReport{StartedAt: time.Now(), Action: opts.Action, Issues: []ObjectIssue{}}
+	// list the bucket first: objects added after the listing are not collected even without a grace period
+	objects, err := c.minioClient.FileList(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	media, err := c.mediaFacade.GetCollection().Finder().Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not list media: %w", err)
+	}
+	existing := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		existing[obj.Key] = true
+	}
+	referenced := map[string]bool{}
+	for _, m := range media {
+		refs := []struct{ field, name string }{
+			{types.MediaFileDoc__ThumbnailField, m.Thumbnail},
+			{types.MediaFileDoc__SpriteField, m.Sprite},
+			{types.MediaFileDoc__VttField, m.Vtt},
+		}
+		for _, sub := range m.Subtitles {
+			refs = append(refs, struct{ field, name string }{types.MediaFileDoc__SubtitlesField, sub.File})
+		}
+		for _, ref := range refs {
+			if ref.name == "" {
+				continue
+			}
+			referenced[ref.name] = true
+			if !existing[ref.name] {
+				report.Issues = append(report.Issues, ObjectIssue{Kind: MISSINGObjectIssue, Object: ref.name, MediaID: &m.ID, Field: ref.field})
+			}
+		}
+	}
+	for _, obj := range objects {
+		if strings.HasPrefix(obj.Key, QuarantinePrefix) {
+			if time.Since(obj.LastModified) < opts.GracePeriod {
+				continue
+			}
+			issue := ObjectIssue{Kind: EXPIREDObjectIssue, Object: obj.Key, Size: obj.Size, LastModified: &obj.LastModified}
+			if opts.Action != REPORTGCAction {
+				if err := c.minioClient.FileRm(ctx, obj.Key); err != nil {
+					issue.Error = err.Error()
+				} else {
+					issue.Fixed = true
+					report.Reclaimed += obj.Size
+				}
+			}
+			report.Issues = append(report.Issues, issue)
+			continue
+		}
+		report.Objects++
+		if referenced[obj.Key] {
+			report.Referenced++
+			continue
+		}
+		if time.Since(obj.LastModified) < opts.GracePeriod {
+			report.Recent++
+			continue
+		}
+		issue := ObjectIssue{Kind: ORPHANObjectIssue, Object: obj.Key, Size: obj.Size, LastModified: &obj.LastModified}
+		if err := c.collect(ctx, obj.Key, opts.Action); err != nil {
+			issue.Error = err.Error()
+		} else if opts.Action == QUARANTINEGCAction {
+			issue.Fixed = true
+			report.Quarantined += obj.Size
+		} else if opts.Action == DELETEGCAction {
+			issue.Fixed = true
+			report.Reclaimed += obj.Size
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	report.FinishedAt = time.Now()
+	ll.Infof("%d objects, %d orphan, %d missing, %d expired (%s)", report.Objects, report.Count(ORPHANObjectIssue), report.Count(MISSINGObjectIssue), report.Count(EXPIREDObjectIssue), opts.Action)
+	return report, nil
+}
+
+// collect applies the action to an orphan object.
+func (c *ObjectCollector) collect(ctx context.Context, name string, action GCActionEnum) error {
+	switch action {
+	case QUARANTINEGCAction:
+		return c.minioClient.FileMove(ctx, name, QuarantinePrefix+name)
+	case DELETEGCAction:
+		return c.minioClient.FileRm(ctx, name)
+	}
+	return nil
+}
+func (c *ObjectCollector) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.ReconcileModule).WithField("func", fmt.Sprintf("%T.%s", c, fn))
+}
+
+// NewObjectCollector creates a collector of the objects of minioClient not referenced by the media of mediaFacade.
+func NewObjectCollector(mediaFacade facade.IFacade[types.MediaFileDoc], minioClient minio.IMinioClient) *ObjectCollector {
+	return &ObjectCollector{mediaFacade: mediaFacade, minioClient: minioClient}
+}
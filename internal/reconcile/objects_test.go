@@ -0,0 +1,104 @@
+package reconcile_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/reconcile"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMinio "github.com/amirdaaee/TGMon/mocks/db/minio"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/minio/minio-go/v7"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("ObjectCollector", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		mockFinder   *mMongoX.MockIFinder[types.MediaFileDoc]
+		mockMinio    *mMinio.MockIMinioClient
+		testContext  context.Context
+		media        *types.MediaFileDoc
+		old          time.Time
+	)
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockFinder = mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mockColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mockColl.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mockMediaFac.EXPECT().GetCollection().Return(mockColl).AnyTimes()
+		mockMinio = mMinio.NewMockIMinioClient(ctrl)
+		media = &types.MediaFileDoc{Thumbnail: "thumb.jpg", Sprite: "sprite.jpg", Vtt: "sprite.vtt"}
+		media.ID = bson.NewObjectID()
+		old = time.Now().Add(-48 * time.Hour)
+	})
+	setupObjects := func() {
+		mockMinio.EXPECT().FileList(testContext, "").Return([]minio.ObjectInfo{
+			{Key: "thumb.jpg", Size: 1, LastModified: old},
+			{Key: "sprite.jpg", Size: 2, LastModified: old},
+			{Key: "orphan.jpg", Size: 3, LastModified: old},
+			{Key: "recent.jpg", Size: 4, LastModified: time.Now()},
+			{Key: reconcile.QuarantinePrefix + "old.jpg", Size: 5, LastModified: old},
+			{Key: reconcile.QuarantinePrefix + "new.jpg", Size: 6, LastModified: time.Now()},
+		}, nil)
+		mockFinder.EXPECT().Find(testContext).Return([]*types.MediaFileDoc{media}, nil)
+	}
+	Describe("Run", func() {
+		It("should report orphan and missing objects", func() {
+			setupObjects()
+			report, err := reconcile.NewObjectCollector(mockMediaFac, mockMinio).Run(testContext, reconcile.GCOptions{GracePeriod: time.Hour})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Action).To(Equal(reconcile.REPORTGCAction))
+			Expect(report.Objects).To(Equal(4))
+			Expect(report.Referenced).To(Equal(2))
+			Expect(report.Recent).To(Equal(1))
+			Expect(report.Quarantined).To(BeZero())
+			Expect(report.Reclaimed).To(BeZero())
+			Expect(report.Issues).To(Equal([]reconcile.ObjectIssue{
+				{Kind: reconcile.MISSINGObjectIssue, Object: "sprite.vtt", MediaID: &media.ID, Field: types.MediaFileDoc__VttField},
+				{Kind: reconcile.ORPHANObjectIssue, Object: "orphan.jpg", Size: 3, LastModified: &old},
+				{Kind: reconcile.EXPIREDObjectIssue, Object: reconcile.QuarantinePrefix + "old.jpg", Size: 5, LastModified: &old},
+			}))
+		})
+		It("should quarantine orphan objects and delete expired ones", func() {
+			setupObjects()
+			mockMinio.EXPECT().FileMove(testContext, "orphan.jpg", reconcile.QuarantinePrefix+"orphan.jpg").Return(nil)
+			mockMinio.EXPECT().FileRm(testContext, reconcile.QuarantinePrefix+"old.jpg").Return(nil)
+			report, err := reconcile.NewObjectCollector(mockMediaFac, mockMinio).Run(testContext, reconcile.GCOptions{Action: reconcile.QUARANTINEGCAction, GracePeriod: time.Hour})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Count(reconcile.ORPHANObjectIssue)).To(Equal(1))
+			Expect(report.Issues[1].Fixed).To(BeTrue())
+			Expect(report.Issues[2].Fixed).To(BeTrue())
+			Expect(report.Quarantined).To(Equal(int64(3)))
+			Expect(report.Reclaimed).To(Equal(int64(5)))
+		})
+		It("should report failures to delete orphan objects", func() {
+			setupObjects()
+			mockMinio.EXPECT().FileRm(testContext, "orphan.jpg").Return(fmt.Errorf("mock err"))
+			mockMinio.EXPECT().FileRm(testContext, reconcile.QuarantinePrefix+"old.jpg").Return(nil)
+			report, err := reconcile.NewObjectCollector(mockMediaFac, mockMinio).Run(testContext, reconcile.GCOptions{Action: reconcile.DELETEGCAction, GracePeriod: time.Hour})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Issues[1].Fixed).To(BeFalse())
+			Expect(report.Issues[1].Error).To(Equal("mock err"))
+			Expect(report.Reclaimed).To(Equal(int64(5)))
+		})
+		It("should fail on listing error", func() {
+			mockMinio.EXPECT().FileList(testContext, "").Return(nil, fmt.Errorf("mock err"))
+			_, err := reconcile.NewObjectCollector(mockMediaFac, mockMinio).Run(testContext, reconcile.GCOptions{})
+			Expect(err).To(HaveOccurred())
+		})
+		It("should reject unknown actions", func() {
+			_, err := reconcile.NewObjectCollector(mockMediaFac, mockMinio).Run(testContext, reconcile.GCOptions{Action: "mock"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
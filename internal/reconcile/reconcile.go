@@ -0,0 +1,243 @@
+// Package reconcile compares the media in mongo with the documents of the storage channel and with the objects of the
+// minio bucket, reporting (and optionally fixing) the differences.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gotd/td/tg"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/time/rate"
+)
+
+// batchSize is the number of messages requested at once, the most telegram allows.
+const batchSize = 100
+
+type IssueEnum string
+
+const (
+	MISSINGMEDIAIssue IssueEnum = "MISSING_MEDIA" // document message without a media
+	MESSAGEGONEIssue  IssueEnum = "MESSAGE_GONE"  // media whose message was deleted or holds no document anymore
+	FILECHANGEDIssue  IssueEnum = "FILE_CHANGED"  // media whose message now holds a different file
+)
+
+// Issue is a difference between the channel and mongo.
+type Issue struct {
+	Kind      IssueEnum
+	MessageID int
+	MediaID   *bson.ObjectID `json:",omitempty"`
+	FileID    int64          // file of the message, or of the media when the message is gone
+	FileName  string
+	Fixed     bool
+	Error     string `json:",omitempty"` // why fixing failed
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	From       int // first message scanned
+	To         int // last message scanned
+	Messages   int // existing messages scanned
+	Documents  int // document messages scanned
+	Media      int // media checked
+	Issues     []Issue
+}
+
+// Count returns the number of issues of a kind.
+func (r *Report) Count(kind IssueEnum) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Options controls a run.
+type Options struct {
+	// From is the first message to scan. Zero starts at the first message of the channel.
+	From int
+	// To is the last message to scan. Zero scans up to the last media, then until MaxGap messages in a row do not
+	// exist.
+	To int
+	// MaxGap is the number of missing messages in a row after the last media that ends the scan.
+	MaxGap int
+	// Fix creates the media of document messages without one.
+	Fix bool
+	// Prune deletes the media whose message is gone.
+	Prune bool
+	// Limiter throttles the requests to telegram. Nil does not throttle them.
+	Limiter *rate.Limiter
+}
+
+// Reconciler walks the history of the storage channel through the workers and compares it with the media.
+//
+// Bots can not call messages.getHistory, so the history is walked by message IDs with channels.getMessages,
+// batchSize messages at a time.
+type Reconciler struct {
+	mediaFacade facade.IFacade[types.MediaFileDoc]
+	wp          stream.IWorkerPool
+}
+
+// Run scans the channel and returns the report of the differences, fixing them as opts allows. Media whose file
+// changed are only reported, as their thumbnails and sprites have to be regenerated.
+func (r *Reconciler) Run(ctx context.Context, opts Options) (*Report, error) {
+	ll := r.getLogger("Run")
+	report := &Report{StartedAt: time.Now(), Issues: []Issue{}}
+	media, err := r.mediaFacade.GetCollection().Finder().Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not list media: %w", err)
+	}
+	byMessage := map[int][]*types.MediaFileDoc{}
+	fileIDs := map[int64]bool{}
+	lastMedia := 0
+	for _, m := range media {
+		byMessage[m.MessageID] = append(byMessage[m.MessageID], m)
+		fileIDs[m.Meta.FileID] = true
+		lastMedia = max(lastMedia, m.MessageID)
+	}
+	from := max(opts.From, 1)
+	report.From = from
+	gap := 0
+	for start := from; ; start += batchSize {
+		end := start + batchSize - 1
+		if opts.To > 0 {
+			end = min(end, opts.To)
+		}
+		if end < start {
+			break
+		}
+		docs, err := r.getDocs(ctx, start, end, opts.Limiter)
+		if err != nil {
+			return nil, fmt.Errorf("can not get messages %d-%d: %w", start, end, err)
+		}
+		for id := start; id <= end; id++ {
+			doc, exists := docs[id]
+			if exists {
+				gap = 0
+				report.Messages++
+			} else {
+				gap++
+			}
+			if doc != nil {
+				report.Documents++
+			}
+			report.Media += len(byMessage[id])
+			report.Issues = append(report.Issues, r.check(ctx, id, doc, byMessage[id], fileIDs, opts)...)
+		}
+		report.To = end
+		if opts.To > 0 && end >= opts.To {
+			break
+		}
+		if opts.To == 0 && end >= lastMedia && gap >= opts.MaxGap {
+			break
+		}
+	}
+	report.FinishedAt = time.Now()
+	ll.Infof("scanned messages %d-%d: %d documents, %d media, %d issues", report.From, report.To, report.Documents, report.Media, len(report.Issues))
+	return report, nil
+}
+
+// check compares a message with its media, fixing the differences as opts allows.
+func (r *Reconciler) check(ctx context.Context, msgID int, doc *tg.Document, media []*types.MediaFileDoc, fileIDs map[int64]bool, opts Options) []Issue {
+	issues := []Issue{}
+	if len(media) == 0 {
+		// a file posted twice is indexed once, by its first message
+		if doc == nil || fileIDs[doc.ID] {
+			return issues
+		}
+		issue := Issue{Kind: MISSINGMEDIAIssue, MessageID: msgID, FileID: doc.ID, FileName: fileName(doc)}
+		if opts.Fix {
+			if created, err := r.createMedia(ctx, msgID, doc); err != nil {
+				issue.Error = err.Error()
+			} else {
+				issue.MediaID, issue.Fixed = &created.ID, true
+				fileIDs[doc.ID] = true
+			}
+		}
+		return append(issues, issue)
+	}
+	for _, m := range media {
+		issue := Issue{MessageID: msgID, MediaID: &m.ID}
+		switch {
+		case doc == nil:
+			issue.Kind, issue.FileID, issue.FileName = MESSAGEGONEIssue, m.Meta.FileID, m.Meta.FileName
+			if opts.Prune {
+				if _, err := r.mediaFacade.DeleteOne(ctx, query.Id(m.ID)); err != nil {
+					issue.Error = err.Error()
+				} else {
+					issue.Fixed = true
+				}
+			}
+		case doc.ID != m.Meta.FileID:
+			issue.Kind, issue.FileID, issue.FileName = FILECHANGEDIssue, doc.ID, fileName(doc)
+		default:
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// getDocs returns the documents of the messages start to end, trying every worker before failing.
+func (r *Reconciler) getDocs(ctx context.Context, start int, end int, limiter *rate.Limiter) (map[int]*tg.Document, error) {
+	ids := make([]int, 0, end-start+1)
+	for id := start; id <= end; id++ {
+		ids = append(ids, id)
+	}
+	var errs []error
+	for range max(len(r.wp.Traffic()), 1) {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		worker := r.wp.GetNextWorker()
+		if worker == nil {
+			return nil, stream.ErrNoWorker
+		}
+		docs, err := worker.GetChannelDocs(ctx, ids)
+		if err == nil {
+			return docs, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// createMedia creates the media of a document message, like the bot does for forwarded messages.
+func (r *Reconciler) createMedia(ctx context.Context, msgID int, doc *tg.Document) (*types.MediaFileDoc, error) {
+	media, err := types.NewMediaFileDoc(doc, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("can not fill document meta: %w", err)
+	}
+	return r.mediaFacade.CreateOne(ctx, &media)
+}
+func (r *Reconciler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.ReconcileModule).WithField("func", fmt.Sprintf("%T.%s", r, fn))
+}
+
+// fileName returns the file name of a document.
+func fileName(doc *tg.Document) string {
+	meta := types.MediaFileMeta{}
+	if err := meta.FillFromDocument(doc); err != nil {
+		return ""
+	}
+	return meta.FileName
+}
+
+// NewReconciler creates a reconciler of the media of mediaFacade with the channel of the workers of wp.
+func NewReconciler(mediaFacade facade.IFacade[types.MediaFileDoc], wp stream.IWorkerPool) *Reconciler {
+	return &Reconciler{mediaFacade: mediaFacade, wp: wp}
+}
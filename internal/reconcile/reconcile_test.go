@@ -0,0 +1,113 @@
+package reconcile_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amirdaaee/TGMon/internal/reconcile"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mStream "github.com/amirdaaee/TGMon/mocks/stream"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/gotd/td/tg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		ctrl         *gomock.Controller
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		mockFinder   *mMongoX.MockIFinder[types.MediaFileDoc]
+		mockWP       *mStream.MockIWorkerPool
+		mockWorker   *mStream.MockIWorker
+		testContext  context.Context
+		media        []*types.MediaFileDoc
+	)
+	newMedia := func(msgID int, fileID int64) *types.MediaFileDoc {
+		m := &types.MediaFileDoc{MessageID: msgID, Meta: types.MediaFileMeta{FileID: fileID}}
+		m.ID = bson.NewObjectID()
+		return m
+	}
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext = context.Background()
+		mockFinder = mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mockColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mockColl.EXPECT().Finder().Return(mockFinder).AnyTimes()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mockMediaFac.EXPECT().GetCollection().Return(mockColl).AnyTimes()
+		mockWorker = mStream.NewMockIWorker(ctrl)
+		mockWP = mStream.NewMockIWorkerPool(ctrl)
+		mockWP.EXPECT().GetNextWorker().Return(mockWorker).AnyTimes()
+		mockWP.EXPECT().Traffic().Return([]stream.WorkerTraffic{{Worker: 1}, {Worker: 2}}).AnyTimes()
+		// message 1: indexed, 2: gone, 3: file changed, 4: not indexed, 5: not indexed duplicate of 1
+		media = []*types.MediaFileDoc{newMedia(1, 10), newMedia(2, 20), newMedia(3, 30)}
+		mockFinder.EXPECT().Find(testContext).Return(media, nil)
+	})
+	channel := func(ids []int) map[int]*tg.Document {
+		res := map[int]*tg.Document{}
+		for _, id := range ids {
+			switch id {
+			case 1, 5:
+				res[id] = &tg.Document{ID: 10}
+			case 3:
+				res[id] = &tg.Document{ID: 31}
+			case 4:
+				res[id] = &tg.Document{ID: 40, Attributes: []tg.DocumentAttributeClass{&tg.DocumentAttributeFilename{FileName: "mock.mkv"}}}
+			case 6:
+				res[id] = nil
+			}
+		}
+		return res
+	}
+	Describe("Run", func() {
+		It("should report the differences until the end of the channel", func() {
+			mockWorker.EXPECT().GetChannelDocs(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, ids []int) (map[int]*tg.Document, error) {
+				return channel(ids), nil
+			}).Times(2)
+			report, err := reconcile.NewReconciler(mockMediaFac, mockWP).Run(testContext, reconcile.Options{MaxGap: 150})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.From).To(Equal(1))
+			Expect(report.To).To(Equal(200))
+			Expect(report.Messages).To(Equal(5))
+			Expect(report.Documents).To(Equal(4))
+			Expect(report.Media).To(Equal(3))
+			Expect(report.Issues).To(Equal([]reconcile.Issue{
+				{Kind: reconcile.MESSAGEGONEIssue, MessageID: 2, MediaID: &media[1].ID, FileID: 20},
+				{Kind: reconcile.FILECHANGEDIssue, MessageID: 3, MediaID: &media[2].ID, FileID: 31},
+				{Kind: reconcile.MISSINGMEDIAIssue, MessageID: 4, FileID: 40, FileName: "mock.mkv"},
+			}))
+		})
+		It("should fix and prune the differences", func() {
+			mockWorker.EXPECT().GetChannelDocs(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, ids []int) (map[int]*tg.Document, error) {
+				Expect(ids).To(HaveLen(5))
+				return channel(ids), nil
+			})
+			mockMediaFac.EXPECT().DeleteOne(testContext, gomock.Any()).Return(media[1], nil)
+			createdID := bson.NewObjectID()
+			mockMediaFac.EXPECT().CreateOne(testContext, gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.MediaFileDoc) (*types.MediaFileDoc, error) {
+				Expect(doc.MessageID).To(Equal(4))
+				Expect(doc.Meta.FileID).To(Equal(int64(40)))
+				doc.ID = createdID
+				return doc, nil
+			})
+			report, err := reconcile.NewReconciler(mockMediaFac, mockWP).Run(testContext, reconcile.Options{To: 5, Fix: true, Prune: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.To).To(Equal(5))
+			Expect(report.Issues).To(HaveLen(3))
+			Expect(report.Issues[0].Fixed).To(BeTrue())
+			Expect(report.Issues[1].Fixed).To(BeFalse())
+			Expect(report.Issues[2]).To(Equal(reconcile.Issue{Kind: reconcile.MISSINGMEDIAIssue, MessageID: 4, MediaID: &createdID, FileID: 40, FileName: "mock.mkv", Fixed: true}))
+		})
+		It("should try the other workers before failing", func() {
+			mockWorker.EXPECT().GetChannelDocs(testContext, gomock.Any()).Return(nil, fmt.Errorf("mock error")).Times(2)
+			_, err := reconcile.NewReconciler(mockMediaFac, mockWP).Run(testContext, reconcile.Options{To: 5})
+			Expect(err).To(MatchError(ContainSubstring("mock error")))
+		})
+	})
+})
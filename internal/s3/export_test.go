@@ -0,0 +1,8 @@
+package s3
+
+import "time"
+
+// SetNow replaces the clock the gateway checks request dates against.
+func SetNow(gw *Gateway, now func() time.Time) {
+	gw.now = now
+}
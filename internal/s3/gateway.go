@@ -0,0 +1,346 @@
+// Package s3 provides a read-only S3 compatible gateway exposing the media library as a single bucket.
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/webdav"
+)
+
+// maxListKeys is the largest page of a listing, as in s3.
+const maxListKeys = 1000
+
+// Config holds the settings of the gateway.
+type Config struct {
+	// Bucket is the name of the virtual bucket holding the media files.
+	Bucket string
+	// Region is reported by GetBucketLocation. Requests signed for any region are accepted.
+	Region string
+}
+
+// Gateway serves the s3 api over a read-only filesystem, e.g. filesystem.DavFS, so object keys match the
+// FUSE and WebDAV paths. Requests are path-style and authenticated with SigV4 against api keys
+// having the stream scope.
+type Gateway struct {
+	cfg           Config
+	fs            webdav.FileSystem
+	authenticator auth.IAuthenticator
+	now           func() time.Time
+}
+
+var _ http.Handler = (*Gateway)(nil)
+
+// ServeHTTP authenticates the request and dispatches it to the s3 operation it calls.
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := gw.authenticate(r); err != nil {
+		gw.writeError(w, r, err)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		gw.writeError(w, r, errMethodNotAllowed)
+		return
+	}
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case bucketName == "":
+		gw.listBuckets(w, r)
+	case bucketName != gw.cfg.Bucket:
+		gw.writeError(w, r, errNoSuchBucket)
+	case key != "":
+		gw.getObject(w, r, key)
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Query().Has("location"):
+		gw.writeXML(w, http.StatusOK, locationConstraint{Xmlns: s3Namespace, Region: gw.cfg.Region})
+	default:
+		gw.listObjects(w, r)
+	}
+}
+
+// authenticate verifies the SigV4 signature of the request and the scope of its api key.
+func (gw *Gateway) authenticate(r *http.Request) (*auth.Principal, *apiError) {
+	ll := gw.getLogger("authenticate")
+	sig, apiErr := parseSigV4(r)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if apiErr := sig.checkTime(gw.now()); apiErr != nil {
+		return nil, apiErr
+	}
+	p, secret, err := gw.authenticator.AuthenticateS3(r.Context(), sig.accessKeyID)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, errInvalidAccessKey
+		}
+		if errors.Is(err, auth.ErrApiKeyExpired) {
+			return nil, errAccessDenied.withMessage("the access key has expired")
+		}
+		ll.WithError(err).Error("can not authenticate request")
+		return nil, errInternal
+	}
+	if apiErr := sig.verify(r, secret); apiErr != nil {
+		return nil, apiErr
+	}
+	if p.ApiKey == nil || !p.ApiKey.HasScope(types.STREAMApiKeyScope) {
+		return nil, errAccessDenied.withMessage("the access key lacks the %s scope", types.STREAMApiKeyScope)
+	}
+	return p, nil
+}
+
+// listBuckets implements ListBuckets; there is only the media bucket.
+func (gw *Gateway) listBuckets(w http.ResponseWriter, r *http.Request) {
+	info, err := gw.fs.Stat(r.Context(), "/")
+	if err != nil {
+		gw.writeFsError(w, r, err)
+		return
+	}
+	gw.writeXML(w, http.StatusOK, listAllMyBucketsResult{
+		Xmlns:   s3Namespace,
+		Owner:   owner{ID: "tgmon", DisplayName: "tgmon"},
+		Buckets: []bucket{{Name: gw.cfg.Bucket, CreationDate: formatTime(info.ModTime())}},
+	})
+}
+
+// listObjects implements ListObjectsV2 (list-type=2) and ListObjects, with prefix, delimiter and pagination.
+func (gw *Gateway) listObjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v2 := q.Get("list-type") == "2"
+	res := listBucketResult{
+		Xmlns:     s3Namespace,
+		Name:      gw.cfg.Bucket,
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		MaxKeys:   maxListKeys,
+	}
+	if v := q.Get("max-keys"); v != "" {
+		maxKeys, err := strconv.Atoi(v)
+		if err != nil || maxKeys < 0 {
+			gw.writeError(w, r, errInvalidArgument.withMessage("invalid max-keys %q", v))
+			return
+		}
+		res.MaxKeys = min(maxKeys, maxListKeys)
+	}
+	// keys are listed after marker, which is the start-after key or the key a continuation token ends at
+	var marker string
+	if v2 {
+		res.StartAfter = q.Get("start-after")
+		res.ContinuationToken = q.Get("continuation-token")
+		marker = res.StartAfter
+		if res.ContinuationToken != "" {
+			b, err := base64.RawURLEncoding.DecodeString(res.ContinuationToken)
+			if err != nil {
+				gw.writeError(w, r, errInvalidArgument.withMessage("invalid continuation token"))
+				return
+			}
+			marker = string(b)
+		}
+	} else {
+		marker = q.Get("marker")
+		res.Marker = &marker
+	}
+	entries, err := gw.readFiles(r.Context(), res.Prefix, res.Delimiter)
+	if err != nil {
+		gw.writeFsError(w, r, err)
+		return
+	}
+	seenPrefixes := map[string]bool{}
+	last := ""
+	for _, e := range entries {
+		key, fi := e.key, e.fi
+		if !strings.HasPrefix(key, res.Prefix) || key <= marker {
+			continue
+		}
+		// keys containing the delimiter after the prefix are grouped under a common prefix, which is listed once;
+		// a page ending at a common prefix skips its keys on the next page, as they are not after the marker
+		groupPrefix := ""
+		if res.Delimiter != "" {
+			if i := strings.Index(key[len(res.Prefix):], res.Delimiter); i >= 0 {
+				groupPrefix = key[:len(res.Prefix)+i+len(res.Delimiter)]
+				if seenPrefixes[groupPrefix] || groupPrefix <= marker {
+					continue
+				}
+			}
+		}
+		if len(res.Contents)+len(res.CommonPrefixes) >= res.MaxKeys {
+			res.IsTruncated = true
+			break
+		}
+		if groupPrefix != "" {
+			seenPrefixes[groupPrefix] = true
+			res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{Prefix: groupPrefix})
+			last = groupPrefix
+			continue
+		}
+		res.Contents = append(res.Contents, object{
+			Key:          key,
+			LastModified: formatTime(fi.ModTime()),
+			ETag:         etag(fi),
+			Size:         fi.Size(),
+			StorageClass: "STANDARD",
+		})
+		last = key
+	}
+	if v2 {
+		keyCount := len(res.Contents) + len(res.CommonPrefixes)
+		res.KeyCount = &keyCount
+		if res.IsTruncated {
+			res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+		}
+	} else if res.IsTruncated {
+		res.NextMarker = last
+	}
+	gw.writeXML(w, http.StatusOK, res)
+}
+
+// getObject implements GetObject and HeadObject, streaming the requested range through the worker pool.
+func (gw *Gateway) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	ctx := r.Context()
+	f, err := gw.fs.OpenFile(ctx, key, os.O_RDONLY, 0)
+	if err != nil {
+		gw.writeFsError(w, r, err)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		gw.writeFsError(w, r, err)
+		return
+	}
+	if fi.IsDir() {
+		gw.writeError(w, r, errNoSuchKey)
+		return
+	}
+	if ct, ok := fi.(webdav.ContentTyper); ok {
+		if contentType, err := ct.ContentType(ctx); err == nil {
+			w.Header().Set("Content-Type", contentType)
+		}
+	}
+	w.Header().Set("ETag", etag(fi))
+	http.ServeContent(w, r, key, fi.ModTime(), f)
+}
+
+// listEntry is a file of the bucket with its key, or a directory whose files are all grouped under a single common
+// prefix of the listing, with the key of the directory.
+type listEntry struct {
+	key string
+	fi  os.FileInfo
+}
+
+// readFiles returns the files of the bucket which may be listed under prefix, sorted by key. Files in directories
+// (e.g. the item folders of the library view) are keyed by their path, like folder/file. Directories whose files
+// would all be grouped by delimiter are not read, and are returned as a single entry keyed folder/ instead.
+func (gw *Gateway) readFiles(ctx context.Context, prefix string, delimiter string) ([]listEntry, error) {
+	entries := []listEntry{}
+	if err := gw.walk(ctx, "", prefix, delimiter, &entries); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	return entries, nil
+}
+
+// walk adds the files of the directory dir (empty for the root, or ending with a slash) to entries, descending
+// into the directories which may hold keys under prefix.
+func (gw *Gateway) walk(ctx context.Context, dir string, prefix string, delimiter string, entries *[]listEntry) error {
+	f, err := gw.fs.OpenFile(ctx, "/"+dir, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	for _, fi := range infos {
+		key := dir + fi.Name()
+		if !fi.IsDir() {
+			*entries = append(*entries, listEntry{key: key, fi: fi})
+			continue
+		}
+		key += "/"
+		switch {
+		case strings.HasPrefix(key, prefix):
+			if delimiter != "" && strings.Contains(key[len(prefix):], delimiter) {
+				*entries = append(*entries, listEntry{key: key, fi: fi})
+				continue
+			}
+		case !strings.HasPrefix(prefix, key):
+			continue
+		}
+		if err := gw.walk(ctx, key, prefix, delimiter, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFsError reports a filesystem error as NoSuchKey or InternalError.
+func (gw *Gateway) writeFsError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		gw.writeError(w, r, errNoSuchKey)
+		return
+	}
+	gw.getLogger("writeFsError").WithError(err).Error("can not read media")
+	gw.writeError(w, r, errInternal)
+}
+
+// writeError writes an s3 error response. HEAD responses carry the status only.
+func (gw *Gateway) writeError(w http.ResponseWriter, r *http.Request, apiErr *apiError) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(apiErr.Status)
+		return
+	}
+	gw.writeXML(w, apiErr.Status, errorResult{Code: apiErr.Code, Message: apiErr.Message, Resource: r.URL.Path})
+}
+
+func (gw *Gateway) writeXML(w http.ResponseWriter, status int, v any) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		gw.getLogger("writeXML").WithError(err).Error("can not marshal response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(body)
+}
+
+func (gw *Gateway) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.S3Module).WithField("func", fmt.Sprintf("%T.%s", gw, fn))
+}
+
+// etag returns a stable etag for a file. It is shaped like the etag of a multipart upload, so clients do not
+// mistake it for the md5 of the content and fail their integrity checks.
+func etag(fi os.FileInfo) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%d", fi.Name(), fi.Size(), fi.ModTime().UnixNano())))
+	return fmt.Sprintf(`"%s-1"`, hex.EncodeToString(h[:16]))
+}
+
+// NewGateway creates a gateway serving the files of fs as the objects of the configured bucket.
+func NewGateway(cfg Config, fs webdav.FileSystem, authenticator auth.IAuthenticator) *Gateway {
+	return &Gateway{
+		cfg:           cfg,
+		fs:            fs,
+		authenticator: authenticator,
+		now:           time.Now,
+	}
+}
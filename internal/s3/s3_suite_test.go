@@ -0,0 +1,15 @@
+package s3_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestS3(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "S3 Suite")
+}
@@ -0,0 +1,264 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm   = "AWS4-HMAC-SHA256"
+	sigV4Terminator  = "aws4_request"
+	amzDateFormat    = "20060102T150405Z"
+	scopeDateFormat  = "20060102"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// maxClockSkew is how far the date of a signed request may be from the server clock.
+	maxClockSkew = 15 * time.Minute
+	// maxPresignExpiry is the longest validity of a presigned url.
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// sigV4 holds the signature of a request, signed either in the Authorization header or in the query (presigned urls).
+type sigV4 struct {
+	accessKeyID   string
+	scope         string
+	scopeDate     string
+	region        string
+	service       string
+	date          time.Time
+	signedHeaders []string
+	signature     string
+	presigned     bool
+	expires       time.Duration
+}
+
+// parseSigV4 reads the signature of the request. It returns errAccessDenied for anonymous requests.
+func parseSigV4(r *http.Request) (*sigV4, *apiError) {
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		return parsePresignedSigV4(r)
+	}
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errAccessDenied
+	}
+	algorithm, params, ok := strings.Cut(authHeader, " ")
+	if !ok || algorithm != sigV4Algorithm {
+		return nil, errMalformedAuth.withMessage("only %s signatures are supported", sigV4Algorithm)
+	}
+	s := &sigV4{}
+	var credential, signedHeaders string
+	for _, p := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		switch k {
+		case "Credential":
+			credential = v
+		case "SignedHeaders":
+			signedHeaders = v
+		case "Signature":
+			s.signature = v
+		}
+	}
+	if credential == "" || signedHeaders == "" || s.signature == "" {
+		return nil, errMalformedAuth
+	}
+	if err := s.parseCredential(credential); err != nil {
+		return nil, err
+	}
+	s.signedHeaders = strings.Split(signedHeaders, ";")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if amzDate == "" {
+		amzDate = r.Header.Get("Date")
+	}
+	if err := s.parseDate(amzDate); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parsePresignedSigV4 reads the signature of a presigned url.
+func parsePresignedSigV4(r *http.Request) (*sigV4, *apiError) {
+	q := r.URL.Query()
+	if q.Get("X-Amz-Algorithm") != sigV4Algorithm {
+		return nil, errMalformedAuth.withMessage("only %s signatures are supported", sigV4Algorithm)
+	}
+	s := &sigV4{presigned: true, signature: q.Get("X-Amz-Signature")}
+	if s.signature == "" || q.Get("X-Amz-SignedHeaders") == "" {
+		return nil, errMalformedAuth
+	}
+	if err := s.parseCredential(q.Get("X-Amz-Credential")); err != nil {
+		return nil, err
+	}
+	s.signedHeaders = strings.Split(q.Get("X-Amz-SignedHeaders"), ";")
+	if err := s.parseDate(q.Get("X-Amz-Date")); err != nil {
+		return nil, err
+	}
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return nil, errMalformedAuth.withMessage("invalid X-Amz-Expires")
+	}
+	s.expires = time.Duration(expires) * time.Second
+	if s.expires > maxPresignExpiry {
+		return nil, errMalformedAuth.withMessage("X-Amz-Expires must be less than a week")
+	}
+	return s, nil
+}
+
+// parseCredential reads `<access key id>/<date>/<region>/<service>/aws4_request`.
+func (s *sigV4) parseCredential(credential string) *apiError {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != sigV4Terminator {
+		return errMalformedAuth.withMessage("invalid credential %q", credential)
+	}
+	s.accessKeyID, s.scopeDate, s.region, s.service = parts[0], parts[1], parts[2], parts[3]
+	s.scope = strings.Join(parts[1:], "/")
+	return nil
+}
+
+// parseDate reads the request date, which must match the date of the credential scope.
+func (s *sigV4) parseDate(amzDate string) *apiError {
+	date, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		date, err = http.ParseTime(amzDate)
+	}
+	if err != nil {
+		return errMalformedAuth.withMessage("invalid request date %q", amzDate)
+	}
+	s.date = date.UTC()
+	if s.date.Format(scopeDateFormat) != s.scopeDate {
+		return errMalformedAuth.withMessage("credential date does not match the request date")
+	}
+	return nil
+}
+
+// checkTime rejects requests signed too far from now and expired presigned urls.
+func (s *sigV4) checkTime(now time.Time) *apiError {
+	if s.presigned {
+		if now.Before(s.date.Add(-maxClockSkew)) || now.After(s.date.Add(s.expires)) {
+			return errAccessDenied.withMessage("request has expired")
+		}
+		return nil
+	}
+	if skew := now.Sub(s.date); skew > maxClockSkew || skew < -maxClockSkew {
+		return errTimeTooSkewed
+	}
+	return nil
+}
+
+// verify recomputes the signature of the request with the secret access key.
+func (s *sigV4) verify(r *http.Request, secret string) *apiError {
+	expected := s.sign(r, secret)
+	if !hmac.Equal([]byte(expected), []byte(s.signature)) {
+		return errSignatureMismatch
+	}
+	return nil
+}
+
+// sign returns the signature of the request.
+func (s *sigV4) sign(r *http.Request, secret string) string {
+	canonical := s.canonicalRequest(r)
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{sigV4Algorithm, s.date.Format(amzDateFormat), s.scope, hex.EncodeToString(hash[:])}, "\n")
+	key := hmacSHA256([]byte("AWS4"+secret), s.scopeDate)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, sigV4Terminator)
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest builds the canonical form of the request, as defined by SigV4 for s3.
+func (s *sigV4) canonicalRequest(r *http.Request) string {
+	var headers strings.Builder
+	for _, h := range s.signedHeaders {
+		headers.WriteString(h)
+		headers.WriteByte(':')
+		headers.WriteString(canonicalHeaderValue(r, h))
+		headers.WriteByte('\n')
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if s.presigned {
+		payloadHash = unsignedPayload
+	} else if payloadHash == "" {
+		payloadHash = emptyPayloadHash
+	}
+	return strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		canonicalQuery(r.URL.Query(), s.presigned),
+		headers.String(),
+		strings.Join(s.signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalHeaderValue returns the trimmed values of a header, with inner spaces collapsed.
+func canonicalHeaderValue(r *http.Request, name string) string {
+	switch name {
+	case "host":
+		return r.Host
+	case "content-length":
+		if r.Header.Get("Content-Length") == "" && r.ContentLength > 0 {
+			return strconv.FormatInt(r.ContentLength, 10)
+		}
+	}
+	values := []string{}
+	for _, v := range r.Header.Values(name) {
+		values = append(values, strings.Join(strings.Fields(v), " "))
+	}
+	return strings.Join(values, ",")
+}
+
+// canonicalQuery returns the encoded query sorted by key, without the signature of presigned urls.
+func canonicalQuery(q url.Values, presigned bool) string {
+	type param struct{ k, v string }
+	params := make([]param, 0, len(q))
+	for k, values := range q {
+		if presigned && k == "X-Amz-Signature" {
+			continue
+		}
+		for _, v := range values {
+			params = append(params, param{uriEncode(k, true), uriEncode(v, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].k != params[j].k {
+			return params[i].k < params[j].k
+		}
+		return params[i].v < params[j].v
+	})
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, p.k+"="+p.v)
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters, and slashes unless encodeSlash is set.
+func uriEncode(v string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,242 @@
+package stash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/update"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// scenesPerPage is the page size scenes are listed with while rebuilding the mapping.
+const scenesPerPage = 100
+
+// mediaFilenameRe matches the media ID the FUSE filesystem ends file names with (<name>-<id>.<ext> or <id>.<ext>).
+var mediaFilenameRe = regexp.MustCompile(`(?:^|-)([0-9a-f]{24})\.[^.]+$`)
+
+// MappingReport summarizes a rebuild of the scene-to-media mapping.
+type MappingReport struct {
+	Hashed    int      // media whose missing oshash was computed
+	Scenes    int      // scenes found under the library path
+	Mapped    int      // scenes mapped to a media
+	Failed    int      // scenes or media that failed to be processed
+	Unmatched []string // IDs of the scenes no media matched
+}
+
+// SceneMapper maps Stash scenes to the media they are files of. A scene is matched by the oshash fingerprints of its
+// files, then by the scene ID stored on the media, then by the media ID the FUSE filesystem puts in file names. The
+// scene ID of a match is stored on the media (StashSceneID).
+type SceneMapper struct {
+	client      IStashClient
+	mediaFacade facade.IFacade[types.MediaFileDoc]
+	wp          stream.IWorkerPool
+}
+
+// MediaByOsHash returns the media of the file with the given oshash, falling back to resolving the scene of the hash
+// if no media has it.
+func (m *SceneMapper) MediaByOsHash(ctx context.Context, hash string) (*types.MediaFileDoc, error) {
+	media, err := m.findMedia(ctx, query.Eq(types.MediaFileDoc__OsHashField, hash))
+	if err != nil || media != nil {
+		return media, err
+	}
+	scene, err := m.client.FindSceneByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return m.Resolve(ctx, scene)
+}
+
+// MediaBySceneID returns the media mapped to a scene, falling back to resolving the scene if no media is mapped to it.
+func (m *SceneMapper) MediaBySceneID(ctx context.Context, sceneID string) (*types.MediaFileDoc, error) {
+	media, err := m.findMedia(ctx, query.Eq(types.MediaFileDoc__StashSceneIDField, sceneID))
+	if err != nil || media != nil {
+		return media, err
+	}
+	scene, err := m.client.FindSceneById(ctx, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	return m.Resolve(ctx, scene)
+}
+
+// Resolve returns the media of a scene and stores the mapping. It returns an error wrapping
+// facade.ErrNoDocumentsFound if no media matches the scene.
+func (m *SceneMapper) Resolve(ctx context.Context, scene *Scene) (*types.MediaFileDoc, error) {
+	sceneID := string(scene.ID)
+	media, err := m.match(ctx, scene)
+	if err != nil {
+		return nil, err
+	}
+	if media == nil {
+		return nil, fmt.Errorf("%w: no media for scene %s", facade.ErrNoDocumentsFound, sceneID)
+	}
+	if media.StashSceneID != sceneID {
+		if err := m.setSceneID(ctx, media, sceneID); err != nil {
+			return nil, err
+		}
+		media.StashSceneID = sceneID
+	}
+	return media, nil
+}
+
+// SceneOfMedia returns the scene of a media, by its stored scene ID or its oshash, and stores the mapping. It returns
+// nil if Stash has no scene for the media (e.g. it was not scanned yet).
+func (m *SceneMapper) SceneOfMedia(ctx context.Context, media *types.MediaFileDoc) (*Scene, error) {
+	if media.StashSceneID != "" {
+		scene, err := m.client.FindSceneById(ctx, media.StashSceneID)
+		if err == nil {
+			return scene, nil
+		} else if !errors.Is(err, ErrSceneNotFound) {
+			return nil, err
+		}
+	}
+	if media.OsHash == "" {
+		return nil, nil
+	}
+	scene, err := m.client.FindSceneByHash(ctx, media.OsHash)
+	if errors.Is(err, ErrSceneNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if string(scene.ID) != media.StashSceneID {
+		if err := m.setSceneID(ctx, media, string(scene.ID)); err != nil {
+			return nil, err
+		}
+		media.StashSceneID = string(scene.ID)
+	}
+	return scene, nil
+}
+
+// Rebuild maps all scenes whose path includes path. If hash is set, the oshash of media that do not have one is
+// computed first, so their scenes can be matched by fingerprint.
+func (m *SceneMapper) Rebuild(ctx context.Context, path string, hash bool) (*MappingReport, error) {
+	ll := m.getLogger("Rebuild")
+	report := MappingReport{Unmatched: []string{}}
+	if hash {
+		if err := m.hashMissing(ctx, &report); err != nil {
+			return nil, err
+		}
+	}
+	for page := 1; ; page++ {
+		scenes, count, err := m.client.FindScenesByPath(ctx, path, page, scenesPerPage)
+		if err != nil {
+			return nil, err
+		}
+		report.Scenes = count
+		for i := range scenes {
+			if _, err := m.Resolve(ctx, &scenes[i]); errors.Is(err, facade.ErrNoDocumentsFound) {
+				report.Unmatched = append(report.Unmatched, string(scenes[i].ID))
+			} else if err != nil {
+				ll.WithError(err).Errorf("can not resolve scene %s", scenes[i].ID)
+				report.Failed++
+			} else {
+				report.Mapped++
+			}
+		}
+		if len(scenes) < scenesPerPage || page*scenesPerPage >= count {
+			break
+		}
+	}
+	ll.Infof("%d scenes: %d mapped, %d unmatched, %d failed", report.Scenes, report.Mapped, len(report.Unmatched), report.Failed)
+	return &report, nil
+}
+
+// hashMissing computes the oshash of media that do not have one.
+func (m *SceneMapper) hashMissing(ctx context.Context, report *MappingReport) error {
+	ll := m.getLogger("hashMissing")
+	coll := m.mediaFacade.GetCollection()
+	mediaList, err := coll.Finder().Filter(query.In[any](types.MediaFileDoc__OsHashField, "", nil)).Find(ctx)
+	if err != nil {
+		return fmt.Errorf("can not list media without oshash: %w", err)
+	}
+	for _, media := range mediaList {
+		if _, err := facade.SetMediaOsHash(ctx, coll, m.wp, media); err != nil {
+			ll.WithError(err).Errorf("can not compute oshash of %s", media.ID.Hex())
+			report.Failed++
+			continue
+		}
+		report.Hashed++
+	}
+	return nil
+}
+
+// match finds the media of a scene, or returns nil if there is none.
+func (m *SceneMapper) match(ctx context.Context, scene *Scene) (*types.MediaFileDoc, error) {
+	for _, f := range scene.Files {
+		if hash := f.Fingerprint(OsHashFingerprint); hash != "" {
+			if media, err := m.findMedia(ctx, query.Eq(types.MediaFileDoc__OsHashField, hash)); err != nil || media != nil {
+				return media, err
+			}
+		}
+	}
+	if media, err := m.findMedia(ctx, query.Eq(types.MediaFileDoc__StashSceneIDField, string(scene.ID))); err != nil || media != nil {
+		return media, err
+	}
+	for _, f := range scene.Files {
+		id, ok := MediaIDFromFilename(f.Basename)
+		if !ok {
+			continue
+		}
+		if media, err := m.findMedia(ctx, query.Id(id)); err != nil || media != nil {
+			return media, err
+		}
+	}
+	return nil, nil
+}
+
+// setSceneID maps a scene to media, unmapping it from any other media.
+func (m *SceneMapper) setSceneID(ctx context.Context, media *types.MediaFileDoc, sceneID string) error {
+	coll := m.mediaFacade.GetCollection()
+	stale := query.NewBuilder().
+		KeyValue(types.MediaFileDoc__StashSceneIDField, sceneID).
+		Ne("_id", media.ID).
+		Build()
+	if _, err := coll.Updater().Filter(stale).Updates(update.Unset(types.MediaFileDoc__StashSceneIDField)).UpdateMany(ctx); err != nil {
+		return fmt.Errorf("can not unmap scene %s: %w", sceneID, err)
+	}
+	if _, err := coll.Updater().Filter(query.Id(media.ID)).Updates(update.Set(types.MediaFileDoc__StashSceneIDField, sceneID)).UpdateOne(ctx); err != nil {
+		return fmt.Errorf("can not map scene %s: %w", sceneID, err)
+	}
+	return nil
+}
+
+// MediaIDFromFilename returns the media ID the FUSE filesystem puts in the name of the file of a media.
+func MediaIDFromFilename(name string) (bson.ObjectID, bool) {
+	sm := mediaFilenameRe.FindStringSubmatch(name)
+	if sm == nil {
+		return bson.ObjectID{}, false
+	}
+	id, err := bson.ObjectIDFromHex(sm[1])
+	return id, err == nil
+}
+
+// findMedia returns the media matching filter, or nil if there is none.
+func (m *SceneMapper) findMedia(ctx context.Context, filter bson.D) (*types.MediaFileDoc, error) {
+	media, err := m.mediaFacade.GetCollection().Finder().Filter(filter).FindOne(ctx)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not query media: %w", err)
+	}
+	return media, nil
+}
+
+func (m *SceneMapper) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.StashModule).WithField("func", fmt.Sprintf("%T.%s", m, fn))
+}
+
+// NewSceneMapper returns a SceneMapper resolving scenes through client. wp is used to compute missing oshashes.
+func NewSceneMapper(client IStashClient, mediaFacade facade.IFacade[types.MediaFileDoc], wp stream.IWorkerPool) *SceneMapper {
+	return &SceneMapper{client: client, mediaFacade: mediaFacade, wp: wp}
+}
@@ -2,18 +2,37 @@ package stash
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/shurcooL/graphql"
 )
 
+// ErrSceneNotFound is returned when Stash has no scene matching a query.
+var ErrSceneNotFound = errors.New("scene not found")
+
+// IStashClient is the part of the Stash GraphQL API TGMon uses.
+//
+//go:generate mockgen -source=stash.go -destination=../../mocks/stash/stash.go -package=mocks
+type IStashClient interface {
+	FindSceneByHash(ctx context.Context, hash string) (*Scene, error)
+	FindSceneById(ctx context.Context, id string) (*Scene, error)
+	FindScenesByPath(ctx context.Context, path string, page int, perPage int) ([]Scene, int, error)
+	MetadataScan(ctx context.Context, paths []string) (string, error)
+	SceneUpdate(ctx context.Context, input SceneUpdateInput) error
+	FindOrCreateTag(ctx context.Context, name string) (string, error)
+}
+
+// StashQlClient implements IStashClient over the GraphQL endpoint of a Stash server.
 type StashQlClient struct {
 	Endpoint string
 	APIKey   string
 	cl       graphql.Client
 }
 
+var _ IStashClient = (*StashQlClient)(nil)
+
 func (st *StashQlClient) FindSceneByHash(ctx context.Context, hash string) (*Scene, error) {
 	var Query struct {
 		// The struct tag maps the Go struct field 'Scene' to the GraphQL field
@@ -29,7 +48,7 @@ func (st *StashQlClient) FindSceneByHash(ctx context.Context, hash string) (*Sce
 		return nil, fmt.Errorf("can not find scene by hash: %w", err)
 	}
 	if Query.Scene.ID == "" {
-		return nil, fmt.Errorf("scene not found")
+		return nil, ErrSceneNotFound
 	}
 	return &Query.Scene, nil
 }
@@ -48,10 +67,87 @@ func (st *StashQlClient) FindSceneById(ctx context.Context, id string) (*Scene,
 		return nil, fmt.Errorf("can not find scene by hash: %w", err)
 	}
 	if Query.Scene.ID == "" {
-		return nil, fmt.Errorf("scene not found")
+		return nil, ErrSceneNotFound
 	}
 	return &Query.Scene, nil
 }
+
+// FindScenesByPath returns a page of the scenes whose path includes path, and the number of such scenes. Pages
+// start at 1.
+func (st *StashQlClient) FindScenesByPath(ctx context.Context, path string, page int, perPage int) ([]Scene, int, error) {
+	var Query struct {
+		FindScenes struct {
+			Count  int
+			Scenes []Scene
+		} `graphql:"findScenes(scene_filter: $filter, filter: $page)"`
+	}
+	variables := map[string]interface{}{
+		"filter": SceneFilterType{Path: &StringCriterionInput{Value: path, Modifier: "INCLUDES"}},
+		"page":   FindFilterType{Page: page, PerPage: perPage},
+	}
+	if err := st.cl.Query(ctx, &Query, variables); err != nil {
+		return nil, 0, fmt.Errorf("can not find scenes by path: %w", err)
+	}
+	return Query.FindScenes.Scenes, Query.FindScenes.Count, nil
+}
+
+// MetadataScan starts a Stash job scanning the given paths for new and changed files, and returns the ID of the job.
+func (st *StashQlClient) MetadataScan(ctx context.Context, paths []string) (string, error) {
+	var Mutation struct {
+		MetadataScan graphql.ID `graphql:"metadataScan(input: $input)"`
+	}
+	variables := map[string]interface{}{
+		"input": ScanMetadataInput{Paths: paths},
+	}
+	if err := st.cl.Mutate(ctx, &Mutation, variables); err != nil {
+		return "", fmt.Errorf("can not start scan: %w", err)
+	}
+	return fmt.Sprint(Mutation.MetadataScan), nil
+}
+
+// SceneUpdate sets the fields of a scene given in input.
+func (st *StashQlClient) SceneUpdate(ctx context.Context, input SceneUpdateInput) error {
+	var Mutation struct {
+		SceneUpdate struct {
+			ID graphql.ID
+		} `graphql:"sceneUpdate(input: $input)"`
+	}
+	variables := map[string]interface{}{
+		"input": input,
+	}
+	if err := st.cl.Mutate(ctx, &Mutation, variables); err != nil {
+		return fmt.Errorf("can not update scene %s: %w", input.ID, err)
+	}
+	return nil
+}
+
+// FindOrCreateTag returns the ID of the tag with the given name, creating it if there is none.
+func (st *StashQlClient) FindOrCreateTag(ctx context.Context, name string) (string, error) {
+	var Query struct {
+		FindTags struct {
+			Tags []Tag
+		} `graphql:"findTags(tag_filter: $filter)"`
+	}
+	variables := map[string]interface{}{
+		"filter": TagFilterType{Name: &StringCriterionInput{Value: name, Modifier: "EQUALS"}},
+	}
+	if err := st.cl.Query(ctx, &Query, variables); err != nil {
+		return "", fmt.Errorf("can not find tag %s: %w", name, err)
+	}
+	if len(Query.FindTags.Tags) > 0 {
+		return string(Query.FindTags.Tags[0].ID), nil
+	}
+	var Mutation struct {
+		TagCreate Tag `graphql:"tagCreate(input: $input)"`
+	}
+	variables = map[string]interface{}{
+		"input": TagCreateInput{Name: name},
+	}
+	if err := st.cl.Mutate(ctx, &Mutation, variables); err != nil {
+		return "", fmt.Errorf("can not create tag %s: %w", name, err)
+	}
+	return string(Mutation.TagCreate.ID), nil
+}
 func NewStashQlClient(endpoint string, apiKey string) *StashQlClient {
 	httpCl := http.DefaultClient
 	if apiKey != "" {
@@ -0,0 +1,15 @@
+package stash_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStash(t *testing.T) {
+	logrus.SetLevel(logrus.FatalLevel)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Stash Suite")
+}
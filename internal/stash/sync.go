@@ -0,0 +1,342 @@
+package stash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/events"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// SyncOptions configures a Syncer.
+type SyncOptions struct {
+	LibraryPath string             // path Stash sees the FUSE filesystem at
+	MinioClient minio.IMinioClient // presigns the cover urls Stash downloads; covers are not pushed when nil
+	PresignTTL  time.Duration      // validity of presigned cover urls
+	Interval    time.Duration      // how often pending media are pushed (and tags pulled)
+	Attempts    int                // how many intervals a new media waits for its scene before it is given up
+	PullTags    bool               // sync tag changes made in Stash back into TGMon
+}
+
+// Syncer keeps Stash up to date with the media. New media make Stash scan the library path, and once their scene
+// exists the title, description, tags and cover of changed media are pushed to it. With PullTags, the tags of the
+// scenes under the library path are copied back to their media. Tags are merged rather than replaced: a tag is
+// removed from one side only if it was removed from the other since the last sync.
+type Syncer struct {
+	client      IStashClient
+	mapper      *SceneMapper
+	mediaFacade facade.IFacade[types.MediaFileDoc]
+	tagFacade   facade.IFacade[types.TagDoc]
+	opts        SyncOptions
+	mu          sync.Mutex
+	pending     map[bson.ObjectID]int // media to push, by the number of intervals they waited for their scene
+	scan        bool
+	tagIDs      map[string]string          // Stash tag IDs by name
+	synced      map[bson.ObjectID][]string // tag names of media at their last sync
+}
+
+// Run pushes the media changes published on bus until ctx is done.
+func (s *Syncer) Run(ctx context.Context, bus events.IBus) error {
+	ll := s.getLogger("Run")
+	backlog, ch, cancel := bus.Subscribe(0)
+	defer func() { cancel() }()
+	var lastID uint64
+	for _, e := range backlog {
+		s.handle(e)
+		lastID = e.ID
+	}
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				// dropped for falling behind; resume from the backlog
+				ll.Warn("resubscribing to events")
+				backlog, ch, cancel = bus.Subscribe(lastID)
+				for _, e := range backlog {
+					s.handle(e)
+					lastID = e.ID
+				}
+				continue
+			}
+			s.handle(e)
+			lastID = e.ID
+		case <-ticker.C:
+			s.Sync(ctx)
+		}
+	}
+}
+
+// Sync starts a scan if new media were added, pulls tags and pushes the pending media whose scene exists. Tags are
+// pulled first, so the edits made in Stash to pending media are merged into them instead of being pushed over.
+func (s *Syncer) Sync(ctx context.Context) {
+	ll := s.getLogger("Sync")
+	s.mu.Lock()
+	scan := s.scan
+	s.scan = false
+	ids := make([]bson.ObjectID, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if scan {
+		if job, err := s.client.MetadataScan(ctx, []string{s.opts.LibraryPath}); err != nil {
+			ll.WithError(err).Error("can not start scan")
+			s.mu.Lock()
+			s.scan = true
+			s.mu.Unlock()
+		} else {
+			ll.Infof("scan started (job %s)", job)
+		}
+	}
+	if s.opts.PullTags {
+		if err := s.pullTags(ctx); err != nil {
+			ll.WithError(err).Error("can not pull tags")
+		}
+	}
+	for _, id := range ids {
+		done, err := s.push(ctx, id)
+		if err != nil {
+			ll.WithError(err).Errorf("can not push media %s", id.Hex())
+		}
+		s.mu.Lock()
+		if done {
+			delete(s.pending, id)
+		} else if s.pending[id]++; s.pending[id] >= s.opts.Attempts {
+			ll.Warnf("giving up on media %s", id.Hex())
+			delete(s.pending, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// handle queues the media of a media event.
+func (s *Syncer) handle(e events.Event) {
+	if e.Type != events.MEDIACREATEDEventType && e.Type != events.MEDIAUPDATEDEventType {
+		return
+	}
+	media, ok := e.Data.(*types.MediaFileDoc)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[media.ID] = 0
+	if e.Type == events.MEDIACREATEDEventType {
+		s.scan = true
+	}
+}
+
+// push pushes a media to its scene, merging its tags with those of the scene. It returns false if the media has no
+// scene yet.
+func (s *Syncer) push(ctx context.Context, id bson.ObjectID) (bool, error) {
+	ll := s.getLogger("push")
+	media, err := s.mediaFacade.GetCollection().Finder().Filter(query.Id(id)).FindOne(ctx)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return true, nil // deleted meanwhile
+	} else if err != nil {
+		return false, err
+	}
+	scene, err := s.mapper.SceneOfMedia(ctx, media)
+	if err != nil || scene == nil {
+		return false, err
+	}
+	current, err := s.tagNames(ctx, media.Tags)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	base := s.synced[media.ID]
+	s.mu.Unlock()
+	input := SceneUpdateInput{ID: string(scene.ID), TagIds: []string{}}
+	title, details := media.DisplayName(), media.Description
+	input.Title, input.Details = &title, &details
+	if s.opts.MinioClient != nil && media.Thumbnail != "" {
+		if u, err := s.opts.MinioClient.PresignedGet(ctx, media.Thumbnail, s.opts.PresignTTL); err != nil {
+			ll.WithError(err).Warnf("can not presign cover of media %s", media.ID.Hex())
+		} else {
+			cover := u.String()
+			input.CoverImage = &cover
+		}
+	}
+	sceneTagIDs := map[string]string{}
+	for _, t := range scene.Tags {
+		sceneTagIDs[t.Name] = string(t.ID)
+	}
+	for _, name := range mergeTags(base, current, sceneTagNames(scene)) {
+		if tagID, ok := sceneTagIDs[name]; ok {
+			input.TagIds = append(input.TagIds, tagID)
+			continue
+		}
+		tagID, err := s.stashTagID(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		input.TagIds = append(input.TagIds, tagID)
+	}
+	if err := s.client.SceneUpdate(ctx, input); err != nil {
+		// a cached tag may have been deleted in Stash
+		s.mu.Lock()
+		s.tagIDs = map[string]string{}
+		s.mu.Unlock()
+		return false, err
+	}
+	s.mu.Lock()
+	s.synced[media.ID] = current
+	s.mu.Unlock()
+	s.getLogger("push").Infof("media %s pushed to scene %s", media.ID.Hex(), scene.ID)
+	return true, nil
+}
+
+// pullTags sets the tags of the media of the scenes under the library path to the tags of their scene.
+func (s *Syncer) pullTags(ctx context.Context) error {
+	ll := s.getLogger("pullTags")
+	for page := 1; ; page++ {
+		scenes, count, err := s.client.FindScenesByPath(ctx, s.opts.LibraryPath, page, scenesPerPage)
+		if err != nil {
+			return err
+		}
+		for i := range scenes {
+			if err := s.pullSceneTags(ctx, &scenes[i]); err != nil {
+				ll.WithError(err).Errorf("can not pull tags of scene %s", scenes[i].ID)
+			}
+		}
+		if len(scenes) < scenesPerPage || page*scenesPerPage >= count {
+			return nil
+		}
+	}
+}
+
+// pullSceneTags merges the tags of a scene into the tags of its media. Without a last sync to merge against, the tags
+// of the scene win, unless the media is pending a push, in which case both sides are kept.
+func (s *Syncer) pullSceneTags(ctx context.Context, scene *Scene) error {
+	media, err := s.mapper.Resolve(ctx, scene)
+	if errors.Is(err, facade.ErrNoDocumentsFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	current, err := s.tagNames(ctx, media.Tags)
+	if err != nil {
+		return err
+	}
+	names := sceneTagNames(scene)
+	s.mu.Lock()
+	base, synced := s.synced[media.ID]
+	_, pending := s.pending[media.ID]
+	s.mu.Unlock()
+	if !synced && !pending {
+		base = current
+	}
+	merged := mergeTags(base, current, names)
+	if !slices.Equal(merged, current) {
+		ids, err := facade.EnsureTags(ctx, s.tagFacade, merged)
+		if err != nil {
+			return err
+		}
+		if _, err := s.mediaFacade.UpdateOne(ctx, query.Id(media.ID), bson.D{{Key: types.MediaFileDoc__TagsField, Value: ids}}); err != nil {
+			return err
+		}
+	}
+	if slices.Equal(merged, names) {
+		// the scene has the merged tags too; otherwise the pending push still has to merge into it
+		s.mu.Lock()
+		s.synced[media.ID] = merged
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// mergeTags merges two versions of the tag names base was synced as. A name either version has is kept, unless base
+// has it and one of the versions removed it. The result is sorted.
+func mergeTags(base, a, b []string) []string {
+	merged := []string{}
+	for _, name := range slices.Concat(a, b) {
+		if slices.Contains(merged, name) {
+			continue
+		}
+		if slices.Contains(base, name) && !(slices.Contains(a, name) && slices.Contains(b, name)) {
+			continue
+		}
+		merged = append(merged, name)
+	}
+	slices.Sort(merged)
+	return merged
+}
+
+// sceneTagNames returns the sorted names of the tags of a scene.
+func sceneTagNames(scene *Scene) []string {
+	names := make([]string, 0, len(scene.Tags))
+	for _, t := range scene.Tags {
+		names = append(names, t.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// tagNames returns the sorted names of the given tags.
+func (s *Syncer) tagNames(ctx context.Context, ids []bson.ObjectID) ([]string, error) {
+	names := []string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	tags, err := s.tagFacade.GetCollection().Finder().Filter(query.In("_id", ids...)).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not find tags: %w", err)
+	}
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// stashTagID returns the ID of the Stash tag with the given name, creating it if needed.
+func (s *Syncer) stashTagID(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	id, ok := s.tagIDs[name]
+	s.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	id, err := s.client.FindOrCreateTag(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.tagIDs[name] = id
+	s.mu.Unlock()
+	return id, nil
+}
+
+func (s *Syncer) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.StashModule).WithField("func", fmt.Sprintf("%T.%s", s, fn))
+}
+
+// NewSyncer returns a Syncer pushing media to the scenes mapper resolves them to through client.
+func NewSyncer(client IStashClient, mapper *SceneMapper, mediaFacade facade.IFacade[types.MediaFileDoc], tagFacade facade.IFacade[types.TagDoc], opts SyncOptions) *Syncer {
+	return &Syncer{
+		client:      client,
+		mapper:      mapper,
+		mediaFacade: mediaFacade,
+		tagFacade:   tagFacade,
+		opts:        opts,
+		pending:     map[bson.ObjectID]int{},
+		tagIDs:      map[string]string{},
+		synced:      map[bson.ObjectID][]string{},
+	}
+}
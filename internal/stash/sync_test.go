@@ -0,0 +1,332 @@
+package stash_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/events"
+	"github.com/amirdaaee/TGMon/internal/stash"
+	"github.com/amirdaaee/TGMon/internal/types"
+	mMinio "github.com/amirdaaee/TGMon/mocks/db/minio"
+	mMongo "github.com/amirdaaee/TGMon/mocks/db/mongo"
+	mEvents "github.com/amirdaaee/TGMon/mocks/events"
+	mFacade "github.com/amirdaaee/TGMon/mocks/facade"
+	mStash "github.com/amirdaaee/TGMon/mocks/stash"
+	"github.com/chenmingyong0423/go-mongox/v2/finder"
+	mMongoX "github.com/chenmingyong0423/go-mongox/v2/mock"
+	"github.com/hasura/go-graphql-client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Syncer", func() {
+	const (
+		libraryPath = "/mnt/tgmon"
+		sceneID     = "42"
+	)
+	var (
+		ctrl         *gomock.Controller
+		mockClient   *mStash.MockIStashClient
+		mockMediaFac *mFacade.MockIFacade[types.MediaFileDoc]
+		mockTagFac   *mFacade.MockIFacade[types.TagDoc]
+		mockBus      *mEvents.MockIBus
+		media        *types.MediaFileDoc
+		mediaMissing bool
+		scene        stash.Scene
+		tags         map[bson.ObjectID]string // the tag collection, names by ID
+		tagFilter    bson.D
+		opts         stash.SyncOptions
+		eventCh      chan events.Event
+		testContext  context.Context
+		cancel       context.CancelFunc
+	)
+	tagID := func(name string) bson.ObjectID {
+		for id, n := range tags {
+			if n == name {
+				return id
+			}
+		}
+		id := bson.NewObjectID()
+		tags[id] = name
+		return id
+	}
+	setMediaTags := func(names ...string) {
+		media.Tags = []bson.ObjectID{}
+		for _, n := range names {
+			media.Tags = append(media.Tags, tagID(n))
+		}
+	}
+	mediaTagNames := func() []string {
+		names := []string{}
+		for _, id := range media.Tags {
+			names = append(names, tags[id])
+		}
+		slices.Sort(names)
+		return names
+	}
+	// Stash tag IDs are the tag names prefixed with "st-"
+	setSceneTags := func(names ...string) {
+		scene.Tags = []stash.Tag{}
+		for _, n := range names {
+			scene.Tags = append(scene.Tags, stash.Tag{ID: graphql.ID("st-" + n), Name: n})
+		}
+	}
+	inputTagNames := func(input stash.SceneUpdateInput) []string {
+		names := []string{}
+		for _, id := range input.TagIds {
+			names = append(names, strings.TrimPrefix(id, "st-"))
+		}
+		slices.Sort(names)
+		return names
+	}
+	newSyncer := func() *stash.Syncer {
+		mapper := stash.NewSceneMapper(mockClient, mockMediaFac, nil)
+		return stash.NewSyncer(mockClient, mapper, mockMediaFac, mockTagFac, opts)
+	}
+	// runWith runs a Syncer until cancel is called, with evts as the backlog of the bus
+	runWith := func(evts ...events.Event) {
+		mockBus.EXPECT().Subscribe(uint64(0)).Return(evts, (<-chan events.Event)(eventCh), func() {})
+		Expect(newSyncer().Run(testContext, mockBus)).To(Succeed())
+		Expect(testContext.Err()).To(MatchError(context.Canceled))
+	}
+	mediaEvent := func(typ events.EventTypeEnum) events.Event {
+		return events.Event{ID: 1, Type: typ, Data: media}
+	}
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		testContext, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		DeferCleanup(func() { cancel() })
+		tags = map[bson.ObjectID]string{}
+		media = &types.MediaFileDoc{Name: "mock", Description: "mock description", Thumbnail: "thumb.jpg", StashSceneID: sceneID}
+		media.ID = bson.NewObjectID()
+		mediaMissing = false
+		scene = stash.Scene{ID: graphql.ID(sceneID)}
+		minioCl := mMinio.NewMockIMinioClient(ctrl)
+		minioCl.EXPECT().PresignedGet(gomock.Any(), gomock.Any(), time.Minute).DoAndReturn(func(ctx context.Context, fileName string, expires time.Duration) (*url.URL, error) {
+			return url.Parse("http://minio/bucket/" + fileName + "?X-Amz-Signature=sig")
+		}).AnyTimes()
+		opts = stash.SyncOptions{LibraryPath: libraryPath, MinioClient: minioCl, PresignTTL: time.Minute, Interval: 5 * time.Millisecond, Attempts: 3}
+		eventCh = make(chan events.Event, 1)
+		mockClient = mStash.NewMockIStashClient(ctrl)
+		mockClient.EXPECT().FindSceneById(gomock.Any(), sceneID).DoAndReturn(func(ctx context.Context, id string) (*stash.Scene, error) {
+			s := scene
+			return &s, nil
+		}).AnyTimes()
+		mockClient.EXPECT().FindOrCreateTag(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, name string) (string, error) {
+			return "st-" + name, nil
+		}).AnyTimes()
+		mockBus = mEvents.NewMockIBus(ctrl)
+		// media
+		mediaFinder := mMongoX.NewMockIFinder[types.MediaFileDoc](ctrl)
+		mediaFinder.EXPECT().Filter(gomock.Any()).Return(mediaFinder).AnyTimes()
+		mediaFinder.EXPECT().FindOne(gomock.Any()).DoAndReturn(func(ctx context.Context, _ ...any) (*types.MediaFileDoc, error) {
+			if mediaMissing {
+				return nil, mongo.ErrNoDocuments
+			}
+			m := *media
+			m.Tags = slices.Clone(media.Tags)
+			return &m, nil
+		}).AnyTimes()
+		mediaColl := mMongo.NewMockICollection[types.MediaFileDoc](ctrl)
+		mediaColl.EXPECT().Finder().Return(mediaFinder).AnyTimes()
+		mockMediaFac = mFacade.NewMockIFacade[types.MediaFileDoc](ctrl)
+		mockMediaFac.EXPECT().GetCollection().Return(mediaColl).AnyTimes()
+		// tags, found by ID or by name
+		tagFinder := mMongoX.NewMockIFinder[types.TagDoc](ctrl)
+		tagFinder.EXPECT().Filter(gomock.Any()).DoAndReturn(func(filter any) finder.IFinder[types.TagDoc] {
+			tagFilter = filter.(bson.D)
+			return tagFinder
+		}).AnyTimes()
+		tagFinder.EXPECT().Find(gomock.Any()).DoAndReturn(func(ctx context.Context, _ ...any) ([]*types.TagDoc, error) {
+			found := []*types.TagDoc{}
+			add := func(id bson.ObjectID) {
+				t := &types.TagDoc{Name: tags[id]}
+				t.ID = id
+				found = append(found, t)
+			}
+			switch tagFilter[0].Key {
+			case "_id":
+				for _, id := range tagFilter[0].Value.(bson.D)[0].Value.([]bson.ObjectID) {
+					add(id)
+				}
+			case types.TagDoc__NameField:
+				for id, n := range tags {
+					if n == tagFilter[0].Value {
+						add(id)
+					}
+				}
+			}
+			return found, nil
+		}).AnyTimes()
+		tagColl := mMongo.NewMockICollection[types.TagDoc](ctrl)
+		tagColl.EXPECT().Finder().Return(tagFinder).AnyTimes()
+		mockTagFac = mFacade.NewMockIFacade[types.TagDoc](ctrl)
+		mockTagFac.EXPECT().GetCollection().Return(tagColl).AnyTimes()
+		mockTagFac.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, doc *types.TagDoc) (*types.TagDoc, error) {
+			doc.ID = tagID(doc.Name)
+			return doc, nil
+		}).AnyTimes()
+	})
+	// expectMediaUpdate expects the tags of the media to be set, and sets them
+	expectMediaUpdate := func() *gomock.Call {
+		return mockMediaFac.EXPECT().UpdateOne(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter bson.D, fields bson.D) (*types.MediaFileDoc, error) {
+			Expect(fields[0].Key).To(Equal(types.MediaFileDoc__TagsField))
+			media.Tags = fields[0].Value.([]bson.ObjectID)
+			return media, nil
+		})
+	}
+	Describe("Run", func() {
+		It("should scan for created media and push them", func() {
+			setMediaTags("tgmon")
+			setSceneTags("stash")
+			var input stash.SceneUpdateInput
+			mockClient.EXPECT().MetadataScan(gomock.Any(), []string{libraryPath}).Return("1", nil)
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+				input = in
+				cancel()
+				return nil
+			})
+			runWith(mediaEvent(events.MEDIACREATEDEventType))
+			Expect(input.ID).To(Equal(sceneID))
+			Expect(*input.Title).To(Equal("mock"))
+			Expect(*input.Details).To(Equal("mock description"))
+			Expect(*input.CoverImage).To(Equal("http://minio/bucket/thumb.jpg?X-Amz-Signature=sig"))
+			Expect(inputTagNames(input)).To(Equal([]string{"stash", "tgmon"}))
+		})
+		It("should push media changed after they were created", func() {
+			mockClient.EXPECT().MetadataScan(gomock.Any(), gomock.Any()).Times(0)
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+				cancel()
+				return nil
+			})
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+		})
+		It("should ignore media deletions", func() {
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).Times(0)
+			time.AfterFunc(50*time.Millisecond, cancel)
+			runWith(mediaEvent(events.MEDIADELETEDEventType))
+		})
+		It("should keep a media pending after a failed push", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).Return(fmt.Errorf("mock update error")),
+				mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+					cancel()
+					return nil
+				}),
+			)
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+		})
+		It("should give up on a media without a scene after Attempts intervals", func() {
+			media.StashSceneID = ""
+			media.OsHash = "mock-hash"
+			calls := 0
+			mockClient.EXPECT().FindSceneByHash(gomock.Any(), "mock-hash").DoAndReturn(func(ctx context.Context, hash string) (*stash.Scene, error) {
+				if calls++; calls == opts.Attempts {
+					time.AfterFunc(50*time.Millisecond, cancel)
+				}
+				return nil, stash.ErrSceneNotFound
+			}).Times(opts.Attempts)
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).Times(0)
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+		})
+		It("should drop a media deleted before it was pushed", func() {
+			mediaMissing = true
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).Times(0)
+			time.AfterFunc(50*time.Millisecond, cancel)
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+		})
+		type mergeCase struct {
+			mediaTags []string // tags of the media changed after the first push
+			sceneTags []string // tags of the scene changed after the first push
+			expect    []string
+		}
+		DescribeTable("should merge the tag edits made on both sides since the last push", func(tc mergeCase) {
+			setMediaTags("a", "b")
+			setSceneTags("a", "b")
+			var input stash.SceneUpdateInput
+			gomock.InOrder(
+				mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+					setMediaTags(tc.mediaTags...)
+					setSceneTags(tc.sceneTags...)
+					eventCh <- mediaEvent(events.MEDIAUPDATEDEventType)
+					return nil
+				}),
+				mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+					input = in
+					cancel()
+					return nil
+				}),
+			)
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+			Expect(inputTagNames(input)).To(Equal(tc.expect))
+		},
+			Entry("tag added in TGMon", mergeCase{mediaTags: []string{"a", "b", "c"}, sceneTags: []string{"a", "b"}, expect: []string{"a", "b", "c"}}),
+			Entry("tag removed in TGMon", mergeCase{mediaTags: []string{"a"}, sceneTags: []string{"a", "b"}, expect: []string{"a"}}),
+			Entry("tag added in Stash", mergeCase{mediaTags: []string{"a", "b"}, sceneTags: []string{"a", "b", "c"}, expect: []string{"a", "b", "c"}}),
+			Entry("tag removed in Stash", mergeCase{mediaTags: []string{"a", "b"}, sceneTags: []string{"b"}, expect: []string{"b"}}),
+			Entry("tags changed on both sides", mergeCase{mediaTags: []string{"a", "c"}, sceneTags: []string{"b", "d"}, expect: []string{"c", "d"}}),
+		)
+	})
+	Describe("Sync", func() {
+		BeforeEach(func() {
+			opts.PullTags = true
+		})
+		expectScenes := func() *gomock.Call {
+			return mockClient.EXPECT().FindScenesByPath(gomock.Any(), libraryPath, 1, gomock.Any()).DoAndReturn(func(ctx context.Context, path string, page int, perPage int) ([]stash.Scene, int, error) {
+				return []stash.Scene{scene}, 1, nil
+			})
+		}
+		It("should copy the tags of a scene to its media", func() {
+			setMediaTags("a")
+			setSceneTags("b", "c")
+			expectScenes()
+			expectMediaUpdate()
+			mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).Times(0)
+			newSyncer().Sync(testContext)
+			Expect(mediaTagNames()).To(Equal([]string{"b", "c"}))
+		})
+		It("should not update a media with the tags of its scene", func() {
+			setMediaTags("a", "b")
+			setSceneTags("b", "a")
+			expectScenes()
+			mockMediaFac.EXPECT().UpdateOne(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			newSyncer().Sync(testContext)
+		})
+		It("should skip scenes without a media", func() {
+			mediaMissing = true
+			setSceneTags("a")
+			expectScenes()
+			mockMediaFac.EXPECT().UpdateOne(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			newSyncer().Sync(testContext)
+		})
+		It("should not pull tags without PullTags", func() {
+			opts.PullTags = false
+			mockClient.EXPECT().FindScenesByPath(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			newSyncer().Sync(testContext)
+		})
+		It("should pull the tags of a pending media before pushing it", func() {
+			setMediaTags("a", "tgmon")
+			setSceneTags("a", "stash")
+			var input stash.SceneUpdateInput
+			gomock.InOrder(
+				expectScenes(),
+				expectMediaUpdate(),
+				mockClient.EXPECT().SceneUpdate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, in stash.SceneUpdateInput) error {
+					input = in
+					cancel()
+					return nil
+				}),
+			)
+			runWith(mediaEvent(events.MEDIAUPDATEDEventType))
+			Expect(mediaTagNames()).To(Equal([]string{"a", "stash", "tgmon"}))
+			Expect(inputTagNames(input)).To(Equal([]string{"a", "stash", "tgmon"}))
+		})
+	})
+})
@@ -2,13 +2,86 @@ package stash
 
 import "github.com/hasura/go-graphql-client"
 
+// OsHashFingerprint is the type of the fingerprint holding the oshash of a file.
+const OsHashFingerprint = "oshash"
+
+// Fingerprint represents a hash of a file.
+type Fingerprint struct {
+	Type  string
+	Value string
+}
+
 // File represents the 'files' field inside the scene.
 type File struct {
-	Basename string
+	Basename     string
+	Path         string
+	Fingerprints []Fingerprint
+}
+
+// Fingerprint returns the value of the fingerprint of the given type, or an empty string.
+func (f File) Fingerprint(typ string) string {
+	for _, fp := range f.Fingerprints {
+		if fp.Type == typ {
+			return fp.Value
+		}
+	}
+	return ""
+}
+
+// Tag represents a tag of scenes.
+type Tag struct {
+	ID   graphql.ID
+	Name string
 }
 
 // Scene represents the main object returned by findSceneByHash.
 type Scene struct {
 	ID    graphql.ID
 	Files []File
+	Tags  []Tag
+}
+
+// CriterionModifier is how a criterion compares values (e.g. INCLUDES).
+type CriterionModifier string
+
+// StringCriterionInput filters a string field.
+type StringCriterionInput struct {
+	Value    string            `json:"value"`
+	Modifier CriterionModifier `json:"modifier"`
+}
+
+// SceneFilterType filters the scenes of findScenes.
+type SceneFilterType struct {
+	Path *StringCriterionInput `json:"path,omitempty"`
+}
+
+// TagFilterType filters the tags of findTags.
+type TagFilterType struct {
+	Name *StringCriterionInput `json:"name,omitempty"`
+}
+
+// ScanMetadataInput selects what metadataScan scans.
+type ScanMetadataInput struct {
+	Paths []string `json:"paths"`
+}
+
+// SceneUpdateInput holds the fields sceneUpdate sets. Nil fields are left unchanged, TagIds replaces the tags of the
+// scene.
+type SceneUpdateInput struct {
+	ID         string   `json:"id"`
+	Title      *string  `json:"title,omitempty"`
+	Details    *string  `json:"details,omitempty"`
+	CoverImage *string  `json:"cover_image,omitempty"` // url or base64 data url of the image
+	TagIds     []string `json:"tag_ids"`
+}
+
+// TagCreateInput holds the fields of a new tag.
+type TagCreateInput struct {
+	Name string `json:"name"`
+}
+
+// FindFilterType paginates findScenes.
+type FindFilterType struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
 }
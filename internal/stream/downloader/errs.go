@@ -13,3 +13,9 @@ type ErrFloodWaitTooLong struct {
 func (efw *ErrFloodWaitTooLong) Error() string {
 	return fmt.Sprintf("flood wait too long: %d vs %f", efw.expected, efw.actual)
 }
+
+// Is reports any ErrFloodWaitTooLong as a match, so errors.Is works regardless of the wait values.
+func (efw *ErrFloodWaitTooLong) Is(target error) bool {
+	_, ok := target.(*ErrFloodWaitTooLong)
+	return ok
+}
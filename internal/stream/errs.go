@@ -1,7 +1,10 @@
 // Package stream defines error types used by the streaming subsystem.
 package stream
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // StreamError wraps a message and an underlying error to provide context
 // while preserving unwrap semantics.
@@ -28,3 +31,12 @@ func NewStreamError(msg string, err error) *StreamError {
 
 // ErrNoThumbnail indicates that the target document has no thumbnail sizes.
 var ErrNoThumbnail = fmt.Errorf("doc doesnt have any thumbnail")
+
+// ErrMessageNotFound indicates that the channel message of a media no longer exists.
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrNoWorker indicates that the pool has no worker to serve the request.
+var ErrNoWorker = errors.New("no available worker")
+
+// ErrFloodWait indicates that every worker of the pool is waiting out a Telegram flood wait.
+var ErrFloodWait = errors.New("all workers are rate limited by telegram")
@@ -26,11 +26,38 @@ type IWorkerPool interface {
 	// Stream creates a buffered reader that streams document content from
 	// Telegram using the pool for resiliency.
 	Stream(ctx context.Context, msgID int, offset int64, end int64) (IStreamer, error)
+	// OnStateChange registers a listener for worker state changes. The current
+	// state of every worker is reported to the listener right away.
+	OnStateChange(l WorkerStateListener)
+	// Traffic returns the state of every worker and the traffic it served since startup.
+	Traffic() []WorkerTraffic
 }
+
+type WorkerStateEnum string
+
+const (
+	READYWorkerState     WorkerStateEnum = "READY"
+	FLOODWAITWorkerState WorkerStateEnum = "FLOOD_WAIT"
+)
+
+// WorkerTraffic is the state of a worker and the file content it downloaded since startup.
+type WorkerTraffic struct {
+	Worker int // pool index, as reported to state listeners
+	State  WorkerStateEnum
+	Bytes  int64
+	Chunks int64
+}
+
+// WorkerStateListener is called with the pool index of a worker and its new state.
+type WorkerStateListener func(worker int, state WorkerStateEnum)
+
 type workerPool struct {
-	Bots     []IWorker
-	curIndex int
-	mut      sync.Mutex
+	Bots      []IWorker
+	curIndex  int
+	mut       sync.Mutex
+	states    map[IWorker]WorkerStateEnum
+	traffic   map[IWorker]*WorkerTraffic
+	listeners []WorkerStateListener
 }
 
 var _ IWorkerPool = (*workerPool)(nil)
@@ -51,6 +78,62 @@ func (wp *workerPool) GetNextWorker() IWorker {
 	return worker
 }
 
+// OnStateChange registers l and reports the current state of all workers to it.
+func (wp *workerPool) OnStateChange(l WorkerStateListener) {
+	wp.mut.Lock()
+	defer wp.mut.Unlock()
+	wp.listeners = append(wp.listeners, l)
+	for i, w := range wp.Bots {
+		l(i+1, wp.states[w])
+	}
+}
+
+// setWorkerState records the state of a worker and notifies listeners if it changed.
+func (wp *workerPool) setWorkerState(w IWorker, state WorkerStateEnum) {
+	wp.mut.Lock()
+	defer wp.mut.Unlock()
+	if wp.states[w] == state {
+		return
+	}
+	wp.states[w] = state
+	for i, b := range wp.Bots {
+		if b != w {
+			continue
+		}
+		wp.getLogger("setWorkerState").Infof("worker (%d/%d) is %s", i+1, len(wp.Bots), state)
+		for _, l := range wp.listeners {
+			l(i+1, state)
+		}
+	}
+}
+
+// Traffic returns the state and traffic of all workers, in pool order.
+func (wp *workerPool) Traffic() []WorkerTraffic {
+	wp.mut.Lock()
+	defer wp.mut.Unlock()
+	res := make([]WorkerTraffic, len(wp.Bots))
+	for i, w := range wp.Bots {
+		res[i] = WorkerTraffic{Worker: i + 1, State: wp.states[w]}
+		if t, ok := wp.traffic[w]; ok {
+			res[i].Bytes, res[i].Chunks = t.Bytes, t.Chunks
+		}
+	}
+	return res
+}
+
+// addWorkerTraffic records a chunk of n bytes downloaded by a worker.
+func (wp *workerPool) addWorkerTraffic(w IWorker, n int) {
+	wp.mut.Lock()
+	defer wp.mut.Unlock()
+	t, ok := wp.traffic[w]
+	if !ok {
+		t = &WorkerTraffic{}
+		wp.traffic[w] = t
+	}
+	t.Bytes += int64(n)
+	t.Chunks++
+}
+
 // Stream constructs a new Streamer over the pool for the specified message
 // and byte range [offset, end].
 func (wp *workerPool) Stream(ctx context.Context, msgID int, offset int64, end int64) (IStreamer, error) {
@@ -65,7 +148,7 @@ func (wp *workerPool) getLogger(fn string) *logrus.Entry {
 // and aggregates them into a pool. Returns error if no worker could be started.
 func NewWorkerPool(tokens []string, sessCfg *tlg.SessionConfig, channelID int64, cacheRoot string) (IWorkerPool, error) {
 	ll := log.GetLogger(log.StreamModule).WithField("func", "NewWorkerPool")
-	wp := workerPool{}
+	wp := workerPool{states: map[IWorker]WorkerStateEnum{}, traffic: map[IWorker]*WorkerTraffic{}}
 	var wg sync.WaitGroup
 
 	for _, token := range tokens {
@@ -83,6 +166,7 @@ func NewWorkerPool(tokens []string, sessCfg *tlg.SessionConfig, channelID int64,
 
 			wp.mut.Lock()
 			wp.Bots = append(wp.Bots, worker)
+			wp.states[worker] = READYWorkerState
 			wp.mut.Unlock()
 
 			workerLog.Info("worker initiated")
@@ -113,6 +197,16 @@ type Streamer struct {
 
 var _ IStreamer = (*Streamer)(nil)
 
+// workerStateSetter is implemented by pools that track worker states.
+type workerStateSetter interface {
+	setWorkerState(w IWorker, state WorkerStateEnum)
+}
+
+// workerTrafficRecorder is implemented by pools that track worker traffic.
+type workerTrafficRecorder interface {
+	addWorkerTraffic(w IWorker, n int)
+}
+
 // Read implements io.Reader, reading from the current worker. If a flood wait
 // is encountered, it switches to the next worker transparently. Leftover bytes
 // from larger chunks are preserved and returned first on the next Read.
@@ -124,14 +218,23 @@ func (s *Streamer) Read(p []byte) (n int, err error) {
 		return n, nil
 	}
 
-	// Try to get data from workers
+	// Try to get data from workers, until every one of them hit a flood wait
+	flooded := map[IWorker]bool{}
 	for {
 		worker := s.wp.GetNextWorker()
+		if worker == nil {
+			return 0, ErrNoWorker
+		}
+		if flooded[worker] {
+			return 0, NewStreamError("can not stream", ErrFloodWait)
+		}
 		data, err := worker.Stream(s.ctx, s.reader)
 
 		if err != nil {
 			if errors.Is(err, &downloader.ErrFloodWaitTooLong{}) {
 				s.getLogger("Read").Warn("flood wait too long, trying next worker")
+				s.setWorkerState(worker, FLOODWAITWorkerState)
+				flooded[worker] = true
 				continue
 			}
 			if errors.Is(err, io.EOF) {
@@ -141,6 +244,10 @@ func (s *Streamer) Read(p []byte) (n int, err error) {
 			return 0, fmt.Errorf("error streaming: %w", err)
 		}
 
+		s.setWorkerState(worker, READYWorkerState)
+		if v, ok := s.wp.(workerTrafficRecorder); ok {
+			v.addWorkerTraffic(worker, len(data))
+		}
 		// Copy data to buffer, save leftover if needed
 		n := copy(p, data)
 		if n < len(data) {
@@ -150,6 +257,13 @@ func (s *Streamer) Read(p []byte) (n int, err error) {
 	}
 }
 
+// setWorkerState reports the state of a worker to the pool, if it tracks states.
+func (s *Streamer) setWorkerState(w IWorker, state WorkerStateEnum) {
+	if v, ok := s.wp.(workerStateSetter); ok {
+		v.setWorkerState(w, state)
+	}
+}
+
 // GetBuffer returns the sized bufio.Reader created for this Streamer.
 func (s *Streamer) GetBuffer() *bufio.Reader {
 	return s.buff
@@ -161,7 +275,11 @@ func (s *Streamer) getLogger(fn string) *logrus.Entry {
 // NewStreamer prepares a downloader.Reader for the target document and wraps
 // it into a Streamer buffered according to runtime configuration.
 func NewStreamer(ctx context.Context, wp IWorkerPool, msgID int, offset int64, end int64) (*Streamer, error) {
-	doc, err := wp.GetNextWorker().GetDoc(ctx, msgID)
+	worker := wp.GetNextWorker()
+	if worker == nil {
+		return nil, ErrNoWorker
+	}
+	doc, err := worker.GetDoc(ctx, msgID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting doc: %w", err)
 	}
@@ -8,12 +8,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"strings"
 	"sync"
 
 	"github.com/amirdaaee/TGMon/internal/stream/downloader"
 	"github.com/amirdaaee/TGMon/internal/tlg"
 	"github.com/celestix/gotgproto"
 	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/uploader"
 	"github.com/gotd/td/tg"
 )
 
@@ -29,6 +32,27 @@ type IWorker interface {
 	GetDoc(ctx context.Context, messageID int) (*tg.Document, error)
 	// Stream fetches the next block using the provided downloader.Reader.
 	Stream(ctx context.Context, reader *downloader.Reader) ([]byte, error)
+	// GetChannelDocs returns the documents of channel messages by message ID,
+	// bypassing the cache. Messages that do not exist are left out, and
+	// messages without a document map to nil.
+	GetChannelDocs(ctx context.Context, messageIDs []int) (map[int]*tg.Document, error)
+	// UploadDoc uploads a file to the channel as a document message and returns
+	// the ID of the message.
+	UploadDoc(ctx context.Context, doc DocUpload) (int, error)
+}
+
+// DocUpload is a file to send to the channel as a document.
+type DocUpload struct {
+	Name     string
+	MimeType string
+	// Size is the size of the file in bytes, or -1 when it is not known in
+	// advance (e.g. the file is streamed from a request).
+	Size   int64
+	Reader io.Reader
+	// Video attributes, sent for video mime types. Zero values are unknown.
+	Duration float64 // seconds
+	Width    int
+	Height   int
 }
 type worker struct {
 	cl            tlg.IClient
@@ -143,6 +167,99 @@ func (w *worker) Stream(ctx context.Context, reader *downloader.Reader) ([]byte,
 
 	return block.Data(), nil
 }
+
+// GetChannelDocs fetches the given channel messages in a single request (at
+// most 100 IDs are allowed by Telegram) and returns their documents.
+func (w *worker) GetChannelDocs(ctx context.Context, messageIDs []int) (map[int]*tg.Document, error) {
+	channel, err := w.getChannel(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting channel: %w", err)
+	}
+	inputMsgs := make([]tg.InputMessageClass, len(messageIDs))
+	for i, id := range messageIDs {
+		inputMsgs[i] = &tg.InputMessageID{ID: id}
+	}
+	response, err := w.getTgApi().ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+		Channel: channel,
+		ID:      inputMsgs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages: %w", err)
+	}
+	channelMessages, ok := response.(*tg.MessagesChannelMessages)
+	if !ok {
+		return nil, &tlg.UnexpectedTypeErrType{ExpectedType: &tg.MessagesChannelMessages{}, GotType: response}
+	}
+	res := make(map[int]*tg.Document, len(channelMessages.Messages))
+	for _, message := range channelMessages.Messages {
+		switch msg := message.(type) {
+		case *tg.Message:
+			res[msg.ID] = nil
+			if media, ok := msg.Media.(*tg.MessageMediaDocument); ok {
+				if doc, ok := media.Document.(*tg.Document); ok {
+					res[msg.ID] = doc
+				}
+			}
+		case *tg.MessageService:
+			res[msg.ID] = nil
+		}
+	}
+	return res, nil
+}
+
+// UploadDoc streams the file to Telegram in parts (upload.saveBigFilePart for
+// files over 10MB, so the file is never held in memory), then sends it to the
+// channel as a document with its file name and, for videos, video attributes.
+func (w *worker) UploadDoc(ctx context.Context, doc DocUpload) (int, error) {
+	channel, err := w.getChannel(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error getting channel: %w", err)
+	}
+	inputChannel, ok := channel.(*tg.InputChannel)
+	if !ok {
+		return 0, &tlg.UnexpectedTypeErrType{ExpectedType: &tg.InputChannel{}, GotType: channel}
+	}
+	api := w.getTgApi()
+	file, err := uploader.NewUploader(api).Upload(ctx, uploader.NewUpload(doc.Name, doc.Reader, doc.Size))
+	if err != nil {
+		return 0, fmt.Errorf("error uploading file: %w", err)
+	}
+	attrs := []tg.DocumentAttributeClass{&tg.DocumentAttributeFilename{FileName: doc.Name}}
+	if strings.HasPrefix(doc.MimeType, "video/") {
+		attrs = append(attrs, &tg.DocumentAttributeVideo{
+			SupportsStreaming: true,
+			Duration:          doc.Duration,
+			W:                 doc.Width,
+			H:                 doc.Height,
+		})
+	}
+	mimeType := doc.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	upd, err := api.MessagesSendMedia(ctx, &tg.MessagesSendMediaRequest{
+		Peer: &tg.InputPeerChannel{ChannelID: inputChannel.ChannelID, AccessHash: inputChannel.AccessHash},
+		Media: &tg.InputMediaUploadedDocument{
+			File:       file,
+			MimeType:   mimeType,
+			Attributes: attrs,
+		},
+		RandomID: rand.Int64(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error sending document: %w", err)
+	}
+	updates, ok := upd.(*tg.Updates)
+	if !ok {
+		return 0, &tlg.UnexpectedTypeErrType{ExpectedType: &tg.Updates{}, GotType: upd}
+	}
+	for _, u := range updates.Updates {
+		if newMsg, ok := u.(*tg.UpdateNewChannelMessage); ok {
+			return newMsg.Message.GetID(), nil
+		}
+	}
+	return 0, fmt.Errorf("no message in updates of sent document")
+}
 func (w *worker) getChannel(ctx context.Context) (tg.InputChannelClass, error) {
 	w.tgChannelLock.Lock()
 	defer w.tgChannelLock.Unlock()
@@ -223,7 +340,7 @@ func (w *worker) retrieveChannelMessage(ctx context.Context, messageID int) (tg.
 	messages := channelMessages.Messages
 	switch len(messages) {
 	case 0:
-		return nil, fmt.Errorf("message not found")
+		return nil, ErrMessageNotFound
 	case 1:
 		return messages[0], nil
 	default:
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/chenmingyong0423/go-mongox/v2"
 	"github.com/gotd/td/tg"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -8,10 +10,21 @@ import (
 
 // ...
 const (
-	MediaFileDoc__VttField       = "Vtt"
-	MediaFileDoc__SpriteField    = "Sprite"
-	MediaFileDoc__ThumbnailField = "Thumbnail"
-	MediaFileDoc__FileIDField    = "Meta.FileID"
+	MediaFileDoc__VttField           = "Vtt"
+	MediaFileDoc__SpriteField        = "Sprite"
+	MediaFileDoc__ThumbnailField     = "Thumbnail"
+	MediaFileDoc__ThumbnailSizeField = "ThumbnailSize"
+	MediaFileDoc__FileIDField        = "Meta.FileID"
+	MediaFileDoc__MessageIDField     = "MessageID"
+	MediaFileDoc__MimeTypeField      = "Meta.MimeType"
+	MediaFileDoc__FileNameField      = "Meta.FileName"
+	MediaFileDoc__NameField          = "Name"
+	MediaFileDoc__DescriptionField   = "Description"
+	MediaFileDoc__FieldsField        = "Fields"
+	MediaFileDoc__TagsField          = "Tags"
+	MediaFileDoc__SubtitlesField     = "Subtitles"
+	MediaFileDoc__OsHashField        = "OsHash"
+	MediaFileDoc__StashSceneIDField  = "StashSceneID"
 )
 
 type MediaFileMeta struct {
@@ -22,18 +35,69 @@ type MediaFileMeta struct {
 	Duration float64 `bson:"Duration"`
 }
 type MediaFileDoc struct {
-	mongox.Model `bson:",inline"`
-	Meta         MediaFileMeta `bson:"Meta"`
-	MessageID    int           `bson:"MessageID"`
-	Thumbnail    string        `bson:"Thumbnail"`
-	Vtt          string        `bson:"Vtt"`
-	Sprite       string        `bson:"Sprite"`
+	mongox.Model  `bson:",inline"`
+	Meta          MediaFileMeta     `bson:"Meta"`
+	MessageID     int               `bson:"MessageID"`
+	Thumbnail     string            `bson:"Thumbnail"`
+	ThumbnailSize int64             `bson:"ThumbnailSize"` // bytes; 0 for thumbnails stored before sizes were recorded
+	Vtt           string            `bson:"Vtt"`
+	Sprite        string            `bson:"Sprite"`
+	Name          string            `bson:"Name"`
+	Description   string            `bson:"Description"`
+	Fields        map[string]string `bson:"Fields"`
+	Tags          []bson.ObjectID   `bson:"Tags"`
+	Subtitles     []MediaSubtitle   `bson:"Subtitles"`
+	OsHash        string            `bson:"OsHash"`       // hash Stash identifies the file by
+	StashSceneID  string            `bson:"StashSceneID"` // scene of the file in Stash
 }
 
 func (m MediaFileDoc) String() string {
 	return m.ID.String()
 }
 
+// DisplayName returns the user provided name of the media, falling back to the original file name.
+func (m MediaFileDoc) DisplayName() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.Meta.FileName
+}
+
+// NewMediaFileDoc returns the media of a document posted as the channel message msgID.
+func NewMediaFileDoc(doc *tg.Document, msgID int) (MediaFileDoc, error) {
+	meta := MediaFileMeta{}
+	if err := meta.FillFromDocument(doc); err != nil {
+		return MediaFileDoc{}, err
+	}
+	return MediaFileDoc{Meta: meta, MessageID: msgID}, nil
+}
+
+type SubtitleFormatEnum string
+
+const (
+	VTTSubtitleFormat SubtitleFormatEnum = "vtt" // srt files are converted to vtt
+	ASSSubtitleFormat SubtitleFormatEnum = "ass"
+)
+
+// MediaSubtitle is a subtitle track of a media, stored in minio.
+type MediaSubtitle struct {
+	ID       bson.ObjectID      `bson:"ID"`
+	Language string             `bson:"Language"` // label of the track (e.g. en), one track per language
+	Format   SubtitleFormatEnum `bson:"Format"`
+	File     string             `bson:"File"` // object name in minio
+	Size     int64              `bson:"Size"`
+}
+
+// Subtitle returns the subtitle track of the media with the given ID, or nil if there is none.
+func (m MediaFileDoc) Subtitle(id bson.ObjectID) *MediaSubtitle {
+	for i := range m.Subtitles {
+		if m.Subtitles[i].ID == id {
+			return &m.Subtitles[i]
+		}
+	}
+	return nil
+}
+
 func (m *MediaFileMeta) FillFromDocument(doc *tg.Document) error {
 	for _, attr := range doc.Attributes {
 		switch v := attr.(type) {
@@ -57,13 +121,16 @@ const (
 	SPRITEJobType    JobTypeEnum = "SPRITE"
 )
 const (
-	JobReqDoc__MediaIDField = "MediaID"
+	JobReqDoc__MediaIDField   = "MediaID"
+	JobReqDoc__ClaimedAtField = "ClaimedAt"
+	JobReqDoc__TypeField      = "JobType"
 )
 
 type JobReqDoc struct {
 	mongox.Model `bson:",inline"`
 	MediaID      bson.ObjectID `bson:"MediaID"`
 	Type         JobTypeEnum   `bson:"JobType"`
+	ClaimedAt    *time.Time    `bson:"ClaimedAt"` // set by the worker processing the job
 }
 
 func (m JobReqDoc) String() string {
@@ -81,3 +148,197 @@ type JobResDoc struct {
 func (m JobResDoc) String() string {
 	return m.ID.String()
 }
+
+// ...
+const (
+	TagDoc__NameField = "Name"
+)
+
+type TagDoc struct {
+	mongox.Model `bson:",inline"`
+	Name         string `bson:"Name"`
+}
+
+func (m TagDoc) String() string {
+	return m.ID.String()
+}
+
+// ...
+const (
+	PlaylistDoc__NameField        = "Name"
+	PlaylistDoc__DescriptionField = "Description"
+	PlaylistDoc__ItemsField       = "Items"
+)
+
+// PlaylistDoc is an ordered collection of media.
+type PlaylistDoc struct {
+	mongox.Model `bson:",inline"`
+	Name         string          `bson:"Name"`
+	Description  string          `bson:"Description"`
+	Items        []bson.ObjectID `bson:"Items"`
+}
+
+func (m PlaylistDoc) String() string {
+	return m.ID.String()
+}
+
+// ...
+type UserRoleEnum string
+
+const (
+	ADMINUserRole  UserRoleEnum = "ADMIN"
+	EDITORUserRole UserRoleEnum = "EDITOR"
+	VIEWERUserRole UserRoleEnum = "VIEWER"
+)
+
+// userRoleRank orders roles so that higher ranked roles include the permissions of lower ones.
+var userRoleRank = map[UserRoleEnum]int{
+	VIEWERUserRole: 1,
+	EDITORUserRole: 2,
+	ADMINUserRole:  3,
+}
+
+// IsValid reports whether r is a known role.
+func (r UserRoleEnum) IsValid() bool {
+	_, ok := userRoleRank[r]
+	return ok
+}
+
+// Allows reports whether a user with role r may access a route requiring the given role.
+func (r UserRoleEnum) Allows(required UserRoleEnum) bool {
+	return r.IsValid() && userRoleRank[r] >= userRoleRank[required]
+}
+
+const (
+	UserDoc__UsernameField     = "Username"
+	UserDoc__PasswordField     = "Password"
+	UserDoc__PasswordHashField = "PasswordHash"
+	UserDoc__RoleField         = "Role"
+	UserDoc__FailedLoginsField = "FailedLogins"
+	UserDoc__LockedUntilField  = "LockedUntil"
+)
+
+type UserDoc struct {
+	mongox.Model `bson:",inline"`
+	Username     string       `bson:"Username"`
+	Password     string       `bson:"-" json:"-"` // plain password, only set on create and never stored
+	PasswordHash string       `bson:"PasswordHash" json:"-"`
+	Role         UserRoleEnum `bson:"Role"`
+	FailedLogins int          `bson:"FailedLogins"`
+	LockedUntil  time.Time    `bson:"LockedUntil"`
+}
+
+func (m UserDoc) String() string {
+	return m.ID.String()
+}
+
+// ...
+const (
+	SessionDoc__UserIDField  = "UserID"
+	SessionDoc__RevokedField = "Revoked"
+)
+
+// SessionDoc is a login session. Session tokens reference it, so revoking it invalidates the token.
+type SessionDoc struct {
+	mongox.Model `bson:",inline"`
+	UserID       bson.ObjectID `bson:"UserID"`
+	ExpiresAt    time.Time     `bson:"ExpiresAt"`
+	Revoked      bool          `bson:"Revoked"`
+}
+
+func (m SessionDoc) String() string {
+	return m.ID.String()
+}
+
+// ...
+type ApiKeyScopeEnum string
+
+const (
+	MEDIAREADApiKeyScope   ApiKeyScopeEnum = "media:read"
+	MEDIAWRITEApiKeyScope  ApiKeyScopeEnum = "media:write"
+	MEDIADELETEApiKeyScope ApiKeyScopeEnum = "media:delete"
+	JOBSCLAIMApiKeyScope   ApiKeyScopeEnum = "jobs:claim"
+	JOBSSUBMITApiKeyScope  ApiKeyScopeEnum = "jobs:submit"
+	STREAMApiKeyScope      ApiKeyScopeEnum = "stream"
+)
+
+// ApiKeyScopes lists all known api key scopes.
+var ApiKeyScopes = []ApiKeyScopeEnum{
+	MEDIAREADApiKeyScope,
+	MEDIAWRITEApiKeyScope,
+	MEDIADELETEApiKeyScope,
+	JOBSCLAIMApiKeyScope,
+	JOBSSUBMITApiKeyScope,
+	STREAMApiKeyScope,
+}
+
+// IsValid reports whether s is a known scope.
+func (s ApiKeyScopeEnum) IsValid() bool {
+	for _, v := range ApiKeyScopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ApiKeyDoc__NameField       = "Name"
+	ApiKeyDoc__HashField       = "Hash"
+	ApiKeyDoc__LastUsedAtField = "LastUsedAt"
+	ApiKeyDoc__CreatedByField  = "CreatedBy"
+)
+
+// ApiKeyDoc is a long lived credential for automation clients. Only the hash of the key is stored.
+type ApiKeyDoc struct {
+	mongox.Model `bson:",inline"`
+	Name         string            `bson:"Name"`
+	Key          string            `bson:"-" json:",omitempty"` // plain key, only set right after creation
+	Hash         string            `bson:"Hash" json:"-"`
+	Prefix       string            `bson:"Prefix"` // first characters of the key, to tell keys apart
+	Scopes       []ApiKeyScopeEnum `bson:"Scopes"`
+	ExpiresAt    *time.Time        `bson:"ExpiresAt"`
+	LastUsedAt   *time.Time        `bson:"LastUsedAt"`
+	CreatedBy    bson.ObjectID     `bson:"CreatedBy"`
+}
+
+func (m ApiKeyDoc) String() string {
+	return m.ID.String()
+}
+
+// HasScope reports whether the key grants the given scope.
+func (m ApiKeyDoc) HasScope(scope ApiKeyScopeEnum) bool {
+	for _, v := range m.Scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ...
+const (
+	WatchProgressDoc__UserIDField       = "UserID"
+	WatchProgressDoc__MediaIDField      = "MediaID"
+	WatchProgressDoc__PositionField     = "Position"
+	WatchProgressDoc__FinishedField     = "Finished"
+	WatchProgressDoc__WatchedField      = "Watched"
+	WatchProgressDoc__PlayCountField    = "PlayCount"
+	WatchProgressDoc__LastPlayedAtField = "LastPlayedAt"
+)
+
+// WatchProgressDoc is the playback state of a media for a user.
+type WatchProgressDoc struct {
+	mongox.Model `bson:",inline"`
+	UserID       bson.ObjectID `bson:"UserID"`
+	MediaID      bson.ObjectID `bson:"MediaID"`
+	Position     float64       `bson:"Position"` // last reported position, in seconds
+	Finished     bool          `bson:"Finished"` // Position is at the end of the media, so there is nothing to resume
+	Watched      bool          `bson:"Watched"`
+	PlayCount    int           `bson:"PlayCount"`
+	LastPlayedAt time.Time     `bson:"LastPlayedAt"`
+}
+
+func (m WatchProgressDoc) String() string {
+	return m.ID.String()
+}
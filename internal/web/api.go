@@ -2,21 +2,80 @@ package web
 
 import (
 	"fmt"
+	"net/http"
 
+	"github.com/amirdaaee/TGMon/internal/types"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// RouteAccess describes what a principal needs to call a route.
+type RouteAccess struct {
+	// Role is the minimum user role required for session tokens.
+	Role types.UserRoleEnum
+	// Scope is the scope required for api keys. Api keys can not call routes without a scope.
+	Scope types.ApiKeyScopeEnum
+	// QueryToken allows passing the token in the `token` query parameter, for clients that can not set headers.
+	QueryToken bool
+	// BasicAuth accepts the token as the password of Basic credentials and asks for them on failures,
+	// for clients that only support username and password (e.g. WebDAV clients).
+	BasicAuth bool
+}
+
+// IRoleApiHandler can be implemented by ApiHandler and CRDApiHandler handlers to override the user role
+// required per http method. Without it, GET routes require a viewer and all other methods an editor.
+type IRoleApiHandler interface {
+	RequiredRole(method string) types.UserRoleEnum
+}
+
+// IScopeApiHandler can be implemented by ApiHandler and CRDApiHandler handlers to declare the api key scope
+// required per http method. An empty scope closes the route to api keys.
+type IScopeApiHandler interface {
+	RequiredScope(method string) types.ApiKeyScopeEnum
+}
+
+// IQueryTokenApiHandler can be implemented by handlers whose clients can not set headers (e.g. EventSource),
+// to accept the token in the `token` query parameter.
+type IQueryTokenApiHandler interface {
+	AllowQueryToken() bool
+}
+
+// IConditionalUpdateApiHandler can be implemented by CRDApiHandler handlers whose update filters may hold a
+// precondition on the document besides its id. An update with a precondition matching no document is answered
+// with a conflict rather than not found.
+type IConditionalUpdateApiHandler interface {
+	IsConditionalUpdate(filter bson.D) bool
+}
+
+// requiredAccess returns what is required to call the given method of the handler.
+func requiredAccess(hndler any, method string) RouteAccess {
+	access := RouteAccess{Role: types.EDITORUserRole}
+	if method == http.MethodGet {
+		access.Role = types.VIEWERUserRole
+	}
+	if v, ok := hndler.(IRoleApiHandler); ok {
+		access.Role = v.RequiredRole(method)
+	}
+	if v, ok := hndler.(IScopeApiHandler); ok {
+		access.Scope = v.RequiredScope(method)
+	}
+	if v, ok := hndler.(IQueryTokenApiHandler); ok {
+		access.QueryToken = v.AllowQueryToken()
+	}
+	return access
+}
+
 type ApiHandler struct {
 	hndler any
 	name   string
 }
 
-func (a *ApiHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+func (a *ApiHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
 	apiG := r.Group(fmt.Sprintf("/%s", a.name))
 	if v, ok := a.hndler.(IPostApiHandler); ok {
 		mid := []gin.HandlerFunc{}
 		if v.AuthPost() {
-			mid = append(mid, authMiddleware)
+			mid = append(mid, authMiddleware(requiredAccess(a.hndler, http.MethodPost)))
 		}
 		mid = append(mid, v.Post)
 		apiG.POST(v.RelativePathPost(), mid...)
@@ -24,11 +83,19 @@ func (a *ApiHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware gin.Handl
 	if v, ok := a.hndler.(IGetApiHandler); ok {
 		mid := []gin.HandlerFunc{}
 		if v.AuthGet() {
-			mid = append(mid, authMiddleware)
+			mid = append(mid, authMiddleware(requiredAccess(a.hndler, http.MethodGet)))
 		}
 		mid = append(mid, v.Get)
 		apiG.GET(v.RelativePathGet(), mid...)
 	}
+	if v, ok := a.hndler.(IPutApiHandler); ok {
+		mid := []gin.HandlerFunc{}
+		if v.AuthPut() {
+			mid = append(mid, authMiddleware(requiredAccess(a.hndler, http.MethodPut)))
+		}
+		mid = append(mid, v.Put)
+		apiG.PUT(v.RelativePathPut(), mid...)
+	}
 }
 
 func NewApiHandler(hndler any, name string) *ApiHandler {
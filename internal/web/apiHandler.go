@@ -7,12 +7,16 @@ import (
 	"math/rand"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db/minio"
 	"github.com/amirdaaee/TGMon/internal/facade"
 	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/amirdaaee/TGMon/internal/stash"
 	"github.com/amirdaaee/TGMon/internal/types"
 	"github.com/chenmingyong0423/go-mongox/v2/bsonx"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -28,40 +32,74 @@ type IGetApiHandler interface {
 	AuthGet() bool
 	RelativePathGet() string
 }
+type IPutApiHandler interface {
+	Put(g *gin.Context)
+	AuthPut() bool
+	RelativePathPut() string
+}
 
 type InfoApiHandler struct {
 	MediaFacade facade.IFacade[types.MediaFileDoc]
 }
 type LoginApiHandler struct {
-	UserName string
-	UserPass string
-	Token    string
+	Authenticator auth.IAuthenticator
 }
-type SessionApiHandler struct {
-	Token string
+type SessionApiHandler struct{}
+type LogoutApiHandler struct {
+	Authenticator auth.IAuthenticator
 }
 type RandomMediaApiHandler struct {
 	MediaFacade facade.IFacade[types.MediaFileDoc]
 }
 type StashVTTRedirectorApiHandler struct {
-	MinioUrl    string
-	StashCl     *stash.StashQlClient
-	MediaFacade facade.IFacade[types.MediaFileDoc]
+	MinioClient minio.IMinioClient
+	PresignTTL  time.Duration
+	Mapper      *stash.SceneMapper
 }
 type StashCoverRedirectorApiHandler struct {
 	StashVTTRedirectorApiHandler
 }
+type PlaylistM3UApiHandler struct {
+	PlaylistFacade facade.IFacade[types.PlaylistDoc]
+	MediaFacade    facade.IFacade[types.MediaFileDoc]
+	PublicUrl      string
+	StreamSigner   auth.IStreamSigner // signs stream urls when stream auth is enabled
+}
+
+// WatchProgressApiHandler reads and reports the playback progress of the authenticated user on a media.
+type WatchProgressApiHandler struct {
+	ProgressFacade facade.IFacade[types.WatchProgressDoc]
+	MediaFacade    facade.IFacade[types.MediaFileDoc]
+}
+
+// ContinueWatchingApiHandler lists media the authenticated user started but did not finish.
+type ContinueWatchingApiHandler struct {
+	ProgressFacade facade.IFacade[types.WatchProgressDoc]
+	MediaFacade    facade.IFacade[types.MediaFileDoc]
+}
 
 var _ IGetApiHandler = (*InfoApiHandler)(nil)
 var _ IGetApiHandler = (*SessionApiHandler)(nil)
 var _ IGetApiHandler = (*RandomMediaApiHandler)(nil)
 var _ IPostApiHandler = (*LoginApiHandler)(nil)
+var _ IPostApiHandler = (*LogoutApiHandler)(nil)
 var _ IGetApiHandler = (*StashVTTRedirectorApiHandler)(nil)
 var _ IGetApiHandler = (*StashCoverRedirectorApiHandler)(nil)
+var _ IGetApiHandler = (*PlaylistM3UApiHandler)(nil)
+var _ IScopeApiHandler = (*InfoApiHandler)(nil)
+var _ IScopeApiHandler = (*RandomMediaApiHandler)(nil)
+var _ IScopeApiHandler = (*PlaylistM3UApiHandler)(nil)
+var _ IGetApiHandler = (*WatchProgressApiHandler)(nil)
+var _ IPutApiHandler = (*WatchProgressApiHandler)(nil)
+var _ IRoleApiHandler = (*WatchProgressApiHandler)(nil)
+var _ IScopeApiHandler = (*WatchProgressApiHandler)(nil)
+var _ IGetApiHandler = (*ContinueWatchingApiHandler)(nil)
+var _ IScopeApiHandler = (*ContinueWatchingApiHandler)(nil)
 
 // @Summary	Info summary
 // @Produce	json
 // @Success	200	{object}	InfoGetResType
+// @Failure	default	{object}	HttpErr
 // @Router		/api/info/ [get]
 // @Security	ApiKeyAuth
 func (h *InfoApiHandler) Get(g *gin.Context) {
@@ -78,13 +116,17 @@ func (h *InfoApiHandler) AuthGet() bool {
 func (h *InfoApiHandler) RelativePathGet() string {
 	return "/"
 }
+func (h *InfoApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
 
 // ===
 // @Summary      Login
-// @Description  Authenticate user and return a token
+// @Description  Authenticate user and return a session token
 // @Accept       json
 // @Produce      json
 // @Param        data  body      LoginPostReqType  true  "Login Data"
+// @Failure      default   {object}  HttpErr
 // @Router       /api/auth/login/ [post]
 // @Success	200	{object}	LoginPostResType
 func (h *LoginApiHandler) Post(g *gin.Context) {
@@ -93,11 +135,20 @@ func (h *LoginApiHandler) Post(g *gin.Context) {
 		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
 		return
 	}
-	if req.Username != h.UserName || req.Password != h.UserPass {
-		g.Error(NewHttpError(errors.New("invalid username or password"), http.StatusUnauthorized)) //nolint:golint,errcheck
+	token, p, err := h.Authenticator.Login(g.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			g.Error(NewHttpError(err, http.StatusUnauthorized)) //nolint:golint,errcheck
+			return
+		}
+		if errors.Is(err, auth.ErrUserLocked) {
+			g.Error(NewHttpError(err, http.StatusTooManyRequests)) //nolint:golint,errcheck
+			return
+		}
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
 		return
 	}
-	g.JSON(http.StatusOK, LoginPostResType{Token: h.Token})
+	g.JSON(http.StatusOK, newLoginPostRes(token, p))
 }
 func (h *LoginApiHandler) AuthPost() bool {
 	return false
@@ -109,11 +160,13 @@ func (h *LoginApiHandler) RelativePathPost() string {
 // ===
 // @Summary	Session data
 // @Produce	json
+// @Failure	default	{object}	HttpErr
 // @Router		/api/auth/session/ [get]
 // @Security	ApiKeyAuth
 // @Success	200	{object}	LoginPostResType
 func (h *SessionApiHandler) Get(g *gin.Context) {
-	g.JSON(http.StatusOK, LoginPostResType{Token: h.Token})
+	token, _ := bearerToken(g)
+	g.JSON(http.StatusOK, newLoginPostRes(token, getPrincipal(g)))
 }
 func (h *SessionApiHandler) AuthGet() bool {
 	return true
@@ -122,10 +175,48 @@ func (h *SessionApiHandler) RelativePathGet() string {
 	return "/"
 }
 
+// ===
+// @Summary	Logout
+// @Description	Revoke the current session token
+// @Failure	default	{object}	HttpErr
+// @Router		/api/auth/logout/ [post]
+// @Security	ApiKeyAuth
+// @Success	200
+func (h *LogoutApiHandler) Post(g *gin.Context) {
+	if err := h.Authenticator.Logout(g.Request.Context(), getPrincipal(g)); err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.AbortWithStatus(http.StatusOK)
+}
+func (h *LogoutApiHandler) AuthPost() bool {
+	return true
+}
+func (h *LogoutApiHandler) RelativePathPost() string {
+	return "/"
+}
+
+// RequiredRole lets any authenticated user log out.
+func (h *LogoutApiHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.VIEWERUserRole
+}
+
+func newLoginPostRes(token string, p *auth.Principal) LoginPostResType {
+	res := LoginPostResType{Token: token}
+	if p != nil {
+		res.User = p.User
+		if p.Session != nil {
+			res.ExpiresAt = &p.Session.ExpiresAt
+		}
+	}
+	return res
+}
+
 // ===
 // @Summary	Get random media
 // @Produce	json
 // @Success	200	{object}	RandomMediaGetResType
+// @Failure	default	{object}	HttpErr
 // @Router		/api/media/random/ [get]
 // @Security	ApiKeyAuth
 func (h *RandomMediaApiHandler) Get(g *gin.Context) {
@@ -151,6 +242,9 @@ func (h *RandomMediaApiHandler) AuthGet() bool {
 func (h *RandomMediaApiHandler) RelativePathGet() string {
 	return "/"
 }
+func (h *RandomMediaApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
 
 // getLogger returns a logger entry with function context for the Bot.
 func (h *RandomMediaApiHandler) getLogger(fn string) *logrus.Entry {
@@ -169,22 +263,16 @@ func (h *StashVTTRedirectorApiHandler) Get(g *gin.Context) {
 		return
 	}
 	osHashSplt := strings.Split(id.ID, "_")
-	scene, err := h.StashCl.FindSceneByHash(g.Request.Context(), osHashSplt[0])
+	media, err := h.Mapper.MediaByOsHash(g.Request.Context(), osHashSplt[0])
 	if err != nil {
 		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
 		return
 	}
-	media, err := h.getMediaByScene(g.Request.Context(), scene)
-	if err != nil {
-		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
-		return
-	}
-	if media != nil && media.Vtt != "" {
-		g.Redirect(http.StatusPermanentRedirect, fmt.Sprintf("%s/%s", h.MinioUrl, media.Vtt))
-
-	} else {
+	if media == nil || media.Vtt == "" {
 		g.Error(NewHttpError(errors.New("no vtt file found"), http.StatusNotFound)) //nolint:golint,errcheck
+		return
 	}
+	h.redirect(g, media.Vtt)
 }
 func (h *StashVTTRedirectorApiHandler) AuthGet() bool {
 	return false
@@ -193,49 +281,338 @@ func (h *StashVTTRedirectorApiHandler) RelativePathGet() string {
 	return "/scene/:id"
 }
 
+// redirect sends the client to a presigned url of an object. The url expires, so the redirect is temporary.
+func (h *StashVTTRedirectorApiHandler) redirect(g *gin.Context, fileName string) {
+	u, err := h.MinioClient.PresignedGet(g.Request.Context(), fileName, h.PresignTTL)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.Header("Cache-Control", "no-store")
+	g.Redirect(http.StatusTemporaryRedirect, u.String())
+}
+
 // func (h *StashVTTRedirectorApiHandler) getLogger(fn string) *logrus.Entry {
 // 	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
 // }
 
-func (h *StashVTTRedirectorApiHandler) getMediaByScene(ctx context.Context, scene *stash.Scene) (*types.MediaFileDoc, error) {
-	fname := scene.Files[0].Basename
-	fIDSplit := strings.Split(fname, "-")
-	fID := strings.Split(fIDSplit[len(fIDSplit)-1], ".")[0]
-	mongoID, err := bson.ObjectIDFromHex(fID)
-	if err != nil {
-		return nil, fmt.Errorf("can not parse mongo id: %w", err)
+// ===
+func (h *StashCoverRedirectorApiHandler) Get(g *gin.Context) {
+	var id idURIType
+	if err := g.ShouldBindUri(&id); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
 	}
-	media, err := h.MediaFacade.GetCollection().Finder().Filter(bsonx.Id(mongoID)).FindOne(ctx)
+	media, err := h.Mapper.MediaBySceneID(g.Request.Context(), id.ID)
 	if err != nil {
-		return nil, fmt.Errorf("can not query media by mongo id (%s): %w", mongoID, err)
+		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
+		return
 	}
-	return media, nil
+	if media == nil || media.Thumbnail == "" {
+		g.Error(NewHttpError(errors.New("no cover found"), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	h.redirect(g, media.Thumbnail)
+}
+func (h *StashCoverRedirectorApiHandler) RelativePathGet() string {
+	return "/scene/:id/screenshot"
 }
 
 // ===
-func (h *StashCoverRedirectorApiHandler) Get(g *gin.Context) {
+// @Summary	Export playlist as M3U
+// @Tags		playlist
+// @Produce	audio/x-mpegurl
+// @Param		id	path		string	true	"Playlist ID"
+// @Success	200	{string}	string	"M3U playlist"
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/{id}/m3u [get]
+// @Security	ApiKeyAuth
+func (h *PlaylistM3UApiHandler) Get(g *gin.Context) {
 	var id idURIType
 	if err := g.ShouldBindUri(&id); err != nil {
 		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
 		return
 	}
-	scene, err := h.StashCl.FindSceneById(g.Request.Context(), id.ID)
+	q, err := idQuery(id.ID)
 	if err != nil {
-		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
 		return
 	}
-	media, err := h.getMediaByScene(g.Request.Context(), scene)
+	playlists, err := h.PlaylistFacade.GetCollection().Finder().Filter(q).Find(g.Request.Context())
 	if err != nil {
-		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	if len(playlists) == 0 {
+		g.Error(NewHttpError(fmt.Errorf("playlist (%s) not found", id.ID), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	playlist := playlists[0]
+	media, err := h.getItems(g.Request.Context(), playlist)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	m3u := buildM3U(playlist.Name, media, getBaseUrl(g, h.PublicUrl), h.StreamSigner)
+	g.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.m3u\"", playlist.ID.Hex()))
+	g.Data(http.StatusOK, "audio/x-mpegurl", []byte(m3u))
+}
+func (h *PlaylistM3UApiHandler) AuthGet() bool {
+	return true
+}
+func (h *PlaylistM3UApiHandler) RelativePathGet() string {
+	return "/:id/m3u"
+}
+func (h *PlaylistM3UApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+
+// getItems returns media of the playlist in playlist order, skipping items that no longer exist.
+func (h *PlaylistM3UApiHandler) getItems(ctx context.Context, playlist *types.PlaylistDoc) ([]*types.MediaFileDoc, error) {
+	if len(playlist.Items) == 0 {
+		return []*types.MediaFileDoc{}, nil
+	}
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(query.In("_id", playlist.Items...)).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not query playlist items: %w", err)
+	}
+	byID := make(map[bson.ObjectID]*types.MediaFileDoc, len(media))
+	for _, m := range media {
+		byID[m.ID] = m
+	}
+	res := make([]*types.MediaFileDoc, 0, len(playlist.Items))
+	for _, id := range playlist.Items {
+		if m, ok := byID[id]; ok {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// ===
+// @Summary	Get watch progress
+// @Description	Playback progress of the authenticated user on the media. Api keys act as the user who created them.
+// @Tags		media
+// @Produce	json
+// @Param		id	path		string	true	"Media ID"
+// @Success	200	{object}	types.WatchProgressDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/progress [get]
+// @Security	ApiKeyAuth
+func (h *WatchProgressApiHandler) Get(g *gin.Context) {
+	userID, mediaID, ok := h.bind(g)
+	if !ok {
+		return
+	}
+	progress, err := facade.GetProgress(g.Request.Context(), h.ProgressFacade, userID, mediaID)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
 		return
 	}
-	if media != nil {
-		g.Redirect(http.StatusPermanentRedirect, fmt.Sprintf("%s/%s", h.MinioUrl, media.Thumbnail))
+	if progress == nil {
+		progress = &types.WatchProgressDoc{UserID: userID, MediaID: mediaID}
+	}
+	g.JSON(http.StatusOK, progress)
+}
 
+// @Summary	Report watch progress
+// @Description	Report the playback position (in seconds) and/or set the watched flag of the media for the authenticated user.
+// @Description	Reaching the end of the media marks it as watched and counts a play. Setting the watched flag clears the
+// @Description	position, unless one is given.
+// @Tags		media
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string				true	"Media ID"
+// @Param		data	body		ProgressPutReqType	true	"Progress"
+// @Success	200		{object}	types.WatchProgressDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/progress [put]
+// @Security	ApiKeyAuth
+func (h *WatchProgressApiHandler) Put(g *gin.Context) {
+	userID, mediaID, ok := h.bind(g)
+	if !ok {
+		return
+	}
+	var req ProgressPutReqType
+	if err := g.ShouldBindJSON(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	if req.Position == nil && req.Watched == nil {
+		g.Error(NewHttpError(errors.New("position or watched is required"), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	ctx := g.Request.Context()
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(query.Id(mediaID)).Find(ctx)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	if len(media) == 0 {
+		g.Error(NewHttpError(fmt.Errorf("media (%s) not found", mediaID.Hex()), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	var progress *types.WatchProgressDoc
+	if req.Watched != nil {
+		progress, err = facade.MarkWatched(ctx, h.ProgressFacade, userID, mediaID, *req.Watched, req.Position)
 	} else {
-		g.Error(NewHttpError(err, http.StatusNotFound)) //nolint:golint,errcheck
+		progress, err = facade.ReportProgress(ctx, h.ProgressFacade, userID, media[0], *req.Position)
 	}
+	if err != nil {
+		if errors.Is(err, facade.ErrInvalidDocument) || errors.Is(err, facade.ErrInvalidUpdate) {
+			g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, progress)
 }
-func (h *StashCoverRedirectorApiHandler) RelativePathGet() string {
-	return "/scene/:id/screenshot"
+func (h *WatchProgressApiHandler) AuthGet() bool {
+	return true
+}
+func (h *WatchProgressApiHandler) RelativePathGet() string {
+	return "/:id/progress"
+}
+func (h *WatchProgressApiHandler) AuthPut() bool {
+	return true
+}
+func (h *WatchProgressApiHandler) RelativePathPut() string {
+	return "/:id/progress"
+}
+
+// RequiredRole lets viewers report their own progress.
+func (h *WatchProgressApiHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.VIEWERUserRole
+}
+func (h *WatchProgressApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+
+// bind returns the authenticated user and the media of the request. It reports the error and returns false on failure.
+func (h *WatchProgressApiHandler) bind(g *gin.Context) (bson.ObjectID, bson.ObjectID, bool) {
+	userID, err := getPrincipalUserID(g)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusForbidden)) //nolint:golint,errcheck
+		return bson.NilObjectID, bson.NilObjectID, false
+	}
+	var id idURIType
+	if err := g.ShouldBindUri(&id); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return bson.NilObjectID, bson.NilObjectID, false
+	}
+	mediaID, err := bson.ObjectIDFromHex(id.ID)
+	if err != nil {
+		g.Error(NewHttpError(fmt.Errorf("invalid id: %w", err), http.StatusBadRequest)) //nolint:golint,errcheck
+		return bson.NilObjectID, bson.NilObjectID, false
+	}
+	return userID, mediaID, true
+}
+
+// ===
+// @Summary	Continue watching
+// @Description	Media the authenticated user started but did not finish, most recently played first.
+// @Tags		media
+// @Produce	json
+// @Success	200	{object}	ContinueWatchingGetResType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/continue/ [get]
+// @Security	ApiKeyAuth
+func (h *ContinueWatchingApiHandler) Get(g *gin.Context) {
+	const maxItems = 24
+	userID, err := getPrincipalUserID(g)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusForbidden)) //nolint:golint,errcheck
+		return
+	}
+	ctx := g.Request.Context()
+	filter := query.NewBuilder().
+		KeyValue(types.WatchProgressDoc__UserIDField, userID).
+		KeyValue(types.WatchProgressDoc__FinishedField, false).
+		Gt(types.WatchProgressDoc__PositionField, 0).
+		Build()
+	progress, err := h.ProgressFacade.GetCollection().Finder().Filter(filter).
+		Sort(bsonx.NewD().Add(types.WatchProgressDoc__LastPlayedAtField, -1).Build()).Limit(maxItems).Find(ctx)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	res := ContinueWatchingGetResType{Items: []ContinueWatchingItemType{}}
+	if len(progress) == 0 {
+		g.JSON(http.StatusOK, res)
+		return
+	}
+	ids := make([]bson.ObjectID, len(progress))
+	for i, p := range progress {
+		ids[i] = p.MediaID
+	}
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(query.In("_id", ids...)).Find(ctx)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	byID := make(map[bson.ObjectID]*types.MediaFileDoc, len(media))
+	for _, m := range media {
+		byID[m.ID] = m
+	}
+	for _, p := range progress {
+		if m, ok := byID[p.MediaID]; ok {
+			res.Items = append(res.Items, ContinueWatchingItemType{Media: m, Progress: p})
+		}
+	}
+	g.JSON(http.StatusOK, res)
+}
+func (h *ContinueWatchingApiHandler) AuthGet() bool {
+	return true
+}
+func (h *ContinueWatchingApiHandler) RelativePathGet() string {
+	return "/"
+}
+func (h *ContinueWatchingApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+
+// ===
+// getBaseUrl returns the configured public url of the server or derives it from the request.
+func getBaseUrl(g *gin.Context, publicUrl string) string {
+	if publicUrl != "" {
+		return strings.TrimSuffix(publicUrl, "/")
+	}
+	scheme := "http"
+	if g.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := g.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, g.Request.Host)
+}
+
+// getStreamUrl returns the absolute stream url of a media. With a signer (stream auth enabled), the url is signed
+// for this media only, so it can be played without a token and without handing out the caller's credentials.
+func getStreamUrl(baseUrl string, mediaID bson.ObjectID, signer auth.IStreamSigner) string {
+	streamUrl := fmt.Sprintf("%s/stream/%s", baseUrl, mediaID.Hex())
+	if signer == nil {
+		return streamUrl
+	}
+	return streamUrl + "?" + signer.SignStream(mediaID).Encode()
+}
+
+// buildM3U returns an extended m3u playlist of the media, with their stream urls and durations.
+func buildM3U(title string, media []*types.MediaFileDoc, baseUrl string, signer auth.IStreamSigner) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if title != "" {
+		fmt.Fprintf(&b, "#PLAYLIST:%s\n", m3uEscape(title))
+	}
+	for _, m := range media {
+		fmt.Fprintf(&b, "#EXTINF:%d,%s\n", int64(m.Meta.Duration), m3uEscape(m.DisplayName()))
+		fmt.Fprintf(&b, "%s\n", getStreamUrl(baseUrl, m.ID, signer))
+	}
+	return b.String()
+}
+
+// m3uEscape removes line breaks which would break the m3u format.
+func m3uEscape(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
 }
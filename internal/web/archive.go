@@ -0,0 +1,201 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/archive"
+	"github.com/amirdaaee/TGMon/internal/db"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MediaArchiveApiHandler streams a zip archive of several media, fetched one after another from the worker pool.
+type MediaArchiveApiHandler struct {
+	DBContainer db.IDbContainer
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	StreamPool  stream.IWorkerPool
+}
+
+var _ IPostApiHandler = (*MediaArchiveApiHandler)(nil)
+var _ IRoleApiHandler = (*MediaArchiveApiHandler)(nil)
+var _ IScopeApiHandler = (*MediaArchiveApiHandler)(nil)
+
+// @Summary	Download media archive
+// @Description	Streams an uncompressed zip of the media with the given IDs (in order), or of the media matching the filter
+// @Description	(oldest first), at most 1000 media. The size of the archive is sent up front as Content-Length.
+// @Tags		media
+// @Accept		json
+// @Produce	application/zip
+// @Param		data	body	MediaArchiveReqType	true	"Media to archive"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/archive/ [post]
+// @Security	ApiKeyAuth
+func (h *MediaArchiveApiHandler) Post(g *gin.Context) {
+	ll := h.getLogger("Post")
+	var req MediaArchiveReqType
+	if err := g.ShouldBindJSON(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	media, err := h.getMedia(g, req)
+	if err != nil {
+		g.Error(err) //nolint:golint,errcheck
+		return
+	}
+	if len(media) == 0 {
+		g.Error(NewHttpError(errors.New("no media to archive"), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	entries := make([]archive.Entry, len(media))
+	for i, m := range media {
+		entries[i] = h.newEntry(m)
+	}
+	z := archive.NewZip(entries)
+	size, err := z.Size()
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("tgmon-%s", time.Now().Format("20060102-150405"))
+	}
+	g.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", strings.ReplaceAll(name, `"`, "_")))
+	g.Header("Content-Type", "application/zip")
+	g.Header("Content-Length", strconv.FormatInt(size, 10))
+	g.Status(http.StatusOK)
+	ll.Infof("streaming archive of %d media (%d bytes)", len(media), size)
+	// the response has started, so errors can only be logged; the client sees a truncated download
+	if err := z.Write(g.Request.Context(), g.Writer); err != nil {
+		ll.WithError(err).Error("can not stream archive")
+	}
+}
+func (h *MediaArchiveApiHandler) AuthPost() bool {
+	return true
+}
+func (h *MediaArchiveApiHandler) RelativePathPost() string {
+	return "/"
+}
+
+// RequiredRole lets viewers download what they can stream.
+func (h *MediaArchiveApiHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.VIEWERUserRole
+}
+func (h *MediaArchiveApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.STREAMApiKeyScope
+}
+
+// getMedia returns the media of the request: the given IDs in order, or the media matching the filter. Requests are
+// limited to bulkMaxItems media, like bulk requests.
+func (h *MediaArchiveApiHandler) getMedia(g *gin.Context, req MediaArchiveReqType) ([]*types.MediaFileDoc, error) {
+	ctx := g.Request.Context()
+	if len(req.IDs) > 0 {
+		if len(req.IDs) > bulkMaxItems {
+			return nil, NewHttpError(fmt.Errorf("%d media selected, at most %d allowed", len(req.IDs), bulkMaxItems), http.StatusBadRequest)
+		}
+		ids, err := parseObjectIDs(req.IDs)
+		if err != nil {
+			return nil, NewHttpError(fmt.Errorf("invalid id: %w", err), http.StatusBadRequest)
+		}
+		media, err := h.MediaFacade.GetCollection().Finder().Filter(query.In("_id", toAnySlice(ids)...)).Find(ctx)
+		if err != nil {
+			return nil, NewHttpError(err, http.StatusInternalServerError)
+		}
+		byID := make(map[bson.ObjectID]*types.MediaFileDoc, len(media))
+		for _, m := range media {
+			byID[m.ID] = m
+		}
+		res := make([]*types.MediaFileDoc, 0, len(ids))
+		for _, id := range ids {
+			m, ok := byID[id]
+			if !ok {
+				return nil, NewHttpError(fmt.Errorf("media (%s) not found", id.Hex()), http.StatusNotFound)
+			}
+			res = append(res, m)
+		}
+		return res, nil
+	}
+	if len(req.Tags) == 0 && req.Watched == nil {
+		return nil, NewHttpError(errors.New("IDs or a filter is required"), http.StatusBadRequest)
+	}
+	mediaHandler := MediaHandler{DBContainer: h.DBContainer}
+	filter, err := mediaHandler.getListFilter(g, MediaListReqType{Tags: req.Tags, Watched: req.Watched})
+	if err != nil {
+		return nil, NewHttpError(err, http.StatusBadRequest)
+	}
+	n, err := h.MediaFacade.GetCollection().Finder().Filter(filter).Count(ctx)
+	if err != nil {
+		return nil, NewHttpError(err, http.StatusInternalServerError)
+	}
+	if n > bulkMaxItems {
+		return nil, NewHttpError(fmt.Errorf("%d media selected, at most %d allowed", n, bulkMaxItems), http.StatusBadRequest)
+	}
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(filter).Sort(bson.D{{Key: "created_at", Value: 1}}).Find(ctx)
+	if err != nil {
+		return nil, NewHttpError(err, http.StatusInternalServerError)
+	}
+	return media, nil
+}
+
+// newEntry returns the archive entry of a media, named after its original file name.
+func (h *MediaArchiveApiHandler) newEntry(media *types.MediaFileDoc) archive.Entry {
+	name := media.Meta.FileName
+	if name == "" {
+		name = media.DisplayName()
+	}
+	if name == "" {
+		name = media.ID.Hex()
+	}
+	if path.Ext(name) == "" {
+		if exts, err := mime.ExtensionsByType(media.Meta.MimeType); err == nil && len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return archive.Entry{
+		Name:     name,
+		Size:     media.Meta.FileSize,
+		Modified: media.CreatedAt,
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			if media.Meta.FileSize == 0 {
+				return io.NopCloser(strings.NewReader("")), nil
+			}
+			streamCtx, cancel := context.WithCancel(ctx)
+			streamer, err := h.StreamPool.Stream(streamCtx, media.MessageID, 0, media.Meta.FileSize-1)
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			return &archiveStream{IStreamer: streamer, cancel: cancel}, nil
+		},
+	}
+}
+func (h *MediaArchiveApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
+
+// archiveStream stops streaming a media once its archive entry is written.
+type archiveStream struct {
+	stream.IStreamer
+	cancel context.CancelFunc
+}
+
+func (s *archiveStream) Close() error {
+	s.cancel()
+	return nil
+}
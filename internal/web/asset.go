@@ -0,0 +1,244 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type MediaAssetEnum string
+
+const (
+	THUMBNAILMediaAsset MediaAssetEnum = "thumbnail"
+	SPRITEMediaAsset    MediaAssetEnum = "sprite"
+	VTTMediaAsset       MediaAssetEnum = "vtt"
+)
+
+// assetCacheControl lets clients reuse proxied assets for an hour, then revalidate them by etag.
+const assetCacheControl = "private, max-age=3600"
+
+// MediaAssetApiHandler serves an asset of a media (its thumbnail, sprite or vtt) stored in minio, so clients need
+// neither a public bucket nor to know where minio is. The object is streamed through, or with Redirect, the client is
+// sent to a presigned url valid for PresignTTL.
+//
+// Vtt files are always proxied: they point to the sprite by its object name, which is rewritten to the sprite
+// endpoint (or to a presigned url of the sprite).
+type MediaAssetApiHandler struct {
+	Asset       MediaAssetEnum
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	MinioClient minio.IMinioClient
+	Redirect    bool
+	PresignTTL  time.Duration
+}
+
+var _ IGetApiHandler = (*MediaAssetApiHandler)(nil)
+var _ IScopeApiHandler = (*MediaAssetApiHandler)(nil)
+var _ IQueryTokenApiHandler = (*MediaAssetApiHandler)(nil)
+
+// @Summary	Get media thumbnail, sprite or vtt
+// @Description	Streams the asset from minio with caching headers (etag and last-modified), or redirects to a short-lived
+// @Description	presigned url when HTTP__ASSET_REDIRECT is set. Vtt files are always streamed, with the sprite url rewritten.
+// @Description	The token can be passed in the `token` query parameter, and is passed along to the sprite url of vtt files.
+// @Tags		media
+// @Produce	image/jpeg
+// @Produce	text/vtt
+// @Param		id	path	string	true	"Media ID"
+// @Success	200
+// @Success	307
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/thumbnail [get]
+// @Router		/api/media/{id}/sprite [get]
+// @Router		/api/media/{id}/vtt [get]
+// @Security	ApiKeyAuth
+func (h *MediaAssetApiHandler) Get(g *gin.Context) {
+	var id idURIType
+	if err := g.ShouldBindUri(&id); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	mediaID, err := bson.ObjectIDFromHex(id.ID)
+	if err != nil {
+		g.Error(NewHttpError(fmt.Errorf("invalid id: %w", err), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(query.Id(mediaID)).FindOne(g.Request.Context())
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	fileName := h.fileName(media)
+	if fileName == "" {
+		g.Error(NewHttpError(fmt.Errorf("media has no %s", h.Asset), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	switch {
+	case h.Asset == VTTMediaAsset:
+		err = h.serveVtt(g, media)
+	case h.Redirect:
+		err = h.redirect(g, fileName)
+	default:
+		err = h.serve(g, fileName)
+	}
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+	}
+}
+func (h *MediaAssetApiHandler) AuthGet() bool {
+	return true
+}
+func (h *MediaAssetApiHandler) RelativePathGet() string {
+	return fmt.Sprintf("/:id/%s", h.Asset)
+}
+func (h *MediaAssetApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+
+// AllowQueryToken lets img and track elements load assets.
+func (h *MediaAssetApiHandler) AllowQueryToken() bool {
+	return true
+}
+
+// fileName returns the object name of the asset of the media.
+func (h *MediaAssetApiHandler) fileName(media *types.MediaFileDoc) string {
+	switch h.Asset {
+	case THUMBNAILMediaAsset:
+		return media.Thumbnail
+	case SPRITEMediaAsset:
+		return media.Sprite
+	case VTTMediaAsset:
+		return media.Vtt
+	}
+	return ""
+}
+
+// serve streams an object, answering conditional and range requests.
+func (h *MediaAssetApiHandler) serve(g *gin.Context, fileName string) error {
+	ctx := g.Request.Context()
+	info, err := h.MinioClient.FileStat(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	r, err := h.MinioClient.FileGet(ctx, fileName)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	g.Header("Content-Type", assetContentType(fileName, info.ContentType))
+	g.Header("Cache-Control", assetCacheControl)
+	if info.ETag != "" {
+		g.Header("ETag", fmt.Sprintf("%q", info.ETag))
+	}
+	http.ServeContent(g.Writer, g.Request, "", info.LastModified, r)
+	return nil
+}
+
+// redirect sends the client to a presigned url of an object.
+func (h *MediaAssetApiHandler) redirect(g *gin.Context, fileName string) error {
+	u, err := h.MinioClient.PresignedGet(g.Request.Context(), fileName, h.PresignTTL)
+	if err != nil {
+		return err
+	}
+	g.Header("Cache-Control", "no-store")
+	g.Redirect(http.StatusTemporaryRedirect, u.String())
+	return nil
+}
+
+// serveVtt streams the vtt of the media with its sprite references pointing to the sprite endpoint, or to a
+// presigned url of the sprite.
+func (h *MediaAssetApiHandler) serveVtt(g *gin.Context, media *types.MediaFileDoc) error {
+	ll := h.getLogger("serveVtt")
+	ctx := g.Request.Context()
+	info, err := h.MinioClient.FileStat(ctx, media.Vtt)
+	if err != nil {
+		return err
+	}
+	r, err := h.MinioClient.FileGet(ctx, media.Vtt)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	vtt, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("can not read vtt: %w", err)
+	}
+	if media.Sprite != "" {
+		spriteUrl := string(SPRITEMediaAsset)
+		if token := g.Query("token"); token != "" {
+			spriteUrl += "?token=" + url.QueryEscape(token)
+		}
+		if h.Redirect {
+			u, err := h.MinioClient.PresignedGet(ctx, media.Sprite, h.PresignTTL)
+			if err != nil {
+				return err
+			}
+			spriteUrl = u.String()
+		}
+		vtt = bytes.ReplaceAll(vtt, []byte(media.Sprite), []byte(spriteUrl))
+	} else {
+		ll.Warnf("media %s has a vtt but no sprite", media.ID.Hex())
+	}
+	g.Header("Content-Type", "text/vtt; charset=utf-8")
+	// the content depends on the request, so it is revalidated by modification time only
+	g.Header("Cache-Control", "private, no-cache")
+	http.ServeContent(g.Writer, g.Request, "", info.LastModified, bytes.NewReader(vtt))
+	return nil
+}
+func (h *MediaAssetApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
+
+// assetContentType returns the content type of an object by its extension, as objects are stored without one.
+func assetContentType(fileName string, stored string) string {
+	if ct := mime.TypeByExtension(path.Ext(fileName)); ct != "" {
+		return ct
+	}
+	if stored != "" {
+		return stored
+	}
+	return "application/octet-stream"
+}
+
+// presignThumbnail returns a presigned url of the thumbnail of a media, valid for ttl, for clients which can not
+// authenticate to the thumbnail endpoint. It returns an empty string if the media has no thumbnail or cl is nil.
+func presignThumbnail(ctx context.Context, cl minio.IMinioClient, ttl time.Duration, m *types.MediaFileDoc) string {
+	if cl == nil || m.Thumbnail == "" {
+		return ""
+	}
+	u, err := cl.PresignedGet(ctx, m.Thumbnail, ttl)
+	if err != nil {
+		log.GetLogger(log.WebModule).WithError(err).Warnf("can not presign thumbnail of media %s", m.ID.Hex())
+		return ""
+	}
+	return u.String()
+}
+
+// NewMediaAssetHandlers returns the handlers of the thumbnail, sprite and vtt of media.
+func NewMediaAssetHandlers(mediaFacade facade.IFacade[types.MediaFileDoc], minioClient minio.IMinioClient, redirect bool, presignTTL time.Duration) []*ApiHandler {
+	res := []*ApiHandler{}
+	for _, asset := range []MediaAssetEnum{THUMBNAILMediaAsset, SPRITEMediaAsset, VTTMediaAsset} {
+		h := MediaAssetApiHandler{
+			Asset:       asset,
+			MediaFacade: mediaFacade,
+			MinioClient: minioClient,
+			Redirect:    redirect,
+			PresignTTL:  presignTTL,
+		}
+		res = append(res, NewApiHandler(&h, "media"))
+	}
+	return res
+}
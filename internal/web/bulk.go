@@ -0,0 +1,187 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/amirdaaee/TGMon/internal/db"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/time/rate"
+)
+
+// bulkMaxItems is the most media a single bulk request can apply to.
+const bulkMaxItems = 1000
+
+type MediaBulkActionEnum string
+
+const (
+	DELETEMediaBulkAction     MediaBulkActionEnum = "DELETE"
+	TAGMediaBulkAction        MediaBulkActionEnum = "TAG"
+	UNTAGMediaBulkAction      MediaBulkActionEnum = "UNTAG"
+	REQUESTJOBMediaBulkAction MediaBulkActionEnum = "REQUEST_JOB"
+)
+
+// MediaBulkApiHandler applies an action to many media, one at a time through the media and job facades so the
+// hooks of every media run. Limiter is shared by all bulk requests, and throttles their side effects on minio and
+// telegram.
+type MediaBulkApiHandler struct {
+	DBContainer  db.IDbContainer
+	MediaFacade  facade.IFacade[types.MediaFileDoc]
+	JobReqFacade facade.IFacade[types.JobReqDoc]
+	Limiter      *rate.Limiter
+}
+
+var _ IPostApiHandler = (*MediaBulkApiHandler)(nil)
+var _ IScopeApiHandler = (*MediaBulkApiHandler)(nil)
+
+// @Summary	Bulk media operation
+// @Description	Deletes, tags, untags or requests a job for the media with the given IDs, or the media matching the filter.
+// @Description	Media are processed one at a time and throttled (HTTP__BULK_RATE per second); the result of each is reported.
+// @Description	With DryRun, reports what would be done without changing anything. At most 1000 media per request.
+// @Description	Deleting requires the media:delete scope for api keys.
+// @Tags		media
+// @Accept		json
+// @Produce	json
+// @Param		data	body		MediaBulkReqType	true	"Bulk operation"
+// @Success	200		{object}	MediaBulkResType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/bulk/ [post]
+// @Security	ApiKeyAuth
+func (h *MediaBulkApiHandler) Post(g *gin.Context) {
+	ll := h.getLogger("Post")
+	var req MediaBulkReqType
+	if err := g.ShouldBindJSON(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	switch req.Action {
+	case DELETEMediaBulkAction, TAGMediaBulkAction, UNTAGMediaBulkAction, REQUESTJOBMediaBulkAction:
+	default:
+		g.Error(NewHttpError(fmt.Errorf("unknown action (%s)", req.Action), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	if req.Action == DELETEMediaBulkAction {
+		if p := getPrincipal(g); p != nil && !allowed(p, RouteAccess{Role: types.EDITORUserRole, Scope: types.MEDIADELETEApiKeyScope}) {
+			g.Error(NewHttpError(errForbidden, http.StatusForbidden)) //nolint:golint,errcheck
+			return
+		}
+	}
+	media, missing, err := h.getMedia(g, req)
+	if err != nil {
+		g.Error(err) //nolint:golint,errcheck
+		return
+	}
+	ctx := g.Request.Context()
+	opts := facade.BulkOptions{DryRun: req.DryRun, Limiter: h.Limiter}
+	var items []facade.BulkItemResult
+	switch req.Action {
+	case DELETEMediaBulkAction:
+		items = facade.BulkDeleteMedia(ctx, h.MediaFacade, media, opts)
+	case TAGMediaBulkAction, UNTAGMediaBulkAction:
+		var tags []bson.ObjectID
+		if tags, err = parseObjectIDs(req.TagIDs); err != nil {
+			g.Error(NewHttpError(fmt.Errorf("invalid tag: %w", err), http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		tagColl := h.DBContainer.GetMongoContainer().GetTagCollection()
+		items, err = facade.BulkTagMedia(ctx, h.MediaFacade, tagColl, media, tags, req.Action == UNTAGMediaBulkAction, opts)
+	case REQUESTJOBMediaBulkAction:
+		items, err = facade.BulkRequestJobs(ctx, h.JobReqFacade, media, req.JobType, opts)
+	}
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	for _, id := range missing {
+		items = append(items, facade.BulkItemResult{ID: id, Status: facade.FAILEDBulkStatus, Error: "media not found"})
+	}
+	res := MediaBulkResType{DryRun: req.DryRun, Total: len(items), Items: items}
+	for _, item := range items {
+		switch item.Status {
+		case facade.DONEBulkStatus:
+			res.Done++
+		case facade.SKIPPEDBulkStatus:
+			res.Skipped++
+		case facade.FAILEDBulkStatus:
+			res.Failed++
+		}
+	}
+	ll.Infof("bulk %s (dry run: %t): %d done, %d skipped, %d failed", req.Action, req.DryRun, res.Done, res.Skipped, res.Failed)
+	g.JSON(http.StatusOK, res)
+}
+func (h *MediaBulkApiHandler) AuthPost() bool {
+	return true
+}
+func (h *MediaBulkApiHandler) RelativePathPost() string {
+	return "/"
+}
+func (h *MediaBulkApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAWRITEApiKeyScope
+}
+
+// getMedia returns the media of the request: the given IDs, with the IDs not found, or the media matching the filter.
+func (h *MediaBulkApiHandler) getMedia(g *gin.Context, req MediaBulkReqType) ([]*types.MediaFileDoc, []bson.ObjectID, error) {
+	ctx := g.Request.Context()
+	var filter bson.D
+	var ids []bson.ObjectID
+	if len(req.IDs) > 0 {
+		var err error
+		if ids, err = parseObjectIDs(req.IDs); err != nil {
+			return nil, nil, NewHttpError(fmt.Errorf("invalid id: %w", err), http.StatusBadRequest)
+		}
+		filter = query.In("_id", toAnySlice(ids)...)
+	} else {
+		if len(req.Tags) == 0 && req.Watched == nil {
+			return nil, nil, NewHttpError(errors.New("IDs or a filter is required"), http.StatusBadRequest)
+		}
+		mediaHandler := MediaHandler{DBContainer: h.DBContainer}
+		var err error
+		if filter, err = mediaHandler.getListFilter(g, MediaListReqType{Tags: req.Tags, Watched: req.Watched}); err != nil {
+			return nil, nil, NewHttpError(err, http.StatusBadRequest)
+		}
+	}
+	n, err := h.MediaFacade.GetCollection().Finder().Filter(filter).Count(ctx)
+	if err != nil {
+		return nil, nil, NewHttpError(err, http.StatusInternalServerError)
+	}
+	if n > bulkMaxItems {
+		return nil, nil, NewHttpError(fmt.Errorf("%d media selected, at most %d allowed", n, bulkMaxItems), http.StatusBadRequest)
+	}
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(filter).Sort(bson.D{{Key: "created_at", Value: 1}}).Find(ctx)
+	if err != nil {
+		return nil, nil, NewHttpError(err, http.StatusInternalServerError)
+	}
+	if len(ids) == 0 {
+		return media, nil, nil
+	}
+	// keep the order of the request
+	byID := make(map[bson.ObjectID]*types.MediaFileDoc, len(media))
+	for _, m := range media {
+		byID[m.ID] = m
+	}
+	res := make([]*types.MediaFileDoc, 0, len(media))
+	var missing []bson.ObjectID
+	seen := map[bson.ObjectID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if m, ok := byID[id]; ok {
+			res = append(res, m)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return res, missing, nil
+}
+func (h *MediaBulkApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
@@ -55,10 +55,6 @@ func (a *CRDApiHandler[T]) HandleRead(g *gin.Context) {
 	}
 	res, err := a.fac.GetCRD().GetCollection().Finder().Filter(q).FindOne(g.Request.Context())
 	if err != nil {
-		if errors.Is(err, facade.ErrNoDocumentsFound) || errors.Is(err, facade.ErrMultipleDocumentsFound) {
-			g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
-			return
-		}
 		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
 		return
 	}
@@ -106,29 +102,55 @@ func (a *CRDApiHandler[T]) HandleDelete(g *gin.Context) {
 		return
 	}
 	if _, err := a.fac.DeleteOne(g.Request.Context(), q); err != nil {
-		if errors.Is(err, facade.ErrNoDocumentsFound) || errors.Is(err, facade.ErrMultipleDocumentsFound) {
-			g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
-			return
-		}
 		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
 		return
 	}
 	g.AbortWithStatus(http.StatusOK)
 }
-func (a *CRDApiHandler[T]) RegisterRoutes(r *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+func (a *CRDApiHandler[T]) HandleUpdate(g *gin.Context) {
+	// HandleUpdate handles HTTP PATCH requests to update a resource.
+	handler, ok := a.hndler.(IUpdateApiHandler[T])
+	if !ok {
+		g.Error(NewHttpError(fmt.Errorf("handler is not a IUpdateApiHandler"), http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	q, fields, err := handler.BindUpdateRequest(g)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	res, err := a.fac.UpdateOne(g.Request.Context(), q, fields)
+	if c, ok := a.hndler.(IConditionalUpdateApiHandler); ok && errors.Is(err, facade.ErrNoDocumentsFound) && c.IsConditionalUpdate(q) {
+		err = fmt.Errorf("%w: %s", facade.ErrUpdateConflict, err)
+	}
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	h, err := handler.MarshalUpdateResponse(g, res)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, h)
+}
+func (a *CRDApiHandler[T]) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
 	// RegisterRoutes registers CRUD routes for the resource on the given router group.
 	apiG := r.Group(fmt.Sprintf("/%s", a.name))
 	if _, ok := a.hndler.(ICreateApiHandler[T]); ok {
-		apiG.POST("/", authMiddleware, a.HandleCreate)
+		apiG.POST("/", authMiddleware(requiredAccess(a.hndler, http.MethodPost)), a.HandleCreate)
 	}
 	if _, ok := a.hndler.(IListApiHandler[T]); ok {
-		apiG.GET("/", authMiddleware, a.HandleList)
+		apiG.GET("/", authMiddleware(requiredAccess(a.hndler, http.MethodGet)), a.HandleList)
 	}
 	if _, ok := a.hndler.(IDeleteApiHandler[T]); ok {
-		apiG.DELETE("/:id", authMiddleware, a.HandleDelete)
+		apiG.DELETE("/:id", authMiddleware(requiredAccess(a.hndler, http.MethodDelete)), a.HandleDelete)
 	}
 	if _, ok := a.hndler.(IReadApiHandler[T]); ok {
-		apiG.GET("/:id", authMiddleware, a.HandleRead)
+		apiG.GET("/:id", authMiddleware(requiredAccess(a.hndler, http.MethodGet)), a.HandleRead)
+	}
+	if _, ok := a.hndler.(IUpdateApiHandler[T]); ok {
+		apiG.PATCH("/:id", authMiddleware(requiredAccess(a.hndler, http.MethodPatch)), a.HandleUpdate)
 	}
 }
 func NewCRDApiHandler[T any](hndler any, fac facade.IFacade[T], name string) *CRDApiHandler[T] {
@@ -3,7 +3,10 @@ package web
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/amirdaaee/TGMon/internal/auth"
 	"github.com/amirdaaee/TGMon/internal/db"
 	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/amirdaaee/TGMon/internal/types"
@@ -31,6 +34,10 @@ type IListApiHandler[T any] interface {
 type IDeleteApiHandler[T any] interface {
 	BindDeleteRequest(g *gin.Context) (bson.D, error)
 }
+type IUpdateApiHandler[T any] interface {
+	BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error)
+	MarshalUpdateResponse(g *gin.Context, v *T) (any, error)
+}
 
 // IHandler defines methods for binding requests and marshaling responses for a resource type T.
 
@@ -45,22 +52,71 @@ type JobReqHandler struct{}
 // JobResHandler implements IHandler for media resources.
 type JobResHandler struct{}
 
+// TagHandler implements IHandler for tag resources.
+type TagHandler struct{}
+
+// PlaylistHandler implements IHandler for playlist resources.
+type PlaylistHandler struct{}
+
+// UserHandler implements IHandler for user resources. All of its routes require an admin.
+type UserHandler struct{}
+
+// ApiKeyHandler implements IHandler for api key resources. All of its routes require an admin.
+type ApiKeyHandler struct {
+	Authenticator auth.IAuthenticator
+}
+
 var _ IReadApiHandler[types.MediaFileDoc] = (*MediaHandler)(nil)
 var _ IListApiHandler[types.MediaFileDoc] = (*MediaHandler)(nil)
 var _ IDeleteApiHandler[types.MediaFileDoc] = (*MediaHandler)(nil)
+var _ IUpdateApiHandler[types.MediaFileDoc] = (*MediaHandler)(nil)
 
 var _ ICreateApiHandler[types.JobReqDoc] = (*JobReqHandler)(nil)
 var _ IListApiHandler[types.JobReqDoc] = (*JobReqHandler)(nil)
 var _ IDeleteApiHandler[types.JobReqDoc] = (*JobReqHandler)(nil)
+var _ IUpdateApiHandler[types.JobReqDoc] = (*JobReqHandler)(nil)
+var _ IConditionalUpdateApiHandler = (*JobReqHandler)(nil)
 
 var _ ICreateApiHandler[types.JobResDoc] = (*JobResHandler)(nil)
 
+var _ ICreateApiHandler[types.TagDoc] = (*TagHandler)(nil)
+var _ IReadApiHandler[types.TagDoc] = (*TagHandler)(nil)
+var _ IListApiHandler[types.TagDoc] = (*TagHandler)(nil)
+var _ IUpdateApiHandler[types.TagDoc] = (*TagHandler)(nil)
+var _ IDeleteApiHandler[types.TagDoc] = (*TagHandler)(nil)
+
+var _ ICreateApiHandler[types.PlaylistDoc] = (*PlaylistHandler)(nil)
+var _ IReadApiHandler[types.PlaylistDoc] = (*PlaylistHandler)(nil)
+var _ IListApiHandler[types.PlaylistDoc] = (*PlaylistHandler)(nil)
+var _ IUpdateApiHandler[types.PlaylistDoc] = (*PlaylistHandler)(nil)
+var _ IDeleteApiHandler[types.PlaylistDoc] = (*PlaylistHandler)(nil)
+
+var _ ICreateApiHandler[types.UserDoc] = (*UserHandler)(nil)
+var _ IReadApiHandler[types.UserDoc] = (*UserHandler)(nil)
+var _ IListApiHandler[types.UserDoc] = (*UserHandler)(nil)
+var _ IUpdateApiHandler[types.UserDoc] = (*UserHandler)(nil)
+var _ IDeleteApiHandler[types.UserDoc] = (*UserHandler)(nil)
+var _ IRoleApiHandler = (*UserHandler)(nil)
+
+var _ ICreateApiHandler[types.ApiKeyDoc] = (*ApiKeyHandler)(nil)
+var _ IReadApiHandler[types.ApiKeyDoc] = (*ApiKeyHandler)(nil)
+var _ IListApiHandler[types.ApiKeyDoc] = (*ApiKeyHandler)(nil)
+var _ IDeleteApiHandler[types.ApiKeyDoc] = (*ApiKeyHandler)(nil)
+var _ IRoleApiHandler = (*ApiKeyHandler)(nil)
+
+var _ IScopeApiHandler = (*MediaHandler)(nil)
+var _ IScopeApiHandler = (*JobReqHandler)(nil)
+var _ IScopeApiHandler = (*JobResHandler)(nil)
+var _ IScopeApiHandler = (*TagHandler)(nil)
+var _ IScopeApiHandler = (*PlaylistHandler)(nil)
+
 // =====
 // @Summary	Read media
 // @Tags		media
 // @Produce	json
 // @Param		id	path	string	true	"Media ID"
 // @Success	200	{object}	MediaReadResType
+// @Failure	default	{object}	HttpErr
 // @Router		/api/media/{id}/ [get]
 // @Security	ApiKeyAuth
 func (h *MediaHandler) BindReadRequest(g *gin.Context) (bson.D, error) {
@@ -95,8 +151,11 @@ func (h *MediaHandler) MarshalReadResponse(g *gin.Context, v *types.MediaFileDoc
 // @Summary	List media
 // @Tags		media
 // @Produce	json
-// @Param		page	query	int	false	"page"
+// @Param		page	query	int			false	"page"
+// @Param		tag		query	[]string	false	"only media having all of these tag IDs"	collectionFormat(multi)
+// @Param		watched	query	bool		false	"only media the authenticated user has (or has not) watched"
 // @Success	200		{object}	MediaListResType
+// @Failure	default	{object}	HttpErr
 // @Router		/api/media/ [get]
 // @Security	ApiKeyAuth
 func (h *MediaHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.MediaFileDoc]) (finder.IFinder[types.MediaFileDoc], error) {
@@ -105,7 +164,11 @@ func (h *MediaHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.
 	if err := g.ShouldBindQuery(&v); err != nil {
 		return nil, err
 	}
-	fnd = fnd.Sort(bson.D{{Key: "created_at", Value: -1}}).Skip(resultPerPage * int64(v.Page)).Limit(resultPerPage)
+	filter, err := h.getListFilter(g, v)
+	if err != nil {
+		return nil, err
+	}
+	fnd = fnd.Filter(filter).Sort(bson.D{{Key: "created_at", Value: -1}}).Skip(resultPerPage * int64(v.Page)).Limit(resultPerPage)
 	return fnd, nil
 }
 
@@ -114,6 +177,7 @@ func (h *MediaHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.
 // @Produce	json
 // @Param		id	path	string	true	"Media ID"
 // @Success	200
+// @Failure	default	{object}	HttpErr
 // @Router		/api/media/{id}/ [delete]
 // @Security	ApiKeyAuth
 func (h *MediaHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
@@ -128,13 +192,67 @@ func (h *MediaHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
 	q := query.Id(idObj)
 	return q, nil
 }
+
+// @Summary	Update media
+// @Tags		media
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string				true	"Media ID"
+// @Param		data	body		MediaUpdateReqType	true	"Fields to update"
+// @Success	200		{object}	types.MediaFileDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/ [patch]
+// @Security	ApiKeyAuth
+func (h *MediaHandler) BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error) {
+	var qID MediaReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, nil, err
+	}
+	idObj, err := bson.ObjectIDFromHex(qID.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid id: %w", err)
+	}
+	var v MediaUpdateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, nil, err
+	}
+	fields := bson.D{}
+	if v.Name != nil {
+		fields = append(fields, bson.E{Key: types.MediaFileDoc__NameField, Value: *v.Name})
+	}
+	if v.Description != nil {
+		fields = append(fields, bson.E{Key: types.MediaFileDoc__DescriptionField, Value: *v.Description})
+	}
+	if v.Fields != nil {
+		fields = append(fields, bson.E{Key: types.MediaFileDoc__FieldsField, Value: v.Fields})
+	}
+	if v.Tags != nil {
+		tags, err := parseObjectIDs(v.Tags)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid tags: %w", err)
+		}
+		fields = append(fields, bson.E{Key: types.MediaFileDoc__TagsField, Value: tags})
+	}
+	return query.Id(idObj), fields, nil
+}
+func (h *MediaHandler) MarshalUpdateResponse(g *gin.Context, v *types.MediaFileDoc) (any, error) {
+	return v, nil
+}
 func (h *MediaHandler) MarshalListResponse(g *gin.Context, v []*types.MediaFileDoc) (any, error) {
 	res := make([]*types.MediaFileDoc, len(v))
 	for i, doc := range v {
 		_v := types.MediaFileDoc(*doc)
 		res[i] = &_v
 	}
-	total, err := h.DBContainer.GetMongoContainer().GetMediaFileCollection().Finder().Count(g.Request.Context())
+	var req MediaListReqType
+	if err := g.ShouldBindQuery(&req); err != nil {
+		return nil, err
+	}
+	filter, err := h.getListFilter(g, req)
+	if err != nil {
+		return nil, err
+	}
+	total, err := h.DBContainer.GetMongoContainer().GetMediaFileCollection().Finder().Filter(filter).Count(g.Request.Context())
 	if err != nil {
 		return nil, fmt.Errorf("error counting media: %w", err)
 	}
@@ -146,6 +264,46 @@ func (h *MediaHandler) MarshalListResponse(g *gin.Context, v []*types.MediaFileD
 func (h *MediaHandler) getLogger(fn string) *logrus.Entry {
 	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
 }
+func (h *MediaHandler) getListFilter(g *gin.Context, req MediaListReqType) (bson.D, error) {
+	b := query.NewBuilder()
+	if len(req.Tags) > 0 {
+		tags, err := parseObjectIDs(req.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag: %w", err)
+		}
+		b.All(types.MediaFileDoc__TagsField, toAnySlice(tags)...)
+	}
+	if req.Watched != nil {
+		watched, err := h.getWatchedIDs(g)
+		if err != nil {
+			return nil, err
+		}
+		if *req.Watched {
+			b.In("_id", toAnySlice(watched)...)
+		} else {
+			b.Nin("_id", toAnySlice(watched)...)
+		}
+	}
+	return b.Build(), nil
+}
+
+// getWatchedIDs returns the IDs of media the authenticated user has watched.
+func (h *MediaHandler) getWatchedIDs(g *gin.Context) ([]bson.ObjectID, error) {
+	userID, err := getPrincipalUserID(g)
+	if err != nil {
+		return nil, fmt.Errorf("can not filter by watched state: %w", err)
+	}
+	filter := bsonx.NewD().Add(types.WatchProgressDoc__UserIDField, userID).Add(types.WatchProgressDoc__WatchedField, true).Build()
+	progress, err := h.DBContainer.GetMongoContainer().GetWatchProgressCollection().Finder().Filter(filter).Find(g.Request.Context())
+	if err != nil {
+		return nil, fmt.Errorf("error finding watched media: %w", err)
+	}
+	ids := make([]bson.ObjectID, len(progress))
+	for i, p := range progress {
+		ids[i] = p.MediaID
+	}
+	return ids, nil
+}
 func (h *MediaHandler) getNeighborsId(ctx context.Context, v *types.MediaFileDoc, qFactory func(string, any) *query.Builder, sort int) (*bson.ObjectID, error) {
 	fnd := h.DBContainer.GetMongoContainer().GetMediaFileCollection().Finder()
 	createdAtField := "created_at"
@@ -165,6 +323,7 @@ func (h *MediaHandler) getNeighborsId(ctx context.Context, v *types.MediaFileDoc
 // @Produce	json
 // @Param		data	body		types.JobReqDoc	true	"Job Request Data"
 // @Success	200	{object}	types.JobReqDoc
+// @Failure	default	{object}	HttpErr
 // @Router		/api/jobReq/ [post]
 // @Security	ApiKeyAuth
 func (h *JobReqHandler) BindCreateRequest(g *gin.Context) (*types.JobReqDoc, error) {
@@ -182,6 +341,7 @@ func (h *JobReqHandler) MarshalCreateResponse(g *gin.Context, v *types.JobReqDoc
 // @Tags		jobReq
 // @Produce	json
 // @Success	200	{array}	types.JobReqDoc
+// @Failure	default	{object}	HttpErr
 // @Router		/api/jobReq/ [get]
 // @Security	ApiKeyAuth
 func (h *JobReqHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.JobReqDoc]) (finder.IFinder[types.JobReqDoc], error) {
@@ -193,6 +353,7 @@ func (h *JobReqHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types
 // @Produce	json
 // @Param		id	path		string	true	"Job Request ID"
 // @Success	200	{string}	string	"OK"
+// @Failure	default	{object}	HttpErr
 // @Router		/api/jobReq/{id}/ [delete]
 // @Security	ApiKeyAuth
 func (h *JobReqHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
@@ -207,6 +368,53 @@ func (h *JobReqHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
 	q := query.Id(idObj)
 	return q, nil
 }
+
+// @Summary	Claim job request
+// @Description	Mark a job request as claimed (or release it) so other workers skip it. Claiming a request which is
+// @Description	already claimed fails with a conflict, so a single worker gets it.
+// @Tags		jobReq
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string				true	"Job Request ID"
+// @Param		data	body		JobReqUpdateReqType	true	"Claim state"
+// @Success	200		{object}	types.JobReqDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/jobReq/{id}/ [patch]
+// @Security	ApiKeyAuth
+func (h *JobReqHandler) BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error) {
+	var qID JobReqDelReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, nil, err
+	}
+	q, err := idQuery(qID.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var v JobReqUpdateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, nil, err
+	}
+	var claimedAt any
+	if v.Claimed {
+		claimedAt = time.Now()
+		// the request is claimed only if no other worker did
+		q = append(q, bson.E{Key: types.JobReqDoc__ClaimedAtField, Value: nil})
+	}
+	return q, bson.D{{Key: types.JobReqDoc__ClaimedAtField, Value: claimedAt}}, nil
+}
+
+// IsConditionalUpdate reports whether the update is a claim, which requires the request to be unclaimed.
+func (h *JobReqHandler) IsConditionalUpdate(filter bson.D) bool {
+	for _, e := range filter {
+		if e.Key == types.JobReqDoc__ClaimedAtField {
+			return true
+		}
+	}
+	return false
+}
+func (h *JobReqHandler) MarshalUpdateResponse(g *gin.Context, v *types.JobReqDoc) (any, error) {
+	return v, nil
+}
 func (h *JobReqHandler) MarshalListResponse(g *gin.Context, v []*types.JobReqDoc) (any, error) {
 	res := make([]*types.JobReqDoc, len(v))
 	for i, doc := range v {
@@ -224,6 +432,7 @@ func (h *JobReqHandler) MarshalListResponse(g *gin.Context, v []*types.JobReqDoc
 //	@Produce	json
 //	@Param		data	body		types.JobResDoc	true	"Job Response Data"
 //	@Success	200		{object}	types.JobResDoc
+//	@Failure	default	{object}	HttpErr
 //	@Router		/api/jobRes/ [post]
 //	@Security	ApiKeyAuth
 func (h *JobResHandler) BindCreateRequest(g *gin.Context) (*types.JobResDoc, error) {
@@ -236,3 +445,493 @@ func (h *JobResHandler) BindCreateRequest(g *gin.Context) (*types.JobResDoc, err
 func (h *JobResHandler) MarshalCreateResponse(g *gin.Context, v *types.JobResDoc) (any, error) {
 	return v, nil
 }
+
+// =====
+// @Summary	Create tag
+// @Tags		tag
+// @Accept		json
+// @Produce	json
+// @Param		data	body		TagCreateReqType	true	"Tag Data"
+// @Success	200		{object}	types.TagDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/tag/ [post]
+// @Security	ApiKeyAuth
+func (h *TagHandler) BindCreateRequest(g *gin.Context) (*types.TagDoc, error) {
+	var v TagCreateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, err
+	}
+	return &types.TagDoc{Name: v.Name}, nil
+}
+func (h *TagHandler) MarshalCreateResponse(g *gin.Context, v *types.TagDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Read tag
+// @Tags		tag
+// @Produce	json
+// @Param		id	path		string	true	"Tag ID"
+// @Success	200	{object}	types.TagDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/tag/{id}/ [get]
+// @Security	ApiKeyAuth
+func (h *TagHandler) BindReadRequest(g *gin.Context) (bson.D, error) {
+	var qID TagReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+func (h *TagHandler) MarshalReadResponse(g *gin.Context, v *types.TagDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	List tags
+// @Tags		tag
+// @Produce	json
+// @Success	200	{array}	types.TagDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/tag/ [get]
+// @Security	ApiKeyAuth
+func (h *TagHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.TagDoc]) (finder.IFinder[types.TagDoc], error) {
+	return fnd.Sort(bson.D{{Key: types.TagDoc__NameField, Value: 1}}), nil
+}
+func (h *TagHandler) MarshalListResponse(g *gin.Context, v []*types.TagDoc) (any, error) {
+	return TagListResType(v), nil
+}
+
+// @Summary	Update tag
+// @Tags		tag
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string				true	"Tag ID"
+// @Param		data	body		TagUpdateReqType	true	"Fields to update"
+// @Success	200		{object}	types.TagDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/tag/{id}/ [patch]
+// @Security	ApiKeyAuth
+func (h *TagHandler) BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error) {
+	var qID TagReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, nil, err
+	}
+	q, err := idQuery(qID.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var v TagUpdateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, nil, err
+	}
+	fields := bson.D{}
+	if v.Name != nil {
+		fields = append(fields, bson.E{Key: types.TagDoc__NameField, Value: *v.Name})
+	}
+	return q, fields, nil
+}
+func (h *TagHandler) MarshalUpdateResponse(g *gin.Context, v *types.TagDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Delete tag
+// @Tags		tag
+// @Produce	json
+// @Param		id	path	string	true	"Tag ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/tag/{id}/ [delete]
+// @Security	ApiKeyAuth
+func (h *TagHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
+	var qID TagReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+
+// =====
+// @Summary	Create playlist
+// @Tags		playlist
+// @Accept		json
+// @Produce	json
+// @Param		data	body		PlaylistCreateReqType	true	"Playlist Data"
+// @Success	200		{object}	types.PlaylistDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/ [post]
+// @Security	ApiKeyAuth
+func (h *PlaylistHandler) BindCreateRequest(g *gin.Context) (*types.PlaylistDoc, error) {
+	var v PlaylistCreateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, err
+	}
+	items, err := parseObjectIDs(v.Items)
+	if err != nil {
+		return nil, fmt.Errorf("invalid items: %w", err)
+	}
+	return &types.PlaylistDoc{Name: v.Name, Description: v.Description, Items: items}, nil
+}
+func (h *PlaylistHandler) MarshalCreateResponse(g *gin.Context, v *types.PlaylistDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Read playlist
+// @Tags		playlist
+// @Produce	json
+// @Param		id	path		string	true	"Playlist ID"
+// @Success	200	{object}	types.PlaylistDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/{id}/ [get]
+// @Security	ApiKeyAuth
+func (h *PlaylistHandler) BindReadRequest(g *gin.Context) (bson.D, error) {
+	var qID PlaylistReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+func (h *PlaylistHandler) MarshalReadResponse(g *gin.Context, v *types.PlaylistDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	List playlists
+// @Tags		playlist
+// @Produce	json
+// @Success	200	{array}	types.PlaylistDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/ [get]
+// @Security	ApiKeyAuth
+func (h *PlaylistHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.PlaylistDoc]) (finder.IFinder[types.PlaylistDoc], error) {
+	return fnd.Sort(bson.D{{Key: types.PlaylistDoc__NameField, Value: 1}}), nil
+}
+func (h *PlaylistHandler) MarshalListResponse(g *gin.Context, v []*types.PlaylistDoc) (any, error) {
+	return PlaylistListResType(v), nil
+}
+
+// @Summary	Update playlist
+// @Tags		playlist
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string					true	"Playlist ID"
+// @Param		data	body		PlaylistUpdateReqType	true	"Fields to update"
+// @Success	200		{object}	types.PlaylistDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/{id}/ [patch]
+// @Security	ApiKeyAuth
+func (h *PlaylistHandler) BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error) {
+	var qID PlaylistReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, nil, err
+	}
+	q, err := idQuery(qID.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var v PlaylistUpdateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, nil, err
+	}
+	fields := bson.D{}
+	if v.Name != nil {
+		fields = append(fields, bson.E{Key: types.PlaylistDoc__NameField, Value: *v.Name})
+	}
+	if v.Description != nil {
+		fields = append(fields, bson.E{Key: types.PlaylistDoc__DescriptionField, Value: *v.Description})
+	}
+	if v.Items != nil {
+		items, err := parseObjectIDs(v.Items)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid items: %w", err)
+		}
+		fields = append(fields, bson.E{Key: types.PlaylistDoc__ItemsField, Value: items})
+	}
+	return q, fields, nil
+}
+func (h *PlaylistHandler) MarshalUpdateResponse(g *gin.Context, v *types.PlaylistDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Delete playlist
+// @Tags		playlist
+// @Produce	json
+// @Param		id	path	string	true	"Playlist ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/playlist/{id}/ [delete]
+// @Security	ApiKeyAuth
+func (h *PlaylistHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
+	var qID PlaylistReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+
+// =====
+// @Summary	Create user
+// @Tags		user
+// @Accept		json
+// @Produce	json
+// @Param		data	body		UserCreateReqType	true	"User Data"
+// @Success	200		{object}	types.UserDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/user/ [post]
+// @Security	ApiKeyAuth
+func (h *UserHandler) BindCreateRequest(g *gin.Context) (*types.UserDoc, error) {
+	var v UserCreateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, err
+	}
+	return &types.UserDoc{Username: v.Username, Password: v.Password, Role: v.Role}, nil
+}
+func (h *UserHandler) MarshalCreateResponse(g *gin.Context, v *types.UserDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Read user
+// @Tags		user
+// @Produce	json
+// @Param		id	path		string	true	"User ID"
+// @Success	200	{object}	types.UserDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/user/{id}/ [get]
+// @Security	ApiKeyAuth
+func (h *UserHandler) BindReadRequest(g *gin.Context) (bson.D, error) {
+	var qID UserReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+func (h *UserHandler) MarshalReadResponse(g *gin.Context, v *types.UserDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	List users
+// @Tags		user
+// @Produce	json
+// @Success	200	{array}	types.UserDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/user/ [get]
+// @Security	ApiKeyAuth
+func (h *UserHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.UserDoc]) (finder.IFinder[types.UserDoc], error) {
+	return fnd.Sort(bson.D{{Key: types.UserDoc__UsernameField, Value: 1}}), nil
+}
+func (h *UserHandler) MarshalListResponse(g *gin.Context, v []*types.UserDoc) (any, error) {
+	return UserListResType(v), nil
+}
+
+// @Summary	Update user
+// @Description	Change the role or password of a user. Changing the password revokes all of the user's sessions.
+// @Tags		user
+// @Accept		json
+// @Produce	json
+// @Param		id		path		string				true	"User ID"
+// @Param		data	body		UserUpdateReqType	true	"Fields to update"
+// @Success	200		{object}	types.UserDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/user/{id}/ [patch]
+// @Security	ApiKeyAuth
+func (h *UserHandler) BindUpdateRequest(g *gin.Context) (bson.D, bson.D, error) {
+	var qID UserReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, nil, err
+	}
+	q, err := idQuery(qID.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var v UserUpdateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, nil, err
+	}
+	fields := bson.D{}
+	if v.Role != nil {
+		fields = append(fields, bson.E{Key: types.UserDoc__RoleField, Value: *v.Role})
+	}
+	if v.Password != nil {
+		fields = append(fields, bson.E{Key: types.UserDoc__PasswordField, Value: *v.Password})
+	}
+	return q, fields, nil
+}
+func (h *UserHandler) MarshalUpdateResponse(g *gin.Context, v *types.UserDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	Delete user
+// @Tags		user
+// @Produce	json
+// @Param		id	path	string	true	"User ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/user/{id}/ [delete]
+// @Security	ApiKeyAuth
+func (h *UserHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
+	var qID UserReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+
+// RequiredRole restricts user management to admins.
+func (h *UserHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.ADMINUserRole
+}
+
+// =====
+// @Summary	Create api key
+// @Description	The plain key and its s3 gateway credentials are only returned by this call.
+// @Tags		apikey
+// @Accept		json
+// @Produce	json
+// @Param		data	body		ApiKeyCreateReqType	true	"Api Key Data"
+// @Success	200		{object}	ApiKeyCreateResType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/apikey/ [post]
+// @Security	ApiKeyAuth
+func (h *ApiKeyHandler) BindCreateRequest(g *gin.Context) (*types.ApiKeyDoc, error) {
+	var v ApiKeyCreateReqType
+	if err := g.ShouldBindJSON(&v); err != nil {
+		return nil, err
+	}
+	doc := &types.ApiKeyDoc{Name: v.Name, Scopes: v.Scopes, ExpiresAt: v.ExpiresAt}
+	if p := getPrincipal(g); p != nil && p.User != nil {
+		doc.CreatedBy = p.User.ID
+	}
+	return doc, nil
+}
+func (h *ApiKeyHandler) MarshalCreateResponse(g *gin.Context, v *types.ApiKeyDoc) (any, error) {
+	res := ApiKeyCreateResType{ApiKeyDoc: v}
+	res.S3AccessKeyID, res.S3SecretAccessKey = h.Authenticator.S3Credentials(v)
+	return res, nil
+}
+
+// @Summary	Read api key
+// @Tags		apikey
+// @Produce	json
+// @Param		id	path		string	true	"Api Key ID"
+// @Success	200	{object}	types.ApiKeyDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/apikey/{id}/ [get]
+// @Security	ApiKeyAuth
+func (h *ApiKeyHandler) BindReadRequest(g *gin.Context) (bson.D, error) {
+	var qID ApiKeyReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+func (h *ApiKeyHandler) MarshalReadResponse(g *gin.Context, v *types.ApiKeyDoc) (any, error) {
+	return v, nil
+}
+
+// @Summary	List api keys
+// @Tags		apikey
+// @Produce	json
+// @Success	200	{array}	types.ApiKeyDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/apikey/ [get]
+// @Security	ApiKeyAuth
+func (h *ApiKeyHandler) BindListRequest(g *gin.Context, fnd finder.IFinder[types.ApiKeyDoc]) (finder.IFinder[types.ApiKeyDoc], error) {
+	return fnd.Sort(bson.D{{Key: types.ApiKeyDoc__NameField, Value: 1}}), nil
+}
+func (h *ApiKeyHandler) MarshalListResponse(g *gin.Context, v []*types.ApiKeyDoc) (any, error) {
+	return ApiKeyListResType(v), nil
+}
+
+// @Summary	Revoke api key
+// @Tags		apikey
+// @Produce	json
+// @Param		id	path	string	true	"Api Key ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/apikey/{id}/ [delete]
+// @Security	ApiKeyAuth
+func (h *ApiKeyHandler) BindDeleteRequest(g *gin.Context) (bson.D, error) {
+	var qID ApiKeyReadReqType
+	if err := g.ShouldBindUri(&qID); err != nil {
+		return nil, err
+	}
+	return idQuery(qID.ID)
+}
+
+// RequiredRole restricts api key management to admins.
+func (h *ApiKeyHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.ADMINUserRole
+}
+
+// =====
+// RequiredScope declares the api key scopes of media routes.
+func (h *MediaHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	switch method {
+	case http.MethodGet:
+		return types.MEDIAREADApiKeyScope
+	case http.MethodDelete:
+		return types.MEDIADELETEApiKeyScope
+	default:
+		return types.MEDIAWRITEApiKeyScope
+	}
+}
+
+// RequiredScope lets job workers list and remove job requests. Creating requests is left to users.
+func (h *JobReqHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	if method == http.MethodPost {
+		return ""
+	}
+	return types.JOBSCLAIMApiKeyScope
+}
+
+// RequiredScope lets job workers submit job results.
+func (h *JobResHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.JOBSSUBMITApiKeyScope
+}
+
+// RequiredScope declares the api key scopes of tag routes.
+func (h *TagHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return mediaReadWriteScope(method)
+}
+
+// RequiredScope declares the api key scopes of playlist routes.
+func (h *PlaylistHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return mediaReadWriteScope(method)
+}
+
+// mediaReadWriteScope maps GET to media:read and any other method to media:write.
+func mediaReadWriteScope(method string) types.ApiKeyScopeEnum {
+	if method == http.MethodGet {
+		return types.MEDIAREADApiKeyScope
+	}
+	return types.MEDIAWRITEApiKeyScope
+}
+
+// =====
+// idQuery builds a query matching the document with the given hex ID.
+func idQuery(id string) (bson.D, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %w", err)
+	}
+	return query.Id(idObj), nil
+}
+
+// parseObjectIDs parses a list of hex IDs.
+func parseObjectIDs(ids []string) ([]bson.ObjectID, error) {
+	res := make([]bson.ObjectID, len(ids))
+	for i, id := range ids {
+		idObj, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id (%s): %w", id, err)
+		}
+		res[i] = idObj
+	}
+	return res, nil
+}
+
+// toAnySlice converts a typed slice to []any, as required by the variadic query builders.
+func toAnySlice[T any](v []T) []any {
+	res := make([]any, len(v))
+	for i := range v {
+		res[i] = v[i]
+	}
+	return res
+}
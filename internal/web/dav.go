@@ -0,0 +1,78 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+// davPrefix is the path the WebDAV handler is mounted at.
+const davPrefix = "/dav"
+
+// davReadMethods are the WebDAV methods served; the filesystem is read-only and all other methods are refused.
+var davReadMethods = []string{http.MethodOptions, http.MethodGet, http.MethodHead, "PROPFIND", "LOCK", "UNLOCK"}
+
+// davWriteMethods are the WebDAV methods refused with 405.
+var davWriteMethods = []string{http.MethodPut, http.MethodDelete, http.MethodPost, "PROPPATCH", "MKCOL", "COPY", "MOVE"}
+
+// DavHandler serves a read-only webdav.FileSystem at /dav/, as an alternative to the FUSE mount
+// for clients that can not mount filesystems (e.g. Kodi, rclone or file managers).
+type DavHandler struct {
+	fs  webdav.FileSystem
+	dav *webdav.Handler
+}
+
+// Handle serves a WebDAV request.
+func (d *DavHandler) Handle(g *gin.Context) {
+	r := g.Request
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		// set the content type up front, so http.ServeContent does not read the file to sniff it
+		if fi, err := d.fs.Stat(r.Context(), strings.TrimPrefix(r.URL.Path, davPrefix)); err == nil {
+			if ct, ok := fi.(webdav.ContentTyper); ok {
+				if contentType, err := ct.ContentType(r.Context()); err == nil {
+					g.Header("Content-Type", contentType)
+				}
+			}
+		}
+	}
+	d.dav.ServeHTTP(g.Writer, r)
+}
+
+// ReadOnly refuses methods that would modify the filesystem.
+func (d *DavHandler) ReadOnly(g *gin.Context) {
+	g.Header("Allow", strings.Join(davReadMethods, ", "))
+	g.AbortWithStatus(http.StatusMethodNotAllowed)
+}
+
+// RegisterRoutes registers the WebDAV routes on the given router group. Reading requires the viewer role
+// or the stream scope, and the token can be passed as the password of Basic credentials.
+func (d *DavHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
+	access := RouteAccess{Role: types.VIEWERUserRole, Scope: types.STREAMApiKeyScope, BasicAuth: true}
+	for _, method := range davReadMethods {
+		r.Handle(method, "dav/*path", authMiddleware(access), d.Handle)
+	}
+	for _, method := range davWriteMethods {
+		r.Handle(method, "dav/*path", d.ReadOnly)
+	}
+}
+
+// NewDavHandler creates a WebDAV handler serving fs.
+func NewDavHandler(fs webdav.FileSystem) *DavHandler {
+	return &DavHandler{
+		fs: fs,
+		dav: &webdav.Handler{
+			Prefix:     davPrefix,
+			FileSystem: fs,
+			LockSystem: webdav.NewMemLS(),
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					log.GetLogger(log.WebModule).WithError(err).WithField("func", "DavHandler").Debugf("%s %s", r.Method, r.URL.Path)
+				}
+			},
+		},
+	}
+}
@@ -1,24 +1,139 @@
 package web
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/bot"
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrorCode is a stable, machine-readable code of an api error. Clients should rely on it rather than on messages.
+type ErrorCode string
+
+const (
+	BADREQUESTErrorCode         ErrorCode = "BAD_REQUEST"
+	UNAUTHORIZEDErrorCode       ErrorCode = "UNAUTHORIZED"
+	FORBIDDENErrorCode          ErrorCode = "FORBIDDEN"
+	NOTFOUNDErrorCode           ErrorCode = "NOT_FOUND"
+	METHODNOTALLOWEDErrorCode   ErrorCode = "METHOD_NOT_ALLOWED"
+	CONFLICTErrorCode           ErrorCode = "CONFLICT"
+	TOOMANYREQUESTSErrorCode    ErrorCode = "TOO_MANY_REQUESTS"
+	INTERNALErrorCode           ErrorCode = "INTERNAL"
+	UNAVAILABLEErrorCode        ErrorCode = "UNAVAILABLE"
+	INVALIDDOCUMENTErrorCode    ErrorCode = "INVALID_DOCUMENT"
+	INVALIDUPDATEErrorCode      ErrorCode = "INVALID_UPDATE"
+	UPDATECONFLICTErrorCode     ErrorCode = "UPDATE_CONFLICT"
+	MULTIPLEDOCUMENTSErrorCode  ErrorCode = "MULTIPLE_DOCUMENTS"
+	FILEEXISTSErrorCode         ErrorCode = "FILE_EXISTS"
+	TAGEXISTSErrorCode          ErrorCode = "TAG_EXISTS"
+	USEREXISTSErrorCode         ErrorCode = "USER_EXISTS"
+	LASTADMINErrorCode          ErrorCode = "LAST_ADMIN"
+	INVALIDCREDENTIALSErrorCode ErrorCode = "INVALID_CREDENTIALS"
+	USERLOCKEDErrorCode         ErrorCode = "USER_LOCKED"
+	WEAKPASSWORDErrorCode       ErrorCode = "WEAK_PASSWORD"
+	INVALIDTOKENErrorCode       ErrorCode = "INVALID_TOKEN"
+	SESSIONEXPIREDErrorCode     ErrorCode = "SESSION_EXPIRED"
+	APIKEYEXPIREDErrorCode      ErrorCode = "API_KEY_EXPIRED"
+	STREAMURLEXPIREDErrorCode   ErrorCode = "STREAM_URL_EXPIRED"
+	NOTHUMBNAILErrorCode        ErrorCode = "NO_THUMBNAIL"
+	MESSAGENOTFOUNDErrorCode    ErrorCode = "MESSAGE_NOT_FOUND"
+	NOTDOCUMENTErrorCode        ErrorCode = "NOT_DOCUMENT"
+	NOWORKERErrorCode           ErrorCode = "NO_WORKER"
+	FLOODWAITErrorCode          ErrorCode = "FLOOD_WAIT"
 )
 
+// errorKinds maps the sentinel errors of the other packages to their code and status. The first match wins, so
+// specific errors go before the generic ones they may wrap.
+var errorKinds = []struct {
+	err    error
+	code   ErrorCode
+	status int
+}{
+	{facade.ErrInvalidDocument, INVALIDDOCUMENTErrorCode, http.StatusBadRequest},
+	{facade.ErrInvalidUpdate, INVALIDUPDATEErrorCode, http.StatusBadRequest},
+	{facade.ErrUpdateConflict, UPDATECONFLICTErrorCode, http.StatusConflict},
+	{facade.ErrNoDocumentsFound, NOTFOUNDErrorCode, http.StatusNotFound},
+	{mongo.ErrNoDocuments, NOTFOUNDErrorCode, http.StatusNotFound},
+	{facade.ErrMultipleDocumentsFound, MULTIPLEDOCUMENTSErrorCode, http.StatusConflict},
+	{facade.ErrFileAlreadyExists, FILEEXISTSErrorCode, http.StatusConflict},
+	{facade.ErrTagAlreadyExists, TAGEXISTSErrorCode, http.StatusConflict},
+	{facade.ErrUserAlreadyExists, USEREXISTSErrorCode, http.StatusConflict},
+	{facade.ErrLastAdmin, LASTADMINErrorCode, http.StatusConflict},
+	{auth.ErrInvalidCredentials, INVALIDCREDENTIALSErrorCode, http.StatusUnauthorized},
+	{auth.ErrUserLocked, USERLOCKEDErrorCode, http.StatusTooManyRequests},
+	{auth.ErrWeakPassword, WEAKPASSWORDErrorCode, http.StatusBadRequest},
+	{auth.ErrInvalidToken, INVALIDTOKENErrorCode, http.StatusUnauthorized},
+	{auth.ErrSessionExpired, SESSIONEXPIREDErrorCode, http.StatusUnauthorized},
+	{auth.ErrApiKeyExpired, APIKEYEXPIREDErrorCode, http.StatusUnauthorized},
+	{auth.ErrStreamUrlExpired, STREAMURLEXPIREDErrorCode, http.StatusUnauthorized},
+	{stream.ErrNoThumbnail, NOTHUMBNAILErrorCode, http.StatusNotFound},
+	{stream.ErrMessageNotFound, MESSAGENOTFOUNDErrorCode, http.StatusNotFound},
+	{stream.ErrFloodWait, FLOODWAITErrorCode, http.StatusServiceUnavailable},
+	{stream.ErrNoWorker, NOWORKERErrorCode, http.StatusServiceUnavailable},
+	{bot.ErrNoWorker, NOWORKERErrorCode, http.StatusServiceUnavailable},
+	{bot.ErrNotDocument, NOTDOCUMENTErrorCode, http.StatusBadRequest},
+	{minio.ErrFileNotFound, NOTFOUNDErrorCode, http.StatusNotFound},
+}
+
+// statusCodes are the generic codes of errors without a specific kind.
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          BADREQUESTErrorCode,
+	http.StatusUnauthorized:        UNAUTHORIZEDErrorCode,
+	http.StatusForbidden:           FORBIDDENErrorCode,
+	http.StatusNotFound:            NOTFOUNDErrorCode,
+	http.StatusMethodNotAllowed:    METHODNOTALLOWEDErrorCode,
+	http.StatusConflict:            CONFLICTErrorCode,
+	http.StatusTooManyRequests:     TOOMANYREQUESTSErrorCode,
+	http.StatusInternalServerError: INTERNALErrorCode,
+	http.StatusServiceUnavailable:  UNAVAILABLEErrorCode,
+}
+
+// HttpErr is the body of api error responses.
 type HttpErr struct {
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"msg"`
+	StatusCode int       `json:"statusCode"`
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"msg"`
+	RequestID  string    `json:"requestId,omitempty"` // also sent as the X-Request-ID header
 }
 
 func (e HttpErr) Error() string {
 	return e.Message
 }
 
+// NewHttpError creates an api error. Known sentinel errors get their own code and status; statusCode applies to
+// all other errors.
 func NewHttpError(err error, statusCode int) HttpErr {
 	e := HttpErr{
 		StatusCode: statusCode,
+		Code:       statusCodeError(statusCode),
 		Message:    err.Error(),
 	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			e.StatusCode, e.Code = k.status, k.code
+			break
+		}
+	}
 	return e
 }
 
+// statusCodeError returns the generic code of a status.
+func statusCodeError(statusCode int) ErrorCode {
+	if code, ok := statusCodes[statusCode]; ok {
+		return code
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return INTERNALErrorCode
+	}
+	return BADREQUESTErrorCode
+}
+
 var ErrNotImplemented = fmt.Errorf("not supported method")
+var errMissingToken = errors.New("missing token")
+var errForbidden = errors.New("the role or scope of the token does not allow this request")
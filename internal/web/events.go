@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/events"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// eventsKeepAlive is the interval of comment lines sent to keep idle connections open.
+const eventsKeepAlive = 30 * time.Second
+
+// EventsApiHandler streams bus events as server-sent events.
+type EventsApiHandler struct {
+	Bus events.IBus
+}
+
+var _ IGetApiHandler = (*EventsApiHandler)(nil)
+var _ IScopeApiHandler = (*EventsApiHandler)(nil)
+var _ IQueryTokenApiHandler = (*EventsApiHandler)(nil)
+
+// @Summary	Event stream
+// @Description	Server-sent events for media, job and worker changes. Send `Last-Event-ID` (or `lastEventId`) to resume from the backlog.
+// @Produce	text/event-stream
+// @Param		type		query	[]string	false	"Only send events of these types"	collectionFormat(multi)
+// @Param		lastEventId	query	int			false	"Resume after this event ID"
+// @Success	200	{object}	events.Event
+// @Failure	default	{object}	HttpErr
+// @Router		/api/events/ [get]
+// @Security	ApiKeyAuth
+func (h *EventsApiHandler) Get(g *gin.Context) {
+	ll := h.getLogger("Get")
+	var req EventsGetReqType
+	if err := g.ShouldBindQuery(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	lastID := req.LastEventID
+	if v := g.GetHeader("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			g.Error(NewHttpError(fmt.Errorf("invalid Last-Event-ID: %w", err), http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		lastID = id
+	}
+	backlog, ch, cancel := h.Bus.Subscribe(lastID)
+	defer cancel()
+	g.Header("Content-Type", "text/event-stream")
+	g.Header("Cache-Control", "no-cache")
+	g.Header("Connection", "keep-alive")
+	g.Header("X-Accel-Buffering", "no")
+	g.Status(http.StatusOK)
+	for _, e := range backlog {
+		if err := writeEvent(g.Writer, e, req.Types); err != nil {
+			ll.WithError(err).Debug("can not write event")
+			return
+		}
+	}
+	g.Writer.Flush()
+	ticker := time.NewTicker(eventsKeepAlive)
+	defer ticker.Stop()
+	g.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if err := writeEvent(w, e, req.Types); err != nil {
+				ll.WithError(err).Debug("can not write event")
+				return false
+			}
+			return true
+		case <-ticker.C:
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			return err == nil
+		case <-g.Request.Context().Done():
+			return false
+		}
+	})
+}
+func (h *EventsApiHandler) AuthGet() bool {
+	return true
+}
+func (h *EventsApiHandler) RelativePathGet() string {
+	return "/"
+}
+func (h *EventsApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+func (h *EventsApiHandler) AllowQueryToken() bool {
+	return true
+}
+func (h *EventsApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
+
+// writeEvent writes e in the server-sent events format, unless it is filtered out by typs.
+func writeEvent(w io.Writer, e events.Event, typs []events.EventTypeEnum) error {
+	if len(typs) > 0 && !slices.Contains(typs, e.Type) {
+		return nil
+	}
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return fmt.Errorf("can not marshal event data: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
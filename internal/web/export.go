@@ -0,0 +1,307 @@
+package web
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/db"
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// feedSize is the number of recently added media listed in rss and atom feeds.
+const feedSize = 50
+
+// export formats of MediaExportApiHandler
+const (
+	m3u8ExportFormat = "m3u8"
+	rssExportFormat  = "rss"
+	atomExportFormat = "atom"
+	jsonExportFormat = "json"
+	csvExportFormat  = "csv"
+)
+
+// MediaExportApiHandler exports the media matching the filters of the media list as an m3u8 playlist, an rss or
+// atom feed of the recently added ones, or a json or csv dump of their metadata.
+type MediaExportApiHandler struct {
+	DBContainer  db.IDbContainer
+	MediaFacade  facade.IFacade[types.MediaFileDoc]
+	PublicUrl    string
+	MinioClient  minio.IMinioClient // presigns thumbnail urls in feeds; thumbnails are omitted when nil
+	PresignTTL   time.Duration      // validity of presigned thumbnail urls
+	StreamSigner auth.IStreamSigner // signs stream urls when stream auth is enabled
+}
+
+var _ IGetApiHandler = (*MediaExportApiHandler)(nil)
+var _ IScopeApiHandler = (*MediaExportApiHandler)(nil)
+var _ IQueryTokenApiHandler = (*MediaExportApiHandler)(nil)
+
+// @Summary	Export media
+// @Description	Exports the media matching the filters, newest first: `m3u8` is a playlist of stream urls, `rss` and `atom`
+// @Description	are feeds of the recently added media with their thumbnails, `json` and `csv` dump the metadata.
+// @Description	For feed readers, the token can be passed in the `token` query parameter. With stream auth, stream urls
+// @Description	are signed for their media and expire, the token is never included in them.
+// @Tags		media
+// @Produce	audio/x-mpegurl,application/rss+xml,application/atom+xml,json,text/csv
+// @Param		format	path	string		true	"export format"	Enums(m3u8, rss, atom, json, csv)
+// @Param		tag		query	[]string	false	"only media having all of these tag IDs"	collectionFormat(multi)
+// @Param		watched	query	bool		false	"only media the authenticated user has (or has not) watched"
+// @Success	200		{array}	types.MediaFileDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/export/{format} [get]
+// @Security	ApiKeyAuth
+func (h *MediaExportApiHandler) Get(g *gin.Context) {
+	format := g.Param("format")
+	var req MediaListReqType
+	if err := g.ShouldBindQuery(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	mediaHandler := MediaHandler{DBContainer: h.DBContainer}
+	filter, err := mediaHandler.getListFilter(g, req)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	fnd := h.MediaFacade.GetCollection().Finder().Filter(filter).Sort(bson.D{{Key: "created_at", Value: -1}})
+	switch format {
+	case rssExportFormat, atomExportFormat:
+		fnd = fnd.Limit(feedSize)
+	case m3u8ExportFormat, jsonExportFormat, csvExportFormat:
+	default:
+		g.Error(NewHttpError(fmt.Errorf("unknown export format: %s", format), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	media, err := fnd.Find(g.Request.Context())
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	h.getLogger("Get").Infof("exporting %d media as %s", len(media), format)
+	baseUrl := getBaseUrl(g, h.PublicUrl)
+	switch format {
+	case m3u8ExportFormat:
+		g.Header("Content-Disposition", "attachment; filename=\"media.m3u8\"")
+		g.Data(http.StatusOK, "audio/x-mpegurl", []byte(buildM3U("", media, baseUrl, h.StreamSigner)))
+	case rssExportFormat:
+		h.writeXML(g, "application/rss+xml", h.buildRSS(g.Request.Context(), media, baseUrl))
+	case atomExportFormat:
+		h.writeXML(g, "application/atom+xml", h.buildAtom(g.Request.Context(), media, baseUrl))
+	case jsonExportFormat:
+		body, err := json.Marshal(media)
+		if err != nil {
+			g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+			return
+		}
+		g.Header("Content-Disposition", "attachment; filename=\"media.json\"")
+		g.Data(http.StatusOK, "application/json", body)
+	case csvExportFormat:
+		body, err := buildMediaCSV(media)
+		if err != nil {
+			g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+			return
+		}
+		g.Header("Content-Disposition", "attachment; filename=\"media.csv\"")
+		g.Data(http.StatusOK, "text/csv; charset=utf-8", body)
+	}
+}
+func (h *MediaExportApiHandler) AuthGet() bool {
+	return true
+}
+func (h *MediaExportApiHandler) RelativePathGet() string {
+	return "/:format"
+}
+func (h *MediaExportApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+func (h *MediaExportApiHandler) AllowQueryToken() bool {
+	return true
+}
+
+// buildRSS returns an rss 2.0 feed of the media, linking their streams and enclosing their thumbnails.
+func (h *MediaExportApiHandler) buildRSS(ctx context.Context, media []*types.MediaFileDoc, baseUrl string) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "TGMon",
+			Link:        baseUrl,
+			Description: "Recently added media",
+			Items:       make([]rssItem, len(media)),
+		},
+	}
+	if len(media) > 0 {
+		feed.Channel.LastBuildDate = media[0].CreatedAt.UTC().Format(time.RFC1123Z)
+	}
+	for i, m := range media {
+		item := rssItem{
+			Title:       m.DisplayName(),
+			Link:        getStreamUrl(baseUrl, m.ID, h.StreamSigner),
+			GUID:        rssGUID{IsPermaLink: false, Value: m.ID.Hex()},
+			PubDate:     m.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: m.Description,
+		}
+		if thumb := presignThumbnail(ctx, h.MinioClient, h.PresignTTL, m); thumb != "" {
+			item.Enclosure = &rssEnclosure{URL: thumb, Length: 0, Type: "image/jpeg"}
+		}
+		feed.Channel.Items[i] = item
+	}
+	return feed
+}
+
+// buildAtom returns an atom feed of the media, linking their streams and enclosing their thumbnails.
+func (h *MediaExportApiHandler) buildAtom(ctx context.Context, media []*types.MediaFileDoc, baseUrl string) *atomFeed {
+	feed := &atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      baseUrl + "/api/media/export/atom",
+		Title:   "TGMon",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "TGMon"},
+		Links:   []atomLink{{Href: baseUrl, Rel: "alternate"}},
+		Entries: make([]atomEntry, len(media)),
+	}
+	if len(media) > 0 {
+		feed.Updated = media[0].CreatedAt.UTC().Format(time.RFC3339)
+	}
+	for i, m := range media {
+		entry := atomEntry{
+			ID:        "urn:tgmon:media:" + m.ID.Hex(),
+			Title:     m.DisplayName(),
+			Updated:   m.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: m.CreatedAt.UTC().Format(time.RFC3339),
+			Summary:   m.Description,
+			Links:     []atomLink{{Href: getStreamUrl(baseUrl, m.ID, h.StreamSigner), Rel: "alternate", Type: m.Meta.MimeType}},
+		}
+		if thumb := presignThumbnail(ctx, h.MinioClient, h.PresignTTL, m); thumb != "" {
+			entry.Links = append(entry.Links, atomLink{Href: thumb, Rel: "enclosure", Type: "image/jpeg"})
+		}
+		feed.Entries[i] = entry
+	}
+	return feed
+}
+
+func (h *MediaExportApiHandler) writeXML(g *gin.Context, contentType string, v any) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.Data(http.StatusOK, contentType+"; charset=utf-8", append([]byte(xml.Header), body...))
+}
+func (h *MediaExportApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
+
+// mediaCSVHeader is the header row of csv exports.
+var mediaCSVHeader = []string{
+	"ID", "Name", "Description", "FileName", "MimeType", "FileSize", "Duration", "MessageID",
+	"Tags", "Fields", "Thumbnail", "Vtt", "Sprite", "CreatedAt", "UpdatedAt",
+}
+
+// buildMediaCSV returns a csv dump of the media metadata. Tags are separated by `;` and custom fields are a json
+// object, so any value survives the round trip.
+func buildMediaCSV(media []*types.MediaFileDoc) ([]byte, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(mediaCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, m := range media {
+		tags := make([]string, len(m.Tags))
+		for i, t := range m.Tags {
+			tags[i] = t.Hex()
+		}
+		fields := ""
+		if len(m.Fields) > 0 {
+			v, err := json.Marshal(m.Fields)
+			if err != nil {
+				return nil, err
+			}
+			fields = string(v)
+		}
+		row := []string{
+			m.ID.Hex(), m.Name, m.Description, m.Meta.FileName, m.Meta.MimeType,
+			strconv.FormatInt(m.Meta.FileSize, 10), strconv.FormatFloat(m.Meta.Duration, 'f', -1, 64),
+			strconv.Itoa(m.MessageID), strings.Join(tags, ";"), fields, m.Thumbnail, m.Vtt, m.Sprite,
+			m.CreatedAt.UTC().Format(time.RFC3339), m.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// rssEnclosure is the media attached to an item; the length of thumbnails is unknown, which rss allows as 0.
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Summary   string     `xml:"summary,omitempty"`
+	Links     []atomLink `xml:"link"`
+}
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
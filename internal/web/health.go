@@ -0,0 +1,42 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/amirdaaee/TGMon/internal/health"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the unauthenticated liveness and readiness probes at /healthz and /readyz.
+type HealthHandler struct {
+	Checker *health.Checker
+}
+
+// @Summary	Liveness probe
+// @Description	Succeeds as long as the web server is serving requests.
+// @Tags		health
+// @Produce	json
+// @Success	200	{object}	health.LiveResType
+// @Router		/healthz [get]
+func (h *HealthHandler) Live(g *gin.Context) {
+	g.JSON(http.StatusOK, health.LiveResType{Status: health.OKStatus})
+}
+
+// @Summary	Readiness probe
+// @Description	Checks mongo, the minio bucket, the connected workers and the fuse mount, with the detail of each.
+// @Description	Fails with 503 when a check fails, and from the start of a graceful shutdown.
+// @Tags		health
+// @Produce	json
+// @Success	200	{object}	health.Report
+// @Failure	503	{object}	health.Report
+// @Router		/readyz [get]
+func (h *HealthHandler) Ready(g *gin.Context) {
+	res := h.Checker.Ready(g.Request.Context())
+	g.JSON(res.StatusCode(), res)
+}
+
+// RegisterRoutes registers the probes on the given router group.
+func (h *HealthHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("healthz", h.Live)
+	r.GET("readyz", h.Ready)
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// messageLinkRe matches links to messages of private channels (t.me/c/<channel>/<message>), optionally in a topic
+// (t.me/c/<channel>/<topic>/<message>).
+var messageLinkRe = regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:t|telegram)\.me/c/(\d+)/(?:\d+/)?(\d+)/?(?:[?#].*)?$`)
+
+// MediaImportApiHandler registers documents already posted in the storage channel (ChannelID) as media. Limiter
+// throttles the messages resolved through the workers.
+type MediaImportApiHandler struct {
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	WorkerPool  stream.IWorkerPool
+	ChannelID   int64
+	Limiter     *rate.Limiter
+}
+
+var _ IPostApiHandler = (*MediaImportApiHandler)(nil)
+var _ IScopeApiHandler = (*MediaImportApiHandler)(nil)
+
+// @Summary	Import channel messages
+// @Description	Creates the media of documents already in the storage channel, given by t.me/c/<channel>/<message> links,
+// @Description	message IDs, or an inclusive range of message IDs (From, To). Messages are resolved one at a time and
+// @Description	throttled (HTTP__BULK_RATE per second). Messages already imported, or whose file is already a media, are reported as SKIPPED.
+// @Description	At most 1000 messages per request.
+// @Tags		media
+// @Accept		json
+// @Produce	json
+// @Param		data	body		MediaImportReqType	true	"Messages to import"
+// @Success	200		{object}	MediaImportResType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/import/ [post]
+// @Security	ApiKeyAuth
+func (h *MediaImportApiHandler) Post(g *gin.Context) {
+	ll := h.getLogger("Post")
+	var req MediaImportReqType
+	if err := g.ShouldBindJSON(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	msgIDs, err := h.getMessageIDs(req)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	items := facade.ImportMessages(g.Request.Context(), h.MediaFacade, h.WorkerPool, msgIDs, facade.BulkOptions{Limiter: h.Limiter})
+	res := MediaImportResType{Total: len(items), Items: items}
+	for _, item := range items {
+		switch item.Status {
+		case facade.DONEBulkStatus:
+			res.Done++
+		case facade.SKIPPEDBulkStatus:
+			res.Skipped++
+		case facade.FAILEDBulkStatus:
+			res.Failed++
+		}
+	}
+	ll.Infof("import: %d done, %d skipped, %d failed", res.Done, res.Skipped, res.Failed)
+	g.JSON(http.StatusOK, res)
+}
+func (h *MediaImportApiHandler) AuthPost() bool {
+	return true
+}
+func (h *MediaImportApiHandler) RelativePathPost() string {
+	return "/"
+}
+func (h *MediaImportApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAWRITEApiKeyScope
+}
+
+// getMessageIDs returns the distinct message IDs of the request, in the order they are given.
+func (h *MediaImportApiHandler) getMessageIDs(req MediaImportReqType) ([]int, error) {
+	ids := append([]int{}, req.MessageIDs...)
+	for _, link := range req.Links {
+		id, err := h.parseLink(link)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if req.From != 0 || req.To != 0 {
+		if req.From <= 0 || req.To < req.From {
+			return nil, fmt.Errorf("invalid range (%d-%d)", req.From, req.To)
+		}
+		if req.To-req.From >= bulkMaxItems {
+			return nil, fmt.Errorf("%d messages selected, at most %d allowed", req.To-req.From+1, bulkMaxItems)
+		}
+		for id := req.From; id <= req.To; id++ {
+			ids = append(ids, id)
+		}
+	}
+	res := make([]int, 0, len(ids))
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, fmt.Errorf("invalid message id (%d)", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errors.New("links, message IDs or a range is required")
+	}
+	if len(res) > bulkMaxItems {
+		return nil, fmt.Errorf("%d messages selected, at most %d allowed", len(res), bulkMaxItems)
+	}
+	return res, nil
+}
+
+// parseLink returns the message ID of a link to a message of the storage channel.
+func (h *MediaImportApiHandler) parseLink(link string) (int, error) {
+	m := messageLinkRe.FindStringSubmatch(link)
+	if m == nil {
+		return 0, fmt.Errorf("invalid message link (%s)", link)
+	}
+	if channelID, err := strconv.ParseInt(m[1], 10, 64); err != nil || channelID != h.ChannelID {
+		return 0, fmt.Errorf("link (%s) is not of the storage channel", link)
+	}
+	id, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, fmt.Errorf("invalid message link (%s): %w", link, err)
+	}
+	return id, nil
+}
+func (h *MediaImportApiHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
@@ -1,49 +1,202 @@
 package web
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/amirdaaee/TGMon/internal/auth"
+	"github.com/amirdaaee/TGMon/internal/log"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // Package api provides HTTP API middlewares for authentication, metrics, and error handling.
 
-func apiAuth(c *gin.Context, expected string) bool {
+// principalCtxKey is the gin context key under which apiAuthMiddleware stores the authenticated principal.
+const principalCtxKey = "principal"
+
+// requestIDCtxKey is the gin context key under which requestIDMiddleware stores the request ID.
+const requestIDCtxKey = "requestID"
+
+const requestIDHeader = "X-Request-ID"
+
+// AuthMiddlewareFactory returns an authentication middleware enforcing the given route access.
+type AuthMiddlewareFactory func(access RouteAccess) gin.HandlerFunc
+
+// bearerToken extracts the token from the Authorization header.
+func bearerToken(c *gin.Context) (string, bool) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		return false
+		return "", false
 	}
 	scheme, value, ok := strings.Cut(authHeader, " ")
 	if !ok || !(scheme == "Basic" || scheme == "Bearer") { //nolint:golint,staticcheck
-		return false
+		return "", false
 	}
-	return value == expected
+	return value, true
 }
 
-// apiAuthMiddleware returns a Gin middleware that enforces API authentication using the provided expected value.
-func apiAuthMiddleware(expected string) gin.HandlerFunc {
+// apiAuthMiddleware returns a Gin middleware that authenticates the request token and enforces the route access:
+// the user role for session tokens and the scope for api keys.
+func apiAuthMiddleware(authenticator auth.IAuthenticator, access RouteAccess) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if expected != "" && !apiAuth(c, expected) {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		token, ok := bearerToken(c)
+		if access.BasicAuth {
+			if _, pass, basic := c.Request.BasicAuth(); basic {
+				token, ok = pass, pass != ""
+			}
 		}
+		if !ok && access.QueryToken {
+			token = c.Query("token")
+			ok = token != ""
+		}
+		if !ok {
+			abortUnauthorized(c, access, errMissingToken)
+			return
+		}
+		p, err := authenticator.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrSessionExpired) || errors.Is(err, auth.ErrApiKeyExpired) {
+				abortUnauthorized(c, access, err)
+				return
+			}
+			log.GetLogger(log.WebModule).WithError(err).Error("can not authenticate request")
+			abortWithError(c, NewHttpError(errors.New("can not authenticate request"), http.StatusInternalServerError))
+			return
+		}
+		if !allowed(p, access) {
+			abortWithError(c, NewHttpError(errForbidden, http.StatusForbidden))
+			return
+		}
+		c.Set(principalCtxKey, p)
 		c.Next()
 	}
 }
 
+// streamAuthMiddleware authenticates stream requests by the signature of a signed stream url (see
+// auth.IStreamSigner), falling back to apiAuthMiddleware for requests without one.
+func streamAuthMiddleware(authenticator auth.IAuthenticator, access RouteAccess) gin.HandlerFunc {
+	tokenAuth := apiAuthMiddleware(authenticator, access)
+	return func(c *gin.Context) {
+		signature := c.Query("signature")
+		if signature == "" {
+			tokenAuth(c)
+			return
+		}
+		mediaID, err := bson.ObjectIDFromHex(c.Param("mediaID"))
+		if err != nil {
+			abortUnauthorized(c, access, auth.ErrInvalidToken)
+			return
+		}
+		if err := authenticator.VerifyStream(mediaID, c.Query("expires"), signature); err != nil {
+			abortUnauthorized(c, access, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortUnauthorized aborts the request with 401, asking for Basic credentials if the route accepts them.
+func abortUnauthorized(c *gin.Context, access RouteAccess, err error) {
+	if access.BasicAuth {
+		c.Header("WWW-Authenticate", `Basic realm="TGMon"`)
+	}
+	abortWithError(c, NewHttpError(err, http.StatusUnauthorized))
+}
+
+// abortWithError stops the handler chain and leaves the response to errMiddleware.
+func abortWithError(c *gin.Context, err HttpErr) {
+	c.Error(err) //nolint:golint,errcheck
+	c.Abort()
+}
+
+// allowed reports whether the principal satisfies the route access.
+func allowed(p *auth.Principal, access RouteAccess) bool {
+	if p.ApiKey != nil {
+		return access.Scope != "" && p.ApiKey.HasScope(access.Scope)
+	}
+	return p.User != nil && p.User.Role.Allows(access.Role)
+}
+
+// getPrincipal returns the principal stored by apiAuthMiddleware, or nil if the route is not authenticated.
+func getPrincipal(c *gin.Context) *auth.Principal {
+	v, ok := c.Get(principalCtxKey)
+	if !ok {
+		return nil
+	}
+	p, _ := v.(*auth.Principal)
+	return p
+}
+
+// getPrincipalUserID returns the user the request acts as. Api keys act as the user who created them.
+// The legacy static token has no user.
+func getPrincipalUserID(c *gin.Context) (bson.ObjectID, error) {
+	p := getPrincipal(c)
+	switch {
+	case p == nil:
+		return bson.NilObjectID, errors.New("not authenticated")
+	case p.ApiKey != nil && !p.ApiKey.CreatedBy.IsZero():
+		return p.ApiKey.CreatedBy, nil
+	case p.ApiKey == nil && p.User != nil && !p.User.ID.IsZero():
+		return p.User.ID, nil
+	}
+	return bson.NilObjectID, errors.New("token is not bound to a user account")
+}
+
+// requestIDMiddleware tags every request with an ID, taken from the X-Request-ID header set by the client or a
+// proxy, or generated. The ID is sent back in the same header and in error responses.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = bson.NewObjectID().Hex()
+		}
+		c.Set(requestIDCtxKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts short printable IDs, so forwarded IDs can not inject into headers or logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
 // errMiddleware is a Gin middleware that handles errors, logs them, and returns appropriate HTTP responses.
+// Errors other than HttpErr are classified by NewHttpError and reported as internal errors when unknown.
 func errMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
-		if len(c.Errors) > 0 {
-			err := c.Errors.Last()
-			switch e := err.Err.(type) {
-			case HttpErr:
-				c.AbortWithStatusJSON(e.StatusCode, e)
-			default:
-				c.AbortWithStatusJSON(http.StatusInternalServerError,
-					map[string]string{"message": "Service Unavailable"})
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last()
+		e, ok := err.Err.(HttpErr)
+		if !ok {
+			e = NewHttpError(err.Err, http.StatusInternalServerError)
+			if e.Code == INTERNALErrorCode {
+				e.Message = "internal error"
 			}
 		}
+		e.RequestID = c.GetString(requestIDCtxKey)
+		ll := log.GetLogger(log.WebModule).WithField("request_id", e.RequestID).WithError(err.Err)
+		if e.StatusCode >= http.StatusInternalServerError {
+			ll.Errorf("%s %s failed", c.Request.Method, c.Request.URL.Path)
+		} else {
+			ll.Debugf("%s %s failed", c.Request.Method, c.Request.URL.Path)
+		}
+		if c.Writer.Written() {
+			return
+		}
+		c.AbortWithStatusJSON(e.StatusCode, e)
 	}
 }
@@ -2,6 +2,7 @@ package web
 
 import (
 	docs "github.com/amirdaaee/TGMon/docs"
+	"github.com/amirdaaee/TGMon/internal/auth"
 	"github.com/amirdaaee/TGMon/internal/types"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -12,30 +13,88 @@ type HandlerContainer struct {
 	MediaHandler                *CRDApiHandler[types.MediaFileDoc]
 	JobReqHandler               *CRDApiHandler[types.JobReqDoc]
 	JobResHandler               *CRDApiHandler[types.JobResDoc]
+	TagHandler                  *CRDApiHandler[types.TagDoc]
+	PlaylistHandler             *CRDApiHandler[types.PlaylistDoc]
+	UserHandler                 *CRDApiHandler[types.UserDoc]
+	ApiKeyHandler               *CRDApiHandler[types.ApiKeyDoc]
+	PlaylistM3UHandler          *ApiHandler
 	InfoHandler                 *ApiHandler
+	StatsHandler                *ApiHandler
 	LoginHandler                *ApiHandler
 	SessionHandler              *ApiHandler
+	LogoutHandler               *ApiHandler
+	EventsHandler               *ApiHandler
+	WatchProgressHandler        *ApiHandler
+	ContinueWatchingHandler     *ApiHandler
 	RandomMediaHandler          *ApiHandler
+	MediaArchiveHandler         *ApiHandler
+	MediaExportHandler          *ApiHandler
+	MediaAssetHandlers          []*ApiHandler
+	MediaBulkHandler            *ApiHandler
+	MediaImportHandler          *ApiHandler
 	StashVTTRedirectorHandler   *ApiHandler
 	StashCoverRedirectorHandler *ApiHandler
+	StashMappingHandler         *ApiHandler
+	StashScraperHandler         *StashScraperHandler
+	UploadHandler               *UploadHandler
+	SubtitleHandler             *SubtitleHandler
+	DavHandler                  *DavHandler
+	HealthHandler               *HealthHandler
 }
 
-func RegisterRoutes(r *gin.Engine, streamHandler *Streamhandler, hndlrs HandlerContainer, apiToken string, swag bool) {
-	webRoot := r.Group("/", errMiddleware())
+func RegisterRoutes(r *gin.Engine, streamHandler *Streamhandler, hndlrs HandlerContainer, authenticator auth.IAuthenticator, streamAuth bool, swag bool) {
+	webRoot := r.Group("/", requestIDMiddleware(), errMiddleware())
 	if swag {
 		docs.SwaggerInfo.Title = "Tgmon API"
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
-	webRoot.Match([]string{"HEAD", "GET"}, "/stream/:mediaID", streamHandler.Stream)
-	authMiddleware := apiAuthMiddleware(apiToken)
+	authMiddleware := func(access RouteAccess) gin.HandlerFunc {
+		return apiAuthMiddleware(authenticator, access)
+	}
+	streamMid := []gin.HandlerFunc{}
+	if streamAuth {
+		streamMid = append(streamMid, streamAuthMiddleware(authenticator, RouteAccess{Role: types.VIEWERUserRole, Scope: types.STREAMApiKeyScope, QueryToken: true}))
+	}
+	if hndlrs.HealthHandler != nil {
+		hndlrs.HealthHandler.RegisterRoutes(webRoot)
+	}
+	webRoot.Match([]string{"HEAD", "GET"}, "/stream/:mediaID", append(streamMid, streamHandler.Stream)...)
+	if hndlrs.DavHandler != nil {
+		hndlrs.DavHandler.RegisterRoutes(webRoot, authMiddleware)
+	}
 	apiRoot := webRoot.Group("api/")
 	hndlrs.MediaHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.JobReqHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.JobResHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.TagHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.PlaylistHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.UserHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.ApiKeyHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.PlaylistM3UHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.InfoHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.StatsHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.LoginHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.SessionHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.LogoutHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.EventsHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.WatchProgressHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.ContinueWatchingHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.RandomMediaHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.MediaArchiveHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.MediaExportHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.MediaBulkHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.MediaImportHandler.RegisterRoutes(apiRoot, authMiddleware)
+	if hndlrs.UploadHandler != nil {
+		hndlrs.UploadHandler.RegisterRoutes(apiRoot, authMiddleware)
+	}
+	for _, h := range hndlrs.MediaAssetHandlers {
+		h.RegisterRoutes(apiRoot, authMiddleware)
+	}
+	hndlrs.SubtitleHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.StashVTTRedirectorHandler.RegisterRoutes(apiRoot, authMiddleware)
 	hndlrs.StashCoverRedirectorHandler.RegisterRoutes(apiRoot, authMiddleware)
+	hndlrs.StashScraperHandler.RegisterRoutes(apiRoot, authMiddleware)
+	if hndlrs.StashMappingHandler != nil {
+		hndlrs.StashMappingHandler.RegisterRoutes(apiRoot, authMiddleware)
+	}
 }
@@ -0,0 +1,261 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stash"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// scraperSearchSize is the number of media returned by a scraper search.
+const scraperSearchSize = 25
+
+// scraperIDRe matches the media ID in urls of media (e.g. <public url>/api/media/<id>).
+var scraperIDRe = regexp.MustCompile(`[0-9a-f]{24}`)
+
+// scraperConfig is the Stash scraper definition of TGMon, formatted with the public url.
+const scraperConfig = `name: TGMon
+sceneByFragment:
+  action: scrapeJson
+  queryURL: "%[1]s/api/stash/scraper/scene?oshash={oshash}&filename={filename}&title={title}"
+  scraper: sceneScraper
+sceneByURL:
+  - action: scrapeJson
+    url:
+      - %[1]s/api/media/
+    queryURL: "{url}"
+    queryURLReplace:
+      url:
+        - regex: '^.*/api/media/([0-9a-f]{24}).*$'
+          with: "%[1]s/api/stash/scraper/scene?id=$1"
+    scraper: sceneScraper
+sceneByName:
+  action: scrapeJson
+  queryURL: "%[1]s/api/stash/scraper/search?name={}"
+  scraper: sceneSearch
+sceneByQueryFragment:
+  action: scrapeJson
+  queryURL: "{url}"
+  queryURLReplace:
+    url:
+      - regex: '^.*/api/media/([0-9a-f]{24}).*$'
+        with: "%[1]s/api/stash/scraper/scene?id=$1"
+  scraper: sceneScraper
+jsonScrapers:
+  sceneScraper:
+    scene:
+      Title: title
+      Details: details
+      Date: date
+      URL: url
+      Code: code
+      Image: image
+      Tags:
+        Name: tags.#.name
+  sceneSearch:
+    scene:
+      Title: "#.title"
+      Date: "#.date"
+      URL: "#.url"
+      Image: "#.image"
+driver:
+  headers:
+    - Key: Authorization
+      Value: Bearer <api key with the media:read scope>
+`
+
+// StashScraperHandler implements the Stash JSON scraper contract, so Stash can scrape the title, details, date, tags
+// and cover of scenes from TGMon: a scene by fragment (oshash, file name or title) or by url, and a search by name.
+// Urls of media are <PublicUrl>/api/media/<id>.
+type StashScraperHandler struct {
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	TagFacade   facade.IFacade[types.TagDoc]
+	PublicUrl   string
+	MinioClient minio.IMinioClient // presigns the cover urls Stash downloads; covers are omitted when nil
+	PresignTTL  time.Duration
+}
+
+// RegisterRoutes registers the scraper routes on the given router group. They require the viewer role or the
+// media:read scope, and accept the token in the query.
+func (h *StashScraperHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
+	read := authMiddleware(RouteAccess{Role: types.VIEWERUserRole, Scope: types.MEDIAREADApiKeyScope, QueryToken: true})
+	r.GET("stash/scraper/scene", read, h.Scene)
+	r.GET("stash/scraper/search", read, h.Search)
+	r.GET("stash/scraper/TGMon.yml", read, h.Config)
+}
+
+// @Summary	Scrape scene
+// @Description	Returns the metadata of a media for Stash. The media is looked up by ID, by the media ID in the url, by
+// @Description	oshash, by the media ID in the file name, then by exact name, of the given parameters.
+// @Tags		stash
+// @Produce	json
+// @Param		id			query		string	false	"Media ID"
+// @Param		url			query		string	false	"Url of the media"
+// @Param		oshash		query		string	false	"Oshash of the file"
+// @Param		filename	query		string	false	"Name of the file in the FUSE filesystem"
+// @Param		title		query		string	false	"Name of the media"
+// @Success	200			{object}	StashScrapedSceneType
+// @Failure	default		{object}	HttpErr
+// @Router		/api/stash/scraper/scene [get]
+// @Security	ApiKeyAuth
+func (h *StashScraperHandler) Scene(g *gin.Context) {
+	var req StashScraperReqType
+	if err := g.ShouldBindQuery(&req); err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	filters, err := h.getFilters(req)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	ctx := g.Request.Context()
+	var media *types.MediaFileDoc
+	for _, filter := range filters {
+		media, err = h.MediaFacade.GetCollection().Finder().Filter(filter).FindOne(ctx)
+		if err == nil {
+			break
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+			return
+		}
+	}
+	if media == nil {
+		g.Error(NewHttpError(fmt.Errorf("%w: no media matches the scene", facade.ErrNoDocumentsFound), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	scenes, err := h.toScenes(g, []*types.MediaFileDoc{media})
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, scenes[0])
+}
+
+// @Summary	Search scenes
+// @Description	Returns the metadata of the media whose name or file name contains name, for Stash.
+// @Tags		stash
+// @Produce	json
+// @Param		name	query		string	true	"Part of the name"
+// @Success	200		{array}		StashScrapedSceneType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/stash/scraper/search [get]
+// @Security	ApiKeyAuth
+func (h *StashScraperHandler) Search(g *gin.Context) {
+	name := strings.TrimSpace(g.Query("name"))
+	if name == "" {
+		g.Error(NewHttpError(errors.New("name is required"), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	pattern := bson.Regex{Pattern: regexp.QuoteMeta(name), Options: "i"}
+	filter := query.Or(
+		query.Eq(types.MediaFileDoc__NameField, pattern),
+		query.Eq(types.MediaFileDoc__FileNameField, pattern),
+	)
+	mediaList, err := h.MediaFacade.GetCollection().Finder().Filter(filter).
+		Sort(bson.D{{Key: "created_at", Value: -1}}).Limit(scraperSearchSize).Find(g.Request.Context())
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	scenes, err := h.toScenes(g, mediaList)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, scenes)
+}
+
+// @Summary	Scraper config
+// @Description	Returns the Stash scraper definition of TGMon, to be saved in the scrapers directory of Stash. The api
+// @Description	key in its headers is to be filled in.
+// @Tags		stash
+// @Produce	application/yaml
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/stash/scraper/TGMon.yml [get]
+// @Security	ApiKeyAuth
+func (h *StashScraperHandler) Config(g *gin.Context) {
+	g.Header("Content-Disposition", `attachment; filename="TGMon.yml"`)
+	g.Data(http.StatusOK, "application/yaml; charset=utf-8", []byte(fmt.Sprintf(scraperConfig, getBaseUrl(g, h.PublicUrl))))
+}
+
+// getFilters returns the filters the media of a scene fragment is looked up by, in order. Parameters that can not
+// identify a media (e.g. a file name without a media ID) are skipped.
+func (h *StashScraperHandler) getFilters(req StashScraperReqType) ([]bson.D, error) {
+	filters := []bson.D{}
+	if req.ID != "" {
+		id, err := bson.ObjectIDFromHex(req.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+		filters = append(filters, query.Id(id))
+	}
+	if matches := scraperIDRe.FindAllString(req.URL, -1); len(matches) > 0 {
+		if id, err := bson.ObjectIDFromHex(matches[len(matches)-1]); err == nil {
+			filters = append(filters, query.Id(id))
+		}
+	}
+	if req.OsHash != "" {
+		filters = append(filters, query.Eq(types.MediaFileDoc__OsHashField, req.OsHash))
+	}
+	if id, ok := stash.MediaIDFromFilename(req.Filename); ok {
+		filters = append(filters, query.Id(id))
+	}
+	if req.Title != "" {
+		filters = append(filters, query.Eq(types.MediaFileDoc__NameField, req.Title))
+	}
+	if len(filters) == 0 {
+		return nil, errors.New("id, url, oshash, filename or title is required")
+	}
+	return filters, nil
+}
+
+// toScenes returns the scraped scenes of media.
+func (h *StashScraperHandler) toScenes(g *gin.Context, mediaList []*types.MediaFileDoc) ([]StashScrapedSceneType, error) {
+	tagIDs := []bson.ObjectID{}
+	for _, m := range mediaList {
+		tagIDs = append(tagIDs, m.Tags...)
+	}
+	tagNames := map[bson.ObjectID]string{}
+	if len(tagIDs) > 0 {
+		tags, err := h.TagFacade.GetCollection().Finder().Filter(query.In("_id", tagIDs...)).Find(g.Request.Context())
+		if err != nil {
+			return nil, fmt.Errorf("error finding tags: %w", err)
+		}
+		for _, t := range tags {
+			tagNames[t.ID] = t.Name
+		}
+	}
+	baseUrl := getBaseUrl(g, h.PublicUrl)
+	scenes := make([]StashScrapedSceneType, 0, len(mediaList))
+	for _, m := range mediaList {
+		scene := StashScrapedSceneType{
+			Title:   m.DisplayName(),
+			Details: m.Description,
+			Date:    m.CreatedAt.Format("2006-01-02"),
+			URL:     fmt.Sprintf("%s/api/media/%s", baseUrl, m.ID.Hex()),
+			Code:    m.ID.Hex(),
+			Tags:    []StashScrapedTagType{},
+		}
+		scene.Image = presignThumbnail(g.Request.Context(), h.MinioClient, h.PresignTTL, m)
+		for _, id := range m.Tags {
+			if name, ok := tagNames[id]; ok {
+				scene.Tags = append(scene.Tags, StashScrapedTagType{Name: name})
+			}
+		}
+		scenes = append(scenes, scene)
+	}
+	return scenes, nil
+}
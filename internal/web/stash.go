@@ -0,0 +1,58 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/amirdaaee/TGMon/internal/stash"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// StashMappingApiHandler rebuilds the mapping of Stash scenes to media from the scenes under LibraryPath, the path
+// Stash sees the FUSE filesystem at.
+type StashMappingApiHandler struct {
+	Mapper      *stash.SceneMapper
+	LibraryPath string
+}
+
+var _ IPostApiHandler = (*StashMappingApiHandler)(nil)
+var _ IRoleApiHandler = (*StashMappingApiHandler)(nil)
+
+// @Summary	Rebuild Stash mapping
+// @Description	Lists the Stash scenes under the library path and maps each to its media, by oshash fingerprint, stored
+// @Description	scene ID or the media ID in the file name. With Hash, the oshash of media that do not have one is
+// @Description	computed first, reading the head and tail of each file from Telegram.
+// @Tags		stash
+// @Accept		json
+// @Produce	json
+// @Param		data	body		StashMappingReqType	false	"Rebuild options"
+// @Success	200		{object}	stash.MappingReport
+// @Failure	default	{object}	HttpErr
+// @Router		/api/stash/mapping/ [post]
+// @Security	ApiKeyAuth
+func (h *StashMappingApiHandler) Post(g *gin.Context) {
+	var req StashMappingReqType
+	if err := g.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	report, err := h.Mapper.Rebuild(g.Request.Context(), h.LibraryPath, req.Hash)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, report)
+}
+func (h *StashMappingApiHandler) AuthPost() bool {
+	return true
+}
+func (h *StashMappingApiHandler) RelativePathPost() string {
+	return "/"
+}
+
+// RequiredRole restricts rebuilding the mapping to admins.
+func (h *StashMappingApiHandler) RequiredRole(method string) types.UserRoleEnum {
+	return types.ADMINUserRole
+}
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// statsDays and statsWeeks are how far back media added per day and per week are reported.
+	statsDays  = 30
+	statsWeeks = 12
+	// staleJobClaim is how long a claimed job can go without a result before it is counted as failed.
+	staleJobClaim = time.Hour
+)
+
+// sizeBuckets are the upper bounds (exclusive) of the file size breakdown; larger files fall in the last bucket.
+var sizeBuckets = []struct {
+	Name  string
+	Below int64
+}{
+	{"<100MiB", 100 << 20},
+	{"100MiB-500MiB", 500 << 20},
+	{"500MiB-1GiB", 1 << 30},
+	{"1GiB-2GiB", 2 << 30},
+	{"2GiB-4GiB", 4 << 30},
+}
+
+const largestSizeBucket = ">=4GiB"
+
+// StatsApiHandler reports library statistics. Aggregations over the library are cached for CacheTTL, so large
+// libraries are not scanned on every request; worker traffic is always live.
+type StatsApiHandler struct {
+	MediaFacade  facade.IFacade[types.MediaFileDoc]
+	JobReqFacade facade.IFacade[types.JobReqDoc]
+	StreamPool   stream.IWorkerPool
+	CacheTTL     time.Duration
+
+	mu       sync.Mutex
+	cached   *StatsGetResType
+	cachedAt time.Time
+}
+
+var _ IGetApiHandler = (*StatsApiHandler)(nil)
+var _ IScopeApiHandler = (*StatsApiHandler)(nil)
+
+// @Summary	Library statistics
+// @Description	Totals and breakdowns of the media library, job queue counts and per-worker traffic.
+// @Description	Library figures are cached, for 30 seconds by default (HTTP__STATS_CACHE_TTL).
+// @Produce	json
+// @Success	200	{object}	StatsGetResType
+// @Failure	default	{object}	HttpErr
+// @Router		/api/stats/ [get]
+// @Security	ApiKeyAuth
+func (h *StatsApiHandler) Get(g *gin.Context) {
+	res, err := h.getStats(g.Request.Context())
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	res.Workers = h.StreamPool.Traffic()
+	g.JSON(http.StatusOK, res)
+}
+func (h *StatsApiHandler) AuthGet() bool {
+	return true
+}
+func (h *StatsApiHandler) RelativePathGet() string {
+	return "/"
+}
+func (h *StatsApiHandler) RequiredScope(method string) types.ApiKeyScopeEnum {
+	return types.MEDIAREADApiKeyScope
+}
+
+// getStats returns a copy of the cached library statistics, computing them when the cache is stale.
+func (h *StatsApiHandler) getStats(ctx context.Context) (StatsGetResType, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cached != nil && time.Since(h.cachedAt) < h.CacheTTL {
+		return *h.cached, nil
+	}
+	now := time.Now()
+	res := StatsGetResType{GeneratedAt: now}
+	if err := h.fillMediaStats(ctx, &res, now); err != nil {
+		return res, fmt.Errorf("can not aggregate media: %w", err)
+	}
+	if err := h.fillJobStats(ctx, &res, now); err != nil {
+		return res, fmt.Errorf("can not aggregate jobs: %w", err)
+	}
+	h.cached, h.cachedAt = &res, now
+	return res, nil
+}
+
+// mediaStatsFacet is the result of the media statistics pipeline.
+type mediaStatsFacet struct {
+	Totals []struct {
+		Count    int64   `bson:"count"`
+		Bytes    int64   `bson:"bytes"`
+		Duration float64 `bson:"duration"`
+	} `bson:"totals"`
+	MimeTypes        []statsGroup `bson:"mimeTypes"`
+	Sizes            []statsGroup `bson:"sizes"`
+	PerDay           []statsGroup `bson:"perDay"`
+	PerWeek          []statsGroup `bson:"perWeek"`
+	MissingThumbnail []statsGroup `bson:"missingThumbnail"`
+	MissingSprite    []statsGroup `bson:"missingSprite"`
+}
+type statsGroup struct {
+	ID    string `bson:"_id"`
+	Count int64  `bson:"count"`
+	Bytes int64  `bson:"bytes"`
+}
+
+// fillMediaStats computes all media figures in a single $facet aggregation.
+func (h *StatsApiHandler) fillMediaStats(ctx context.Context, res *StatsGetResType, now time.Time) error {
+	countBytes := bson.D{
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		{Key: "bytes", Value: bson.D{{Key: "$sum", Value: "$Meta.FileSize"}}},
+	}
+	groupBy := func(id any) bson.D {
+		return bson.D{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: id}}, countBytes...)}}
+	}
+	addedSince := func(since time.Time, format string) bson.A {
+		return bson.A{
+			bson.D{{Key: "$match", Value: bson.D{{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}}}}},
+			groupBy(bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: format}, {Key: "date", Value: "$created_at"}}}}),
+		}
+	}
+	missing := func(field string) bson.A {
+		return bson.A{
+			bson.D{{Key: "$match", Value: bson.D{{Key: field, Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}}}}},
+			groupBy(nil),
+		}
+	}
+	sizeBranches := bson.A{}
+	for _, b := range sizeBuckets {
+		sizeBranches = append(sizeBranches, bson.D{
+			{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$Meta.FileSize", b.Below}}}},
+			{Key: "then", Value: b.Name},
+		})
+	}
+	day := now.UTC().Truncate(24 * time.Hour)
+	pipeline := bson.A{bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "totals", Value: bson.A{bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "bytes", Value: bson.D{{Key: "$sum", Value: "$Meta.FileSize"}}},
+			{Key: "duration", Value: bson.D{{Key: "$sum", Value: "$Meta.Duration"}}},
+		}}}}},
+		{Key: "mimeTypes", Value: bson.A{groupBy("$Meta.MimeType")}},
+		{Key: "sizes", Value: bson.A{groupBy(bson.D{{Key: "$switch", Value: bson.D{
+			{Key: "branches", Value: sizeBranches},
+			{Key: "default", Value: largestSizeBucket},
+		}}})}},
+		{Key: "perDay", Value: addedSince(day.AddDate(0, 0, -(statsDays-1)), "%Y-%m-%d")},
+		{Key: "perWeek", Value: addedSince(day.AddDate(0, 0, -7*(statsWeeks-1)-isoWeekday(day)+1), "%G-W%V")},
+		{Key: "missingThumbnail", Value: missing(types.MediaFileDoc__ThumbnailField)},
+		{Key: "missingSprite", Value: missing(types.MediaFileDoc__SpriteField)},
+	}}}}
+	var facets []mediaStatsFacet
+	if err := h.MediaFacade.GetCollection().Aggregator().Pipeline(pipeline).AggregateWithParse(ctx, &facets); err != nil {
+		return err
+	}
+	if len(facets) == 0 {
+		return nil
+	}
+	f := facets[0]
+	if len(f.Totals) > 0 {
+		res.MediaCount, res.TotalBytes, res.TotalDuration = f.Totals[0].Count, f.Totals[0].Bytes, f.Totals[0].Duration
+	}
+	res.MimeTypes = toStatsBuckets(f.MimeTypes)
+	sort.SliceStable(res.MimeTypes, func(i, j int) bool {
+		return res.MimeTypes[i].Count > res.MimeTypes[j].Count
+	})
+	sizes := map[string]statsGroup{}
+	for _, s := range f.Sizes {
+		sizes[s.ID] = s
+	}
+	res.SizeBuckets = make([]StatsBucketType, 0, len(sizeBuckets)+1)
+	for _, b := range sizeBuckets {
+		res.SizeBuckets = append(res.SizeBuckets, StatsBucketType{Name: b.Name, Count: sizes[b.Name].Count, Bytes: sizes[b.Name].Bytes})
+	}
+	res.SizeBuckets = append(res.SizeBuckets, StatsBucketType{Name: largestSizeBucket, Count: sizes[largestSizeBucket].Count, Bytes: sizes[largestSizeBucket].Bytes})
+	res.AddedPerDay = toStatsBuckets(f.PerDay)
+	res.AddedPerWeek = toStatsBuckets(f.PerWeek)
+	for _, v := range [][]StatsBucketType{res.AddedPerDay, res.AddedPerWeek} {
+		sort.Slice(v, func(i, j int) bool {
+			return v[i].Name < v[j].Name
+		})
+	}
+	if len(f.MissingThumbnail) > 0 {
+		res.MissingThumbnail = f.MissingThumbnail[0].Count
+	}
+	if len(f.MissingSprite) > 0 {
+		res.MissingSprite = f.MissingSprite[0].Count
+	}
+	return nil
+}
+
+// fillJobStats counts pending jobs, and claimed jobs by whether their claim went stale without a result.
+func (h *StatsApiHandler) fillJobStats(ctx context.Context, res *StatsGetResType, now time.Time) error {
+	staleBefore := now.Add(-staleJobClaim)
+	pipeline := bson.A{bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$switch", Value: bson.D{
+			{Key: "branches", Value: bson.A{
+				bson.D{
+					{Key: "case", Value: bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$" + types.JobReqDoc__ClaimedAtField, nil}}}, nil}}}},
+					{Key: "then", Value: "pending"},
+				},
+				bson.D{
+					{Key: "case", Value: bson.D{{Key: "$lt", Value: bson.A{"$" + types.JobReqDoc__ClaimedAtField, staleBefore}}}},
+					{Key: "then", Value: "failed"},
+				},
+			}},
+			{Key: "default", Value: "claimed"},
+		}}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}}
+	var groups []statsGroup
+	if err := h.JobReqFacade.GetCollection().Aggregator().Pipeline(pipeline).AggregateWithParse(ctx, &groups); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		switch g.ID {
+		case "pending":
+			res.PendingJobs = g.Count
+		case "claimed":
+			res.ClaimedJobs = g.Count
+		case "failed":
+			res.FailedJobs = g.Count
+		}
+	}
+	return nil
+}
+
+func toStatsBuckets(groups []statsGroup) []StatsBucketType {
+	res := make([]StatsBucketType, len(groups))
+	for i, g := range groups {
+		res[i] = StatsBucketType{Name: g.ID, Count: g.Count, Bytes: g.Bytes}
+	}
+	return res
+}
+
+// isoWeekday returns the ISO 8601 weekday of t, from 1 (Monday) to 7 (Sunday).
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
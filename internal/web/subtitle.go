@@ -0,0 +1,165 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/amirdaaee/TGMon/internal/db/minio"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/chenmingyong0423/go-mongox/v2/builder/query"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// subtitleContentTypes are the content types subtitles are served with, by format.
+var subtitleContentTypes = map[types.SubtitleFormatEnum]string{
+	types.VTTSubtitleFormat: "text/vtt; charset=utf-8",
+	types.ASSSubtitleFormat: "text/x-ssa; charset=utf-8",
+}
+
+// SubtitleHandler attaches subtitle tracks to media and serves them from minio. Tracks are listed in the Subtitles
+// of the media.
+type SubtitleHandler struct {
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	MinioClient minio.IMinioClient
+}
+
+// RegisterRoutes registers the subtitle routes on the given router group. Reading subtitles requires the viewer role
+// or the media:read scope, and accepts the token in the query for track elements; changing them requires the editor
+// role or the media:write scope.
+func (h *SubtitleHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
+	read := authMiddleware(RouteAccess{Role: types.VIEWERUserRole, Scope: types.MEDIAREADApiKeyScope, QueryToken: true})
+	write := authMiddleware(RouteAccess{Role: types.EDITORUserRole, Scope: types.MEDIAWRITEApiKeyScope})
+	r.POST("media/:id/subtitles", write, h.Post)
+	r.GET("media/:id/subtitles/:subtitle", read, h.Get)
+	r.DELETE("media/:id/subtitles/:subtitle", write, h.Delete)
+}
+
+// @Summary	Add subtitle
+// @Description	Attaches a srt, vtt or ass file to the media as the track of Language, replacing the track of that
+// @Description	language if there is one. Srt files are converted to vtt.
+// @Tags		media
+// @Accept		multipart/form-data
+// @Produce	json
+// @Param		id			path		string	true	"Media ID"
+// @Param		Language	formData	string	true	"Language label (e.g. en)"
+// @Param		file		formData	file	true	"Subtitle file"
+// @Success	200	{object}	types.MediaSubtitle
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/subtitles [post]
+// @Security	ApiKeyAuth
+func (h *SubtitleHandler) Post(g *gin.Context) {
+	mediaID, ok := bindObjectIDParam(g, "id")
+	if !ok {
+		return
+	}
+	g.Request.Body = http.MaxBytesReader(g.Writer, g.Request.Body, facade.SubtitleMaxSize+int64(uploadFieldLimit)*4)
+	fh, err := g.FormFile("file")
+	if err != nil {
+		g.Error(NewHttpError(fmt.Errorf("file is required: %w", err), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	sub, err := facade.AddSubtitle(g.Request.Context(), h.MediaFacade, h.MinioClient, mediaID, g.PostForm("Language"), fh.Filename, data)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.JSON(http.StatusOK, sub)
+}
+
+// @Summary	Get subtitle
+// @Tags		media
+// @Produce	text/vtt
+// @Produce	text/x-ssa
+// @Param		id			path	string	true	"Media ID"
+// @Param		subtitle	path	string	true	"Subtitle ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/subtitles/{subtitle} [get]
+// @Security	ApiKeyAuth
+func (h *SubtitleHandler) Get(g *gin.Context) {
+	mediaID, ok := bindObjectIDParam(g, "id")
+	if !ok {
+		return
+	}
+	subID, ok := bindObjectIDParam(g, "subtitle")
+	if !ok {
+		return
+	}
+	ctx := g.Request.Context()
+	media, err := h.MediaFacade.GetCollection().Finder().Filter(query.Id(mediaID)).FindOne(ctx)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	sub := media.Subtitle(subID)
+	if sub == nil {
+		g.Error(NewHttpError(errors.New("media has no such subtitle"), http.StatusNotFound)) //nolint:golint,errcheck
+		return
+	}
+	info, err := h.MinioClient.FileStat(ctx, sub.File)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	r, err := h.MinioClient.FileGet(ctx, sub.File)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	defer r.Close()
+	g.Header("Content-Type", subtitleContentTypes[sub.Format])
+	g.Header("Cache-Control", assetCacheControl)
+	if info.ETag != "" {
+		g.Header("ETag", fmt.Sprintf("%q", info.ETag))
+	}
+	http.ServeContent(g.Writer, g.Request, "", info.LastModified, r)
+}
+
+// @Summary	Delete subtitle
+// @Tags		media
+// @Param		id			path	string	true	"Media ID"
+// @Param		subtitle	path	string	true	"Subtitle ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/{id}/subtitles/{subtitle} [delete]
+// @Security	ApiKeyAuth
+func (h *SubtitleHandler) Delete(g *gin.Context) {
+	mediaID, ok := bindObjectIDParam(g, "id")
+	if !ok {
+		return
+	}
+	subID, ok := bindObjectIDParam(g, "subtitle")
+	if !ok {
+		return
+	}
+	if err := facade.RemoveSubtitle(g.Request.Context(), h.MediaFacade, h.MinioClient, mediaID, subID); err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.Status(http.StatusOK)
+}
+
+// bindObjectIDParam parses the path parameter name as an ObjectID, writing a bad request error if it is not one.
+func bindObjectIDParam(g *gin.Context, name string) (bson.ObjectID, bool) {
+	id, err := bson.ObjectIDFromHex(g.Param(name))
+	if err != nil {
+		g.Error(NewHttpError(fmt.Errorf("invalid %s: %w", name, err), http.StatusBadRequest)) //nolint:golint,errcheck
+		return bson.ObjectID{}, false
+	}
+	return id, true
+}
@@ -1,6 +1,11 @@
 package web
 
 import (
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/events"
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/stream"
 	"github.com/amirdaaee/TGMon/internal/types"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -36,7 +41,51 @@ type MediaReadResType struct {
 	NextID *bson.ObjectID `json:"nextID"`
 }
 type MediaListReqType struct {
-	Page int `form:"page"`
+	Page    int      `form:"page"`
+	Tags    []string `form:"tag"`
+	Watched *bool    `form:"watched"`
+}
+type MediaArchiveReqType struct {
+	IDs     []string // media to archive, in order; when empty, the media matching Tags and Watched
+	Tags    []string
+	Watched *bool
+	Name    string // name of the zip file, without extension
+}
+type MediaBulkReqType struct {
+	Action  MediaBulkActionEnum `binding:"required" enums:"DELETE,TAG,UNTAG,REQUEST_JOB"`
+	IDs     []string            // media to apply to; when empty, the media matching Tags and Watched
+	Tags    []string
+	Watched *bool
+	TagIDs  []string          // tags to add or remove, for TAG and UNTAG
+	JobType types.JobTypeEnum `enums:"THUMBNAIL,SPRITE"` // for REQUEST_JOB
+	DryRun  bool
+}
+type MediaBulkResType struct {
+	DryRun  bool
+	Total   int
+	Done    int
+	Skipped int
+	Failed  int
+	Items   []facade.BulkItemResult
+}
+type MediaImportReqType struct {
+	Links      []string // t.me/c/<channel>/<message> links to messages of the storage channel
+	MessageIDs []int
+	From       int // first message ID of a range
+	To         int // last message ID of a range, inclusive
+}
+type MediaImportResType struct {
+	Total   int
+	Done    int
+	Skipped int
+	Failed  int
+	Items   []facade.ImportItemResult
+}
+type MediaUpdateReqType struct {
+	Name        *string
+	Description *string
+	Fields      map[string]string // replaces all custom fields of the media
+	Tags        []string          // replaces all tags of the media
 }
 type MediaDelReqType struct {
 	ID string `uri:"id" binding:"required"`
@@ -51,11 +100,113 @@ type JobReqDelReqType struct {
 	ID string `uri:"id" binding:"required"`
 }
 type JobReqListResType []*types.JobReqDoc
+type JobReqUpdateReqType struct {
+	Claimed bool
+}
+
+// ===
+type TagCreateReqType struct {
+	Name string `binding:"required"`
+}
+type TagUpdateReqType struct {
+	Name *string
+}
+type TagReadReqType struct {
+	ID string `uri:"id" binding:"required"`
+}
+type TagListResType []*types.TagDoc
+
+// ===
+type PlaylistCreateReqType struct {
+	Name        string `binding:"required"`
+	Description string
+	Items       []string
+}
+type PlaylistUpdateReqType struct {
+	Name        *string
+	Description *string
+	Items       []string // replaces all items of the playlist
+}
+type PlaylistReadReqType struct {
+	ID string `uri:"id" binding:"required"`
+}
+type PlaylistListResType []*types.PlaylistDoc
+
+// ===
+type UserCreateReqType struct {
+	Username string             `binding:"required"`
+	Password string             `binding:"required"`
+	Role     types.UserRoleEnum `binding:"required"`
+}
+type UserUpdateReqType struct {
+	Role     *types.UserRoleEnum
+	Password *string
+}
+type UserReadReqType struct {
+	ID string `uri:"id" binding:"required"`
+}
+type UserListResType []*types.UserDoc
+
+// ===
+type ApiKeyCreateReqType struct {
+	Name      string                  `binding:"required"`
+	Scopes    []types.ApiKeyScopeEnum `binding:"required"`
+	ExpiresAt *time.Time
+}
+type ApiKeyCreateResType struct {
+	*types.ApiKeyDoc
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+type ApiKeyReadReqType struct {
+	ID string `uri:"id" binding:"required"`
+}
+type ApiKeyListResType []*types.ApiKeyDoc
+
+// ===
+type ProgressPutReqType struct {
+	Position *float64 // seconds
+	Watched  *bool
+}
+type ContinueWatchingItemType struct {
+	Media    *types.MediaFileDoc
+	Progress *types.WatchProgressDoc
+}
+type ContinueWatchingGetResType struct {
+	Items []ContinueWatchingItemType
+}
+
+// ===
+type EventsGetReqType struct {
+	Types       []events.EventTypeEnum `form:"type"`
+	LastEventID uint64                 `form:"lastEventId"`
+}
 
 // ===
 type InfoGetResType struct {
 	MediaCount int64
 }
+type StatsGetResType struct {
+	MediaCount       int64
+	TotalBytes       int64
+	TotalDuration    float64           // seconds
+	MimeTypes        []StatsBucketType // most common first
+	SizeBuckets      []StatsBucketType
+	AddedPerDay      []StatsBucketType // last 30 days, named YYYY-MM-DD (UTC); days without media are omitted
+	AddedPerWeek     []StatsBucketType // last 12 ISO weeks, named YYYY-Www
+	MissingThumbnail int64
+	MissingSprite    int64
+	PendingJobs      int64
+	ClaimedJobs      int64
+	FailedJobs       int64 // claimed over an hour ago without a result
+	Workers          []stream.WorkerTraffic
+	GeneratedAt      time.Time
+}
+type StatsBucketType struct {
+	Name  string
+	Count int64
+	Bytes int64
+}
 
 // ===
 type LoginPostReqType struct {
@@ -63,10 +214,36 @@ type LoginPostReqType struct {
 	Password string `binding:"required"`
 }
 type LoginPostResType struct {
-	Token string
+	Token     string
+	ExpiresAt *time.Time // nil for the static api token
+	User      *types.UserDoc
 }
 
 // ===
 type RandomMediaGetResType struct {
 	MediaID *bson.ObjectID
 }
+type StashMappingReqType struct {
+	Hash bool // compute the oshash of media that do not have one first
+}
+type StashScraperReqType struct {
+	ID       string `form:"id"`
+	URL      string `form:"url"`
+	OsHash   string `form:"oshash"`
+	Filename string `form:"filename"`
+	Title    string `form:"title"`
+}
+
+// StashScrapedSceneType is a scene in the json the Stash scraper of TGMon reads.
+type StashScrapedSceneType struct {
+	Title   string                `json:"title"`
+	Details string                `json:"details"`
+	Date    string                `json:"date"`
+	URL     string                `json:"url"`
+	Code    string                `json:"code"`
+	Image   string                `json:"image,omitempty"`
+	Tags    []StashScrapedTagType `json:"tags"`
+}
+type StashScrapedTagType struct {
+	Name string `json:"name"`
+}
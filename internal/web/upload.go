@@ -0,0 +1,589 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/amirdaaee/TGMon/internal/facade"
+	"github.com/amirdaaee/TGMon/internal/log"
+	"github.com/amirdaaee/TGMon/internal/stream"
+	"github.com/amirdaaee/TGMon/internal/types"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	tusVersion = "1.0.0"
+	// tusExtensions are the tus extensions the resumable uploads support.
+	tusExtensions = "creation,termination"
+	// uploadSweepInterval is how often expired resumable uploads are removed.
+	uploadSweepInterval = time.Minute
+	// uploadFileExt is the extension of the files of resumable uploads in the upload dir.
+	uploadFileExt = ".upload"
+	// uploadFieldLimit caps the size of the form fields sent along with a multipart upload.
+	uploadFieldLimit = 4096
+)
+
+// uploadMetaType describes an uploaded file. It is read from the form fields of multipart uploads, and from the
+// Upload-Metadata header of resumable ones.
+type uploadMetaType struct {
+	FileName string
+	MimeType string
+	Duration float64
+	Width    int
+	Height   int
+	Tags     []string
+}
+
+// set sets a field of the metadata by its (case-insensitive) name. Unknown names are ignored.
+func (m *uploadMetaType) set(key string, value string) error {
+	var err error
+	switch strings.ToLower(key) {
+	case "filename":
+		m.FileName = value
+	case "filetype", "mimetype":
+		m.MimeType = value
+	case "duration":
+		m.Duration, err = strconv.ParseFloat(value, 64)
+	case "width":
+		m.Width, err = strconv.Atoi(value)
+	case "height":
+		m.Height, err = strconv.Atoi(value)
+	case "tags":
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				m.Tags = append(m.Tags, t)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return nil
+}
+
+// parseTusMetadata parses an Upload-Metadata header: comma separated pairs of a key and its base64 encoded value.
+func parseTusMetadata(header string) (uploadMetaType, error) {
+	meta := uploadMetaType{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return meta, fmt.Errorf("invalid value of %s: %w", key, err)
+		}
+		if err := meta.set(key, string(value)); err != nil {
+			return meta, err
+		}
+	}
+	return meta, nil
+}
+
+// uploadSession is a resumable upload, written to a file of the upload dir until it is complete.
+type uploadSession struct {
+	mu        sync.Mutex // held while a chunk is written or the file is sent to telegram
+	path      string
+	meta      uploadMetaType
+	length    int64
+	offset    atomic.Int64
+	createdAt time.Time
+	msgID     int    // message of the file once it is sent to telegram, so a retry only creates the media
+	owner     string // principal which created the upload, the only one it is visible to (see uploadOwner)
+}
+
+// UploadHandler adds media by uploading files to the storage channel through a worker, then creating their media
+// like the bot does for forwarded messages.
+//
+// Files are either sent in a single multipart request, streamed to telegram as they are received, or with the
+// resumable tus protocol (https://tus.io, core and creation and termination extensions), where chunks are written
+// to Dir and the file is sent once complete. Resumable uploads are kept in memory: they do not survive restarts,
+// and expire after SessionTTL (see Run).
+type UploadHandler struct {
+	WorkerPool  stream.IWorkerPool
+	MediaFacade facade.IFacade[types.MediaFileDoc]
+	TagFacade   facade.IFacade[types.TagDoc]
+	Dir         string
+	MaxSize     int64
+	SessionTTL  time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*uploadSession
+}
+
+// RegisterRoutes registers the upload routes on the given router group. Uploading requires the editor role or the
+// media:write scope.
+func (h *UploadHandler) RegisterRoutes(r *gin.RouterGroup, authMiddleware AuthMiddlewareFactory) {
+	auth := authMiddleware(RouteAccess{Role: types.EDITORUserRole, Scope: types.MEDIAWRITEApiKeyScope})
+	r.POST("media/upload", auth, h.Post)
+	// discovery is not authenticated, like CORS preflight requests
+	r.OPTIONS("media/upload/files", h.Options)
+	r.OPTIONS("media/upload/files/:id", h.Options)
+	r.POST("media/upload/files", auth, h.Create)
+	r.HEAD("media/upload/files/:id", auth, h.Head)
+	r.PATCH("media/upload/files/:id", auth, h.Patch)
+	r.DELETE("media/upload/files/:id", auth, h.Delete)
+}
+
+// @Summary	Upload media
+// @Description	Uploads a file to the storage channel and adds it as a media. The file is streamed to telegram while it is
+// @Description	received, so the form fields must come before the file. Duration (seconds), Width and Height are sent as
+// @Description	video attributes of video files; Tags are names of tags to add to the media, created when missing.
+// @Tags		media
+// @Accept		multipart/form-data
+// @Produce	json
+// @Param		FileName	formData	string	false	"File name (defaults to the name of the file)"
+// @Param		MimeType	formData	string	false	"Mime type (defaults to the content type of the file)"
+// @Param		Duration	formData	number	false	"Duration in seconds"
+// @Param		Width		formData	int		false	"Video width"
+// @Param		Height		formData	int		false	"Video height"
+// @Param		Tags		formData	string	false	"Comma separated tag names"
+// @Param		file		formData	file	true	"File"
+// @Success	200	{object}	types.MediaFileDoc
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/upload [post]
+// @Security	ApiKeyAuth
+func (h *UploadHandler) Post(g *gin.Context) {
+	g.Request.Body = http.MaxBytesReader(g.Writer, g.Request.Body, h.MaxSize+int64(uploadFieldLimit)*16)
+	mr, err := g.Request.MultipartReader()
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	meta := uploadMetaType{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			g.Error(NewHttpError(errors.New("file is required"), http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		if err != nil {
+			g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, uploadFieldLimit))
+			if err == nil {
+				err = meta.set(part.FormName(), string(value))
+			}
+			if err != nil {
+				g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+				return
+			}
+			continue
+		}
+		if meta.FileName == "" {
+			meta.FileName = part.FileName()
+		}
+		if meta.MimeType == "" {
+			meta.MimeType = part.Header.Get("Content-Type")
+		}
+		if meta.FileName == "" {
+			g.Error(NewHttpError(errors.New("file name is required"), http.StatusBadRequest)) //nolint:golint,errcheck
+			return
+		}
+		media, err := h.upload(g.Request.Context(), meta, part, -1)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				g.Error(NewHttpError(fmt.Errorf("file is larger than %d bytes", h.MaxSize), http.StatusRequestEntityTooLarge)) //nolint:golint,errcheck
+				return
+			}
+			g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+			return
+		}
+		g.JSON(http.StatusOK, media)
+		return
+	}
+}
+
+// @Summary	Get the capabilities of resumable uploads
+// @Description	Returns the tus versions and extensions supported by the server, and the maximum size of uploads, in the
+// @Description	Tus-Version, Tus-Extension and Tus-Max-Size headers.
+// @Tags		media
+// @Success	204
+// @Router		/api/media/upload/files [options]
+func (h *UploadHandler) Options(g *gin.Context) {
+	g.Header("Tus-Resumable", tusVersion)
+	g.Header("Tus-Version", tusVersion)
+	g.Header("Tus-Extension", tusExtensions)
+	g.Header("Tus-Max-Size", strconv.FormatInt(h.MaxSize, 10))
+	g.Status(http.StatusNoContent)
+}
+
+// @Summary	Create a resumable upload
+// @Description	Creates a tus upload of Upload-Length bytes. Upload-Metadata takes the same fields as the multipart upload
+// @Description	(filename is required, filetype is the mime type). The upload url is returned in the Location header. Uploads
+// @Description	are only visible to the user who created them (and to their api keys).
+// @Tags		media
+// @Param		Upload-Length	header	int		true	"Size of the file in bytes"
+// @Param		Upload-Metadata	header	string	true	"Comma separated keys and base64 encoded values"
+// @Success	201
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/upload/files [post]
+// @Security	ApiKeyAuth
+func (h *UploadHandler) Create(g *gin.Context) {
+	ll := h.getLogger("Create")
+	g.Header("Tus-Resumable", tusVersion)
+	length, err := strconv.ParseInt(g.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		g.Error(NewHttpError(errors.New("invalid Upload-Length"), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	if length > h.MaxSize {
+		g.Error(NewHttpError(fmt.Errorf("file is larger than %d bytes", h.MaxSize), http.StatusRequestEntityTooLarge)) //nolint:golint,errcheck
+		return
+	}
+	meta, err := parseTusMetadata(g.GetHeader("Upload-Metadata"))
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	if meta.FileName == "" {
+		g.Error(NewHttpError(errors.New("filename metadata is required"), http.StatusBadRequest)) //nolint:golint,errcheck
+		return
+	}
+	id, s, err := h.newSession(meta, length, uploadOwner(g))
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	ll.Infof("upload %s created: %s (%d bytes)", id, meta.FileName, length)
+	g.Header("Location", strings.TrimSuffix(g.Request.URL.Path, "/")+"/"+id)
+	g.Header("Upload-Offset", strconv.FormatInt(s.offset.Load(), 10))
+	g.Status(http.StatusCreated)
+}
+
+// @Summary	Get the offset of a resumable upload
+// @Tags		media
+// @Param		id	path	string	true	"Upload ID"
+// @Success	200
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/upload/files/{id} [head]
+// @Security	ApiKeyAuth
+func (h *UploadHandler) Head(g *gin.Context) {
+	g.Header("Tus-Resumable", tusVersion)
+	s, ok := h.getSession(g)
+	if !ok {
+		return
+	}
+	g.Header("Upload-Offset", strconv.FormatInt(s.offset.Load(), 10))
+	g.Header("Upload-Length", strconv.FormatInt(s.length, 10))
+	g.Header("Cache-Control", "no-store")
+	g.Status(http.StatusOK)
+}
+
+// @Summary	Upload a chunk of a resumable upload
+// @Description	Appends the body at Upload-Offset, which must be the current offset of the upload. Once all bytes are
+// @Description	received, the file is sent to telegram and the created media is returned. If sending the file or creating
+// @Description	its media fails, a PATCH at the end of the upload (with an empty body) retries it; a file already sent is
+// @Description	not sent again.
+// @Tags		media
+// @Accept		application/offset+octet-stream
+// @Produce	json
+// @Param		id				path	string	true	"Upload ID"
+// @Param		Upload-Offset	header	int		true	"Offset of the chunk"
+// @Success	200	{object}	types.MediaFileDoc
+// @Success	204
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/upload/files/{id} [patch]
+// @Security	ApiKeyAuth
+func (h *UploadHandler) Patch(g *gin.Context) {
+	ll := h.getLogger("Patch")
+	g.Header("Tus-Resumable", tusVersion)
+	if g.ContentType() != "application/offset+octet-stream" {
+		g.Error(NewHttpError(errors.New("content type must be application/offset+octet-stream"), http.StatusUnsupportedMediaType)) //nolint:golint,errcheck
+		return
+	}
+	s, ok := h.getSession(g)
+	if !ok {
+		return
+	}
+	if !s.mu.TryLock() {
+		g.Error(NewHttpError(errors.New("upload is in progress"), http.StatusConflict)) //nolint:golint,errcheck
+		return
+	}
+	defer s.mu.Unlock()
+	offset, err := strconv.ParseInt(g.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset != s.offset.Load() {
+		g.Error(NewHttpError(fmt.Errorf("Upload-Offset must be %d", s.offset.Load()), http.StatusConflict)) //nolint:golint,errcheck
+		return
+	}
+	if err := h.writeChunk(s, g.Request.Body); err != nil {
+		g.Header("Upload-Offset", strconv.FormatInt(s.offset.Load(), 10))
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	g.Header("Upload-Offset", strconv.FormatInt(s.offset.Load(), 10))
+	if s.offset.Load() < s.length {
+		g.Status(http.StatusNoContent)
+		return
+	}
+	ctx := g.Request.Context()
+	if s.msgID == 0 {
+		msgID, err := h.sendFile(ctx, s)
+		if err != nil {
+			g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+			return
+		}
+		s.msgID = msgID
+	}
+	media, err := h.createMedia(ctx, s.meta, s.msgID)
+	if err != nil {
+		g.Error(NewHttpError(err, http.StatusInternalServerError)) //nolint:golint,errcheck
+		return
+	}
+	h.removeSession(g.Param("id"), s)
+	ll.Infof("upload %s complete: media %s", g.Param("id"), media.ID.Hex())
+	g.JSON(http.StatusOK, media)
+}
+
+// @Summary	Cancel a resumable upload
+// @Tags		media
+// @Param		id	path	string	true	"Upload ID"
+// @Success	204
+// @Failure	default	{object}	HttpErr
+// @Router		/api/media/upload/files/{id} [delete]
+// @Security	ApiKeyAuth
+func (h *UploadHandler) Delete(g *gin.Context) {
+	g.Header("Tus-Resumable", tusVersion)
+	s, ok := h.getSession(g)
+	if !ok {
+		return
+	}
+	if !s.mu.TryLock() {
+		g.Error(NewHttpError(errors.New("upload is in progress"), http.StatusConflict)) //nolint:golint,errcheck
+		return
+	}
+	defer s.mu.Unlock()
+	h.removeSession(g.Param("id"), s)
+	g.Status(http.StatusNoContent)
+}
+
+// upload sends a file to the channel, then creates its media.
+func (h *UploadHandler) upload(ctx context.Context, meta uploadMetaType, r io.Reader, size int64) (*types.MediaFileDoc, error) {
+	msgID, err := h.send(ctx, meta, r, size)
+	if err != nil {
+		return nil, err
+	}
+	return h.createMedia(ctx, meta, msgID)
+}
+
+// sendFile sends the file of a complete resumable upload to the channel.
+func (h *UploadHandler) sendFile(ctx context.Context, s *uploadSession) (int, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return h.send(ctx, s.meta, f, s.length)
+}
+
+// send sends a file to the channel through a worker, and returns the ID of its message.
+func (h *UploadHandler) send(ctx context.Context, meta uploadMetaType, r io.Reader, size int64) (int, error) {
+	worker := h.WorkerPool.GetNextWorker()
+	if worker == nil {
+		return 0, stream.ErrNoWorker
+	}
+	msgID, err := worker.UploadDoc(ctx, stream.DocUpload{
+		Name:     meta.FileName,
+		MimeType: meta.MimeType,
+		Size:     size,
+		Reader:   r,
+		Duration: meta.Duration,
+		Width:    meta.Width,
+		Height:   meta.Height,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("can not upload file to channel: %w", err)
+	}
+	return msgID, nil
+}
+
+// createMedia creates the media of an uploaded message, tagged with the tags of meta.
+func (h *UploadHandler) createMedia(ctx context.Context, meta uploadMetaType, msgID int) (*types.MediaFileDoc, error) {
+	ll := h.getLogger("createMedia")
+	worker := h.WorkerPool.GetNextWorker()
+	if worker == nil {
+		return nil, stream.ErrNoWorker
+	}
+	doc, err := worker.GetDoc(ctx, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("can not get document of uploaded message: %w", err)
+	}
+	media, err := types.NewMediaFileDoc(doc, msgID)
+	if err != nil {
+		return nil, fmt.Errorf("can not fill document meta: %w", err)
+	}
+	if len(meta.Tags) > 0 {
+		tagIDs, err := facade.EnsureTags(ctx, h.TagFacade, meta.Tags)
+		if err != nil {
+			ll.WithError(err).Error("can not tag media file doc")
+		} else {
+			media.Tags = tagIDs
+		}
+	}
+	return h.MediaFacade.CreateOne(ctx, &media)
+}
+
+// writeChunk appends a chunk to the file of an upload, up to its length. The bytes written before a failure are
+// kept, so the client can resume from the new offset.
+func (h *UploadHandler) writeChunk(s *uploadSession, r io.Reader) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset.Load(), io.SeekStart); err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, s.length-s.offset.Load()))
+	s.offset.Add(n)
+	if err != nil {
+		return fmt.Errorf("can not write chunk: %w", err)
+	}
+	return nil
+}
+
+// newSession creates a resumable upload of owner and its file.
+func (h *UploadHandler) newSession(meta uploadMetaType, length int64, owner string) (string, *uploadSession, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(b)
+	s := &uploadSession{
+		path:      filepath.Join(h.Dir, id+uploadFileExt),
+		meta:      meta,
+		length:    length,
+		createdAt: time.Now(),
+		owner:     owner,
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", nil, fmt.Errorf("can not create upload file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions[id] = s
+	return id, s, nil
+}
+
+// getSession returns the upload of the request. It reports the error and returns false when it does not exist or
+// belongs to another principal, so upload IDs of others can not be probed.
+func (h *UploadHandler) getSession(g *gin.Context) (*uploadSession, bool) {
+	h.mu.Lock()
+	s, ok := h.sessions[g.Param("id")]
+	h.mu.Unlock()
+	if ok && s.owner != uploadOwner(g) {
+		h.getLogger("getSession").Warnf("upload %s requested by another principal", g.Param("id"))
+		s, ok = nil, false
+	}
+	if !ok {
+		g.Error(NewHttpError(fmt.Errorf("upload (%s) not found", g.Param("id")), http.StatusNotFound)) //nolint:golint,errcheck
+	}
+	return s, ok
+}
+
+// uploadOwner identifies the principal of the request as the owner of resumable uploads: the user it acts as (api
+// keys act as their creator), or else the api key itself. The legacy static token is the empty owner.
+func uploadOwner(g *gin.Context) string {
+	if id, err := getPrincipalUserID(g); err == nil {
+		return id.Hex()
+	}
+	if p := getPrincipal(g); p != nil && p.ApiKey != nil {
+		return "apikey:" + p.ApiKey.ID.Hex()
+	}
+	return ""
+}
+
+// removeSession forgets an upload and removes its file. The caller holds the lock of the upload.
+func (h *UploadHandler) removeSession(id string, s *uploadSession) {
+	h.mu.Lock()
+	delete(h.sessions, id)
+	h.mu.Unlock()
+	if err := os.Remove(s.path); err != nil {
+		h.getLogger("removeSession").WithError(err).Warnf("can not remove file of upload %s", id)
+	}
+}
+
+// Run removes expired resumable uploads until ctx is done.
+func (h *UploadHandler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			h.sweep()
+		}
+	}
+}
+
+// sweep removes the uploads older than the session ttl, unless a chunk of them is being received.
+func (h *UploadHandler) sweep() {
+	h.mu.Lock()
+	expired := map[string]*uploadSession{}
+	for id, s := range h.sessions {
+		if time.Since(s.createdAt) > h.SessionTTL {
+			expired[id] = s
+		}
+	}
+	h.mu.Unlock()
+	for id, s := range expired {
+		if !s.mu.TryLock() {
+			continue
+		}
+		h.getLogger("sweep").Infof("upload %s expired", id)
+		h.removeSession(id, s)
+		s.mu.Unlock()
+	}
+}
+func (h *UploadHandler) getLogger(fn string) *logrus.Entry {
+	return log.GetLogger(log.WebModule).WithField("func", fmt.Sprintf("%T.%s", h, fn))
+}
+
+// NewUploadHandler creates an upload handler keeping resumable uploads in dir. Files left in dir by a previous run
+// are removed, as their uploads can not be resumed.
+func NewUploadHandler(wp stream.IWorkerPool, mediaFacade facade.IFacade[types.MediaFileDoc], tagFacade facade.IFacade[types.TagDoc], dir string, maxSize int64, sessionTTL time.Duration) (*UploadHandler, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can not create upload dir: %w", err)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*"+uploadFileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range leftovers {
+		if err := os.Remove(f); err != nil {
+			return nil, fmt.Errorf("can not remove stale upload: %w", err)
+		}
+	}
+	return &UploadHandler{
+		WorkerPool:  wp,
+		MediaFacade: mediaFacade,
+		TagFacade:   tagFacade,
+		Dir:         dir,
+		MaxSize:     maxSize,
+		SessionTTL:  sessionTTL,
+		sessions:    map[string]*uploadSession{},
+	}, nil
+}
@@ -0,0 +1,201 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: auth.go
+//
+// Generated by this command:
+//
+//	mockgen -source=auth.go -destination=../../mocks/auth/auth.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	url "net/url"
+	reflect "reflect"
+
+	auth "github.com/amirdaaee/TGMon/internal/auth"
+	types "github.com/amirdaaee/TGMon/internal/types"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIStreamSigner is a mock of IStreamSigner interface.
+type MockIStreamSigner struct {
+	ctrl     *gomock.Controller
+	recorder *MockIStreamSignerMockRecorder
+	isgomock struct{}
+}
+
+// MockIStreamSignerMockRecorder is the mock recorder for MockIStreamSigner.
+type MockIStreamSignerMockRecorder struct {
+	mock *MockIStreamSigner
+}
+
+// NewMockIStreamSigner creates a new mock instance.
+func NewMockIStreamSigner(ctrl *gomock.Controller) *MockIStreamSigner {
+	mock := &MockIStreamSigner{ctrl: ctrl}
+	mock.recorder = &MockIStreamSignerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIStreamSigner) EXPECT() *MockIStreamSignerMockRecorder {
+	return m.recorder
+}
+
+// SignStream mocks base method.
+func (m *MockIStreamSigner) SignStream(mediaID bson.ObjectID) url.Values {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignStream", mediaID)
+	ret0, _ := ret[0].(url.Values)
+	return ret0
+}
+
+// SignStream indicates an expected call of SignStream.
+func (mr *MockIStreamSignerMockRecorder) SignStream(mediaID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignStream", reflect.TypeOf((*MockIStreamSigner)(nil).SignStream), mediaID)
+}
+
+// VerifyStream mocks base method.
+func (m *MockIStreamSigner) VerifyStream(mediaID bson.ObjectID, expires, signature string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyStream", mediaID, expires, signature)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyStream indicates an expected call of VerifyStream.
+func (mr *MockIStreamSignerMockRecorder) VerifyStream(mediaID, expires, signature any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyStream", reflect.TypeOf((*MockIStreamSigner)(nil).VerifyStream), mediaID, expires, signature)
+}
+
+// MockIAuthenticator is a mock of IAuthenticator interface.
+type MockIAuthenticator struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAuthenticatorMockRecorder
+	isgomock struct{}
+}
+
+// MockIAuthenticatorMockRecorder is the mock recorder for MockIAuthenticator.
+type MockIAuthenticatorMockRecorder struct {
+	mock *MockIAuthenticator
+}
+
+// NewMockIAuthenticator creates a new mock instance.
+func NewMockIAuthenticator(ctrl *gomock.Controller) *MockIAuthenticator {
+	mock := &MockIAuthenticator{ctrl: ctrl}
+	mock.recorder = &MockIAuthenticatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAuthenticator) EXPECT() *MockIAuthenticatorMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockIAuthenticator) Authenticate(ctx context.Context, token string) (*auth.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, token)
+	ret0, _ := ret[0].(*auth.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockIAuthenticatorMockRecorder) Authenticate(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockIAuthenticator)(nil).Authenticate), ctx, token)
+}
+
+// AuthenticateS3 mocks base method.
+func (m *MockIAuthenticator) AuthenticateS3(ctx context.Context, accessKeyID string) (*auth.Principal, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateS3", ctx, accessKeyID)
+	ret0, _ := ret[0].(*auth.Principal)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AuthenticateS3 indicates an expected call of AuthenticateS3.
+func (mr *MockIAuthenticatorMockRecorder) AuthenticateS3(ctx, accessKeyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateS3", reflect.TypeOf((*MockIAuthenticator)(nil).AuthenticateS3), ctx, accessKeyID)
+}
+
+// Login mocks base method.
+func (m *MockIAuthenticator) Login(ctx context.Context, username, password string) (string, *auth.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, username, password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*auth.Principal)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockIAuthenticatorMockRecorder) Login(ctx, username, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockIAuthenticator)(nil).Login), ctx, username, password)
+}
+
+// Logout mocks base method.
+func (m *MockIAuthenticator) Logout(ctx context.Context, p *auth.Principal) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockIAuthenticatorMockRecorder) Logout(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockIAuthenticator)(nil).Logout), ctx, p)
+}
+
+// S3Credentials mocks base method.
+func (m *MockIAuthenticator) S3Credentials(key *types.ApiKeyDoc) (string, string) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "S3Credentials", key)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	return ret0, ret1
+}
+
+// S3Credentials indicates an expected call of S3Credentials.
+func (mr *MockIAuthenticatorMockRecorder) S3Credentials(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "S3Credentials", reflect.TypeOf((*MockIAuthenticator)(nil).S3Credentials), key)
+}
+
+// SignStream mocks base method.
+func (m *MockIAuthenticator) SignStream(mediaID bson.ObjectID) url.Values {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SignStream", mediaID)
+	ret0, _ := ret[0].(url.Values)
+	return ret0
+}
+
+// SignStream indicates an expected call of SignStream.
+func (mr *MockIAuthenticatorMockRecorder) SignStream(mediaID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignStream", reflect.TypeOf((*MockIAuthenticator)(nil).SignStream), mediaID)
+}
+
+// VerifyStream mocks base method.
+func (m *MockIAuthenticator) VerifyStream(mediaID bson.ObjectID, expires, signature string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyStream", mediaID, expires, signature)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyStream indicates an expected call of VerifyStream.
+func (mr *MockIAuthenticatorMockRecorder) VerifyStream(mediaID, expires, signature any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyStream", reflect.TypeOf((*MockIAuthenticator)(nil).VerifyStream), mediaID, expires, signature)
+}
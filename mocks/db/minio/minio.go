@@ -12,7 +12,9 @@ package mocks
 import (
 	context "context"
 	io "io"
+	url "net/url"
 	reflect "reflect"
+	time "time"
 
 	minio "github.com/minio/minio-go/v7"
 	gomock "go.uber.org/mock/gomock"
@@ -57,6 +59,50 @@ func (mr *MockIMinioClMockRecorder) BucketExists(ctx, bucketName any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BucketExists", reflect.TypeOf((*MockIMinioCl)(nil).BucketExists), ctx, bucketName)
 }
 
+// CopyObject mocks base method.
+func (m *MockIMinioCl) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CopyObject", ctx, dst, src)
+	ret0, _ := ret[0].(minio.UploadInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CopyObject indicates an expected call of CopyObject.
+func (mr *MockIMinioClMockRecorder) CopyObject(ctx, dst, src any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyObject", reflect.TypeOf((*MockIMinioCl)(nil).CopyObject), ctx, dst, src)
+}
+
+// GetObject mocks base method.
+func (m *MockIMinioCl) GetObject(ctx context.Context, bucketName, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObject", ctx, bucketName, objectName, opts)
+	ret0, _ := ret[0].(*minio.Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObject indicates an expected call of GetObject.
+func (mr *MockIMinioClMockRecorder) GetObject(ctx, bucketName, objectName, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObject", reflect.TypeOf((*MockIMinioCl)(nil).GetObject), ctx, bucketName, objectName, opts)
+}
+
+// ListObjects mocks base method.
+func (m *MockIMinioCl) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListObjects", ctx, bucketName, opts)
+	ret0, _ := ret[0].(<-chan minio.ObjectInfo)
+	return ret0
+}
+
+// ListObjects indicates an expected call of ListObjects.
+func (mr *MockIMinioClMockRecorder) ListObjects(ctx, bucketName, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListObjects", reflect.TypeOf((*MockIMinioCl)(nil).ListObjects), ctx, bucketName, opts)
+}
+
 // MakeBucket mocks base method.
 func (m *MockIMinioCl) MakeBucket(ctx context.Context, bucketName string, opts minio.MakeBucketOptions) error {
 	m.ctrl.T.Helper()
@@ -71,6 +117,21 @@ func (mr *MockIMinioClMockRecorder) MakeBucket(ctx, bucketName, opts any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MakeBucket", reflect.TypeOf((*MockIMinioCl)(nil).MakeBucket), ctx, bucketName, opts)
 }
 
+// PresignedGetObject mocks base method.
+func (m *MockIMinioCl) PresignedGetObject(ctx context.Context, bucketName, objectName string, expires time.Duration, reqParams url.Values) (*url.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PresignedGetObject", ctx, bucketName, objectName, expires, reqParams)
+	ret0, _ := ret[0].(*url.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PresignedGetObject indicates an expected call of PresignedGetObject.
+func (mr *MockIMinioClMockRecorder) PresignedGetObject(ctx, bucketName, objectName, expires, reqParams any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PresignedGetObject", reflect.TypeOf((*MockIMinioCl)(nil).PresignedGetObject), ctx, bucketName, objectName, expires, reqParams)
+}
+
 // PutObject mocks base method.
 func (m *MockIMinioCl) PutObject(ctx context.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
 	m.ctrl.T.Helper()
@@ -100,6 +161,21 @@ func (mr *MockIMinioClMockRecorder) RemoveObject(ctx, bucketName, objectName, op
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveObject", reflect.TypeOf((*MockIMinioCl)(nil).RemoveObject), ctx, bucketName, objectName, opts)
 }
 
+// StatObject mocks base method.
+func (m *MockIMinioCl) StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatObject", ctx, bucketName, objectName, opts)
+	ret0, _ := ret[0].(minio.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StatObject indicates an expected call of StatObject.
+func (mr *MockIMinioClMockRecorder) StatObject(ctx, bucketName, objectName, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatObject", reflect.TypeOf((*MockIMinioCl)(nil).StatObject), ctx, bucketName, objectName, opts)
+}
+
 // MockIMinioClient is a mock of IMinioClient interface.
 type MockIMinioClient struct {
 	ctrl     *gomock.Controller
@@ -166,6 +242,50 @@ func (mr *MockIMinioClientMockRecorder) FileAddStr(ctx, fileName, data any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileAddStr", reflect.TypeOf((*MockIMinioClient)(nil).FileAddStr), ctx, fileName, data)
 }
 
+// FileGet mocks base method.
+func (m *MockIMinioClient) FileGet(ctx context.Context, fileName string) (io.ReadSeekCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileGet", ctx, fileName)
+	ret0, _ := ret[0].(io.ReadSeekCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileGet indicates an expected call of FileGet.
+func (mr *MockIMinioClientMockRecorder) FileGet(ctx, fileName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileGet", reflect.TypeOf((*MockIMinioClient)(nil).FileGet), ctx, fileName)
+}
+
+// FileList mocks base method.
+func (m *MockIMinioClient) FileList(ctx context.Context, prefix string) ([]minio.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileList", ctx, prefix)
+	ret0, _ := ret[0].([]minio.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileList indicates an expected call of FileList.
+func (mr *MockIMinioClientMockRecorder) FileList(ctx, prefix any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileList", reflect.TypeOf((*MockIMinioClient)(nil).FileList), ctx, prefix)
+}
+
+// FileMove mocks base method.
+func (m *MockIMinioClient) FileMove(ctx context.Context, fileName, newName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileMove", ctx, fileName, newName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FileMove indicates an expected call of FileMove.
+func (mr *MockIMinioClientMockRecorder) FileMove(ctx, fileName, newName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileMove", reflect.TypeOf((*MockIMinioClient)(nil).FileMove), ctx, fileName, newName)
+}
+
 // FileRm mocks base method.
 func (m *MockIMinioClient) FileRm(ctx context.Context, fileName string) error {
 	m.ctrl.T.Helper()
@@ -179,3 +299,47 @@ func (mr *MockIMinioClientMockRecorder) FileRm(ctx, fileName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileRm", reflect.TypeOf((*MockIMinioClient)(nil).FileRm), ctx, fileName)
 }
+
+// FileStat mocks base method.
+func (m *MockIMinioClient) FileStat(ctx context.Context, fileName string) (minio.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FileStat", ctx, fileName)
+	ret0, _ := ret[0].(minio.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FileStat indicates an expected call of FileStat.
+func (mr *MockIMinioClientMockRecorder) FileStat(ctx, fileName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileStat", reflect.TypeOf((*MockIMinioClient)(nil).FileStat), ctx, fileName)
+}
+
+// Ping mocks base method.
+func (m *MockIMinioClient) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockIMinioClientMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIMinioClient)(nil).Ping), ctx)
+}
+
+// PresignedGet mocks base method.
+func (m *MockIMinioClient) PresignedGet(ctx context.Context, fileName string, expires time.Duration) (*url.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PresignedGet", ctx, fileName, expires)
+	ret0, _ := ret[0].(*url.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PresignedGet indicates an expected call of PresignedGet.
+func (mr *MockIMinioClientMockRecorder) PresignedGet(ctx, fileName, expires any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PresignedGet", reflect.TypeOf((*MockIMinioClient)(nil).PresignedGet), ctx, fileName, expires)
+}
@@ -41,6 +41,20 @@ func (m *MockIMongoContainer) EXPECT() *MockIMongoContainerMockRecorder {
 	return m.recorder
 }
 
+// GetApiKeyCollection mocks base method.
+func (m *MockIMongoContainer) GetApiKeyCollection() mongo.ICollection[types.ApiKeyDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetApiKeyCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.ApiKeyDoc])
+	return ret0
+}
+
+// GetApiKeyCollection indicates an expected call of GetApiKeyCollection.
+func (mr *MockIMongoContainerMockRecorder) GetApiKeyCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApiKeyCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetApiKeyCollection))
+}
+
 // GetJobReqCollection mocks base method.
 func (m *MockIMongoContainer) GetJobReqCollection() mongo.ICollection[types.JobReqDoc] {
 	m.ctrl.T.Helper()
@@ -110,3 +124,73 @@ func (mr *MockIMongoContainerMockRecorder) GetMongoDb() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMongoDb", reflect.TypeOf((*MockIMongoContainer)(nil).GetMongoDb))
 }
+
+// GetPlaylistCollection mocks base method.
+func (m *MockIMongoContainer) GetPlaylistCollection() mongo.ICollection[types.PlaylistDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlaylistCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.PlaylistDoc])
+	return ret0
+}
+
+// GetPlaylistCollection indicates an expected call of GetPlaylistCollection.
+func (mr *MockIMongoContainerMockRecorder) GetPlaylistCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlaylistCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetPlaylistCollection))
+}
+
+// GetSessionCollection mocks base method.
+func (m *MockIMongoContainer) GetSessionCollection() mongo.ICollection[types.SessionDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.SessionDoc])
+	return ret0
+}
+
+// GetSessionCollection indicates an expected call of GetSessionCollection.
+func (mr *MockIMongoContainerMockRecorder) GetSessionCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetSessionCollection))
+}
+
+// GetTagCollection mocks base method.
+func (m *MockIMongoContainer) GetTagCollection() mongo.ICollection[types.TagDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTagCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.TagDoc])
+	return ret0
+}
+
+// GetTagCollection indicates an expected call of GetTagCollection.
+func (mr *MockIMongoContainerMockRecorder) GetTagCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTagCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetTagCollection))
+}
+
+// GetUserCollection mocks base method.
+func (m *MockIMongoContainer) GetUserCollection() mongo.ICollection[types.UserDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.UserDoc])
+	return ret0
+}
+
+// GetUserCollection indicates an expected call of GetUserCollection.
+func (mr *MockIMongoContainerMockRecorder) GetUserCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetUserCollection))
+}
+
+// GetWatchProgressCollection mocks base method.
+func (m *MockIMongoContainer) GetWatchProgressCollection() mongo.ICollection[types.WatchProgressDoc] {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWatchProgressCollection")
+	ret0, _ := ret[0].(mongo.ICollection[types.WatchProgressDoc])
+	return ret0
+}
+
+// GetWatchProgressCollection indicates an expected call of GetWatchProgressCollection.
+func (mr *MockIMongoContainerMockRecorder) GetWatchProgressCollection() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWatchProgressCollection", reflect.TypeOf((*MockIMongoContainer)(nil).GetWatchProgressCollection))
+}
@@ -69,6 +69,20 @@ func (mr *MockIMongoClientMockRecorder) NewDatabase(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewDatabase", reflect.TypeOf((*MockIMongoClient)(nil).NewDatabase), arg0)
 }
 
+// Ping mocks base method.
+func (m *MockIMongoClient) Ping(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockIMongoClientMockRecorder) Ping(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockIMongoClient)(nil).Ping), arg0)
+}
+
 // MockIDatabase is a mock of IDatabase interface.
 type MockIDatabase struct {
 	ctrl     *gomock.Controller
@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: bus.go
+//
+// Generated by this command:
+//
+//	mockgen -source=bus.go -destination=../../mocks/events/bus.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	events "github.com/amirdaaee/TGMon/internal/events"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIBus is a mock of IBus interface.
+type MockIBus struct {
+	ctrl     *gomock.Controller
+	recorder *MockIBusMockRecorder
+	isgomock struct{}
+}
+
+// MockIBusMockRecorder is the mock recorder for MockIBus.
+type MockIBusMockRecorder struct {
+	mock *MockIBus
+}
+
+// NewMockIBus creates a new mock instance.
+func NewMockIBus(ctrl *gomock.Controller) *MockIBus {
+	mock := &MockIBus{ctrl: ctrl}
+	mock.recorder = &MockIBusMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIBus) EXPECT() *MockIBusMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockIBus) Publish(typ events.EventTypeEnum, data any) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Publish", typ, data)
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockIBusMockRecorder) Publish(typ, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockIBus)(nil).Publish), typ, data)
+}
+
+// Subscribe mocks base method.
+func (m *MockIBus) Subscribe(lastID uint64) ([]events.Event, <-chan events.Event, func()) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", lastID)
+	ret0, _ := ret[0].([]events.Event)
+	ret1, _ := ret[1].(<-chan events.Event)
+	ret2, _ := ret[2].(func())
+	return ret0, ret1, ret2
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockIBusMockRecorder) Subscribe(lastID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockIBus)(nil).Subscribe), lastID)
+}
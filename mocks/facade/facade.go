@@ -85,6 +85,20 @@ func (mr *MockICrudMockRecorder[T]) PostDelete(ctx, doc any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostDelete", reflect.TypeOf((*MockICrud[T])(nil).PostDelete), ctx, doc)
 }
 
+// PostUpdate mocks base method.
+func (m *MockICrud[T]) PostUpdate(ctx context.Context, doc *T) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostUpdate", ctx, doc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PostUpdate indicates an expected call of PostUpdate.
+func (mr *MockICrudMockRecorder[T]) PostUpdate(ctx, doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostUpdate", reflect.TypeOf((*MockICrud[T])(nil).PostUpdate), ctx, doc)
+}
+
 // PreCreate mocks base method.
 func (m *MockICrud[T]) PreCreate(ctx context.Context, doc *T) error {
 	m.ctrl.T.Helper()
@@ -113,6 +127,20 @@ func (mr *MockICrudMockRecorder[T]) PreDelete(ctx, doc any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreDelete", reflect.TypeOf((*MockICrud[T])(nil).PreDelete), ctx, doc)
 }
 
+// PreUpdate mocks base method.
+func (m *MockICrud[T]) PreUpdate(ctx context.Context, doc *T, fields bson.D) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PreUpdate", ctx, doc, fields)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PreUpdate indicates an expected call of PreUpdate.
+func (mr *MockICrudMockRecorder[T]) PreUpdate(ctx, doc, fields any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PreUpdate", reflect.TypeOf((*MockICrud[T])(nil).PreUpdate), ctx, doc, fields)
+}
+
 // MockIFacade is a mock of IFacade interface.
 type MockIFacade[T any] struct {
 	ctrl     *gomock.Controller
@@ -194,3 +222,90 @@ func (mr *MockIFacadeMockRecorder[T]) GetCollection() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCollection", reflect.TypeOf((*MockIFacade[T])(nil).GetCollection))
 }
+
+// UpdateOne mocks base method.
+func (m *MockIFacade[T]) UpdateOne(ctx context.Context, filter, fields bson.D) (*T, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOne", ctx, filter, fields)
+	ret0, _ := ret[0].(*T)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateOne indicates an expected call of UpdateOne.
+func (mr *MockIFacadeMockRecorder[T]) UpdateOne(ctx, filter, fields any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOne", reflect.TypeOf((*MockIFacade[T])(nil).UpdateOne), ctx, filter, fields)
+}
+
+// MockIFacadeObserver is a mock of IFacadeObserver interface.
+type MockIFacadeObserver[T any] struct {
+	ctrl     *gomock.Controller
+	recorder *MockIFacadeObserverMockRecorder[T]
+	isgomock struct{}
+}
+
+// MockIFacadeObserverMockRecorder is the mock recorder for MockIFacadeObserver.
+type MockIFacadeObserverMockRecorder[T any] struct {
+	mock *MockIFacadeObserver[T]
+}
+
+// NewMockIFacadeObserver creates a new mock instance.
+func NewMockIFacadeObserver[T any](ctrl *gomock.Controller) *MockIFacadeObserver[T] {
+	mock := &MockIFacadeObserver[T]{ctrl: ctrl}
+	mock.recorder = &MockIFacadeObserverMockRecorder[T]{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIFacadeObserver[T]) EXPECT() *MockIFacadeObserverMockRecorder[T] {
+	return m.recorder
+}
+
+// OnCreateFailed mocks base method.
+func (m *MockIFacadeObserver[T]) OnCreateFailed(doc *T, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCreateFailed", doc, err)
+}
+
+// OnCreateFailed indicates an expected call of OnCreateFailed.
+func (mr *MockIFacadeObserverMockRecorder[T]) OnCreateFailed(doc, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCreateFailed", reflect.TypeOf((*MockIFacadeObserver[T])(nil).OnCreateFailed), doc, err)
+}
+
+// OnCreated mocks base method.
+func (m *MockIFacadeObserver[T]) OnCreated(doc *T) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnCreated", doc)
+}
+
+// OnCreated indicates an expected call of OnCreated.
+func (mr *MockIFacadeObserverMockRecorder[T]) OnCreated(doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnCreated", reflect.TypeOf((*MockIFacadeObserver[T])(nil).OnCreated), doc)
+}
+
+// OnDeleted mocks base method.
+func (m *MockIFacadeObserver[T]) OnDeleted(doc *T) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnDeleted", doc)
+}
+
+// OnDeleted indicates an expected call of OnDeleted.
+func (mr *MockIFacadeObserverMockRecorder[T]) OnDeleted(doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnDeleted", reflect.TypeOf((*MockIFacadeObserver[T])(nil).OnDeleted), doc)
+}
+
+// OnUpdated mocks base method.
+func (m *MockIFacadeObserver[T]) OnUpdated(doc *T) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnUpdated", doc)
+}
+
+// OnUpdated indicates an expected call of OnUpdated.
+func (mr *MockIFacadeObserverMockRecorder[T]) OnUpdated(doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnUpdated", reflect.TypeOf((*MockIFacadeObserver[T])(nil).OnUpdated), doc)
+}
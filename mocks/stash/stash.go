@@ -0,0 +1,132 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: stash.go
+//
+// Generated by this command:
+//
+//	mockgen -source=stash.go -destination=../../mocks/stash/stash.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	stash "github.com/amirdaaee/TGMon/internal/stash"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIStashClient is a mock of IStashClient interface.
+type MockIStashClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockIStashClientMockRecorder
+	isgomock struct{}
+}
+
+// MockIStashClientMockRecorder is the mock recorder for MockIStashClient.
+type MockIStashClientMockRecorder struct {
+	mock *MockIStashClient
+}
+
+// NewMockIStashClient creates a new mock instance.
+func NewMockIStashClient(ctrl *gomock.Controller) *MockIStashClient {
+	mock := &MockIStashClient{ctrl: ctrl}
+	mock.recorder = &MockIStashClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIStashClient) EXPECT() *MockIStashClientMockRecorder {
+	return m.recorder
+}
+
+// FindOrCreateTag mocks base method.
+func (m *MockIStashClient) FindOrCreateTag(ctx context.Context, name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreateTag", ctx, name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreateTag indicates an expected call of FindOrCreateTag.
+func (mr *MockIStashClientMockRecorder) FindOrCreateTag(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateTag", reflect.TypeOf((*MockIStashClient)(nil).FindOrCreateTag), ctx, name)
+}
+
+// FindSceneByHash mocks base method.
+func (m *MockIStashClient) FindSceneByHash(ctx context.Context, hash string) (*stash.Scene, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSceneByHash", ctx, hash)
+	ret0, _ := ret[0].(*stash.Scene)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSceneByHash indicates an expected call of FindSceneByHash.
+func (mr *MockIStashClientMockRecorder) FindSceneByHash(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSceneByHash", reflect.TypeOf((*MockIStashClient)(nil).FindSceneByHash), ctx, hash)
+}
+
+// FindSceneById mocks base method.
+func (m *MockIStashClient) FindSceneById(ctx context.Context, id string) (*stash.Scene, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSceneById", ctx, id)
+	ret0, _ := ret[0].(*stash.Scene)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSceneById indicates an expected call of FindSceneById.
+func (mr *MockIStashClientMockRecorder) FindSceneById(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSceneById", reflect.TypeOf((*MockIStashClient)(nil).FindSceneById), ctx, id)
+}
+
+// FindScenesByPath mocks base method.
+func (m *MockIStashClient) FindScenesByPath(ctx context.Context, path string, page, perPage int) ([]stash.Scene, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindScenesByPath", ctx, path, page, perPage)
+	ret0, _ := ret[0].([]stash.Scene)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindScenesByPath indicates an expected call of FindScenesByPath.
+func (mr *MockIStashClientMockRecorder) FindScenesByPath(ctx, path, page, perPage any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindScenesByPath", reflect.TypeOf((*MockIStashClient)(nil).FindScenesByPath), ctx, path, page, perPage)
+}
+
+// MetadataScan mocks base method.
+func (m *MockIStashClient) MetadataScan(ctx context.Context, paths []string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MetadataScan", ctx, paths)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MetadataScan indicates an expected call of MetadataScan.
+func (mr *MockIStashClientMockRecorder) MetadataScan(ctx, paths any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MetadataScan", reflect.TypeOf((*MockIStashClient)(nil).MetadataScan), ctx, paths)
+}
+
+// SceneUpdate mocks base method.
+func (m *MockIStashClient) SceneUpdate(ctx context.Context, input stash.SceneUpdateInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SceneUpdate", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SceneUpdate indicates an expected call of SceneUpdate.
+func (mr *MockIStashClientMockRecorder) SceneUpdate(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SceneUpdate", reflect.TypeOf((*MockIStashClient)(nil).SceneUpdate), ctx, input)
+}
@@ -56,6 +56,18 @@ func (mr *MockIWorkerPoolMockRecorder) GetNextWorker() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNextWorker", reflect.TypeOf((*MockIWorkerPool)(nil).GetNextWorker))
 }
 
+// OnStateChange mocks base method.
+func (m *MockIWorkerPool) OnStateChange(l stream.WorkerStateListener) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnStateChange", l)
+}
+
+// OnStateChange indicates an expected call of OnStateChange.
+func (mr *MockIWorkerPoolMockRecorder) OnStateChange(l any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnStateChange", reflect.TypeOf((*MockIWorkerPool)(nil).OnStateChange), l)
+}
+
 // Stream mocks base method.
 func (m *MockIWorkerPool) Stream(ctx context.Context, msgID int, offset, end int64) (stream.IStreamer, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +83,20 @@ func (mr *MockIWorkerPoolMockRecorder) Stream(ctx, msgID, offset, end any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockIWorkerPool)(nil).Stream), ctx, msgID, offset, end)
 }
 
+// Traffic mocks base method.
+func (m *MockIWorkerPool) Traffic() []stream.WorkerTraffic {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Traffic")
+	ret0, _ := ret[0].([]stream.WorkerTraffic)
+	return ret0
+}
+
+// Traffic indicates an expected call of Traffic.
+func (mr *MockIWorkerPoolMockRecorder) Traffic() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Traffic", reflect.TypeOf((*MockIWorkerPool)(nil).Traffic))
+}
+
 // MockIStreamer is a mock of IStreamer interface.
 type MockIStreamer struct {
 	ctrl     *gomock.Controller
@@ -123,3 +149,75 @@ func (mr *MockIStreamerMockRecorder) Read(p any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockIStreamer)(nil).Read), p)
 }
+
+// MockworkerStateSetter is a mock of workerStateSetter interface.
+type MockworkerStateSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockworkerStateSetterMockRecorder
+	isgomock struct{}
+}
+
+// MockworkerStateSetterMockRecorder is the mock recorder for MockworkerStateSetter.
+type MockworkerStateSetterMockRecorder struct {
+	mock *MockworkerStateSetter
+}
+
+// NewMockworkerStateSetter creates a new mock instance.
+func NewMockworkerStateSetter(ctrl *gomock.Controller) *MockworkerStateSetter {
+	mock := &MockworkerStateSetter{ctrl: ctrl}
+	mock.recorder = &MockworkerStateSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockworkerStateSetter) EXPECT() *MockworkerStateSetterMockRecorder {
+	return m.recorder
+}
+
+// setWorkerState mocks base method.
+func (m *MockworkerStateSetter) setWorkerState(w stream.IWorker, state stream.WorkerStateEnum) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setWorkerState", w, state)
+}
+
+// setWorkerState indicates an expected call of setWorkerState.
+func (mr *MockworkerStateSetterMockRecorder) setWorkerState(w, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setWorkerState", reflect.TypeOf((*MockworkerStateSetter)(nil).setWorkerState), w, state)
+}
+
+// MockworkerTrafficRecorder is a mock of workerTrafficRecorder interface.
+type MockworkerTrafficRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockworkerTrafficRecorderMockRecorder
+	isgomock struct{}
+}
+
+// MockworkerTrafficRecorderMockRecorder is the mock recorder for MockworkerTrafficRecorder.
+type MockworkerTrafficRecorderMockRecorder struct {
+	mock *MockworkerTrafficRecorder
+}
+
+// NewMockworkerTrafficRecorder creates a new mock instance.
+func NewMockworkerTrafficRecorder(ctrl *gomock.Controller) *MockworkerTrafficRecorder {
+	mock := &MockworkerTrafficRecorder{ctrl: ctrl}
+	mock.recorder = &MockworkerTrafficRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockworkerTrafficRecorder) EXPECT() *MockworkerTrafficRecorderMockRecorder {
+	return m.recorder
+}
+
+// addWorkerTraffic mocks base method.
+func (m *MockworkerTrafficRecorder) addWorkerTraffic(w stream.IWorker, n int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "addWorkerTraffic", w, n)
+}
+
+// addWorkerTraffic indicates an expected call of addWorkerTraffic.
+func (mr *MockworkerTrafficRecorderMockRecorder) addWorkerTraffic(w, n any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "addWorkerTraffic", reflect.TypeOf((*MockworkerTrafficRecorder)(nil).addWorkerTraffic), w, n)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	stream "github.com/amirdaaee/TGMon/internal/stream"
 	downloader "github.com/amirdaaee/TGMon/internal/stream/downloader"
 	tg "github.com/gotd/td/tg"
 	gomock "go.uber.org/mock/gomock"
@@ -42,6 +43,21 @@ func (m *MockIWorker) EXPECT() *MockIWorkerMockRecorder {
 	return m.recorder
 }
 
+// GetChannelDocs mocks base method.
+func (m *MockIWorker) GetChannelDocs(ctx context.Context, messageIDs []int) (map[int]*tg.Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChannelDocs", ctx, messageIDs)
+	ret0, _ := ret[0].(map[int]*tg.Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelDocs indicates an expected call of GetChannelDocs.
+func (mr *MockIWorkerMockRecorder) GetChannelDocs(ctx, messageIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelDocs", reflect.TypeOf((*MockIWorker)(nil).GetChannelDocs), ctx, messageIDs)
+}
+
 // GetDoc mocks base method.
 func (m *MockIWorker) GetDoc(ctx context.Context, messageID int) (*tg.Document, error) {
 	m.ctrl.T.Helper()
@@ -86,3 +102,18 @@ func (mr *MockIWorkerMockRecorder) Stream(ctx, reader any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stream", reflect.TypeOf((*MockIWorker)(nil).Stream), ctx, reader)
 }
+
+// UploadDoc mocks base method.
+func (m *MockIWorker) UploadDoc(ctx context.Context, doc stream.DocUpload) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadDoc", ctx, doc)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadDoc indicates an expected call of UploadDoc.
+func (mr *MockIWorkerMockRecorder) UploadDoc(ctx, doc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadDoc", reflect.TypeOf((*MockIWorker)(nil).UploadDoc), ctx, doc)
+}